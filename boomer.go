@@ -0,0 +1,252 @@
+package hrp
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/httprunner/funplugin"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/httprunner/httprunner/v5/code"
+	"github.com/httprunner/httprunner/v5/internal/sdk"
+	"github.com/httprunner/httprunner/v5/pkg/boomer"
+)
+
+// NewBoomer returns a new HRPBoomer, which spawns spawnCount users at spawnRate users per second.
+func NewBoomer(spawnCount int, spawnRate float64) *HRPBoomer {
+	b := &HRPBoomer{
+		Boomer:    boomer.NewStandaloneBoomer(spawnCount, spawnRate),
+		hrpRunner: NewRunner(nil),
+	}
+	return b
+}
+
+// HRPBoomer runs testcases concurrently for load testing,
+// each spawned user runs testcase with its own session runner.
+type HRPBoomer struct {
+	*boomer.Boomer
+	hrpRunner *HRPRunner
+}
+
+// SetFailfast configures whether to stop running current session when one step fails.
+func (b *HRPBoomer) SetFailfast(failfast bool) *HRPBoomer {
+	b.hrpRunner.SetFailfast(failfast)
+	return b
+}
+
+// SetPython3Venv specifies python3 venv.
+func (b *HRPBoomer) SetPython3Venv(venv string) *HRPBoomer {
+	b.hrpRunner.SetPython3Venv(venv)
+	return b
+}
+
+// SetRequestsLogOn turns on request & response details logging.
+func (b *HRPBoomer) SetRequestsLogOn() *HRPBoomer {
+	b.hrpRunner.SetRequestsLogOn()
+	return b
+}
+
+// AddListeners registers listeners to observe load testing, case and step callbacks
+// are invoked for each session run by spawned users.
+func (b *HRPBoomer) AddListeners(listeners ...RunListener) *HRPBoomer {
+	b.hrpRunner.AddListeners(listeners...)
+	return b
+}
+
+// Run starts load testing with the given testcases,
+// testcases are picked randomly by the weight in config.
+func (b *HRPBoomer) Run(testcases ...ITestCase) (err error) {
+	log.Info().Int("spawnCount", b.GetSpawnCount()).Msg("start load testing")
+
+	startTime := time.Now()
+	defer func() {
+		// report boom event
+		sdk.SendGA4Event("hrp_boom", map[string]interface{}{
+			"success":              err == nil,
+			"engagement_time_msec": time.Since(startTime).Milliseconds(),
+		})
+	}()
+
+	testCases, err := LoadTestCases(testcases...)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load testcases")
+		return err
+	}
+
+	// quit all plugins
+	defer func() {
+		pluginMap.Range(func(key, value interface{}) bool {
+			if plugin, ok := value.(funplugin.IPlugin); ok {
+				plugin.Quit()
+			}
+			return true
+		})
+	}()
+
+	// set client transport for high concurrency load testing
+	b.hrpRunner.SetClientTransport(b.GetSpawnCount(),
+		b.GetDisableKeepAlive(), b.GetDisableCompression())
+
+	var tasks []*boomer.Task
+	for _, testcase := range testCases {
+		caseRunner, err := NewCaseRunner(*testcase, b.hrpRunner)
+		if err != nil {
+			log.Error().Err(err).Msg("[Boom] init case runner failed")
+			return err
+		}
+
+		rendezvousList := InitRendezvous(testcase, int64(b.GetSpawnCount()))
+		b.OnSpawnDone(func() {
+			for _, rendezvous := range rendezvousList {
+				rendezvous.SetSpawnDone()
+			}
+		})
+		WaitRendezvous(rendezvousList, b)
+
+		tasks = append(tasks, b.convertBoomerTask(caseRunner))
+	}
+
+	// stop load testing when interrupted
+	interruptSignal := make(chan os.Signal, 1)
+	signal.Notify(interruptSignal, syscall.SIGTERM, syscall.SIGINT)
+	defer signal.Stop(interruptSignal)
+	go func() {
+		if _, ok := <-interruptSignal; ok {
+			log.Warn().Msg("interrupted, stop load testing")
+			b.Stop()
+		}
+	}()
+
+	b.Boomer.Run(tasks...)
+	return nil
+}
+
+// convertBoomerTask converts testcase to boomer task, each task run creates a new session
+func (b *HRPBoomer) convertBoomerTask(caseRunner *CaseRunner) *boomer.Task {
+	config := caseRunner.Config.Get()
+
+	// parameters are iterated endlessly in load testing
+	parametersIterator := caseRunner.parametersIterator
+	parametersIterator.SetUnlimitedMode()
+	var mutex sync.Mutex
+
+	return &boomer.Task{
+		Name:   config.Name,
+		Weight: config.Weight,
+		Fn: func() {
+			sessionRunner, err := caseRunner.newIsolatedSession()
+			if err != nil {
+				b.RecordFailure(string(StepTypeTestCase), config.Name, 0, err.Error())
+				return
+			}
+
+			mutex.Lock()
+			if parametersIterator.HasNext() {
+				sessionRunner.InitWithParameters(parametersIterator.Next())
+			}
+			mutex.Unlock()
+			b.hrpRunner.notifyListeners(func(listener RunListener) {
+				listener.OnCaseStart(sessionRunner)
+			})
+
+			startTime := time.Now()
+			var sessionErr error
+			for _, step := range sessionRunner.caseRunner.TestSteps {
+				_, err := sessionRunner.RunStep(step)
+				if err == nil {
+					continue
+				}
+				sessionErr = err
+				if errors.Is(err, code.InterruptError) || b.hrpRunner.failfast {
+					break
+				}
+			}
+			endTime := time.Now()
+			sessionRunner.ReleaseResources()
+			b.hrpRunner.notifyListeners(func(listener RunListener) {
+				listener.OnCaseEnd(sessionRunner, sessionRunner.GetSummary(), sessionErr)
+			})
+
+			b.recordSession(sessionRunner, startTime, endTime)
+		},
+	}
+}
+
+// recordSession reports step results, transactions and testcase of one session to boomer
+func (b *HRPBoomer) recordSession(sessionRunner *SessionRunner, startTime, endTime time.Time) {
+	summary := sessionRunner.summary
+	transactions := sessionRunner.GetTransactions()
+	transactionsSuccess := make(map[string]bool, len(transactions))
+	for name := range transactions {
+		transactionsSuccess[name] = true
+	}
+
+	for _, record := range summary.Records {
+		switch record.StepType {
+		case StepTypeRendezvous, StepTypeThinkTime, StepTypeTransaction:
+			continue
+		}
+		if record.Skipped {
+			continue
+		}
+
+		if record.Success {
+			b.RecordSuccess(string(record.StepType), record.Name, record.Elapsed, record.ContentSize)
+			continue
+		}
+
+		b.RecordFailure(string(record.StepType), record.Name, record.Elapsed, stepErrorMessage(record))
+		// mark transactions failed if failed step is inside
+		for name, transaction := range transactions {
+			if isStepInTransaction(record, transaction, endTime) {
+				transactionsSuccess[name] = false
+			}
+		}
+	}
+
+	for name, transaction := range transactions {
+		start, ok := transaction[TransactionStart]
+		if !ok {
+			start = startTime
+		}
+		end, ok := transaction[TransactionEnd]
+		if !ok {
+			// transaction not ended, use testcase end time instead
+			end = endTime
+		}
+		b.RecordTransaction(name, transactionsSuccess[name], end.Sub(start).Milliseconds(), 0)
+	}
+
+	// report testcase as a whole transaction
+	caseName := sessionRunner.caseRunner.Config.Get().Name
+	elapsed := endTime.Sub(startTime).Milliseconds()
+	if summary.Success {
+		b.RecordSuccess(string(StepTypeTestCase), caseName, elapsed, 0)
+	} else {
+		b.RecordFailure(string(StepTypeTestCase), caseName, elapsed, "testcase failed")
+	}
+}
+
+func isStepInTransaction(record *StepResult, transaction map[TransactionType]time.Time, endTime time.Time) bool {
+	start, ok := transaction[TransactionStart]
+	if ok && record.StartTime < start.UnixMilli() {
+		return false
+	}
+	end, ok := transaction[TransactionEnd]
+	if !ok {
+		end = endTime
+	}
+	return record.StartTime <= end.UnixMilli()
+}
+
+func stepErrorMessage(record *StepResult) string {
+	if msg, ok := record.Attachments.(string); ok && msg != "" {
+		return msg
+	}
+	return fmt.Sprintf("step %s failed", record.StepType)
+}
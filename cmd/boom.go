@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+
+	hrp "github.com/httprunner/httprunner/v5"
+	"github.com/httprunner/httprunner/v5/pkg/boomer"
+)
+
+// CmdBoom represents the boom command
+var CmdBoom = &cobra.Command{
+	Use:   "boom $path...",
+	Short: "Run load test with boomer",
+	Long:  `Run yaml/json testcase files for load test`,
+	Example: `  $ hrp boom demo.json	# run specified json testcase file
+  $ hrp boom demo.yaml	# run specified yaml testcase file
+  $ hrp boom examples/	# run testcases in specified folder
+  $ hrp boom demo.json --spawn-count 100 --spawn-rate 10 --run-time 60`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var paths []hrp.ITestCase
+		for _, arg := range args {
+			path := hrp.TestCasePath(arg)
+			paths = append(paths, &path)
+		}
+		hrpBoomer := makeHRPBoomer()
+		if boomPrometheusAddr != "" || boomPushgatewayURL != "" {
+			metrics, err := makePrometheusMetrics(boomPrometheusAddr, boomPushgatewayURL, boomPushInterval)
+			if err != nil {
+				return err
+			}
+			defer metrics.Close()
+			hrpBoomer.AddListeners(metrics)
+		}
+		return hrpBoomer.Run(paths...)
+	},
+}
+
+var (
+	spawnCount           int
+	spawnRate            float64
+	runTime              int
+	loopCount            int64
+	reportInterval       int
+	disableKeepalive     bool
+	disableCompression   bool
+	disableConsoleOutput bool
+	boomContinueOnFail   bool
+	boomRequestsLogOn    bool
+	boomPrometheusAddr   string
+	boomPushgatewayURL   string
+	boomPushInterval     int
+)
+
+func init() {
+	CmdBoom.Flags().IntVar(&spawnCount, "spawn-count", 1, "The number of users to spawn for load testing")
+	CmdBoom.Flags().Float64Var(&spawnRate, "spawn-rate", 1, "The rate for spawning users")
+	CmdBoom.Flags().IntVar(&runTime, "run-time", 0, "Stop after the specified amount of time(s), defaults to run forever")
+	CmdBoom.Flags().Int64Var(&loopCount, "loop-count", -1, "The specify running cycles for load testing")
+	CmdBoom.Flags().IntVar(&reportInterval, "report-interval", 3, "The interval(s) of reporting aggregated stats")
+	CmdBoom.Flags().BoolVar(&disableKeepalive, "disable-keepalive", false, "Disable keepalive")
+	CmdBoom.Flags().BoolVar(&disableCompression, "disable-compression", false, "Disable compression")
+	CmdBoom.Flags().BoolVar(&disableConsoleOutput, "disable-console-output", false, "Disable console output")
+	CmdBoom.Flags().BoolVarP(&boomContinueOnFail, "continue-on-failure", "c", false, "continue running next step when failure occurs")
+	CmdBoom.Flags().BoolVar(&boomRequestsLogOn, "log-requests-on", false, "turn on request & response details logging")
+	CmdBoom.Flags().StringVar(&boomPrometheusAddr, "prometheus-addr", "", "serve prometheus metrics on /metrics, e.g. localhost:9091")
+	CmdBoom.Flags().StringVar(&boomPushgatewayURL, "pushgateway-url", "", "push prometheus metrics to pushgateway url")
+	CmdBoom.Flags().IntVar(&boomPushInterval, "push-interval", 10, "The interval(s) of pushing metrics to pushgateway")
+}
+
+func makeHRPBoomer() *hrp.HRPBoomer {
+	hrpBoomer := hrp.NewBoomer(spawnCount, spawnRate).
+		SetFailfast(!boomContinueOnFail)
+	hrpBoomer.SetLoopCount(loopCount)
+	hrpBoomer.SetRunTime(time.Duration(runTime) * time.Second)
+	hrpBoomer.SetReportInterval(time.Duration(reportInterval) * time.Second)
+	hrpBoomer.SetDisableKeepAlive(disableKeepalive)
+	hrpBoomer.SetDisableCompression(disableCompression)
+	if !disableConsoleOutput {
+		hrpBoomer.AddOutput(boomer.NewConsoleOutput())
+	}
+	if boomRequestsLogOn {
+		hrpBoomer.SetRequestsLogOn()
+	}
+	if venv != "" {
+		hrpBoomer.SetPython3Venv(venv)
+	}
+	return hrpBoomer
+}
@@ -14,6 +14,7 @@ import (
 
 func addAllCommands() {
 	// adds all child commands to the root command and sets flags appropriately.
+	cmd.RootCmd.AddCommand(cmd.CmdBoom)
 	cmd.RootCmd.AddCommand(cmd.CmdBuild)
 	cmd.RootCmd.AddCommand(cmd.CmdConvert)
 	cmd.RootCmd.AddCommand(cmd.CmdPytest)
@@ -24,6 +25,8 @@ func addAllCommands() {
 	cmd.RootCmd.AddCommand(cmd.CmdWiki)
 	cmd.RootCmd.AddCommand(cmd.CmdMCPHost)
 	cmd.RootCmd.AddCommand(cmd.CmdMCPServer)
+	cmd.RootCmd.AddCommand(cmd.CmdMock)
+	cmd.RootCmd.AddCommand(cmd.CmdRecord)
 
 	cmd.RootCmd.AddCommand(ios.CmdIOSRoot)
 	cmd.RootCmd.AddCommand(adb.CmdAndroidRoot)
@@ -31,6 +31,8 @@ var CmdConvert = &cobra.Command{
 			fromType = convert.FromTypeHAR
 		} else if fromCurlFlag {
 			fromType = convert.FromTypeCurl
+		} else if fromGraphQLFlag {
+			fromType = convert.FromTypeGraphQL
 		} else {
 			fromType = convert.FromTypeJSON
 			log.Info().Str("fromType", fromType.String()).Msg("set default")
@@ -98,6 +100,7 @@ var (
 	fromPostmanFlag bool
 	fromHARFlag     bool
 	fromCurlFlag    bool
+	fromGraphQLFlag bool
 
 	toJSONFlag   bool
 	toYAMLFlag   bool
@@ -110,6 +113,7 @@ func init() {
 	CmdConvert.Flags().BoolVar(&fromHARFlag, "from-har", false, "load from HAR format")
 	CmdConvert.Flags().BoolVar(&fromPostmanFlag, "from-postman", false, "load from postman format")
 	CmdConvert.Flags().BoolVar(&fromCurlFlag, "from-curl", false, "load from curl format")
+	CmdConvert.Flags().BoolVar(&fromGraphQLFlag, "from-graphql", false, "load from graphql introspection schema")
 
 	CmdConvert.Flags().BoolVar(&toJSONFlag, "to-json", true, "convert to JSON case scripts")
 	CmdConvert.Flags().BoolVar(&toYAMLFlag, "to-yaml", false, "convert to YAML case scripts")
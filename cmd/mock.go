@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/httprunner/httprunner/v5/server"
+)
+
+var CmdMock = &cobra.Command{
+	Use:   "mock $path...",
+	Short: "Start mock server with stubbed responses",
+	Long: `Start mock server with responses stubbed from mock rules, testcases, HAR or OpenAPI documents.
+Received requests could be queried from /__mock/requests for verification.
+
+Examples:
+  $ hrp mock mocks.yml
+  $ hrp mock testcases/ api.har openapi.json --port 18080 --latency 100ms --fault-rate 0.1`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rules, err := server.LoadMockRules(args...)
+		if err != nil {
+			return err
+		}
+		mockServer := server.NewMockServer(rules, server.MockOptions{
+			Latency:   mockLatency,
+			FaultRate: mockFaultRate,
+		})
+		return mockServer.Run(fmt.Sprintf("%s:%d", mockHost, mockPort))
+	},
+}
+
+var (
+	mockHost      string
+	mockPort      int
+	mockLatency   time.Duration
+	mockFaultRate float64
+)
+
+func init() {
+	CmdMock.Flags().StringVar(&mockHost, "host", "localhost", "host to run the mock server on")
+	CmdMock.Flags().IntVarP(&mockPort, "port", "p", 18080, "port to run the mock server on")
+	CmdMock.Flags().DurationVar(&mockLatency, "latency", 0, "extra latency of all responses, e.g. 100ms")
+	CmdMock.Flags().Float64Var(&mockFaultRate, "fault-rate", 0, "probability to abort connection of requests, 0 to 1")
+}
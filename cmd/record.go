@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/httprunner/httprunner/v5/convert"
+	"github.com/httprunner/httprunner/v5/server"
+)
+
+var CmdRecord = &cobra.Command{
+	Use:   "record $name",
+	Short: "Record HTTP(S) traffic via proxy and convert to testcase",
+	Long: `Start a local HTTP(S) proxy to capture requests and responses, and convert them to testcase when interrupted.
+Forward proxy is used by default, https traffic is intercepted with certificates signed by the record CA.
+Reverse proxy is used if --target is specified.
+
+Examples:
+  $ hrp record demo --hosts api.example.com --validate --think-time
+  $ hrp record demo --target https://api.example.com --paths /api/ --dedup --to-yaml`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		recorder, err := server.NewRecorder(recordOptions)
+		if err != nil {
+			return err
+		}
+		if recordOptions.Target == "" && recordOptions.CACert == "" {
+			caPath := filepath.Join(os.TempDir(), "hrp-record-ca.pem")
+			if err := os.WriteFile(caPath, recorder.CACertPEM(), 0o644); err != nil {
+				return err
+			}
+			log.Info().Str("path", caPath).Msg("trust record CA to capture https traffic")
+		}
+		if err := recorder.Run(fmt.Sprintf("%s:%d", recordHost, recordPort)); err != nil {
+			return err
+		}
+
+		tCase, err := recorder.TestCase(args[0])
+		if err != nil {
+			return err
+		}
+		outputType := convert.OutputTypeJSON
+		if recordToYAML {
+			outputType = convert.OutputTypeYAML
+		}
+		outputFile, err := convert.NewConverter(outputDir, profilePath).
+			ConvertTestCase(tCase, args[0]+".har", outputType)
+		if err != nil {
+			return err
+		}
+		log.Info().Str("outputFile", outputFile).Int("steps", len(tCase.Steps)).Msg("record completed")
+		return nil
+	},
+}
+
+var (
+	recordHost    string
+	recordPort    int
+	recordToYAML  bool
+	recordOptions server.RecordOptions
+)
+
+func init() {
+	CmdRecord.Flags().StringVar(&recordHost, "host", "localhost", "host to run the record proxy on")
+	CmdRecord.Flags().IntVarP(&recordPort, "port", "P", 18081, "port to run the record proxy on")
+	CmdRecord.Flags().StringVar(&recordOptions.Target, "target", "", "upstream url in reverse proxy mode")
+	CmdRecord.Flags().StringSliceVar(&recordOptions.Hosts, "hosts", nil, "only record requests to hosts, e.g. *.example.com")
+	CmdRecord.Flags().StringSliceVar(&recordOptions.Paths, "paths", nil, "only record requests with path prefixes")
+	CmdRecord.Flags().BoolVar(&recordOptions.Dedup, "dedup", false, "skip requests with same method, url and body")
+	CmdRecord.Flags().BoolVar(&recordOptions.Validate, "validate", false, "generate validators from recorded status codes")
+	CmdRecord.Flags().BoolVar(&recordOptions.ThinkTime, "think-time", false, "generate think time steps from gaps between requests")
+	CmdRecord.Flags().DurationVar(&recordOptions.MinThinkTime, "min-think-time", time.Second, "ignore gaps shorter than it")
+	CmdRecord.Flags().StringVar(&recordOptions.CACert, "ca-cert", "", "CA cert file to intercept https traffic, generated if not specified")
+	CmdRecord.Flags().StringVar(&recordOptions.CAKey, "ca-key", "", "CA key file to intercept https traffic")
+	CmdRecord.Flags().BoolVarP(&recordOptions.Insecure, "insecure", "k", false, "skip verifying certificates of upstream servers")
+	CmdRecord.Flags().StringVarP(&outputDir, "output-dir", "d", "", "specify output directory")
+	CmdRecord.Flags().StringVarP(&profilePath, "profile", "p", "", "specify profile path to override headers and cookies")
+	CmdRecord.Flags().BoolVar(&recordToYAML, "to-yaml", false, "convert to YAML case scripts")
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/spf13/cobra"
 
 	hrp "github.com/httprunner/httprunner/v5"
+	"github.com/httprunner/httprunner/v5/internal/builtin"
 	"github.com/httprunner/httprunner/v5/internal/config"
 )
 
@@ -16,10 +17,12 @@ var CmdReport = &cobra.Command{
 	Short: "Generate HTML report from test results",
 	Long: `Generate report.html from test results in the specified folder.
 The folder should contain summary.json and optionally hrp.log files.
+JUnit XML, Allure results and CTRF json could also be generated with --report.
 
 Examples:
   $ hrp report results/20250607234602/
-  $ hrp report /path/to/test/results/`,
+  $ hrp report /path/to/test/results/
+  $ hrp report results/20250607234602/ --report html,junit,allure,ctrf`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		resultFolder := args[0]
@@ -30,11 +33,31 @@ Examples:
 		reportFile := filepath.Join(resultFolder, config.ReportFileName)
 
 		// Generate HTML report
-		if err := hrp.GenerateHTMLReportFromFiles(summaryFile, logFile, reportFile); err != nil {
-			return fmt.Errorf("failed to generate HTML report: %w", err)
+		if builtin.Contains(reportFormatsOfResults, "html") {
+			if err := hrp.GenerateHTMLReportFromFiles(summaryFile, logFile, reportFile); err != nil {
+				return fmt.Errorf("failed to generate HTML report: %w", err)
+			}
+			log.Info().Str("report_file", reportFile).Msg("HTML report generated successfully")
 		}
 
-		log.Info().Str("report_file", reportFile).Msg("HTML report generated successfully")
+		// Generate other reports, e.g. junit, allure, ctrf
+		var formats []string
+		for _, format := range reportFormatsOfResults {
+			if format != "html" {
+				formats = append(formats, format)
+			}
+		}
+		if len(formats) > 0 {
+			if err := hrp.GenerateReportsFromFiles(summaryFile, formats...); err != nil {
+				return fmt.Errorf("failed to generate reports: %w", err)
+			}
+		}
 		return nil
 	},
 }
+
+var reportFormatsOfResults []string
+
+func init() {
+	CmdReport.Flags().StringSliceVar(&reportFormatsOfResults, "report", []string{"html"}, "report formats to generate, e.g. html,junit,allure,ctrf")
+}
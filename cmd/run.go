@@ -1,6 +1,9 @@
 package cmd
 
 import (
+	"io"
+	"time"
+
 	"github.com/spf13/cobra"
 
 	hrp "github.com/httprunner/httprunner/v5"
@@ -22,6 +25,18 @@ var CmdRun = &cobra.Command{
 			paths = append(paths, &path)
 		}
 		runner := makeHRPRunner()
+		listeners, err := makeRunListeners()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			for _, listener := range listeners {
+				if closer, ok := listener.(io.Closer); ok {
+					closer.Close()
+				}
+			}
+		}()
+		runner.AddListeners(listeners...)
 		return runner.Run(paths...)
 	},
 }
@@ -34,9 +49,18 @@ var (
 	proxyUrl          string
 	saveTests         bool
 	genHTMLReport     bool
+	reportFormats     []string
+	eventsFile        string // write run events to NDJSON file
+	eventsAddr        string // serve run events via SSE and WebSocket
+	eventsWebhook     string // post run events to webhook
+	otelEndpoint      string // export traces and metrics to OTLP/HTTP endpoint
+	prometheusAddr    string // serve prometheus metrics on /metrics
+	pushgatewayURL    string // push prometheus metrics to pushgateway
+	pushInterval      int    // interval(s) of pushing metrics to pushgateway
 	caseTimeout       float32
 	runMCPConfigPath  string // MCP config path for run command
 	autoPopupHandler  bool   // enable auto popup handler for all steps
+	parallel          int    // number of sessions running concurrently
 )
 
 func init() {
@@ -47,9 +71,18 @@ func init() {
 	CmdRun.Flags().StringVarP(&proxyUrl, "proxy-url", "p", "", "set proxy url")
 	CmdRun.Flags().BoolVarP(&saveTests, "save-tests", "s", false, "save tests summary")
 	CmdRun.Flags().BoolVarP(&genHTMLReport, "gen-html-report", "g", false, "generate html report")
+	CmdRun.Flags().StringSliceVar(&reportFormats, "report", nil, "generate reports in results folder, e.g. junit,allure,ctrf")
+	CmdRun.Flags().StringVar(&eventsFile, "events-file", "", "write run events to NDJSON file")
+	CmdRun.Flags().StringVar(&eventsAddr, "events-addr", "", "serve run events via SSE on /events and WebSocket on /ws, e.g. localhost:8090")
+	CmdRun.Flags().StringVar(&eventsWebhook, "events-webhook", "", "post run events to webhook url")
+	CmdRun.Flags().StringVar(&otelEndpoint, "otel-endpoint", "", "export OpenTelemetry traces and metrics to OTLP/HTTP endpoint, e.g. http://localhost:4318")
+	CmdRun.Flags().StringVar(&prometheusAddr, "prometheus-addr", "", "serve prometheus metrics on /metrics, e.g. localhost:9091")
+	CmdRun.Flags().StringVar(&pushgatewayURL, "pushgateway-url", "", "push prometheus metrics to pushgateway url")
+	CmdRun.Flags().IntVar(&pushInterval, "push-interval", 10, "the interval(s) of pushing metrics to pushgateway")
 	CmdRun.Flags().Float32Var(&caseTimeout, "case-timeout", 3600, "set testcase timeout (seconds)")
 	CmdRun.Flags().StringVar(&runMCPConfigPath, "mcp-config", "", "path to the MCP config file")
 	CmdRun.Flags().BoolVar(&autoPopupHandler, "enable-auto-popup-handler", false, "enable auto popup handler for all UI steps")
+	CmdRun.Flags().IntVar(&parallel, "parallel", 1, "run testcases and parameter iterations concurrently with N sessions")
 }
 
 func makeHRPRunner() *hrp.HRPRunner {
@@ -60,6 +93,9 @@ func makeHRPRunner() *hrp.HRPRunner {
 	if genHTMLReport {
 		runner.GenHTMLReport()
 	}
+	if len(reportFormats) > 0 {
+		runner.SetReporters(reportFormats...)
+	}
 	if !requestsLogOff {
 		runner.SetRequestsLogOn()
 	}
@@ -81,5 +117,53 @@ func makeHRPRunner() *hrp.HRPRunner {
 	if autoPopupHandler {
 		runner.EnableAutoPopupHandler(autoPopupHandler)
 	}
+	if parallel > 1 {
+		runner.SetParallelism(parallel)
+	}
+	if otelEndpoint != "" {
+		runner.SetTelemetry(otelEndpoint)
+	}
 	return runner
 }
+
+func makeRunListeners() ([]hrp.RunListener, error) {
+	var listeners []hrp.RunListener
+	if eventsFile != "" {
+		listener, err := hrp.NewNDJSONListener(eventsFile)
+		if err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, listener)
+	}
+	if eventsAddr != "" {
+		server := hrp.NewEventStreamServer()
+		if err := server.Start(eventsAddr); err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, server)
+	}
+	if eventsWebhook != "" {
+		listeners = append(listeners, hrp.NewWebhookListener(eventsWebhook, nil))
+	}
+	if prometheusAddr != "" || pushgatewayURL != "" {
+		metrics, err := makePrometheusMetrics(prometheusAddr, pushgatewayURL, pushInterval)
+		if err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, metrics)
+	}
+	return listeners, nil
+}
+
+func makePrometheusMetrics(addr, pushURL string, interval int) (*hrp.PrometheusMetrics, error) {
+	metrics := hrp.NewPrometheusMetrics()
+	if addr != "" {
+		if err := metrics.Start(addr); err != nil {
+			return nil, err
+		}
+	}
+	if pushURL != "" {
+		metrics.StartPush(pushURL, "hrp", time.Duration(interval)*time.Second)
+	}
+	return metrics, nil
+}
@@ -28,6 +28,13 @@ func ConvertCaseCompatibility(tc *TestCaseDef) (err error) {
 			return err
 		}
 
+		if step.Retry != nil {
+			err = convertCompatValidator(step.Retry.UntilValidators)
+			if err != nil {
+				return err
+			}
+		}
+
 		// 3. deal with extract expr including hyphen
 		convertExtract(step.Extract)
 
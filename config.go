@@ -25,15 +25,25 @@ func NewConfig(name string) *TConfig {
 type TConfig struct {
 	Name              string                         `json:"name" yaml:"name"` // required
 	Verify            bool                           `json:"verify,omitempty" yaml:"verify,omitempty"`
-	BaseURL           string                         `json:"base_url,omitempty" yaml:"base_url,omitempty"`   // deprecated in v4.1, moved to env
-	Headers           map[string]string              `json:"headers,omitempty" yaml:"headers,omitempty"`     // public request headers
-	Environs          map[string]string              `json:"environs,omitempty" yaml:"environs,omitempty"`   // environment variables
-	Variables         map[string]interface{}         `json:"variables,omitempty" yaml:"variables,omitempty"` // global variables
-	OriginalVariables map[string]interface{}         `json:"-" yaml:"-"`                                     // original user variables before env merge (not serialized)
+	SkipVerify        bool                           `json:"skip_verify,omitempty" yaml:"skip_verify,omitempty"`         // disable verification unless CA bundle is set
+	TLS               *RequestTLS                    `json:"tls,omitempty" yaml:"tls,omitempty"`                         // CA bundle, client certificate and SNI
+	AllowRedirects    *bool                          `json:"allow_redirects,omitempty" yaml:"allow_redirects,omitempty"` // default true
+	MaxRedirects      int                            `json:"max_redirects,omitempty" yaml:"max_redirects,omitempty"`     // default 10
+	Proxies           map[string]string              `json:"proxies,omitempty" yaml:"proxies,omitempty"`                 // keyed by scheme, e.g. http, https or all
+	Auth              *HTTPAuth                      `json:"auth,omitempty" yaml:"auth,omitempty"`                       // basic, digest or bearer auth
+	Sign              *RequestSign                   `json:"sign,omitempty" yaml:"sign,omitempty"`                       // sign HTTP requests, e.g. hmac_sha256 or aws_sigv4
+	OAuth2            *OAuth2Config                  `json:"oauth2,omitempty" yaml:"oauth2,omitempty"`                   // oauth2 token provider
+	DisableCookies    bool                           `json:"disable_cookies,omitempty" yaml:"disable_cookies,omitempty"` // disable session cookie jar
+	BaseURL           string                         `json:"base_url,omitempty" yaml:"base_url,omitempty"`               // deprecated in v4.1, moved to env
+	Headers           map[string]string              `json:"headers,omitempty" yaml:"headers,omitempty"`                 // public request headers
+	Environs          map[string]string              `json:"environs,omitempty" yaml:"environs,omitempty"`               // environment variables
+	Variables         map[string]interface{}         `json:"variables,omitempty" yaml:"variables,omitempty"`             // global variables
+	OriginalVariables map[string]interface{}         `json:"-" yaml:"-"`                                                 // original user variables before env merge (not serialized)
 	Parameters        map[string]interface{}         `json:"parameters,omitempty" yaml:"parameters,omitempty"`
 	ParametersSetting *TParamsConfig                 `json:"parameters_setting,omitempty" yaml:"parameters_setting,omitempty"`
 	ThinkTimeSetting  *ThinkTimeConfig               `json:"think_time,omitempty" yaml:"think_time,omitempty"`
 	WebSocketSetting  *WebSocketConfig               `json:"websocket,omitempty" yaml:"websocket,omitempty"`
+	RedisSetting      *RedisConfig                   `json:"redis,omitempty" yaml:"redis,omitempty"`
 	IOS               []*option.IOSDeviceOptions     `json:"ios,omitempty" yaml:"ios,omitempty"`
 	Android           []*option.AndroidDeviceOptions `json:"android,omitempty" yaml:"android,omitempty"`
 	Harmony           []*option.HarmonyDeviceOptions `json:"harmony,omitempty" yaml:"harmony,omitempty"`
@@ -48,6 +58,7 @@ type TConfig struct {
 	AntiRisk          bool                           `json:"anti_risk,omitempty" yaml:"anti_risk,omitempty"`                   // global anti-risk switch
 	AutoPopupHandler  bool                           `json:"auto_popup_handler,omitempty" yaml:"auto_popup_handler,omitempty"` // enable auto popup handler
 	AIOptions         *option.AIServiceOptions       `json:"ai_options,omitempty" yaml:"ai_options,omitempty"`
+	ValidateMode      ValidateMode                   `json:"validate_mode,omitempty" yaml:"validate_mode,omitempty"` // first(default) or all
 }
 
 func (c *TConfig) Get() *TConfig {
@@ -75,6 +86,50 @@ func (c *TConfig) SetHeaders(headers map[string]string) *TConfig {
 // SetVerifySSL sets whether to verify SSL for current testcase.
 func (c *TConfig) SetVerifySSL(verify bool) *TConfig {
 	c.Verify = verify
+	c.SkipVerify = !verify
+	return c
+}
+
+// SetTLS sets CA bundle, client certificate and SNI for all HTTP requests in current testcase.
+func (c *TConfig) SetTLS(tls *RequestTLS) *TConfig {
+	c.TLS = tls
+	return c
+}
+
+// SetAllowRedirects sets whether to follow redirects and the max redirect hops for current testcase.
+func (c *TConfig) SetAllowRedirects(allowRedirects bool, maxRedirects int) *TConfig {
+	c.AllowRedirects = &allowRedirects
+	c.MaxRedirects = maxRedirects
+	return c
+}
+
+// SetProxies sets proxies for current testcase, keyed by scheme, e.g. http, https or all.
+func (c *TConfig) SetProxies(proxies map[string]string) *TConfig {
+	c.Proxies = proxies
+	return c
+}
+
+// SetAuth sets auth for all HTTP requests in current testcase.
+func (c *TConfig) SetAuth(auth *HTTPAuth) *TConfig {
+	c.Auth = auth
+	return c
+}
+
+// SetSign sets signing of HTTP requests for current testcase.
+func (c *TConfig) SetSign(sign *RequestSign) *TConfig {
+	c.Sign = sign
+	return c
+}
+
+// SetOAuth2 sets oauth2 token provider, the access token is injected into request headers.
+func (c *TConfig) SetOAuth2(oauth2 *OAuth2Config) *TConfig {
+	c.OAuth2 = oauth2
+	return c
+}
+
+// SetDisableCookies disables automatic cookie handling, response cookies will not be stored in session cookie jar.
+func (c *TConfig) SetDisableCookies(disable bool) *TConfig {
+	c.DisableCookies = disable
 	return c
 }
 
@@ -128,6 +183,13 @@ func (c *TConfig) ExportVars(vars ...string) *TConfig {
 	return c
 }
 
+// SetValidateMode sets validate mode for all steps in current testcase,
+// ValidateModeAll evaluates all validators and reports all failures.
+func (c *TConfig) SetValidateMode(mode ValidateMode) *TConfig {
+	c.ValidateMode = mode
+	return c
+}
+
 // SetWeight sets weight for current testcase, which is used in load testing.
 func (c *TConfig) SetWeight(weight int) *TConfig {
 	c.Weight = weight
@@ -167,6 +229,12 @@ func (c *TConfig) SetWebSocket(times, interval, timeout, size int64) *TConfig {
 	return c
 }
 
+// SetRedis sets redis connection for redis steps, e.g. SetRedis(&RedisConfig{URL: "$REDIS_URL"})
+func (c *TConfig) SetRedis(redisConfig *RedisConfig) *TConfig {
+	c.RedisSetting = redisConfig
+	return c
+}
+
 func (c *TConfig) SetIOS(opts ...option.IOSDeviceOption) *TConfig {
 	iosOptions := option.NewIOSDeviceOptions(opts...)
 
@@ -0,0 +1,280 @@
+package convert
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	hrp "github.com/httprunner/httprunner/v5"
+)
+
+// ==================== model definition starts here ====================
+
+/*
+GraphQL introspection query result reference:
+https://spec.graphql.org/October2021/#sec-Schema-Introspection
+*/
+
+// IntrospectionResult represents result of introspection query, with or without data wrapper
+type IntrospectionResult struct {
+	Data   *IntrospectionResult `json:"data,omitempty"`
+	Schema *IntrospectionSchema `json:"__schema,omitempty"`
+}
+
+type IntrospectionSchema struct {
+	QueryType    *IntrospectionTypeName `json:"queryType"`
+	MutationType *IntrospectionTypeName `json:"mutationType"`
+	Types        []*IntrospectionType   `json:"types"`
+}
+
+type IntrospectionTypeName struct {
+	Name string `json:"name"`
+}
+
+type IntrospectionType struct {
+	Kind          string                     `json:"kind"`
+	Name          string                     `json:"name"`
+	Fields        []*IntrospectionField      `json:"fields"`
+	InputFields   []*IntrospectionInputValue `json:"inputFields"`
+	EnumValues    []*IntrospectionTypeName   `json:"enumValues"`
+	PossibleTypes []*IntrospectionTypeName   `json:"possibleTypes"`
+}
+
+type IntrospectionField struct {
+	Name string                     `json:"name"`
+	Args []*IntrospectionInputValue `json:"args"`
+	Type *IntrospectionTypeRef      `json:"type"`
+}
+
+type IntrospectionInputValue struct {
+	Name string                `json:"name"`
+	Type *IntrospectionTypeRef `json:"type"`
+}
+
+type IntrospectionTypeRef struct {
+	Kind   string                `json:"kind"`
+	Name   string                `json:"name"`
+	OfType *IntrospectionTypeRef `json:"ofType"`
+}
+
+// String returns type reference in graphql syntax, e.g. [ID!]!
+func (t *IntrospectionTypeRef) String() string {
+	switch t.Kind {
+	case "NON_NULL":
+		return t.OfType.String() + "!"
+	case "LIST":
+		return "[" + t.OfType.String() + "]"
+	default:
+		return t.Name
+	}
+}
+
+// namedType returns the innermost named type, e.g. ID for [ID!]!
+func (t *IntrospectionTypeRef) namedType() *IntrospectionTypeRef {
+	for t.OfType != nil && (t.Kind == "NON_NULL" || t.Kind == "LIST") {
+		t = t.OfType
+	}
+	return t
+}
+
+// ==================== model definition ends here ====================
+
+const (
+	graphqlDefaultURL      = "/graphql"
+	graphqlSelectionDepth  = 2 // max depth of nested object fields in generated selection set
+	graphqlInputValueDepth = 3 // max depth of nested input objects in generated variables
+)
+
+func LoadGraphQLCase(path string) (*hrp.TestCaseDef, error) {
+	log.Info().Str("path", path).Msg("load graphql introspection file")
+	result := new(IntrospectionResult)
+	err := hrp.LoadFileObject(path, result)
+	if err != nil {
+		return nil, errors.Wrap(err, "load graphql introspection file failed")
+	}
+	if result.Data != nil {
+		result = result.Data
+	}
+	if result.Schema == nil {
+		return nil, errors.New("invalid graphql introspection file, missing __schema")
+	}
+	tCase, err := result.Schema.ToTestCase()
+	if err != nil {
+		return nil, err
+	}
+	err = hrp.ConvertCaseCompatibility(tCase)
+	if err != nil {
+		return nil, err
+	}
+	return tCase, nil
+}
+
+// ToTestCase generates one graphql step for each query and mutation field
+func (s *IntrospectionSchema) ToTestCase() (*hrp.TestCaseDef, error) {
+	types := make(map[string]*IntrospectionType, len(s.Types))
+	for _, t := range s.Types {
+		types[t.Name] = t
+	}
+	g := &graphqlGenerator{types: types}
+
+	tCase := &hrp.TestCaseDef{
+		Config: &hrp.TConfig{
+			Name: "testcase converted from graphql schema",
+		},
+	}
+	for _, root := range []struct {
+		operation string
+		typeName  *IntrospectionTypeName
+	}{
+		{"query", s.QueryType},
+		{"mutation", s.MutationType},
+	} {
+		if root.typeName == nil {
+			continue
+		}
+		rootType, ok := types[root.typeName.Name]
+		if !ok {
+			return nil, errors.Errorf("%s type %s not found in schema", root.operation, root.typeName.Name)
+		}
+		for _, field := range rootType.Fields {
+			tCase.Steps = append(tCase.Steps, g.makeStep(root.operation, field))
+		}
+	}
+	log.Info().Int("steps", len(tCase.Steps)).Msg("convert graphql schema to testcase")
+	return tCase, nil
+}
+
+type graphqlGenerator struct {
+	types map[string]*IntrospectionType
+}
+
+func (g *graphqlGenerator) makeStep(operation string, field *IntrospectionField) *hrp.TStep {
+	var argDefs, args []string
+	variables := make(map[string]interface{})
+	for _, arg := range field.Args {
+		argDefs = append(argDefs, fmt.Sprintf("$%s: %s", arg.Name, arg.Type.String()))
+		args = append(args, fmt.Sprintf("%s: $%s", arg.Name, arg.Name))
+		variables[arg.Name] = g.placeholder(arg.Type, 0)
+	}
+
+	var query strings.Builder
+	query.WriteString(operation + " " + field.Name)
+	if len(argDefs) > 0 {
+		query.WriteString("(" + strings.Join(argDefs, ", ") + ")")
+	}
+	query.WriteString(" {\n  " + field.Name)
+	if len(args) > 0 {
+		query.WriteString("(" + strings.Join(args, ", ") + ")")
+	}
+	query.WriteString(g.selectionSet(field.Type, 1, "  "))
+	query.WriteString("\n}\n")
+
+	step := &hrp.TStep{
+		StepConfig: hrp.StepConfig{
+			StepName: fmt.Sprintf("%s %s", operation, field.Name),
+			Validators: []interface{}{
+				hrp.Validator{
+					Check:   "status_code",
+					Assert:  "equals",
+					Expect:  200,
+					Message: "assert response status code",
+				},
+			},
+		},
+		GraphQL: &hrp.GraphQL{
+			URL:           graphqlDefaultURL,
+			Query:         query.String(),
+			OperationName: field.Name,
+		},
+	}
+	if len(variables) > 0 {
+		step.GraphQL.Variables = variables
+	}
+	return step
+}
+
+// selectionSet generates selection set of scalar and nested object fields
+func (g *graphqlGenerator) selectionSet(typeRef *IntrospectionTypeRef, depth int, indent string) string {
+	t, ok := g.types[typeRef.namedType().Name]
+	if !ok {
+		return ""
+	}
+	switch t.Kind {
+	case "OBJECT", "INTERFACE":
+	case "UNION":
+		return " {\n" + indent + "  __typename\n" + indent + "}"
+	default:
+		return "" // scalar or enum
+	}
+
+	var lines []string
+	for _, field := range t.Fields {
+		if strings.HasPrefix(field.Name, "__") || hasRequiredArgs(field) {
+			continue
+		}
+		fieldType := g.types[field.Type.namedType().Name]
+		if fieldType == nil || fieldType.Kind == "SCALAR" || fieldType.Kind == "ENUM" {
+			lines = append(lines, indent+"  "+field.Name)
+			continue
+		}
+		if depth < graphqlSelectionDepth {
+			lines = append(lines, indent+"  "+field.Name+g.selectionSet(field.Type, depth+1, indent+"  "))
+		}
+	}
+	if len(lines) == 0 {
+		lines = append(lines, indent+"  __typename")
+	}
+	return " {\n" + strings.Join(lines, "\n") + "\n" + indent + "}"
+}
+
+func hasRequiredArgs(field *IntrospectionField) bool {
+	for _, arg := range field.Args {
+		if arg.Type.Kind == "NON_NULL" {
+			return true
+		}
+	}
+	return false
+}
+
+// placeholder generates placeholder value of input type, e.g. 0 for Int, first value for enum
+func (g *graphqlGenerator) placeholder(typeRef *IntrospectionTypeRef, depth int) interface{} {
+	switch typeRef.Kind {
+	case "NON_NULL":
+		return g.placeholder(typeRef.OfType, depth)
+	case "LIST":
+		return []interface{}{g.placeholder(typeRef.OfType, depth)}
+	}
+
+	switch typeRef.Name {
+	case "Int":
+		return 0
+	case "Float":
+		return 0.0
+	case "Boolean":
+		return false
+	case "String", "ID":
+		return ""
+	}
+	t, ok := g.types[typeRef.Name]
+	if !ok {
+		return nil
+	}
+	switch t.Kind {
+	case "ENUM":
+		if len(t.EnumValues) > 0 {
+			return t.EnumValues[0].Name
+		}
+	case "INPUT_OBJECT":
+		if depth >= graphqlInputValueDepth {
+			return nil
+		}
+		value := make(map[string]interface{})
+		for _, field := range t.InputFields {
+			value[field.Name] = g.placeholder(field.Type, depth+1)
+		}
+		return value
+	}
+	return ""
+}
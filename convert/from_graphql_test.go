@@ -0,0 +1,57 @@
+package convert
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var introspectionPath = "../tests/data/graphql/introspection.json"
+
+func TestLoadGraphQLCase(t *testing.T) {
+	tCase, err := LoadGraphQLCase(introspectionPath)
+	require.NoError(t, err)
+	assert.Equal(t, "testcase converted from graphql schema", tCase.Config.Name)
+	require.Len(t, tCase.Steps, 3)
+
+	// query with required argument and nested selection set
+	step := tCase.Steps[0]
+	assert.Equal(t, "query user", step.StepName)
+	assert.Nil(t, step.Request)
+	assert.Equal(t, "/graphql", step.GraphQL.URL)
+	assert.Equal(t, "user", step.GraphQL.OperationName)
+	assert.Equal(t, `query user($id: ID!) {
+  user(id: $id) {
+    id
+    name
+    role
+    friends {
+      id
+      name
+      role
+    }
+  }
+}
+`, step.GraphQL.Query)
+	assert.Equal(t, map[string]interface{}{"id": ""}, step.GraphQL.Variables)
+	assert.Len(t, step.Validators, 1)
+
+	// optional arguments with scalar and enum placeholders
+	step = tCase.Steps[1]
+	assert.Contains(t, step.GraphQL.Query, "query users($first: Int, $role: Role)")
+	assert.Equal(t, map[string]interface{}{"first": 0, "role": "ADMIN"}, step.GraphQL.Variables)
+
+	// mutation with input object
+	step = tCase.Steps[2]
+	assert.Equal(t, "mutation createUser", step.StepName)
+	assert.Contains(t, step.GraphQL.Query, "mutation createUser($input: CreateUserInput!)")
+	assert.Equal(t, map[string]interface{}{
+		"input": map[string]interface{}{"name": "", "age": 0, "role": "ADMIN"},
+	}, step.GraphQL.Variables)
+}
+
+func TestLoadGraphQLCaseInvalid(t *testing.T) {
+	_, err := LoadGraphQLCase("../tests/data/postman/postman_collection.json")
+	assert.Error(t, err)
+}
@@ -31,6 +31,7 @@ const (
 	FromTypeSwagger
 	FromTypePyest
 	FromTypeGotest
+	FromTypeGraphQL
 )
 
 func (fromType FromType) String() string {
@@ -49,6 +50,8 @@ func (fromType FromType) String() string {
 		return "gotest"
 	case FromTypePyest:
 		return "pytest"
+	case FromTypeGraphQL:
+		return "graphql"
 	default:
 		return "json"
 	}
@@ -60,7 +63,7 @@ func (fromType FromType) Extensions() []string {
 		return []string{suffixYAML, ".yml"}
 	case FromTypeHAR:
 		return []string{suffixHAR}
-	case FromTypePostman, FromTypeSwagger:
+	case FromTypePostman, FromTypeSwagger, FromTypeGraphQL:
 		return []string{suffixJSON}
 	case FromTypeCurl:
 		return []string{".txt", ".curl"}
@@ -134,6 +137,8 @@ func (c *TCaseConverter) loadCase(casePath string, fromType FromType) error {
 		c.tCase, err = LoadSwaggerCase(casePath)
 	case FromTypeCurl:
 		c.tCase, err = LoadCurlCase(casePath)
+	case FromTypeGraphQL:
+		c.tCase, err = LoadGraphQLCase(casePath)
 	}
 	return err
 }
@@ -161,29 +166,45 @@ func (c *TCaseConverter) Convert(casePath string, fromType FromType, outputType
 		return err
 	}
 
+	outputFile, err := c.dump(outputType)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("outputFile", outputFile).Msg("conversion completed")
+	return nil
+}
+
+// ConvertTestCase converts TCase built from other sources, e.g. recorded traffic,
+// casePath is used to generate output file path.
+func (c *TCaseConverter) ConvertTestCase(tCase *hrp.TestCaseDef, casePath string, outputType OutputType) (string, error) {
+	log.Info().Str("path", casePath).
+		Str("outputType", outputType.String()).
+		Msg("convert testcase")
+
+	c.fromFile = casePath
+	c.tCase = tCase
+	return c.dump(outputType)
+}
+
+// dump overrides TCase with profile and converts to target format
+func (c *TCaseConverter) dump(outputType OutputType) (string, error) {
 	// override TCase with profile
 	if c.profilePath != "" {
 		c.overrideWithProfile(c.profilePath)
 	}
 
 	// convert to target format
-	var outputFile string
 	switch outputType {
 	case OutputTypeYAML:
-		outputFile, err = c.toYAML()
+		return c.toYAML()
 	case OutputTypeGoTest:
-		outputFile, err = c.toGoTest()
+		return c.toGoTest()
 	case OutputTypePyTest:
-		outputFile, err = c.toPyTest()
+		return c.toPyTest()
 	default:
-		outputFile, err = c.toJSON()
-	}
-	if err != nil {
-		return err
+		return c.toJSON()
 	}
-
-	log.Info().Str("outputFile", outputFile).Msg("conversion completed")
-	return nil
 }
 
 func (c *TCaseConverter) genOutputPath(suffix string) string {
@@ -207,6 +228,19 @@ func (c *TCaseConverter) overrideWithProfile(path string) error {
 
 	log.Info().Interface("profile", profile).Msg("override with profile")
 	for _, step := range c.tCase.Steps {
+		if step.GraphQL != nil {
+			// graphql step has no cookies, only update headers
+			if profile.Override || step.GraphQL.Headers == nil {
+				step.GraphQL.Headers = make(map[string]string)
+			}
+			for k, v := range profile.Headers {
+				step.GraphQL.Headers[k] = v
+			}
+			continue
+		}
+		if step.Request == nil {
+			continue
+		}
 		// override original headers and cookies
 		if profile.Override {
 			step.Request.Headers = make(map[string]string)
@@ -36,6 +36,11 @@ var Functions = map[string]interface{}{
 	"load_ws_message":        loadMessage,
 	"multipart_encoder":      multipartEncoder,
 	"multipart_content_type": multipartContentType,
+	"eq":                     equal,       // used in step conditions, e.g. ${eq($status, done)}
+	"ne":                     notEqual,    // call with two arguments
+	"gt":                     greaterThan, // call with two numbers
+	"lt":                     lessThan,    // call with two numbers
+	"not":                    not,         // call with one argument
 }
 
 // upload file path must starts with @, like @\"PATH\" or @PATH
@@ -51,6 +56,42 @@ func random_range(a, b float64) float64 {
 	return a + rand.Float64()*(b-a)
 }
 
+func equal(a, b interface{}) bool {
+	return fmt.Sprintf("%v", a) == fmt.Sprintf("%v", b)
+}
+
+func notEqual(a, b interface{}) bool {
+	return !equal(a, b)
+}
+
+func greaterThan(a, b interface{}) (bool, error) {
+	x, err := Interface2Float64(a)
+	if err != nil {
+		return false, err
+	}
+	y, err := Interface2Float64(b)
+	if err != nil {
+		return false, err
+	}
+	return x > y, nil
+}
+
+func lessThan(a, b interface{}) (bool, error) {
+	x, err := Interface2Float64(a)
+	if err != nil {
+		return false, err
+	}
+	y, err := Interface2Float64(b)
+	if err != nil {
+		return false, err
+	}
+	return x < y, nil
+}
+
+func not(v bool) bool {
+	return !v
+}
+
 func getTimestamp() int64 {
 	return time.Now().UnixNano() / int64(time.Millisecond)
 }
@@ -0,0 +1,118 @@
+package hrp
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// RunListener observes run progress, e.g. to show progress of long suites in real time.
+// Callbacks are invoked synchronously by running sessions, thus listeners should return quickly,
+// and should be safe for concurrent use if testcases are run in parallel.
+type RunListener interface {
+	OnRunStart(testCases []*TestCase)
+	OnCaseStart(session *SessionRunner)
+	OnStepStart(session *SessionRunner, stepName string, stepType StepType)
+	OnStepEnd(session *SessionRunner, stepResult *StepResult)
+	OnCaseEnd(session *SessionRunner, caseSummary *TestCaseSummary, err error)
+	OnRunEnd(summary *Summary, err error)
+}
+
+type RunEventType string
+
+const (
+	RunEventRunStart  RunEventType = "run_start"
+	RunEventCaseStart RunEventType = "case_start"
+	RunEventStepStart RunEventType = "step_start"
+	RunEventStepEnd   RunEventType = "step_end"
+	RunEventCaseEnd   RunEventType = "case_end"
+	RunEventRunEnd    RunEventType = "run_end"
+)
+
+// RunEvent is the serializable form of run callbacks, which is sent by built-in listeners.
+type RunEvent struct {
+	Type      RunEventType `json:"type"`
+	Time      int64        `json:"time"`                 // event time in millisecond(ms)
+	SessionID string       `json:"session_id,omitempty"` // distinguish sessions of the same testcase
+	Case      string       `json:"case,omitempty"`
+	Step      string       `json:"step,omitempty"`
+	StepType  StepType     `json:"step_type,omitempty"`
+	Success   *bool        `json:"success,omitempty"`
+	Error     string       `json:"error,omitempty"`
+	Data      interface{}  `json:"data,omitempty"` // testcase names, StepResult, TestCaseSummary or Summary stat
+}
+
+// RunEventHandler adapts function handling RunEvent to RunListener.
+type RunEventHandler func(event *RunEvent)
+
+func (h RunEventHandler) OnRunStart(testCases []*TestCase) {
+	names := make([]string, 0, len(testCases))
+	for _, testCase := range testCases {
+		names = append(names, testCase.Config.Get().Name)
+	}
+	h(newRunEvent(RunEventRunStart, nil, names))
+}
+
+func (h RunEventHandler) OnCaseStart(session *SessionRunner) {
+	h(newRunEvent(RunEventCaseStart, session, nil))
+}
+
+func (h RunEventHandler) OnStepStart(session *SessionRunner, stepName string, stepType StepType) {
+	event := newRunEvent(RunEventStepStart, session, nil)
+	event.Step = stepName
+	event.StepType = stepType
+	h(event)
+}
+
+func (h RunEventHandler) OnStepEnd(session *SessionRunner, stepResult *StepResult) {
+	event := newRunEvent(RunEventStepEnd, session, stepResult)
+	event.Step = stepResult.Name
+	event.StepType = stepResult.StepType
+	event.Success = &stepResult.Success
+	h(event)
+}
+
+func (h RunEventHandler) OnCaseEnd(session *SessionRunner, caseSummary *TestCaseSummary, err error) {
+	event := newRunEvent(RunEventCaseEnd, session, caseSummary)
+	event.Success = &caseSummary.Success
+	if err != nil {
+		event.Error = err.Error()
+	}
+	h(event)
+}
+
+func (h RunEventHandler) OnRunEnd(summary *Summary, err error) {
+	event := newRunEvent(RunEventRunEnd, nil, summary.Stat)
+	success := summary.Success && err == nil
+	event.Success = &success
+	if err != nil {
+		event.Error = err.Error()
+	}
+	h(event)
+}
+
+func newRunEvent(eventType RunEventType, session *SessionRunner, data interface{}) *RunEvent {
+	event := &RunEvent{
+		Type: eventType,
+		Time: time.Now().UnixMilli(),
+		Data: data,
+	}
+	if session != nil {
+		event.SessionID = session.ID()
+		event.Case = session.caseRunner.TestCase.Config.Get().Name
+	}
+	return event
+}
+
+// AddListeners registers listeners to observe run progress.
+func (r *HRPRunner) AddListeners(listeners ...RunListener) *HRPRunner {
+	log.Info().Int("count", len(listeners)).Msg("[init] AddListeners")
+	r.listeners = append(r.listeners, listeners...)
+	return r
+}
+
+func (r *HRPRunner) notifyListeners(notify func(listener RunListener)) {
+	for _, listener := range r.listeners {
+		notify(listener)
+	}
+}
@@ -0,0 +1,265 @@
+package hrp
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+	"github.com/rs/zerolog/log"
+)
+
+// PrometheusMetrics is a RunListener maintaining Prometheus metrics of steps, httpstat phases,
+// transactions and active virtual users, which is useful for soak and load runs.
+// Metrics could be scraped on /metrics with Start, or pushed to Pushgateway with StartPush.
+type PrometheusMetrics struct {
+	registry            *prometheus.Registry
+	stepRequests        *prometheus.CounterVec
+	stepFailures        *prometheus.CounterVec
+	stepDuration        *prometheus.HistogramVec
+	httpStatDuration    *prometheus.HistogramVec
+	transactions        *prometheus.CounterVec
+	transactionDuration *prometheus.HistogramVec
+	activeUsers         prometheus.Gauge
+
+	mutex              sync.Mutex
+	failedTransactions map[string]map[string]bool // session id -> names of failed transactions
+
+	server   *http.Server
+	listener net.Listener
+	pusher   *push.Pusher
+	stopPush chan struct{}
+	pushWg   sync.WaitGroup
+}
+
+func NewPrometheusMetrics() *PrometheusMetrics {
+	stepLabels := []string{"testcase", "step", "step_type"}
+	m := &PrometheusMetrics{
+		registry:           prometheus.NewRegistry(),
+		failedTransactions: make(map[string]map[string]bool),
+		stepRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "hrp_step_requests_total",
+			Help: "Total number of executed steps.",
+		}, stepLabels),
+		stepFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "hrp_step_failures_total",
+			Help: "Total number of failed steps.",
+		}, stepLabels),
+		stepDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "hrp_step_duration_seconds",
+			Help:    "Duration of executed steps.",
+			Buckets: prometheus.DefBuckets,
+		}, stepLabels),
+		httpStatDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "hrp_httpstat_duration_seconds",
+			Help:    "Duration of HTTP request phases, e.g. DNSLookup, TCPConnection, ServerProcessing.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"testcase", "step", "phase"}),
+		transactions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "hrp_transactions_total",
+			Help: "Total number of finished transactions.",
+		}, []string{"testcase", "transaction", "success"}),
+		transactionDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "hrp_transaction_duration_seconds",
+			Help:    "Duration of finished transactions.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"testcase", "transaction"}),
+		activeUsers: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "hrp_active_users",
+			Help: "Number of running sessions, i.e. active virtual users.",
+		}),
+	}
+	m.registry.MustRegister(m.stepRequests, m.stepFailures, m.stepDuration,
+		m.httpStatDuration, m.transactions, m.transactionDuration, m.activeUsers)
+	return m
+}
+
+// Registry returns the registry of hrp metrics, custom collectors could be registered too.
+func (m *PrometheusMetrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler returns http handler exposing metrics in Prometheus text format.
+func (m *PrometheusMetrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Start serves metrics on /metrics in background, e.g. localhost:9091.
+func (m *PrometheusMetrics) Start(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return errors.Wrap(err, "listen prometheus metrics server failed")
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+	m.listener = listener
+	m.server = &http.Server{Handler: mux}
+	go func() {
+		if err := m.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Error().Err(err).Msg("prometheus metrics server failed")
+		}
+	}()
+	log.Info().Str("addr", listener.Addr().String()).Msg("prometheus metrics server started")
+	return nil
+}
+
+// Addr returns the listening address of metrics server after started.
+func (m *PrometheusMetrics) Addr() string {
+	if m.listener == nil {
+		return ""
+	}
+	return m.listener.Addr().String()
+}
+
+// StartPush pushes metrics to Pushgateway at intervals in background,
+// metrics are pushed once more when closed.
+func (m *PrometheusMetrics) StartPush(url, job string, interval time.Duration) {
+	if job == "" {
+		job = "hrp"
+	}
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	m.pusher = push.New(url, job).Gatherer(m.registry)
+	if hostname, err := os.Hostname(); err == nil {
+		m.pusher = m.pusher.Grouping("instance", hostname)
+	}
+	m.stopPush = make(chan struct{})
+	m.pushWg.Add(1)
+	go func() {
+		defer m.pushWg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.push()
+			case <-m.stopPush:
+				return
+			}
+		}
+	}()
+	log.Info().Str("url", url).Str("job", job).Dur("interval", interval).
+		Msg("push prometheus metrics to pushgateway")
+}
+
+func (m *PrometheusMetrics) push() {
+	if err := m.pusher.Push(); err != nil {
+		log.Warn().Err(err).Msg("push metrics to pushgateway failed")
+	}
+}
+
+// Close stops pushing after the final push and shuts down metrics server.
+func (m *PrometheusMetrics) Close() error {
+	if m.pusher != nil {
+		close(m.stopPush)
+		m.pushWg.Wait()
+		m.push()
+		m.pusher = nil
+	}
+	if m.server == nil {
+		return nil
+	}
+	return m.server.Close()
+}
+
+func (m *PrometheusMetrics) OnRunStart(testCases []*TestCase) {}
+
+func (m *PrometheusMetrics) OnCaseStart(session *SessionRunner) {
+	m.activeUsers.Inc()
+}
+
+func (m *PrometheusMetrics) OnStepStart(session *SessionRunner, stepName string, stepType StepType) {}
+
+func (m *PrometheusMetrics) OnStepEnd(session *SessionRunner, stepResult *StepResult) {
+	// transactions are recorded when testcase ends
+	switch stepResult.StepType {
+	case StepTypeRendezvous, StepTypeThinkTime, StepTypeTransaction:
+		return
+	}
+	if stepResult.Skipped {
+		return
+	}
+	// iterations of parameters and loops are recorded with the same step label
+	stepName := stepResult.baseName
+	if stepName == "" {
+		stepName = stepResult.Name
+	}
+	testCase := session.caseRunner.TestCase.Config.Get().Name
+	labels := prometheus.Labels{
+		"testcase":  testCase,
+		"step":      stepName,
+		"step_type": string(stepResult.StepType),
+	}
+	m.stepRequests.With(labels).Inc()
+	if !stepResult.Success {
+		m.stepFailures.With(labels).Inc()
+		m.markTransactionsFailed(session)
+	}
+	m.stepDuration.With(labels).Observe(float64(stepResult.Elapsed) / 1000)
+	for phase, elapsed := range stepResult.HttpStat {
+		m.httpStatDuration.WithLabelValues(testCase, stepName, phase).
+			Observe(float64(elapsed) / 1000)
+	}
+}
+
+// markTransactionsFailed marks transactions in progress as failed when step fails inside
+func (m *PrometheusMetrics) markTransactionsFailed(session *SessionRunner) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	failed, ok := m.failedTransactions[session.ID()]
+	if !ok {
+		failed = make(map[string]bool)
+		m.failedTransactions[session.ID()] = failed
+	}
+	for name, transaction := range session.GetTransactions() {
+		_, started := transaction[TransactionStart]
+		_, ended := transaction[TransactionEnd]
+		if started && !ended {
+			failed[name] = true
+		}
+	}
+}
+
+func (m *PrometheusMetrics) OnCaseEnd(session *SessionRunner, caseSummary *TestCaseSummary, err error) {
+	m.activeUsers.Dec()
+	m.mutex.Lock()
+	failed := m.failedTransactions[session.ID()]
+	delete(m.failedTransactions, session.ID())
+	m.mutex.Unlock()
+
+	testCase := session.caseRunner.TestCase.Config.Get().Name
+	endTime := time.Now()
+	for name, transaction := range session.GetTransactions() {
+		success := !failed[name]
+		start, ok := transaction[TransactionStart]
+		if !ok {
+			// transaction not started, use testcase start time instead
+			start = caseSummary.Time.StartAt
+			for _, record := range caseSummary.Records {
+				if !record.Success && isStepInTransaction(record, transaction, endTime) {
+					success = false
+				}
+			}
+		}
+		end, ok := transaction[TransactionEnd]
+		if !ok {
+			// transaction not ended, use testcase end time instead
+			end = endTime
+		}
+		m.transactions.With(prometheus.Labels{
+			"testcase":    testCase,
+			"transaction": name,
+			"success":     strconv.FormatBool(success),
+		}).Inc()
+		m.transactionDuration.WithLabelValues(testCase, name).Observe(end.Sub(start).Seconds())
+	}
+}
+
+func (m *PrometheusMetrics) OnRunEnd(summary *Summary, err error) {}
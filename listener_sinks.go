@@ -0,0 +1,330 @@
+package hrp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// NDJSONListener writes run events to file, one json per line.
+type NDJSONListener struct {
+	RunEventHandler
+	mutex   sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+}
+
+func NewNDJSONListener(path string) (*NDJSONListener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, errors.Wrap(err, "create events file folder failed")
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, errors.Wrap(err, "open events file failed")
+	}
+	l := &NDJSONListener{
+		file:    file,
+		encoder: json.NewEncoder(file),
+	}
+	l.RunEventHandler = l.write
+	return l, nil
+}
+
+func (l *NDJSONListener) write(event *RunEvent) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if err := l.encoder.Encode(event); err != nil {
+		log.Warn().Err(err).Str("type", string(event.Type)).Msg("write run event failed")
+	}
+}
+
+func (l *NDJSONListener) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.file.Close()
+}
+
+const webhookQueueSize = 1000
+
+// WebhookListener posts each run event as json to webhook url in background,
+// events are dropped if the queue is full, Close flushes queued events.
+type WebhookListener struct {
+	RunEventHandler
+	URL     string
+	Headers map[string]string
+	client  *http.Client
+	mutex   sync.RWMutex
+	closed  bool
+	queue   chan []byte
+	done    chan struct{}
+}
+
+func NewWebhookListener(url string, headers map[string]string) *WebhookListener {
+	l := &WebhookListener{
+		URL:     url,
+		Headers: headers,
+		client:  &http.Client{Timeout: 5 * time.Second},
+		queue:   make(chan []byte, webhookQueueSize),
+		done:    make(chan struct{}),
+	}
+	l.RunEventHandler = l.enqueue
+	go l.loop()
+	return l
+}
+
+func (l *WebhookListener) enqueue(event *RunEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Warn().Err(err).Str("type", string(event.Type)).Msg("marshal run event failed")
+		return
+	}
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	if l.closed {
+		return
+	}
+	select {
+	case l.queue <- data:
+	default:
+		log.Warn().Str("type", string(event.Type)).Str("url", l.URL).Msg("webhook queue is full, drop event")
+	}
+}
+
+func (l *WebhookListener) loop() {
+	defer close(l.done)
+	for data := range l.queue {
+		l.post(data)
+	}
+}
+
+func (l *WebhookListener) post(data []byte) {
+	req, err := http.NewRequest(http.MethodPost, l.URL, bytes.NewReader(data))
+	if err != nil {
+		log.Warn().Err(err).Str("url", l.URL).Msg("create webhook request failed")
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range l.Headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := l.client.Do(req)
+	if err != nil {
+		log.Warn().Err(err).Str("url", l.URL).Msg("post run event to webhook failed")
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		log.Warn().Int("status", resp.StatusCode).Str("url", l.URL).Msg("webhook responded with error")
+	}
+}
+
+// Close stops accepting events and waits until queued events are posted.
+func (l *WebhookListener) Close() error {
+	l.mutex.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.queue)
+	}
+	l.mutex.Unlock()
+	<-l.done
+	return nil
+}
+
+const eventStreamHistoryLimit = 1000
+
+// EventStreamServer broadcasts run events to clients via SSE on /events and WebSocket on /ws,
+// events already sent are replayed to new clients, thus dashboards could join at any time.
+type EventStreamServer struct {
+	RunEventHandler
+	mutex       sync.Mutex
+	history     [][]byte
+	subscribers map[chan []byte]struct{}
+	upgrader    websocket.Upgrader
+	server      *http.Server
+	listener    net.Listener
+}
+
+func NewEventStreamServer() *EventStreamServer {
+	s := &EventStreamServer{
+		subscribers: make(map[chan []byte]struct{}),
+		upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+	s.RunEventHandler = s.broadcast
+	return s
+}
+
+// Start serves events on addr in background, e.g. localhost:8090.
+func (s *EventStreamServer) Start(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return errors.Wrap(err, "listen event stream server failed")
+	}
+	s.listener = listener
+	s.server = &http.Server{Handler: s}
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Error().Err(err).Msg("event stream server failed")
+		}
+	}()
+	log.Info().Str("addr", listener.Addr().String()).Msg("event stream server started")
+	return nil
+}
+
+// Addr returns the listening address after started.
+func (s *EventStreamServer) Addr() string {
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+func (s *EventStreamServer) Close() error {
+	s.mutex.Lock()
+	for ch := range s.subscribers {
+		close(ch)
+		delete(s.subscribers, ch)
+	}
+	s.mutex.Unlock()
+	if s.server == nil {
+		return nil
+	}
+	return s.server.Close()
+}
+
+func (s *EventStreamServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/events":
+		s.serveSSE(w, r)
+	case "/ws":
+		s.serveWebSocket(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *EventStreamServer) broadcast(event *RunEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Warn().Err(err).Str("type", string(event.Type)).Msg("marshal run event failed")
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.history = append(s.history, data)
+	if len(s.history) > eventStreamHistoryLimit {
+		s.history = s.history[len(s.history)-eventStreamHistoryLimit:]
+	}
+	for ch := range s.subscribers {
+		select {
+		case ch <- data:
+		default:
+			log.Warn().Msg("event stream subscriber is too slow, drop event")
+		}
+	}
+}
+
+// subscribe returns channel with history events replayed
+func (s *EventStreamServer) subscribe() chan []byte {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	ch := make(chan []byte, len(s.history)+256)
+	for _, data := range s.history {
+		ch <- data
+	}
+	s.subscribers[ch] = struct{}{}
+	return ch
+}
+
+func (s *EventStreamServer) unsubscribe(ch chan []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.subscribers[ch]; ok {
+		delete(s.subscribers, ch)
+		close(ch)
+	}
+}
+
+func (s *EventStreamServer) serveSSE(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ch := s.subscribe()
+	defer s.unsubscribe(ch)
+	for {
+		select {
+		case data, ok := <-ch:
+			if !ok {
+				return
+			}
+			var event struct {
+				Type string `json:"type"`
+			}
+			_ = json.Unmarshal(data, &event)
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (s *EventStreamServer) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Warn().Err(err).Msg("upgrade event stream websocket failed")
+		return
+	}
+	defer conn.Close()
+
+	ch := s.subscribe()
+	defer s.unsubscribe(ch)
+
+	// detect closed connection, messages from client are ignored
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case data, ok := <-ch:
+			if !ok {
+				_ = conn.WriteMessage(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
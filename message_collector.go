@@ -0,0 +1,107 @@
+package hrp
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// defaultMaxCollectedMessages limits unconsumed messages kept by collector,
+// the oldest messages are dropped if messages are received faster than consumed.
+const defaultMaxCollectedMessages = 10000
+
+// messageCollector collects messages received in background, e.g. by mqtt or redis subscriptions,
+// and steps wait for the message matching condition. Consumed messages are dropped.
+type messageCollector[T any] struct {
+	mutex    sync.Mutex
+	messages []*collectedMessage[T] // unconsumed messages in receiving order
+	seq      int64                  // sequence of the next received message
+	maxSize  int
+	dropped  int64
+	notify   chan struct{} // closed when new message arrives
+}
+
+type collectedMessage[T any] struct {
+	seq  int64
+	data T
+}
+
+func newMessageCollector[T any](maxSize int) *messageCollector[T] {
+	if maxSize <= 0 {
+		maxSize = defaultMaxCollectedMessages
+	}
+	return &messageCollector[T]{
+		maxSize: maxSize,
+		notify:  make(chan struct{}),
+	}
+}
+
+// add appends received message, and notifies the waiting steps
+func (c *messageCollector[T]) add(data T) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(c.messages) >= c.maxSize {
+		c.remove(0)
+		c.dropped++
+		if c.dropped == 1 {
+			log.Warn().Int("maxSize", c.maxSize).Msg("too many unconsumed messages, drop the oldest ones")
+		}
+	}
+	c.messages = append(c.messages, &collectedMessage[T]{seq: c.seq, data: data})
+	c.seq++
+	close(c.notify)
+	c.notify = make(chan struct{})
+}
+
+func (c *messageCollector[T]) remove(i int) {
+	copy(c.messages[i:], c.messages[i+1:])
+	c.messages[len(c.messages)-1] = nil
+	c.messages = c.messages[:len(c.messages)-1]
+}
+
+// expect waits for the first unconsumed message matching condition within timeout and consumes it,
+// ok is false if timeout. Messages checked once are not checked again while waiting for new ones.
+func (c *messageCollector[T]) expect(match func(data T) (matched bool, checkValue interface{}, err error),
+	timeout time.Duration,
+) (data T, checkValue interface{}, ok bool, err error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	var next int64 // sequence of the first message not checked yet
+	for {
+		c.mutex.Lock()
+		i := sort.Search(len(c.messages), func(i int) bool {
+			return c.messages[i].seq >= next
+		})
+		for ; i < len(c.messages); i++ {
+			msg := c.messages[i]
+			next = msg.seq + 1
+			matched, value, err := match(msg.data)
+			if err != nil {
+				c.mutex.Unlock()
+				return data, nil, false, err
+			}
+			if matched {
+				c.remove(i)
+				c.mutex.Unlock()
+				return msg.data, value, true, nil
+			}
+		}
+		notify := c.notify
+		c.mutex.Unlock()
+
+		select {
+		case <-notify:
+		case <-timer.C:
+			return data, nil, false, nil
+		}
+	}
+}
+
+// clear drops all collected messages
+func (c *messageCollector[T]) clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.messages = nil
+}
@@ -0,0 +1,56 @@
+package hrp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageCollectorBounded(t *testing.T) {
+	collector := newMessageCollector[int](3)
+	for i := 1; i <= 5; i++ {
+		collector.add(i)
+	}
+	// the oldest messages are dropped
+	assert.Len(t, collector.messages, 3)
+	assert.Equal(t, int64(2), collector.dropped)
+
+	even := func(data int) (bool, interface{}, error) {
+		return data%2 == 0, data, nil
+	}
+	data, checkValue, ok, err := collector.expect(even, time.Second)
+	require.Nil(t, err)
+	require.True(t, ok)
+	assert.Equal(t, 4, data)
+	assert.Equal(t, 4, checkValue)
+
+	// consumed message is dropped
+	assert.Len(t, collector.messages, 2)
+	_, _, ok, err = collector.expect(even, 10*time.Millisecond)
+	require.Nil(t, err)
+	assert.False(t, ok)
+}
+
+func TestMessageCollectorCheckOnce(t *testing.T) {
+	collector := newMessageCollector[int](0)
+	collector.add(1)
+
+	checked := make(map[int]int)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		collector.add(3)
+		collector.add(2)
+	}()
+	data, _, ok, err := collector.expect(func(data int) (bool, interface{}, error) {
+		checked[data]++
+		return data == 2, nil, nil
+	}, time.Second)
+	require.Nil(t, err)
+	require.True(t, ok)
+	assert.Equal(t, 2, data)
+	// messages are checked once while waiting for new ones
+	assert.Equal(t, map[int]int{1: 1, 3: 1, 2: 1}, checked)
+	assert.Len(t, collector.messages, 2)
+}
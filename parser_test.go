@@ -716,7 +716,7 @@ func TestSearchJmespath(t *testing.T) {
 	}
 	resp := http.Response{}
 	resp.Body = io.NopCloser(strings.NewReader(testText))
-	respObj, err := newHttpResponseObject(t, NewParser(), &resp)
+	respObj, err := newHttpResponseObject(t, NewParser(), &resp, time.Time{})
 	require.Nil(t, err)
 	for _, data := range testData {
 		assert.Equal(t, data.expected, respObj.searchJmespath(data.raw))
@@ -755,7 +755,7 @@ func TestSearchRegexp(t *testing.T) {
 	// new response object
 	resp := http.Response{}
 	resp.Body = io.NopCloser(strings.NewReader(testText))
-	respObj, err := newHttpResponseObject(t, NewParser(), &resp)
+	respObj, err := newHttpResponseObject(t, NewParser(), &resp, time.Time{})
 	require.Nil(t, err)
 	for _, data := range testData {
 		assert.Equal(t, data.expected, respObj.searchRegexp(data.raw))
@@ -0,0 +1,330 @@
+package boomer
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	StateInit     = "init"
+	StateSpawning = "spawning"
+	StateRunning  = "running"
+	StateStopped  = "stopped"
+)
+
+const defaultReportInterval = 3 * time.Second
+
+// Task is executed repeatedly by each spawned user, the task is picked randomly by weight.
+type Task struct {
+	Name   string
+	Weight int
+	Fn     func()
+}
+
+// Boomer is a standalone load testing engine, which spawns users to run tasks
+// concurrently and aggregates the recorded request stats.
+type Boomer struct {
+	spawnCount     int
+	spawnRate      float64
+	runTime        time.Duration
+	loopCount      int64
+	reportInterval time.Duration
+
+	disableKeepalive   bool
+	disableCompression bool
+
+	state        atomic.Value
+	currentUsers int64
+	loopsDone    int64
+	startTime    time.Time
+
+	stats   *requestStats
+	outputs []Output
+
+	spawnDoneCallbacks []func()
+
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// NewStandaloneBoomer returns a new Boomer, which spawns spawnCount users at spawnRate users per second.
+func NewStandaloneBoomer(spawnCount int, spawnRate float64) *Boomer {
+	b := &Boomer{
+		spawnCount:     spawnCount,
+		spawnRate:      spawnRate,
+		loopCount:      -1,
+		reportInterval: defaultReportInterval,
+		stats:          newRequestStats(),
+		stopChan:       make(chan struct{}),
+	}
+	b.state.Store(StateInit)
+	return b
+}
+
+// SetRunTime sets the duration of load testing, run forever if not set.
+func (b *Boomer) SetRunTime(runTime time.Duration) {
+	b.runTime = runTime
+}
+
+// SetLoopCount sets the total times of tasks to run for all users, -1 means unlimited.
+func (b *Boomer) SetLoopCount(loopCount int64) {
+	b.loopCount = loopCount
+}
+
+// SetReportInterval sets the interval of reporting stats to outputs.
+func (b *Boomer) SetReportInterval(interval time.Duration) {
+	if interval > 0 {
+		b.reportInterval = interval
+	}
+}
+
+// SetDisableKeepAlive disables keep-alive for tcp connections.
+func (b *Boomer) SetDisableKeepAlive(disableKeepalive bool) {
+	b.disableKeepalive = disableKeepalive
+}
+
+// SetDisableCompression disables compression to prevent the Transport from requesting compression.
+func (b *Boomer) SetDisableCompression(disableCompression bool) {
+	b.disableCompression = disableCompression
+}
+
+func (b *Boomer) GetDisableKeepAlive() bool {
+	return b.disableKeepalive
+}
+
+func (b *Boomer) GetDisableCompression() bool {
+	return b.disableCompression
+}
+
+// GetSpawnCount returns the number of users to spawn, implements hrp.IBoomer interface.
+func (b *Boomer) GetSpawnCount() int {
+	return b.spawnCount
+}
+
+// GetCurrentUsers returns the number of running users.
+func (b *Boomer) GetCurrentUsers() int {
+	return int(atomic.LoadInt64(&b.currentUsers))
+}
+
+func (b *Boomer) GetState() string {
+	return b.state.Load().(string)
+}
+
+// AddOutput appends an output to receive stats reports.
+func (b *Boomer) AddOutput(o Output) {
+	b.outputs = append(b.outputs, o)
+}
+
+// OnSpawnDone registers callback which will be called once all users are spawned.
+func (b *Boomer) OnSpawnDone(fn func()) {
+	b.spawnDoneCallbacks = append(b.spawnDoneCallbacks, fn)
+}
+
+// RecordSuccess reports a success request.
+func (b *Boomer) RecordSuccess(requestType, name string, responseTime int64, responseLength int64) {
+	b.stats.logRequest(requestType, name, responseTime, responseLength)
+}
+
+// RecordFailure reports a failure request.
+func (b *Boomer) RecordFailure(requestType, name string, responseTime int64, exception string) {
+	b.stats.logError(requestType, name, responseTime, exception)
+}
+
+// RecordTransaction reports a transaction, which may contain multiple requests.
+func (b *Boomer) RecordTransaction(name string, success bool, elapsedTime int64, contentSize int64) {
+	if success {
+		b.RecordSuccess("transaction", name, elapsedTime, contentSize)
+	} else {
+		b.RecordFailure("transaction", name, elapsedTime, "transaction failed")
+	}
+}
+
+// Run starts load testing and blocks until it is stopped by Stop,
+// run time or loop count reached.
+func (b *Boomer) Run(tasks ...*Task) {
+	tasks = filterTasks(tasks)
+	if len(tasks) == 0 {
+		log.Error().Msg("no valid testcase to run")
+		return
+	}
+
+	b.startTime = time.Now()
+	for _, o := range b.outputs {
+		o.OnStart()
+	}
+
+	if b.runTime > 0 {
+		time.AfterFunc(b.runTime, func() {
+			log.Info().Dur("runTime", b.runTime).Msg("run time reached, stop running")
+			b.Stop()
+		})
+	}
+
+	reportDone := make(chan struct{})
+	go b.startReporting(reportDone)
+
+	var wg sync.WaitGroup
+	b.spawnWorkers(tasks, &wg)
+	wg.Wait()
+	b.Stop()
+	b.state.Store(StateStopped)
+
+	<-reportDone
+	report := b.report(0)
+	for _, o := range b.outputs {
+		o.OnEvent(report)
+		o.OnStop()
+	}
+}
+
+// Stop stops all running users, current tasks will be finished before quit.
+func (b *Boomer) Stop() {
+	b.stopOnce.Do(func() {
+		close(b.stopChan)
+	})
+}
+
+// GetReport returns the stats of all requests since load testing started.
+func (b *Boomer) GetReport() *Report {
+	return b.report(0)
+}
+
+func (b *Boomer) isStopped() bool {
+	select {
+	case <-b.stopChan:
+		return true
+	default:
+		return false
+	}
+}
+
+func (b *Boomer) spawnWorkers(tasks []*Task, wg *sync.WaitGroup) {
+	b.state.Store(StateSpawning)
+	log.Info().Int("spawnCount", b.spawnCount).
+		Float64("spawnRate", b.spawnRate).Msg("spawning users")
+
+	var interval time.Duration
+	if b.spawnRate > 0 {
+		interval = time.Duration(float64(time.Second) / b.spawnRate)
+	}
+
+	for i := 0; i < b.spawnCount; i++ {
+		if i > 0 && interval > 0 {
+			select {
+			case <-b.stopChan:
+				return
+			case <-time.After(interval):
+			}
+		}
+		if b.isStopped() {
+			return
+		}
+
+		wg.Add(1)
+		atomic.AddInt64(&b.currentUsers, 1)
+		go func() {
+			defer func() {
+				atomic.AddInt64(&b.currentUsers, -1)
+				wg.Done()
+			}()
+			b.runWorker(tasks)
+		}()
+	}
+
+	log.Info().Int("users", b.GetCurrentUsers()).Msg("all users spawned")
+	b.state.Store(StateRunning)
+	for _, fn := range b.spawnDoneCallbacks {
+		fn()
+	}
+}
+
+func (b *Boomer) runWorker(tasks []*Task) {
+	for !b.isStopped() {
+		if b.loopCount > 0 && atomic.AddInt64(&b.loopsDone, 1) > b.loopCount {
+			log.Info().Int64("loopCount", b.loopCount).Msg("loop count reached, stop running")
+			b.Stop()
+			return
+		}
+		b.safeRun(pickTask(tasks).Fn)
+	}
+}
+
+func (b *Boomer) safeRun(fn func()) {
+	defer func() {
+		if err := recover(); err != nil {
+			log.Error().Interface("err", err).Msg("panic occurred in task")
+			b.RecordFailure("unknown", "panic", 0, "panic occurred in task")
+		}
+	}()
+	fn()
+}
+
+func (b *Boomer) startReporting(done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(b.reportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.stopChan:
+			return
+		case <-ticker.C:
+			report := b.report(b.reportInterval)
+			for _, o := range b.outputs {
+				o.OnEvent(report)
+			}
+		}
+	}
+}
+
+func (b *Boomer) report(interval time.Duration) *Report {
+	var duration time.Duration
+	if !b.startTime.IsZero() {
+		duration = time.Since(b.startTime)
+	}
+	entries, total, errors := b.stats.snapshot(interval, duration)
+	return &Report{
+		State:     b.GetState(),
+		Time:      time.Now(),
+		Duration:  duration.Seconds(),
+		UserCount: b.GetCurrentUsers(),
+		Entries:   entries,
+		Total:     total,
+		Errors:    errors,
+	}
+}
+
+func filterTasks(tasks []*Task) (validTasks []*Task) {
+	for _, task := range tasks {
+		if task == nil || task.Fn == nil {
+			continue
+		}
+		if task.Weight <= 0 {
+			task.Weight = 1
+		}
+		validTasks = append(validTasks, task)
+	}
+	return
+}
+
+// pickTask selects a task randomly, tasks with greater weight are more likely to be picked
+func pickTask(tasks []*Task) *Task {
+	if len(tasks) == 1 {
+		return tasks[0]
+	}
+	totalWeight := 0
+	for _, task := range tasks {
+		totalWeight += task.Weight
+	}
+	n := rand.Intn(totalWeight)
+	for _, task := range tasks {
+		n -= task.Weight
+		if n < 0 {
+			return task
+		}
+	}
+	return tasks[len(tasks)-1]
+}
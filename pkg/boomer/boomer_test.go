@@ -0,0 +1,126 @@
+package boomer
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBoomerLoopCount(t *testing.T) {
+	b := NewStandaloneBoomer(5, 100)
+	b.SetLoopCount(50)
+
+	var count int64
+	b.Run(&Task{
+		Name: "task",
+		Fn: func() {
+			atomic.AddInt64(&count, 1)
+			b.RecordSuccess("request-GET", "get", 10, 100)
+		},
+	})
+
+	assert.Equal(t, int64(50), atomic.LoadInt64(&count))
+	report := b.GetReport()
+	assert.Equal(t, StateStopped, report.State)
+	entry := report.GetEntry("request-GET", "get")
+	if assert.NotNil(t, entry) {
+		assert.Equal(t, int64(50), entry.NumRequests)
+		assert.Equal(t, int64(100), entry.AvgContentLength)
+	}
+}
+
+func TestBoomerRunTime(t *testing.T) {
+	b := NewStandaloneBoomer(2, 10)
+	b.SetRunTime(300 * time.Millisecond)
+
+	spawnDone := false
+	b.OnSpawnDone(func() { spawnDone = true })
+
+	start := time.Now()
+	b.Run(&Task{
+		Fn: func() { time.Sleep(10 * time.Millisecond) },
+	})
+	assert.True(t, spawnDone)
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.Equal(t, 0, b.GetCurrentUsers())
+}
+
+func TestBoomerRecoverPanic(t *testing.T) {
+	b := NewStandaloneBoomer(1, 1)
+	b.SetLoopCount(3)
+	b.Run(&Task{
+		Fn: func() { panic("boom") },
+	})
+	entry := b.GetReport().GetEntry("unknown", "panic")
+	if assert.NotNil(t, entry) {
+		assert.Equal(t, int64(3), entry.NumFailures)
+	}
+}
+
+func TestPickTaskByWeight(t *testing.T) {
+	tasks := filterTasks([]*Task{
+		{Name: "heavy", Weight: 9, Fn: func() {}},
+		{Name: "light", Weight: 1, Fn: func() {}},
+		{Name: "invalid"},
+	})
+	assert.Len(t, tasks, 2)
+
+	picked := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		picked[pickTask(tasks).Name]++
+	}
+	assert.Greater(t, picked["heavy"], 8000)
+	assert.Greater(t, picked["light"], 500)
+}
+
+func TestStatsPercentile(t *testing.T) {
+	stats := newRequestStats()
+	for i := int64(1); i <= 100; i++ {
+		stats.logRequest("request-GET", "get", i, 0)
+	}
+	stats.logError("request-GET", "get", 1234, "timeout")
+	stats.logError("request-GET", "get", 1234, "timeout")
+
+	entries, total, errors := stats.snapshot(time.Second, 0)
+	assert.Len(t, entries, 1)
+	entry := entries[0]
+	assert.Equal(t, int64(102), entry.NumRequests)
+	assert.Equal(t, int64(2), entry.NumFailures)
+	assert.Equal(t, int64(1), entry.MinResponseTime)
+	assert.Equal(t, int64(1234), entry.MaxResponseTime)
+	assert.Equal(t, int64(51), entry.Median)
+	assert.Equal(t, int64(92), entry.P90)
+	assert.Equal(t, int64(1200), entry.P99)
+	assert.Equal(t, float64(102), entry.CurrentRPS)
+	assert.Equal(t, int64(102), total.NumRequests)
+	assert.Len(t, errors, 1)
+	assert.Equal(t, int64(2), errors[0].Occurrences)
+
+	// interval counters are reset after snapshot
+	entries, _, _ = stats.snapshot(time.Second, 0)
+	assert.Equal(t, float64(0), entries[0].CurrentRPS)
+}
+
+func TestStatsAggregatedExcludesWrappers(t *testing.T) {
+	stats := newRequestStats()
+	stats.logRequest("request-GET", "get", 10, 0)
+	stats.logRequest("request-POST", "post", 20, 0)
+	stats.logRequest("transaction", "checkout", 30, 0)
+	stats.logError("testcase", "demo", 30, "step failed")
+
+	entries, total, errors := stats.snapshot(time.Second, 0)
+	assert.Len(t, entries, 4)
+	assert.Equal(t, int64(2), total.NumRequests)
+	assert.Equal(t, int64(0), total.NumFailures)
+	assert.Equal(t, int64(20), total.MaxResponseTime)
+	assert.Len(t, errors, 1)
+}
+
+func TestRoundResponseTime(t *testing.T) {
+	assert.Equal(t, int64(99), roundResponseTime(99))
+	assert.Equal(t, int64(150), roundResponseTime(147))
+	assert.Equal(t, int64(1200), roundResponseTime(1234))
+	assert.Equal(t, int64(13000), roundResponseTime(12600))
+}
@@ -0,0 +1,60 @@
+package boomer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+)
+
+// Output is used to receive stats reports during load testing.
+type Output interface {
+	OnStart()
+	OnEvent(report *Report)
+	OnStop()
+}
+
+// ConsoleOutput prints stats reports in table format.
+type ConsoleOutput struct {
+	writer io.Writer
+}
+
+// NewConsoleOutput returns a ConsoleOutput which prints to stdout.
+func NewConsoleOutput() *ConsoleOutput {
+	return &ConsoleOutput{writer: os.Stdout}
+}
+
+func (o *ConsoleOutput) OnStart() {
+	fmt.Fprintln(o.writer, "load testing started")
+}
+
+func (o *ConsoleOutput) OnEvent(report *Report) {
+	fmt.Fprintf(o.writer, "\nstate: %s, users: %d, duration: %.1fs, total RPS: %.1f, total fail ratio: %.1f%%\n",
+		report.State, report.UserCount, report.Duration,
+		report.Total.CurrentRPS, report.Total.FailRatio()*100)
+
+	w := tabwriter.NewWriter(o.writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Type\tName\t# requests\t# fails\tMedian(ms)\tAverage(ms)\tMin(ms)\tMax(ms)\t90%(ms)\t95%(ms)\t99%(ms)\tContent Size(bytes)\tCurrent RPS\tCurrent Fail/s")
+	for _, entry := range append(report.Entries, report.Total) {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%.2f\t%d\t%d\t%d\t%d\t%d\t%d\t%.2f\t%.2f\n",
+			entry.Type, entry.Name, entry.NumRequests, entry.NumFailures,
+			entry.Median, entry.AvgResponseTime, entry.MinResponseTime, entry.MaxResponseTime,
+			entry.P90, entry.P95, entry.P99, entry.AvgContentLength,
+			entry.CurrentRPS, entry.CurrentFailPerSec)
+	}
+	w.Flush()
+
+	if len(report.Errors) == 0 {
+		return
+	}
+	w = tabwriter.NewWriter(o.writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "# occurrences\tType\tName\tError")
+	for _, e := range report.Errors {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", e.Occurrences, e.Type, e.Name, e.Error)
+	}
+	w.Flush()
+}
+
+func (o *ConsoleOutput) OnStop() {
+	fmt.Fprintln(o.writer, "load testing stopped")
+}
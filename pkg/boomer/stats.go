@@ -0,0 +1,261 @@
+package boomer
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// requestStats aggregates all request records of current load testing
+type requestStats struct {
+	mu      sync.Mutex
+	entries map[string]*statsEntry
+	total   *statsEntry
+	errors  map[string]*ErrorReport
+}
+
+// record types of testcases and transactions, which wrap other requests and are excluded from
+// aggregated stats to avoid counting the same requests twice
+var nonAggregatedTypes = map[string]bool{
+	"testcase":    true,
+	"transaction": true,
+}
+
+func newRequestStats() *requestStats {
+	return &requestStats{
+		entries: make(map[string]*statsEntry),
+		total:   newStatsEntry("", "Aggregated"),
+		errors:  make(map[string]*ErrorReport),
+	}
+}
+
+func (s *requestStats) get(requestType, name string) *statsEntry {
+	key := requestType + "\x00" + name
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = newStatsEntry(requestType, name)
+		s.entries[key] = entry
+	}
+	return entry
+}
+
+func (s *requestStats) logRequest(requestType, name string, responseTime int64, contentLength int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.get(requestType, name).log(responseTime, contentLength)
+	if !nonAggregatedTypes[requestType] {
+		s.total.log(responseTime, contentLength)
+	}
+}
+
+func (s *requestStats) logError(requestType, name string, responseTime int64, errMsg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.get(requestType, name).logError(responseTime)
+	if !nonAggregatedTypes[requestType] {
+		s.total.logError(responseTime)
+	}
+
+	key := requestType + "\x00" + name + "\x00" + errMsg
+	e, ok := s.errors[key]
+	if !ok {
+		e = &ErrorReport{Type: requestType, Name: name, Error: errMsg}
+		s.errors[key] = e
+	}
+	e.Occurrences++
+}
+
+// snapshot collects current stats, the RPS is calculated in the given interval and
+// interval counters are reset, or averaged over the whole duration if interval is zero.
+func (s *requestStats) snapshot(interval, duration time.Duration) (entries []*EntryReport, total *EntryReport, errors []*ErrorReport) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, entry := range s.entries {
+		entries = append(entries, entry.report(interval, duration))
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Type != entries[j].Type {
+			return entries[i].Type < entries[j].Type
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	total = s.total.report(interval, duration)
+
+	for _, e := range s.errors {
+		copied := *e
+		errors = append(errors, &copied)
+	}
+	sort.Slice(errors, func(i, j int) bool {
+		return errors[i].Occurrences > errors[j].Occurrences
+	})
+	return
+}
+
+type statsEntry struct {
+	requestType        string
+	name               string
+	numRequests        int64
+	numFailures        int64
+	totalResponseTime  int64
+	minResponseTime    int64
+	maxResponseTime    int64
+	totalContentLength int64
+	responseTimes      map[int64]int64 // rounded response time(ms) -> count
+
+	intervalRequests int64 // requests since last snapshot
+	intervalFailures int64 // failures since last snapshot
+}
+
+func newStatsEntry(requestType, name string) *statsEntry {
+	return &statsEntry{
+		requestType:   requestType,
+		name:          name,
+		responseTimes: make(map[int64]int64),
+	}
+}
+
+func (e *statsEntry) log(responseTime int64, contentLength int64) {
+	e.numRequests++
+	e.intervalRequests++
+	e.logResponseTime(responseTime)
+	e.totalContentLength += contentLength
+}
+
+func (e *statsEntry) logError(responseTime int64) {
+	e.numRequests++
+	e.numFailures++
+	e.intervalRequests++
+	e.intervalFailures++
+	e.logResponseTime(responseTime)
+}
+
+func (e *statsEntry) logResponseTime(responseTime int64) {
+	e.totalResponseTime += responseTime
+	if e.numRequests == 1 || responseTime < e.minResponseTime {
+		e.minResponseTime = responseTime
+	}
+	if responseTime > e.maxResponseTime {
+		e.maxResponseTime = responseTime
+	}
+	e.responseTimes[roundResponseTime(responseTime)]++
+}
+
+// roundResponseTime keeps two significant digits for response time longer than 100ms,
+// in order to reduce memory usage of percentile calculation
+func roundResponseTime(responseTime int64) int64 {
+	if responseTime < 100 {
+		return responseTime
+	}
+	exp := math.Pow10(int(math.Log10(float64(responseTime))) - 1)
+	return int64(math.Round(float64(responseTime)/exp) * exp)
+}
+
+// percentile returns the response time(ms) under which the given percent of requests fall
+func (e *statsEntry) percentile(percent float64) int64 {
+	if e.numRequests == 0 {
+		return 0
+	}
+	times := make([]int64, 0, len(e.responseTimes))
+	for t := range e.responseTimes {
+		times = append(times, t)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+
+	threshold := int64(math.Ceil(float64(e.numRequests) * percent))
+	var count int64
+	for _, t := range times {
+		count += e.responseTimes[t]
+		if count >= threshold {
+			return t
+		}
+	}
+	return times[len(times)-1]
+}
+
+func (e *statsEntry) report(interval, duration time.Duration) *EntryReport {
+	r := &EntryReport{
+		Type:            e.requestType,
+		Name:            e.name,
+		NumRequests:     e.numRequests,
+		NumFailures:     e.numFailures,
+		MinResponseTime: e.minResponseTime,
+		MaxResponseTime: e.maxResponseTime,
+		Median:          e.percentile(0.5),
+		P90:             e.percentile(0.9),
+		P95:             e.percentile(0.95),
+		P99:             e.percentile(0.99),
+	}
+	if e.numRequests > 0 {
+		r.AvgResponseTime = float64(e.totalResponseTime) / float64(e.numRequests)
+		if success := e.numRequests - e.numFailures; success > 0 {
+			r.AvgContentLength = e.totalContentLength / success
+		}
+	}
+	if seconds := interval.Seconds(); seconds > 0 {
+		r.CurrentRPS = float64(e.intervalRequests) / seconds
+		r.CurrentFailPerSec = float64(e.intervalFailures) / seconds
+		e.intervalRequests = 0
+		e.intervalFailures = 0
+	} else if seconds := duration.Seconds(); seconds > 0 {
+		r.CurrentRPS = float64(e.numRequests) / seconds
+		r.CurrentFailPerSec = float64(e.numFailures) / seconds
+	}
+	return r
+}
+
+// ErrorReport records occurrences of one error message.
+type ErrorReport struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Error       string `json:"error"`
+	Occurrences int64  `json:"occurrences"`
+}
+
+// EntryReport is the aggregated stats of one request type and name.
+type EntryReport struct {
+	Type              string  `json:"type"`
+	Name              string  `json:"name"`
+	NumRequests       int64   `json:"num_requests"`
+	NumFailures       int64   `json:"num_failures"`
+	CurrentRPS        float64 `json:"current_rps"`
+	CurrentFailPerSec float64 `json:"current_fail_per_sec"`
+	AvgResponseTime   float64 `json:"avg_response_time"`
+	MinResponseTime   int64   `json:"min_response_time"`
+	MaxResponseTime   int64   `json:"max_response_time"`
+	Median            int64   `json:"median_response_time"`
+	P90               int64   `json:"p90_response_time"`
+	P95               int64   `json:"p95_response_time"`
+	P99               int64   `json:"p99_response_time"`
+	AvgContentLength  int64   `json:"avg_content_length"`
+}
+
+// FailRatio returns the ratio of failures in all requests.
+func (r *EntryReport) FailRatio() float64 {
+	if r.NumRequests == 0 {
+		return 0
+	}
+	return float64(r.NumFailures) / float64(r.NumRequests)
+}
+
+// Report is the stats snapshot of current load testing, reported to outputs periodically.
+type Report struct {
+	State     string         `json:"state"`
+	Time      time.Time      `json:"time"`
+	Duration  float64        `json:"duration"` // seconds since load testing started
+	UserCount int            `json:"user_count"`
+	Entries   []*EntryReport `json:"entries"`
+	Total     *EntryReport   `json:"total"`
+	Errors    []*ErrorReport `json:"errors,omitempty"`
+}
+
+// GetEntry returns the stats entry matched with the given request type and name.
+func (r *Report) GetEntry(requestType, name string) *EntryReport {
+	for _, entry := range r.Entries {
+		if entry.Type == requestType && entry.Name == name {
+			return entry
+		}
+	}
+	return nil
+}
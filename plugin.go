@@ -12,6 +12,7 @@ import (
 	"github.com/httprunner/funplugin"
 	"github.com/httprunner/funplugin/myexec"
 	"github.com/httprunner/httprunner/v5/code"
+	"github.com/httprunner/httprunner/v5/internal/builtin"
 	"github.com/httprunner/httprunner/v5/internal/config"
 	"github.com/httprunner/httprunner/v5/internal/sdk"
 )
@@ -190,3 +191,19 @@ func GetProjectRootDirPath(path string) (rootDir string, err error) {
 	// use current dir instead
 	return config.GetConfig().RootDir, nil
 }
+
+// resolveCaseFilePath resolves file path relative to project root dir of testcase,
+// the path is returned as it is if it is absolute or not found in project root dir.
+func resolveCaseFilePath(casePath, path string) string {
+	if filepath.IsAbs(path) || casePath == "" {
+		return path
+	}
+	rootDir, err := GetProjectRootDirPath(casePath)
+	if err != nil {
+		return path
+	}
+	if p := filepath.Join(rootDir, path); builtin.IsFilePathExists(p) {
+		return p
+	}
+	return path
+}
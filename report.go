@@ -956,6 +956,12 @@ const htmlTemplate = `<!DOCTYPE html>
             text-align: center;
         }
 
+        .trace-id {
+            color: #6c757d;
+            font-size: 0.85em;
+            margin-bottom: 10px;
+        }
+
         .step-number {
             background: linear-gradient(135deg, #007bff 0%, #0056b3 100%);
             color: white;
@@ -988,6 +994,11 @@ const htmlTemplate = `<!DOCTYPE html>
             color: white;
         }
 
+        .status-badge.skipped {
+            background: linear-gradient(135deg, #adb5bd 0%, #868e96 100%);
+            color: white;
+        }
+
         .duration {
             background: linear-gradient(135deg, #6c757d 0%, #5a6268 100%);
             color: white;
@@ -1027,11 +1038,11 @@ const htmlTemplate = `<!DOCTYPE html>
             display: block;
         }
 
-        .actions-section, .validators-section, .screenshots-section, .logs-section {
+        .actions-section, .validators-section, .attempts-section, .screenshots-section, .logs-section {
             margin-bottom: 30px;
         }
 
-        .actions-section h4, .validators-section h4, .screenshots-section h4, .logs-section h4 {
+        .actions-section h4, .validators-section h4, .attempts-section h4, .screenshots-section h4, .logs-section h4 {
             color: #495057;
             margin-bottom: 20px;
             padding-bottom: 12px;
@@ -2533,6 +2544,12 @@ const htmlTemplate = `<!DOCTYPE html>
                     <div class="value">{{.Stat.TestSteps.Total}}</div>
                     <div class="label">Total Steps</div>
                 </div>
+                {{if .Stat.TestSteps.Skipped}}
+                <div class="summary-item">
+                    <div class="value">{{.Stat.TestSteps.Skipped}}</div>
+                    <div class="label">Skipped Steps</div>
+                </div>
+                {{end}}
                 <div class="summary-item">
                     <div class="value">{{calculateTotalActions}}</div>
                     <div class="label">Total Actions</div>
@@ -2603,8 +2620,8 @@ const htmlTemplate = `<!DOCTYPE html>
                             <span class="step-number">{{add $stepIndex 1}}</span>
                             {{$step.Name}}
                             <div class="step-info-group">
-                                <span class="status-badge step-status {{if $step.Success}}success{{else}}failure{{end}}">
-                                    {{if $step.Success}}✓ PASS{{else}}✗ FAIL{{end}}
+                                <span class="status-badge step-status {{if $step.Skipped}}skipped{{else if $step.Success}}success{{else}}failure{{end}}">
+                                    {{if $step.Skipped}}↷ SKIP{{else if $step.Success}}✓ PASS{{else}}✗ FAIL{{end}}
                                 </span>
                                 <span class="duration step-duration">{{formatDuration $step.Elapsed}}</span>
                                 <span class="step-type step-type-fixed">{{$step.StepType}}</span>
@@ -2614,6 +2631,9 @@ const htmlTemplate = `<!DOCTYPE html>
                     </div>
 
                     <div class="step-content" id="step-{{$stepIndex}}">
+                        {{if $step.TraceID}}
+                        <div class="trace-id">Trace ID: <code>{{$step.TraceID}}</code></div>
+                        {{end}}
                         <!-- Actions -->
                         {{if $step.Actions}}
                         <div class="actions-section">
@@ -2956,6 +2976,13 @@ const htmlTemplate = `<!DOCTYPE html>
                                 {{if and $validator.msg (ne $validator.check_result "pass")}}
                                     <div class="validator-message">{{$validator.msg}}</div>
                                 {{end}}
+                                {{if $validator.violations}}
+                                    <div class="validator-message">
+                                    {{range $violation := $validator.violations}}
+                                        <div>{{if $violation.pointer}}{{$violation.pointer}}{{else}}(root){{end}} [{{$violation.keyword}}] {{$violation.message}}</div>
+                                    {{end}}
+                                    </div>
+                                {{end}}
 
                                 <!-- AI Assertion Results -->
                                 {{if $validator.ai_result}}
@@ -3022,6 +3049,30 @@ const htmlTemplate = `<!DOCTYPE html>
                         </div>
                         {{end}}
 
+                        <!-- Retry Attempts -->
+                        {{if $step.Attempts}}
+                        <div class="attempts-section">
+                            <h4>🔁 Attempts ({{len $step.Attempts}})</h4>
+                            {{range $attempt := $step.Attempts}}
+                            <div class="validator-item {{if $attempt.Success}}success{{else}}failure{{end}}">
+                                <div class="validator-header">
+                                    <strong>Attempt {{$attempt.Attempt}}</strong>
+                                    <span class="status-badge {{if $attempt.Success}}success{{else}}failure{{end}}">
+                                        {{if $attempt.Success}}✓ PASS{{else}}✗ FAIL{{end}}
+                                    </span>
+                                    <span class="duration">{{formatDuration $attempt.Elapsed}}</span>
+                                </div>
+                                {{if $attempt.Error}}
+                                    <div class="validator-message">{{$attempt.Error}}</div>
+                                {{end}}
+                                {{if $attempt.RetryReason}}
+                                    <div class="validator-expect">Retry: {{$attempt.RetryReason}}</div>
+                                {{end}}
+                            </div>
+                            {{end}}
+                        </div>
+                        {{end}}
+
                         <!-- ScreenShots -->
                         {{if $step.Attachments}}
                         {{$attachments := $step.Attachments}}
@@ -0,0 +1,235 @@
+package hrp
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/httprunner/httprunner/v5/internal/builtin"
+	"github.com/httprunner/httprunner/v5/internal/config"
+)
+
+// Reporter generates report from tests summary, e.g. JUnit XML, Allure results and CTRF json.
+type Reporter interface {
+	Name() string
+	// Generate writes report into results folder and returns the report path.
+	Generate(summary *Summary, resultsDir string) (string, error)
+}
+
+var reporters sync.Map // name -> Reporter
+
+// RegisterReporter registers reporter which could be selected by name, existing reporter is replaced.
+func RegisterReporter(reporter Reporter) {
+	reporters.Store(strings.ToLower(reporter.Name()), reporter)
+}
+
+// GetReporter returns registered reporter by name.
+func GetReporter(name string) (Reporter, error) {
+	if reporter, ok := reporters.Load(strings.ToLower(strings.TrimSpace(name))); ok {
+		return reporter.(Reporter), nil
+	}
+	return nil, errors.Errorf("reporter %s not found, available reporters: %s",
+		name, strings.Join(ReporterNames(), ","))
+}
+
+// ReporterNames returns names of registered reporters.
+func ReporterNames() []string {
+	var names []string
+	reporters.Range(func(key, value interface{}) bool {
+		names = append(names, key.(string))
+		return true
+	})
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	RegisterReporter(&htmlReporter{})
+	RegisterReporter(&JUnitReporter{})
+	RegisterReporter(&AllureReporter{})
+	RegisterReporter(&CTRFReporter{})
+}
+
+// GenReports generates reports with specified reporters in results folder.
+func (s *Summary) GenReports(names ...string) error {
+	return GenerateReports(s, filepath.Dir(s.GetSummaryFilePath()), names...)
+}
+
+// GenerateReports generates reports with specified reporters, all reporters are tried even if some fails.
+func GenerateReports(summary *Summary, resultsDir string, names ...string) error {
+	var errs []string
+	for _, name := range names {
+		reporter, err := GetReporter(name)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		path, err := reporter.Generate(summary, resultsDir)
+		if err != nil {
+			log.Error().Err(err).Str("reporter", reporter.Name()).Msg("generate report failed")
+			errs = append(errs, fmt.Sprintf("%s: %v", reporter.Name(), err))
+			continue
+		}
+		log.Info().Str("reporter", reporter.Name()).Str("path", path).Msg("report generated")
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// GenerateReportsFromFiles generates reports from summary file in existing results folder.
+func GenerateReportsFromFiles(summaryFile string, names ...string) error {
+	data, err := os.ReadFile(summaryFile)
+	if err != nil {
+		return errors.Wrap(err, "read summary file failed")
+	}
+	summary := &Summary{}
+	if err := json.Unmarshal(data, summary); err != nil {
+		return errors.Wrap(err, "parse summary file failed")
+	}
+	return GenerateReports(summary, filepath.Dir(summaryFile), names...)
+}
+
+// htmlReporter generates report.html from summary and log files in results folder
+type htmlReporter struct{}
+
+func (r *htmlReporter) Name() string {
+	return "html"
+}
+
+func (r *htmlReporter) Generate(summary *Summary, resultsDir string) (string, error) {
+	summaryFile := filepath.Join(resultsDir, config.SummaryFileName)
+	reportFile := filepath.Join(resultsDir, config.ReportFileName)
+	if _, err := os.Stat(summaryFile); os.IsNotExist(err) {
+		if err := builtin.EnsureFolderExists(resultsDir); err != nil {
+			return "", err
+		}
+		if err := builtin.Dump2JSON(summary, summaryFile); err != nil {
+			return "", err
+		}
+	}
+	err := GenerateHTMLReportFromFiles(summaryFile, filepath.Join(resultsDir, config.LogFileName), reportFile)
+	return reportFile, err
+}
+
+// stepDetail is the unified view of step result, data loaded from summary file are maps instead of typed structs
+type stepDetail struct {
+	Data struct {
+		ReqResps *struct {
+			Request  interface{} `json:"request"`
+			Response interface{} `json:"response"`
+		} `json:"req_resps"`
+		Validators []struct {
+			Check       string      `json:"check"`
+			Assert      string      `json:"assert"`
+			Expect      interface{} `json:"expect"`
+			Message     string      `json:"msg"`
+			CheckValue  interface{} `json:"check_value"`
+			CheckResult string      `json:"check_result"`
+		} `json:"validators"`
+	}
+	Actions []struct {
+		Method        string `json:"method"`
+		Error         string `json:"error"`
+		ScreenResults []struct {
+			ImagePath string `json:"image_path"`
+		} `json:"screen_results"`
+	}
+	Attachments struct {
+		Error         string
+		ScreenResults []struct {
+			ImagePath string `json:"image_path"`
+		} `json:"screen_results"`
+	}
+}
+
+func newStepDetail(step *StepResult) *stepDetail {
+	detail := &stepDetail{}
+	if data, err := json.Marshal(step.Data); err == nil {
+		_ = json.Unmarshal(data, &detail.Data)
+	}
+	if data, err := json.Marshal(step.Actions); err == nil {
+		_ = json.Unmarshal(data, &detail.Actions)
+	}
+	switch v := step.Attachments.(type) {
+	case string:
+		detail.Attachments.Error = v
+	case nil:
+	default:
+		var attachments struct {
+			Error         interface{} `json:"error"`
+			ScreenResults []struct {
+				ImagePath string `json:"image_path"`
+			} `json:"screen_results"`
+		}
+		if data, err := json.Marshal(v); err == nil {
+			_ = json.Unmarshal(data, &attachments)
+		}
+		if attachments.Error != nil {
+			detail.Attachments.Error = fmt.Sprint(attachments.Error)
+		}
+		detail.Attachments.ScreenResults = attachments.ScreenResults
+	}
+	return detail
+}
+
+// failure returns failure message of step, including failed validators, action errors and step error
+func (d *stepDetail) failure() string {
+	var messages []string
+	for _, validator := range d.Data.Validators {
+		if validator.CheckResult != "fail" {
+			continue
+		}
+		messages = append(messages, fmt.Sprintf("assert %s %s %v failed, got %v %s",
+			validator.Check, validator.Assert, validator.Expect, validator.CheckValue, validator.Message))
+	}
+	for _, action := range d.Actions {
+		if action.Error != "" {
+			messages = append(messages, fmt.Sprintf("action %s failed: %s", action.Method, action.Error))
+		}
+	}
+	if d.Attachments.Error != "" {
+		messages = append(messages, d.Attachments.Error)
+	}
+	return strings.TrimSpace(strings.Join(messages, "\n"))
+}
+
+// screenshots returns image paths of step, relative paths are joined with results folder
+func (d *stepDetail) screenshots(resultsDir string) []string {
+	var paths []string
+	add := func(path string) {
+		if path == "" {
+			return
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(resultsDir, path)
+		}
+		paths = append(paths, path)
+	}
+	for _, screen := range d.Attachments.ScreenResults {
+		add(screen.ImagePath)
+	}
+	for _, action := range d.Actions {
+		for _, screen := range action.ScreenResults {
+			add(screen.ImagePath)
+		}
+	}
+	return paths
+}
+
+func stepStatus(step *StepResult) string {
+	if step.Skipped {
+		return "skipped"
+	} else if step.Success {
+		return "passed"
+	}
+	return "failed"
+}
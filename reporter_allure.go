@@ -0,0 +1,177 @@
+package hrp
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/httprunner/httprunner/v5/internal/builtin"
+)
+
+const allureResultsDirName = "allure-results"
+
+type allureResult struct {
+	UUID          string              `json:"uuid"`
+	HistoryID     string              `json:"historyId"`
+	Name          string              `json:"name"`
+	FullName      string              `json:"fullName"`
+	Status        string              `json:"status"`
+	StatusDetails *allureStatusDetail `json:"statusDetails,omitempty"`
+	Stage         string              `json:"stage"`
+	Start         int64               `json:"start"`
+	Stop          int64               `json:"stop"`
+	Labels        []allureLabel       `json:"labels"`
+	Steps         []*allureStep       `json:"steps"`
+	Attachments   []*allureAttachment `json:"attachments"`
+}
+
+type allureStep struct {
+	Name          string              `json:"name"`
+	Status        string              `json:"status"`
+	StatusDetails *allureStatusDetail `json:"statusDetails,omitempty"`
+	Stage         string              `json:"stage"`
+	Start         int64               `json:"start"`
+	Stop          int64               `json:"stop"`
+	Attachments   []*allureAttachment `json:"attachments"`
+}
+
+type allureStatusDetail struct {
+	Message string `json:"message,omitempty"`
+	Trace   string `json:"trace,omitempty"`
+}
+
+type allureLabel struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type allureAttachment struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+	Type   string `json:"type"`
+}
+
+// AllureReporter generates allure-results folder, each testcase is a test result with steps,
+// request/response and screenshots of steps are saved as attachments.
+type AllureReporter struct{}
+
+func (r *AllureReporter) Name() string {
+	return "allure"
+}
+
+func (r *AllureReporter) Generate(summary *Summary, resultsDir string) (string, error) {
+	allureDir := filepath.Join(resultsDir, allureResultsDirName)
+	if err := builtin.EnsureFolderExists(allureDir); err != nil {
+		return "", err
+	}
+
+	for _, caseSummary := range summary.Details {
+		result := &allureResult{
+			UUID:      uuid.NewString(),
+			HistoryID: builtin.MD5(caseSummary.Name),
+			Name:      caseSummary.Name,
+			FullName:  caseSummary.Name,
+			Status:    "passed",
+			Stage:     "finished",
+			Labels: []allureLabel{
+				{Name: "framework", Value: "httprunner"},
+				{Name: "language", Value: "go"},
+				{Name: "suite", Value: caseSummary.Name},
+			},
+			Steps:       []*allureStep{},
+			Attachments: []*allureAttachment{},
+		}
+		if caseSummary.Time != nil {
+			result.Start = caseSummary.Time.StartAt.UnixMilli()
+			result.Stop = result.Start + int64(caseSummary.Time.Duration*1000)
+		}
+
+		var failures []string
+		for _, stepResult := range caseSummary.Records {
+			detail := newStepDetail(stepResult)
+			step := &allureStep{
+				Name:        stepResult.Name,
+				Status:      stepStatus(stepResult),
+				Stage:       "finished",
+				Start:       stepResult.StartTime,
+				Stop:        stepResult.StartTime + stepResult.Elapsed,
+				Attachments: []*allureAttachment{},
+			}
+			if step.Status == "failed" {
+				message := detail.failure()
+				step.StatusDetails = &allureStatusDetail{Message: message}
+				failures = append(failures, stepResult.Name+": "+message)
+			}
+			if detail.Data.ReqResps != nil {
+				request, err := writeAllureJSONAttachment(allureDir, "request", detail.Data.ReqResps.Request)
+				if err != nil {
+					return "", err
+				}
+				response, err := writeAllureJSONAttachment(allureDir, "response", detail.Data.ReqResps.Response)
+				if err != nil {
+					return "", err
+				}
+				step.Attachments = append(step.Attachments, request, response)
+			}
+			for _, imagePath := range detail.screenshots(resultsDir) {
+				attachment, err := copyAllureAttachment(allureDir, imagePath)
+				if err != nil {
+					log.Warn().Err(err).Str("path", imagePath).Msg("copy screenshot attachment failed")
+					continue
+				}
+				step.Attachments = append(step.Attachments, attachment)
+			}
+			result.Steps = append(result.Steps, step)
+		}
+		if !caseSummary.Success {
+			result.Status = "failed"
+			result.StatusDetails = &allureStatusDetail{Message: strings.Join(failures, "\n")}
+		}
+
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		resultFile := filepath.Join(allureDir, result.UUID+"-result.json")
+		if err := os.WriteFile(resultFile, data, 0o644); err != nil {
+			return "", err
+		}
+	}
+	return allureDir, nil
+}
+
+func writeAllureJSONAttachment(allureDir, name string, content interface{}) (*allureAttachment, error) {
+	data, err := json.MarshalIndent(content, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	source := uuid.NewString() + "-attachment.json"
+	if err := os.WriteFile(filepath.Join(allureDir, source), data, 0o644); err != nil {
+		return nil, err
+	}
+	return &allureAttachment{Name: name, Source: source, Type: "application/json"}, nil
+}
+
+func copyAllureAttachment(allureDir, path string) (*allureAttachment, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	mimeType := "image/png"
+	switch ext {
+	case ".jpg", ".jpeg":
+		mimeType = "image/jpeg"
+	case ".gif":
+		mimeType = "image/gif"
+	}
+	source := uuid.NewString() + "-attachment" + ext
+	if err := os.WriteFile(filepath.Join(allureDir, source), data, 0o644); err != nil {
+		return nil, err
+	}
+	return &allureAttachment{Name: filepath.Base(path), Source: source, Type: mimeType}, nil
+}
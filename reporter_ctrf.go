@@ -0,0 +1,109 @@
+package hrp
+
+import (
+	"path/filepath"
+
+	"github.com/httprunner/httprunner/v5/internal/builtin"
+)
+
+const ctrfReportFileName = "ctrf-report.json"
+
+// CTRF(Common Test Report Format) schema, https://ctrf.io/docs/schema/overview
+type ctrfReport struct {
+	Results ctrfResults `json:"results"`
+}
+
+type ctrfResults struct {
+	Tool        ctrfTool               `json:"tool"`
+	Summary     ctrfSummary            `json:"summary"`
+	Tests       []*ctrfTest            `json:"tests"`
+	Environment map[string]interface{} `json:"environment,omitempty"`
+}
+
+type ctrfTool struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type ctrfSummary struct {
+	Tests   int   `json:"tests"`
+	Passed  int   `json:"passed"`
+	Failed  int   `json:"failed"`
+	Pending int   `json:"pending"`
+	Skipped int   `json:"skipped"`
+	Other   int   `json:"other"`
+	Start   int64 `json:"start"`
+	Stop    int64 `json:"stop"`
+}
+
+type ctrfTest struct {
+	Name        string   `json:"name"`
+	Status      string   `json:"status"` // passed, failed, skipped
+	Duration    int64    `json:"duration"`
+	Start       int64    `json:"start,omitempty"`
+	Stop        int64    `json:"stop,omitempty"`
+	Suite       string   `json:"suite,omitempty"`
+	Type        string   `json:"type,omitempty"`
+	Message     string   `json:"message,omitempty"`
+	Screenshots []string `json:"screenshots,omitempty"`
+}
+
+// CTRFReporter generates ctrf-report.json, each step is a test.
+type CTRFReporter struct{}
+
+func (r *CTRFReporter) Name() string {
+	return "ctrf"
+}
+
+func (r *CTRFReporter) Generate(summary *Summary, resultsDir string) (string, error) {
+	report := &ctrfReport{
+		Results: ctrfResults{
+			Tool:  ctrfTool{Name: "httprunner"},
+			Tests: []*ctrfTest{},
+		},
+	}
+	if summary.Platform != nil {
+		report.Results.Tool.Version = summary.Platform.HttprunnerVersion
+		report.Results.Environment = map[string]interface{}{
+			"goVersion": summary.Platform.GoVersion,
+			"platform":  summary.Platform.Platform,
+		}
+	}
+	if summary.Time != nil {
+		report.Results.Summary.Start = summary.Time.StartAt.UnixMilli()
+		report.Results.Summary.Stop = report.Results.Summary.Start + int64(summary.Time.Duration*1000)
+	}
+
+	for _, caseSummary := range summary.Details {
+		for _, step := range caseSummary.Records {
+			detail := newStepDetail(step)
+			test := &ctrfTest{
+				Name:        step.Name,
+				Status:      stepStatus(step),
+				Duration:    step.Elapsed,
+				Start:       step.StartTime,
+				Stop:        step.StartTime + step.Elapsed,
+				Suite:       caseSummary.Name,
+				Type:        string(step.StepType),
+				Screenshots: detail.screenshots(resultsDir),
+			}
+			switch test.Status {
+			case "passed":
+				report.Results.Summary.Passed++
+			case "failed":
+				test.Message = detail.failure()
+				report.Results.Summary.Failed++
+			case "skipped":
+				report.Results.Summary.Skipped++
+			}
+			report.Results.Summary.Tests++
+			report.Results.Tests = append(report.Results.Tests, test)
+		}
+	}
+
+	if err := builtin.EnsureFolderExists(resultsDir); err != nil {
+		return "", err
+	}
+	path := filepath.Join(resultsDir, ctrfReportFileName)
+	return path, builtin.Dump2JSON(report, path)
+}
@@ -0,0 +1,129 @@
+package hrp
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/httprunner/httprunner/v5/internal/builtin"
+)
+
+const junitReportFileName = "junit.xml"
+
+type junitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Name     string            `xml:"name,attr"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Skipped  int               `xml:"skipped,attr"`
+	Time     string            `xml:"time,attr"`
+	Suites   []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string           `xml:"name,attr"`
+	Tests     int              `xml:"tests,attr"`
+	Failures  int              `xml:"failures,attr"`
+	Skipped   int              `xml:"skipped,attr"`
+	Time      string           `xml:"time,attr"`
+	Timestamp string           `xml:"timestamp,attr,omitempty"`
+	Cases     []*junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *struct{}     `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Content string `xml:",chardata"`
+}
+
+// JUnitReporter generates junit.xml, each testcase is a test suite and each step is a test case.
+type JUnitReporter struct{}
+
+func (r *JUnitReporter) Name() string {
+	return "junit"
+}
+
+func (r *JUnitReporter) Generate(summary *Summary, resultsDir string) (string, error) {
+	suites := &junitTestSuites{Name: "httprunner"}
+	if summary.Time != nil {
+		suites.Time = formatSeconds(summary.Time.Duration)
+	}
+	for _, caseSummary := range summary.Details {
+		suite := &junitTestSuite{Name: caseSummary.Name}
+		if caseSummary.Time != nil {
+			suite.Time = formatSeconds(caseSummary.Time.Duration)
+			if !caseSummary.Time.StartAt.IsZero() {
+				suite.Timestamp = caseSummary.Time.StartAt.Format("2006-01-02T15:04:05")
+			}
+		}
+		for _, step := range caseSummary.Records {
+			testCase := &junitTestCase{
+				Name:      step.Name,
+				ClassName: caseSummary.Name,
+				Time:      formatSeconds(float64(step.Elapsed) / 1000),
+			}
+			detail := newStepDetail(step)
+			switch stepStatus(step) {
+			case "skipped":
+				testCase.Skipped = &struct{}{}
+				suite.Skipped++
+			case "failed":
+				message := detail.failure()
+				if message == "" {
+					message = fmt.Sprintf("step %s failed", step.Name)
+				}
+				testCase.Failure = &junitFailure{
+					Message: firstLine(message),
+					Type:    string(step.StepType),
+					Content: message,
+				}
+				suite.Failures++
+			}
+			if detail.Data.ReqResps != nil {
+				if data, err := json.MarshalIndent(detail.Data.ReqResps, "", "  "); err == nil {
+					testCase.SystemOut = string(data)
+				}
+			}
+			suite.Cases = append(suite.Cases, testCase)
+			suite.Tests++
+		}
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Skipped += suite.Skipped
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	data, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := builtin.EnsureFolderExists(resultsDir); err != nil {
+		return "", err
+	}
+	path := filepath.Join(resultsDir, junitReportFileName)
+	return path, os.WriteFile(path, append([]byte(xml.Header), data...), 0o644)
+}
+
+func formatSeconds(seconds float64) string {
+	return fmt.Sprintf("%.3f", seconds)
+}
+
+func firstLine(s string) string {
+	for i, c := range s {
+		if c == '\n' {
+			return s[:i]
+		}
+	}
+	return s
+}
@@ -1,22 +1,24 @@
 package hrp
 
 import (
+	"context"
 	"crypto/tls"
 	_ "embed"
 	"fmt"
 	"net"
 	"net/http"
-	"net/http/cookiejar"
 	"net/url"
 	"os"
 	"os/signal"
 	"reflect"
 	"sort"
 	"strings"
+	"sync"
 	"syscall"
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/httprunner/funplugin"
 	"github.com/jinzhu/copier"
@@ -44,7 +46,6 @@ func NewRunner(t *testing.T) *HRPRunner {
 	if t == nil {
 		t = &testing.T{}
 	}
-	jar, _ := cookiejar.New(nil)
 	interruptSignal := make(chan os.Signal, 1)
 	signal.Notify(interruptSignal, syscall.SIGTERM, syscall.SIGINT)
 	return &HRPRunner{
@@ -57,14 +58,12 @@ func NewRunner(t *testing.T) *HRPRunner {
 			Transport: &http.Transport{
 				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 			},
-			Jar:     jar, // insert response cookies into request
 			Timeout: 120 * time.Second,
 		},
 		http2Client: &http.Client{
 			Transport: &http2.Transport{
 				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 			},
-			Jar:     jar, // insert response cookies into request
 			Timeout: 120 * time.Second,
 		},
 		// use default handshake timeout (no timeout limit) here, enable timeout at step level
@@ -73,6 +72,7 @@ func NewRunner(t *testing.T) *HRPRunner {
 		},
 		caseTimeoutTimer: time.NewTimer(time.Hour * 2), // default case timeout to 2 hour
 		interruptSignal:  interruptSignal,
+		parallelism:      1,
 	}
 }
 
@@ -85,13 +85,18 @@ type HRPRunner struct {
 	venv             string
 	saveTests        bool
 	genHTMLReport    bool
-	mcpConfigPath    string // MCP config file path
-	autoPopupHandler bool   // enable auto popup handler for all UI steps
+	reporters        []string      // reporters to generate reports from summary, e.g. junit, allure, ctrf
+	listeners        []RunListener // listeners to observe run progress
+	telemetry        *telemetry    // export traces and metrics with OpenTelemetry
+	mcpConfigPath    string        // MCP config file path
+	autoPopupHandler bool          // enable auto popup handler for all UI steps
 	httpClient       *http.Client
 	http2Client      *http.Client
 	wsDialer         *websocket.Dialer
+	transports       sync.Map       // customized http transports for TLS and proxies settings
 	caseTimeoutTimer *time.Timer    // case timeout timer
 	interruptSignal  chan os.Signal // interrupt signal channel
+	parallelism      int            // max number of sessions running concurrently
 }
 
 // SetClientTransport configures transport of http client for high concurrency load testing
@@ -124,6 +129,16 @@ func (r *HRPRunner) SetFailfast(failfast bool) *HRPRunner {
 	return r
 }
 
+// SetParallelism configures the max number of testcases and parameter iterations running concurrently.
+func (r *HRPRunner) SetParallelism(parallelism int) *HRPRunner {
+	log.Info().Int("parallelism", parallelism).Msg("[init] SetParallelism")
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	r.parallelism = parallelism
+	return r
+}
+
 // SetRequestsLogOn turns on request & response details logging.
 func (r *HRPRunner) SetRequestsLogOn() *HRPRunner {
 	log.Info().Msg("[init] SetRequestsLogOn")
@@ -198,6 +213,14 @@ func (r *HRPRunner) GenHTMLReport() *HRPRunner {
 	return r
 }
 
+// SetReporters configures reporters to generate reports in results folder, e.g. junit, allure, ctrf.
+func (r *HRPRunner) SetReporters(names ...string) *HRPRunner {
+	log.Info().Strs("reporters", names).Bool("saveTests", true).Msg("[init] SetReporters")
+	r.reporters = names
+	r.saveTests = true
+	return r
+}
+
 // EnableAutoPopupHandler configures whether to enable auto popup handler for all UI steps.
 func (r *HRPRunner) EnableAutoPopupHandler(enabled bool) *HRPRunner {
 	log.Info().Bool("autoPopupHandler", enabled).Msg("[init] EnableAutoPopupHandler")
@@ -231,10 +254,20 @@ func (r *HRPRunner) Run(testcases ...ITestCase) (err error) {
 	// defer summary saving and HTML report generation
 	// this ensures they run regardless of how the function exits
 	defer func() {
+		// export pending spans and metrics before run ends
+		if r.telemetry != nil {
+			if shutdownErr := r.telemetry.shutdown(); shutdownErr != nil && err == nil {
+				err = shutdownErr
+			}
+			r.telemetry = nil
+		}
 		s.Time.Duration = time.Since(s.Time.StartAt).Seconds()
 		exitCode := code.GetErrorCode(err)
 		log.Info().Int("duration(s)", int(s.Time.Duration)).
 			Int("exitCode", exitCode).Msg("run testcase finished")
+		r.notifyListeners(func(listener RunListener) {
+			listener.OnRunEnd(s, err)
+		})
 
 		// save summary
 		if r.saveTests {
@@ -253,6 +286,13 @@ func (r *HRPRunner) Run(testcases ...ITestCase) (err error) {
 				log.Info().Msg("HTML report generated successfully")
 			}
 		}
+
+		// generate reports with reporters
+		if len(r.reporters) > 0 {
+			if reportErr := s.GenReports(r.reporters...); reportErr != nil {
+				log.Error().Err(reportErr).Msg("failed to generate reports")
+			}
+		}
 	}()
 
 	// load all testcases
@@ -261,6 +301,9 @@ func (r *HRPRunner) Run(testcases ...ITestCase) (err error) {
 		log.Error().Err(err).Msg("failed to load testcases")
 		return err
 	}
+	r.notifyListeners(func(listener RunListener) {
+		listener.OnRunStart(testCases)
+	})
 
 	// collect all MCP hosts for cleanup
 	var mcpHosts []*mcphost.MCPHost
@@ -296,6 +339,10 @@ func (r *HRPRunner) Run(testcases ...ITestCase) (err error) {
 		}
 	}()
 
+	if r.parallelism > 1 {
+		return r.runConcurrently(testCases, s, &mcpHosts)
+	}
+
 	var runErr error
 	// run testcase one by one
 	for _, testcase := range testCases {
@@ -410,15 +457,8 @@ func NewCaseRunner(testcase TestCase, hrpRunner *HRPRunner) (*CaseRunner, error)
 		log.Info().Bool("autoPopupHandler", true).Msg("applied global auto popup handler setting")
 	}
 
-	// set request timeout in seconds
-	if parsedConfig.RequestTimeout != 0 {
-		hrpRunner.SetRequestTimeout(parsedConfig.RequestTimeout)
-	}
-	// set testcase timeout in seconds
-	if parsedConfig.CaseTimeout != 0 {
-		hrpRunner.SetCaseTimeout(parsedConfig.CaseTimeout)
-	}
-
+	// request timeout and testcase timeout in config are applied in each session,
+	// the shared HRPRunner should not be updated as testcases may run concurrently
 	caseRunner.TestCase.Config = parsedConfig
 	return caseRunner, nil
 }
@@ -650,19 +690,128 @@ func (r *CaseRunner) parseDeviceConfig(device interface{}, configVariables map[s
 func (r *CaseRunner) NewSession() *SessionRunner {
 	log.Info().Msg("create new session runner")
 	sessionRunner := &SessionRunner{
+		id:               uuid.NewString(),
 		caseRunner:       r,
 		sessionVariables: make(map[string]interface{}),
 		summary:          NewCaseSummary(),
 
 		transactions: make(map[string]map[TransactionType]time.Time),
 		ws:           newWSSession(),
+		grpc:         newGRPCSession(),
+		socket:       newSocketSession(),
+		mqtt:         newMQTTSession(),
+		sql:          newSQLSession(),
+		redis:        newRedisSession(),
+
+		ctx:              context.Background(),
+		caseTimeoutTimer: r.hrpRunner.caseTimeoutTimer,
+	}
+	if !r.Config.Get().DisableCookies {
+		sessionRunner.cookieJar = newSessionCookieJar()
+	}
+	if r.Config.Get().OAuth2 != nil {
+		sessionRunner.oauth2 = newOAuth2Session(sessionRunner, r.Config.Get().OAuth2)
 	}
 	return sessionRunner
 }
 
+// newIsolatedSession creates a new session runner with its own copy of teststeps,
+// steps are parsed and updated in place when running, thus sessions of the same
+// testcase running concurrently should not share them.
+func (r *CaseRunner) newIsolatedSession() (*SessionRunner, error) {
+	steps, err := cloneSteps(r.TestSteps)
+	if err != nil {
+		return nil, errors.Wrap(err, "clone teststeps failed")
+	}
+	caseRunner := *r
+	caseRunner.TestCase.TestSteps = steps
+	return caseRunner.NewSession(), nil
+}
+
+func cloneSteps(steps []IStep) ([]IStep, error) {
+	clonedSteps := make([]IStep, 0, len(steps))
+	for _, step := range steps {
+		clonedStep, err := cloneStep(step)
+		if err != nil {
+			return nil, err
+		}
+		clonedSteps = append(clonedSteps, clonedStep)
+	}
+	return clonedSteps, nil
+}
+
+func cloneStep(step IStep) (IStep, error) {
+	clonedStep, err := cloneStepStruct(reflect.ValueOf(step))
+	if err != nil {
+		return nil, err
+	}
+
+	// referenced testcase steps are run with the same session, clone them as well
+	if s, ok := clonedStep.Interface().(*StepTestCaseWithOptionalArgs); ok {
+		if testCase, ok := s.TestCase.(*TestCase); ok {
+			clonedConfig := &TConfig{}
+			err := copier.CopyWithOption(clonedConfig, testCase.Config.Get(), copier.Option{DeepCopy: true})
+			if err != nil {
+				return nil, err
+			}
+			steps, err := cloneSteps(testCase.TestSteps)
+			if err != nil {
+				return nil, err
+			}
+			s.TestCase = &TestCase{
+				Config:    clonedConfig,
+				TestSteps: steps,
+			}
+		}
+	}
+	return clonedStep.Interface().(IStep), nil
+}
+
+var (
+	stepConfigType = reflect.TypeOf(StepConfig{})
+	requestPtrType = reflect.TypeOf(&Request{})
+)
+
+// cloneStepStruct copies the struct pointed by ptr, embedded structs, StepConfig and Request
+// are copied deeply since they are updated when running, other fields (e.g. rendezvous) are shared.
+func cloneStepStruct(ptr reflect.Value) (reflect.Value, error) {
+	cloned := reflect.New(ptr.Type().Elem())
+	cloned.Elem().Set(ptr.Elem())
+
+	for i := 0; i < cloned.Elem().NumField(); i++ {
+		field := cloned.Elem().Field(i)
+		fieldType := cloned.Elem().Type().Field(i)
+		switch {
+		case fieldType.Type == stepConfigType:
+			stepConfig := StepConfig{}
+			if err := copier.CopyWithOption(&stepConfig, field.Addr().Interface(),
+				copier.Option{DeepCopy: true}); err != nil {
+				return reflect.Value{}, err
+			}
+			field.Set(reflect.ValueOf(stepConfig))
+		case fieldType.Type == requestPtrType && !field.IsNil():
+			request := &Request{}
+			if err := copier.CopyWithOption(request, field.Interface(),
+				copier.Option{DeepCopy: true}); err != nil {
+				return reflect.Value{}, err
+			}
+			field.Set(reflect.ValueOf(request))
+		case fieldType.Anonymous && field.Kind() == reflect.Ptr && !field.IsNil() &&
+			field.Type().Elem().Kind() == reflect.Struct:
+			embedded, err := cloneStepStruct(field)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			field.Set(embedded)
+		}
+	}
+	return cloned, nil
+}
+
 // SessionRunner is used to run testcase and its steps.
 // each testcase has its own SessionRunner instance and share session variables.
 type SessionRunner struct {
+	id         string      // unique session id, used to distinguish sessions in run events
 	caseRunner *CaseRunner // all session runners share one CaseRunner
 
 	sessionVariables map[string]interface{} // testcase execution session variables
@@ -674,6 +823,24 @@ type SessionRunner struct {
 
 	// websocket session
 	ws *wsSession
+	// grpc session
+	grpc *grpcSession
+	// tcp and udp socket session
+	socket *socketSession
+	// mqtt clients session
+	mqtt *mqttSession
+	// databases session
+	sql *sqlSession
+	// redis client and subscriptions session
+	redis *redisSession
+	// cookies are kept in session, nil if cookies are disabled
+	cookieJar *sessionCookieJar
+	// oauth2 token cache, nil if oauth2 is not configured
+	oauth2 *oauth2Session
+
+	ctx              context.Context // session is aborted when ctx is done
+	caseTimeoutTimer *time.Timer     // testcase timeout timer
+	retrying         bool            // current step attempt will be retried if failed
 }
 
 // Start runs the test steps in sequential order.
@@ -687,6 +854,24 @@ func (r *SessionRunner) Start(givenVars map[string]interface{}) (summary *TestCa
 
 	// update config variables with given variables
 	r.InitWithParameters(givenVars)
+	r.caseRunner.hrpRunner.notifyListeners(func(listener RunListener) {
+		listener.OnCaseStart(r)
+	})
+	defer func() {
+		r.caseRunner.hrpRunner.notifyListeners(func(listener RunListener) {
+			listener.OnCaseEnd(r, summary, err)
+		})
+	}()
+	endCaseSpan := r.startCaseSpan(config)
+	defer func() {
+		endCaseSpan(summary, err)
+	}()
+
+	// testcase timeout in config takes effect for current session only
+	if config.CaseTimeout != 0 {
+		r.caseTimeoutTimer = time.NewTimer(time.Duration(config.CaseTimeout*1000) * time.Millisecond)
+		defer r.caseTimeoutTimer.Stop()
+	}
 
 	defer func() {
 		// release session resources
@@ -740,7 +925,7 @@ func (r *SessionRunner) Start(givenVars map[string]interface{}) (summary *TestCa
 	// run step in sequential order
 	for _, step := range r.caseRunner.TestSteps {
 		select {
-		case <-r.caseRunner.hrpRunner.caseTimeoutTimer.C:
+		case <-r.caseTimeoutTimer.C:
 			log.Warn().Msg("timeout in session runner")
 			return summary, errors.Wrap(code.TimeoutError, "session runner timeout")
 		default:
@@ -862,11 +1047,36 @@ func (r *SessionRunner) generateExecutionTasks(step IStep) ([]executionTask, err
 }
 
 func (r *SessionRunner) RunStep(step IStep) (stepResult *StepResult, err error) {
+	stepResults, err := r.runStep(step)
+
+	// save all completed results to summary regardless of how the step exits
+	for _, result := range stepResults {
+		r.summary.AddStepResult(result)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// return last result
+	if len(stepResults) > 0 {
+		lastResult := stepResults[len(stepResults)-1]
+		return lastResult, nil
+	}
+
+	return nil, errors.New("no steps were executed")
+}
+
+// runStep runs step with loops and parameters iteration,
+// extracted variables are updated to session variables, step results are not added to summary.
+func (r *SessionRunner) runStep(step IStep) (stepResults []*StepResult, err error) {
 	// check for interrupt signal before running step
 	select {
 	case <-r.caseRunner.hrpRunner.interruptSignal:
 		log.Warn().Msg("interrupted in RunStep")
 		return nil, errors.Wrap(code.InterruptError, "RunStep interrupted")
+	case <-r.ctx.Done():
+		log.Warn().Msg("session cancelled in RunStep")
+		return nil, errors.Wrap(code.InterruptError, "RunStep cancelled")
 	default:
 	}
 
@@ -878,20 +1088,44 @@ func (r *SessionRunner) RunStep(step IStep) (stepResult *StepResult, err error)
 		}
 	}
 
+	// check skip_if and run_if conditions
+	reason, err := r.skipReason(step.Config())
+	if err != nil {
+		log.Error().Err(err).Str("step", step.Name()).Msg("evaluate step condition failed")
+		return []*StepResult{{
+			Name:        step.Name(),
+			StepType:    step.Type(),
+			Success:     false,
+			StartTime:   time.Now().UnixMilli(),
+			Attachments: err.Error(),
+		}}, err
+	}
+	if reason != "" {
+		log.Info().Str("step", step.Name()).Str("reason", reason).Msg("skip step")
+		stepResult := &StepResult{
+			Name:        step.Name(),
+			StepType:    step.Type(),
+			Success:     true,
+			Skipped:     true,
+			StartTime:   time.Now().UnixMilli(),
+			Attachments: reason,
+			baseName:    step.Name(),
+		}
+		r.caseRunner.hrpRunner.notifyListeners(func(listener RunListener) {
+			listener.OnStepEnd(r, stepResult)
+		})
+		return []*StepResult{stepResult}, nil
+	}
+
 	// execute step with parameters iterator
 	tasks, err := r.generateExecutionTasks(step)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to generate execution tasks")
 	}
 
-	var stepResults []*StepResult
-
-	// defer to save results regardless of how the function exits
+	// defer to update extracted variables regardless of how the function exits
 	defer func() {
-		// Save all completed results to summary
 		for _, result := range stepResults {
-			r.summary.AddStepResult(result)
-			// update extracted variables from the last result
 			for k, v := range result.ExportVars {
 				r.sessionVariables[k] = v
 			}
@@ -906,12 +1140,14 @@ func (r *SessionRunner) RunStep(step IStep) (stepResult *StepResult, err error)
 			log.Warn().Int("completed_tasks", len(stepResults)).
 				Int("total_tasks", len(tasks)).
 				Msg("interrupted during parameter iteration")
-			return nil, errors.Wrap(code.InterruptError, "parameter iteration interrupted")
+			return stepResults, errors.Wrap(code.InterruptError, "parameter iteration interrupted")
+		case <-r.ctx.Done():
+			return stepResults, errors.Wrap(code.InterruptError, "parameter iteration cancelled")
 		default:
 		}
 
 		// execute step with merged variables
-		stepResult, stepErr := r.executeStepWithVariables(step, task.stepName, task.parameters)
+		stepResult, stepErr := r.executeStepWithRetry(step, task.stepName, task.parameters)
 
 		// Always add stepResult to stepResults if it exists, even on error
 		// This ensures data is saved in defer function for summary generation
@@ -926,19 +1162,13 @@ func (r *SessionRunner) RunStep(step IStep) (stepResult *StepResult, err error)
 					Str("step", task.stepName).
 					Int("completed_tasks", len(stepResults)).
 					Msg("execute step failed in failfast mode, step result saved")
-				return nil, errors.Wrap(stepErr, "execute step failed")
+				return stepResults, errors.Wrap(stepErr, "execute step failed")
 			}
 			log.Error().Err(stepErr).Str("step", task.stepName).Msg("execute step failed")
 		}
 	}
 
-	// return last result
-	if len(stepResults) > 0 {
-		lastResult := stepResults[len(stepResults)-1]
-		return lastResult, nil
-	}
-
-	return nil, errors.New("no steps were executed")
+	return stepResults, nil
 }
 
 // executeStepWithVariables executes a single step with given parameters
@@ -946,7 +1176,15 @@ func (r *SessionRunner) RunStep(step IStep) (stepResult *StepResult, err error)
 func (r *SessionRunner) executeStepWithVariables(step IStep, stepName string, parameters map[string]interface{}) (stepResult *StepResult, err error) {
 	stepType := string(step.Type())
 	log.Info().Str("step", stepName).Str("type", stepType).Msg(RUN_STEP_START)
+	r.caseRunner.hrpRunner.notifyListeners(func(listener RunListener) {
+		listener.OnStepStart(r, stepName, step.Type())
+	})
+	endStepSpan := r.startStepSpan(stepName, step.Type())
 	defer func() {
+		endStepSpan(stepResult, err)
+		r.caseRunner.hrpRunner.notifyListeners(func(listener RunListener) {
+			listener.OnStepEnd(r, stepResult)
+		})
 		if err == nil && stepResult.Success {
 			log.Info().Str("step", stepName).
 				Str("type", stepType).
@@ -979,6 +1217,7 @@ func (r *SessionRunner) executeStepWithVariables(step IStep, stepName string, pa
 	// execute step
 	stepResult, err = step.Run(r)
 	stepResult.Name = stepName
+	stepResult.baseName = step.Name()
 
 	// restore original variables to avoid side effects
 	stepConfig.Variables = originalVariables
@@ -986,6 +1225,11 @@ func (r *SessionRunner) executeStepWithVariables(step IStep, stepName string, pa
 	return stepResult, err
 }
 
+// ID returns unique id of session.
+func (r *SessionRunner) ID() string {
+	return r.id
+}
+
 func (r *SessionRunner) GetSummary() *TestCaseSummary {
 	r.summary.Time.Duration = time.Since(r.summary.Time.StartAt).Seconds()
 	return r.summary
@@ -1044,8 +1288,8 @@ func (r *SessionRunner) ParseStep(step IStep) error {
 		// validator.Check, _ = check.(string)
 
 		// parse validator expect
-		validator.Expect, err = r.caseRunner.parser.Parse(
-			validator.Expect, stepConfig.Variables)
+		validator.Expect, err = parseValidatorExpect(
+			r.caseRunner.parser, validator, stepConfig.Variables)
 		if err != nil {
 			return errors.Wrap(err, "failed to parse validator expect")
 		}
@@ -1096,7 +1340,13 @@ func (r *SessionRunner) ignorePopup(osType string) bool {
 }
 
 // saveJSONCase saves the original JSON case content to the results directory
+// saveJSONCaseMutex serializes saving JSON case since all sessions dump to the same file
+var saveJSONCaseMutex sync.Mutex
+
 func saveJSONCase(casePath string) error {
+	saveJSONCaseMutex.Lock()
+	defer saveJSONCaseMutex.Unlock()
+
 	// Read the original JSON case content
 	path := TestCasePath(casePath)
 	testCase, err := path.GetTestCase()
@@ -0,0 +1,147 @@
+package hrp
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/httprunner/httprunner/v5/code"
+	"github.com/httprunner/httprunner/v5/mcphost"
+)
+
+// sessionJob is one run of testcase with one group of parameters
+type sessionJob struct {
+	index      int // order of the job when running sequentially
+	caseRunner *CaseRunner
+	parameters map[string]interface{}
+}
+
+type sessionOutcome struct {
+	summary *TestCaseSummary
+	err     error
+}
+
+// runConcurrently runs testcases and parameter iterations with a bounded pool of sessions.
+// case summaries are added in the same order as running one by one, thus the summary is deterministic.
+// when failfast is set, the first failure cancels all in-flight sessions and stops dispatching new ones.
+func (r *HRPRunner) runConcurrently(testCases []*TestCase, s *Summary, mcpHosts *[]*mcphost.MCPHost) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// cancel all running sessions when interrupted
+	var interrupted int32
+	go func() {
+		select {
+		case <-r.interruptSignal:
+			log.Warn().Msg("interrupted in main runner, cancel running sessions")
+			atomic.StoreInt32(&interrupted, 1)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	var mutex sync.Mutex
+	outcomes := make(map[int]*sessionOutcome)
+
+	jobs := make(chan *sessionJob)
+	var wg sync.WaitGroup
+	for i := 0; i < r.parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				summary, err := r.runSessionJob(ctx, job)
+				if err != nil {
+					log.Error().Err(err).Msg("[Run] run testcase failed")
+					if r.failfast {
+						cancel()
+					}
+				}
+				mutex.Lock()
+				outcomes[job.index] = &sessionOutcome{summary: summary, err: err}
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	// dispatch jobs in sequential order until all dispatched or cancelled
+	var initErr error
+	jobsCount := 0
+dispatch:
+	for _, testcase := range testCases {
+		if ctx.Err() != nil {
+			break
+		}
+
+		// each testcase has its own case runner
+		caseRunner, err := NewCaseRunner(*testcase, r)
+		if err != nil {
+			log.Error().Err(err).Msg("[Run] init case runner failed")
+			initErr = err
+			cancel()
+			break
+		}
+
+		// collect MCP host for cleanup
+		if caseRunner.parser.MCPHost != nil {
+			*mcpHosts = append(*mcpHosts, caseRunner.parser.MCPHost)
+		}
+
+		for it := caseRunner.parametersIterator; it.HasNext(); {
+			job := &sessionJob{
+				index:      jobsCount,
+				caseRunner: caseRunner,
+				parameters: it.Next(),
+			}
+			select {
+			case jobs <- job:
+				jobsCount++
+			case <-ctx.Done():
+				break dispatch
+			}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	var runErr error
+	for i := 0; i < jobsCount; i++ {
+		outcome := outcomes[i]
+		if outcome.summary != nil {
+			s.AddCaseSummary(outcome.summary)
+		}
+		// sessions cancelled by failfast are not the cause of failure
+		if outcome.err == nil || errors.Is(outcome.err, code.InterruptError) {
+			continue
+		}
+		if r.failfast {
+			// keep the first failure
+			if runErr == nil {
+				runErr = outcome.err
+			}
+		} else {
+			runErr = outcome.err
+		}
+	}
+
+	if atomic.LoadInt32(&interrupted) == 1 {
+		return errors.Wrap(code.InterruptError, "main runner interrupted")
+	}
+	if initErr != nil {
+		return initErr
+	}
+	return runErr
+}
+
+// runSessionJob runs one session in isolation, the session is aborted when ctx is done.
+func (r *HRPRunner) runSessionJob(ctx context.Context, job *sessionJob) (*TestCaseSummary, error) {
+	sessionRunner, err := job.caseRunner.newIsolatedSession()
+	if err != nil {
+		return nil, err
+	}
+	sessionRunner.ctx = ctx
+	return sessionRunner.Start(job.parameters)
+}
@@ -0,0 +1,462 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	hrp "github.com/httprunner/httprunner/v5"
+)
+
+const mockAdminPrefix = "/__mock"
+
+// MockRule stubs response for matched requests, rules are matched in order.
+type MockRule struct {
+	Name     string        `json:"name,omitempty" yaml:"name,omitempty"`
+	Request  *MockRequest  `json:"request" yaml:"request"`
+	Response *MockResponse `json:"response" yaml:"response"`
+}
+
+// MockRequest matches incoming requests, empty fields match any request.
+type MockRequest struct {
+	Method  string            `json:"method,omitempty" yaml:"method,omitempty"`
+	Path    string            `json:"path,omitempty" yaml:"path,omitempty"`       // path params in {id} or :id, * matches the rest
+	Query   map[string]string `json:"query,omitempty" yaml:"query,omitempty"`     // query params should equal
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"` // headers should equal, names are case-insensitive
+	Body    interface{}       `json:"body,omitempty" yaml:"body,omitempty"`       // json body should contain object fields, or raw body should equal string
+}
+
+type MockFault string
+
+const (
+	MockFaultAbort MockFault = "abort" // close connection without response
+	MockFaultError MockFault = "error" // respond 503 service unavailable
+)
+
+type MockResponse struct {
+	Status    int               `json:"status,omitempty" yaml:"status,omitempty"` // default 200
+	Headers   map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Body      interface{}       `json:"body,omitempty" yaml:"body,omitempty"`             // string is sent as is, others are encoded as json
+	Template  bool              `json:"template,omitempty" yaml:"template,omitempty"`     // parse headers and body with request variables and functions
+	Delay     float64           `json:"delay,omitempty" yaml:"delay,omitempty"`           // latency in milliseconds
+	Fault     MockFault         `json:"fault,omitempty" yaml:"fault,omitempty"`           // abort or error
+	FaultRate float64           `json:"fault_rate,omitempty" yaml:"fault_rate,omitempty"` // probability of fault, default 1 if fault is set
+}
+
+// MockOptions applies to all mock rules.
+type MockOptions struct {
+	Latency   time.Duration // extra latency of all responses
+	FaultRate float64       // probability to abort connection of all requests
+}
+
+// MockRecord is the received request, could be queried for verification.
+type MockRecord struct {
+	Time    time.Time         `json:"time"`
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Query   map[string]string `json:"query,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+	Rule    string            `json:"rule,omitempty"` // name of matched rule, empty if not matched
+	Status  int               `json:"status"`
+}
+
+// MockServer serves stubbed responses and records received requests.
+type MockServer struct {
+	*gin.Engine
+	rules    []*MockRule
+	options  MockOptions
+	parser   *hrp.Parser
+	mutex    sync.Mutex
+	requests []*MockRecord
+}
+
+func NewMockServer(rules []*MockRule, options MockOptions) *MockServer {
+	s := &MockServer{
+		Engine:  gin.Default(),
+		rules:   rules,
+		options: options,
+		parser:  hrp.NewParser(),
+	}
+	admin := s.Group(mockAdminPrefix)
+	admin.GET("/rules", func(c *gin.Context) {
+		RenderSuccess(c, s.rules)
+	})
+	admin.GET("/requests", s.listRequestsHandler)
+	admin.DELETE("/requests", s.clearRequestsHandler)
+	s.NoRoute(s.mockHandler)
+	return s
+}
+
+// Run starts mock server and blocks until interrupted.
+func (s *MockServer) Run(addr string) error {
+	server := &http.Server{
+		Addr:    addr,
+		Handler: s.Engine,
+	}
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+	errCh := make(chan error, 1)
+	go func() {
+		log.Info().Str("addr", addr).Int("rules", len(s.rules)).Msg("Starting hrp mock server")
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			errCh <- err
+		}
+	}()
+
+	select {
+	case err := <-errCh:
+		return errors.Wrap(err, "mock server failed to start")
+	case <-quit:
+	}
+	log.Info().Msg("Shutting down hrp mock server...")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return server.Shutdown(ctx)
+}
+
+// Requests returns received requests.
+func (s *MockServer) Requests() []*MockRecord {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]*MockRecord{}, s.requests...)
+}
+
+func (s *MockServer) listRequestsHandler(c *gin.Context) {
+	method := c.Query("method")
+	path := c.Query("path")
+	records := []*MockRecord{}
+	for _, record := range s.Requests() {
+		if method != "" && !strings.EqualFold(method, record.Method) {
+			continue
+		}
+		if path != "" && strings.TrimSuffix(path, "/") != strings.TrimSuffix(record.Path, "/") {
+			continue
+		}
+		records = append(records, record)
+	}
+	RenderSuccess(c, records)
+}
+
+func (s *MockServer) clearRequestsHandler(c *gin.Context) {
+	s.mutex.Lock()
+	s.requests = nil
+	s.mutex.Unlock()
+	RenderSuccess(c, true)
+}
+
+func (s *MockServer) mockHandler(c *gin.Context) {
+	body, _ := io.ReadAll(c.Request.Body)
+	record := &MockRecord{
+		Time:    time.Now(),
+		Method:  c.Request.Method,
+		Path:    c.Request.URL.Path,
+		Query:   flattenValues(c.Request.URL.Query()),
+		Headers: flattenValues(c.Request.Header),
+		Body:    string(body),
+	}
+	defer func() {
+		s.mutex.Lock()
+		s.requests = append(s.requests, record)
+		s.mutex.Unlock()
+	}()
+
+	rule, pathParams := s.match(c.Request, body)
+	if rule == nil {
+		record.Status = http.StatusNotFound
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":  "no mock rule matched",
+			"method": c.Request.Method,
+			"path":   c.Request.URL.Path,
+		})
+		return
+	}
+	record.Rule = rule.Name
+	resp := rule.Response
+	if resp == nil {
+		resp = &MockResponse{}
+	}
+
+	// latency and fault injection
+	delay := s.options.Latency + time.Duration(resp.Delay*float64(time.Millisecond))
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	fault := MockFault("")
+	if s.options.FaultRate > 0 && rand.Float64() < s.options.FaultRate {
+		fault = MockFaultAbort
+	} else if resp.Fault != "" && (resp.FaultRate <= 0 || rand.Float64() < resp.FaultRate) {
+		fault = resp.Fault
+	}
+	switch fault {
+	case MockFaultAbort:
+		record.Status = 0
+		if conn, _, err := c.Writer.Hijack(); err == nil {
+			conn.Close()
+		}
+		c.Abort()
+		return
+	case MockFaultError:
+		record.Status = http.StatusServiceUnavailable
+		c.String(http.StatusServiceUnavailable, "mock fault")
+		return
+	}
+
+	status := resp.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	headers := resp.Headers
+	respBody := resp.Body
+	if resp.Template {
+		variables := requestVariables(record, body, pathParams)
+		var err error
+		if headers, err = s.parser.ParseHeaders(resp.Headers, variables); err != nil {
+			s.renderTemplateError(c, record, err)
+			return
+		}
+		if respBody, err = s.parser.Parse(resp.Body, variables); err != nil {
+			s.renderTemplateError(c, record, err)
+			return
+		}
+	}
+
+	for key, value := range headers {
+		c.Header(key, value)
+	}
+	record.Status = status
+	switch v := respBody.(type) {
+	case nil:
+		c.Status(status)
+	case string:
+		c.Data(status, c.Writer.Header().Get("Content-Type"), []byte(v))
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			s.renderTemplateError(c, record, err)
+			return
+		}
+		contentType := c.Writer.Header().Get("Content-Type")
+		if contentType == "" {
+			contentType = "application/json; charset=utf-8"
+		}
+		c.Data(status, contentType, data)
+	}
+}
+
+func (s *MockServer) renderTemplateError(c *gin.Context, record *MockRecord, err error) {
+	log.Error().Err(err).Str("path", record.Path).Msg("render mock response failed")
+	record.Status = http.StatusInternalServerError
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// match returns the first matched rule and path params
+func (s *MockServer) match(req *http.Request, body []byte) (*MockRule, map[string]string) {
+	for _, rule := range s.rules {
+		if rule.Request == nil {
+			return rule, nil
+		}
+		if rule.Request.Method != "" && !strings.EqualFold(rule.Request.Method, req.Method) {
+			continue
+		}
+		pathParams, ok := matchPath(rule.Request.Path, req.URL.Path)
+		if !ok {
+			continue
+		}
+		if !matchValues(rule.Request.Query, func(key string) (string, bool) {
+			values, ok := req.URL.Query()[key]
+			if !ok {
+				return "", false
+			}
+			return values[0], true
+		}) {
+			continue
+		}
+		if !matchValues(rule.Request.Headers, func(key string) (string, bool) {
+			values := req.Header.Values(key)
+			if len(values) == 0 {
+				return "", false
+			}
+			return values[0], true
+		}) {
+			continue
+		}
+		if !matchBody(rule.Request.Body, body) {
+			continue
+		}
+		return rule, pathParams
+	}
+	return nil, nil
+}
+
+// matchPath matches path pattern, trailing slash is ignored
+func matchPath(pattern, path string) (map[string]string, bool) {
+	params := make(map[string]string)
+	if pattern == "" {
+		return params, true
+	}
+	patternSegments := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range patternSegments {
+		if segment == "*" {
+			return params, true
+		}
+		if i >= len(pathSegments) {
+			return nil, false
+		}
+		switch {
+		case strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}"):
+			params[segment[1:len(segment)-1]] = pathSegments[i]
+		case strings.HasPrefix(segment, ":"):
+			params[segment[1:]] = pathSegments[i]
+		case segment != pathSegments[i]:
+			return nil, false
+		}
+	}
+	return params, len(patternSegments) == len(pathSegments)
+}
+
+func matchValues(expected map[string]string, get func(key string) (string, bool)) bool {
+	for key, value := range expected {
+		actual, ok := get(key)
+		if !ok || actual != value {
+			return false
+		}
+	}
+	return true
+}
+
+func matchBody(expected interface{}, body []byte) bool {
+	switch v := expected.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(v) == strings.TrimSpace(string(body))
+	default:
+		var actual interface{}
+		if err := json.Unmarshal(body, &actual); err != nil {
+			return false
+		}
+		return containsJSON(normalizeJSON(v), actual)
+	}
+}
+
+// containsJSON checks if actual contains all fields of expected object recursively
+func containsJSON(expected, actual interface{}) bool {
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, value := range e {
+			if !containsJSON(value, a[key]) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok || len(a) != len(e) {
+			return false
+		}
+		for i := range e {
+			if !containsJSON(e[i], a[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return fmt.Sprint(expected) == fmt.Sprint(actual)
+	}
+}
+
+// normalizeJSON converts value loaded from yaml or json.Number to json decoded types
+func normalizeJSON(v interface{}) interface{} {
+	data, err := json.Marshal(stringifyKeys(v))
+	if err != nil {
+		return v
+	}
+	var result interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return v
+	}
+	return result
+}
+
+// stringifyKeys converts map[interface{}]interface{} decoded by yaml.v2 to map[string]interface{}
+func stringifyKeys(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			result[fmt.Sprint(key)] = stringifyKeys(item)
+		}
+		return result
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			result[key] = stringifyKeys(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, item := range value {
+			result[i] = stringifyKeys(item)
+		}
+		return result
+	default:
+		return v
+	}
+}
+
+func flattenValues(values map[string][]string) map[string]string {
+	result := make(map[string]string, len(values))
+	for key, value := range values {
+		if len(value) > 0 {
+			result[key] = value[0]
+		}
+	}
+	return result
+}
+
+// requestVariables are used in response templates, path params are also exposed by name
+func requestVariables(record *MockRecord, body []byte, pathParams map[string]string) map[string]interface{} {
+	variables := make(map[string]interface{})
+	for key, value := range pathParams {
+		variables[key] = value
+	}
+	var requestBody interface{} = string(body)
+	var jsonBody interface{}
+	if err := json.Unmarshal(body, &jsonBody); err == nil {
+		requestBody = jsonBody
+	}
+	query := make(map[string]interface{})
+	for key, value := range record.Query {
+		query[key] = value
+	}
+	headers := make(map[string]interface{})
+	for key, value := range record.Headers {
+		headers[key] = value
+	}
+	variables["request_method"] = record.Method
+	variables["request_path"] = record.Path
+	variables["request_query"] = query
+	variables["request_headers"] = headers
+	variables["request_body"] = requestBody
+	return variables
+}
@@ -0,0 +1,397 @@
+package server
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	hrp "github.com/httprunner/httprunner/v5"
+	"github.com/httprunner/httprunner/v5/convert"
+	"github.com/httprunner/httprunner/v5/internal/builtin"
+)
+
+var mockFileExtensions = []string{".json", ".yaml", ".yml", ".har"}
+
+// skipped HAR response headers, body is served decoded
+var harSkippedHeaders = map[string]bool{
+	"content-length":    true,
+	"content-encoding":  true,
+	"transfer-encoding": true,
+	"connection":        true,
+}
+
+// LoadMockRules loads mock rules from files or folders, supported sources:
+// mock rules file with mocks list, HttpRunner testcase, HAR, OpenAPI 3 or Swagger 2 document.
+func LoadMockRules(paths ...string) ([]*MockRule, error) {
+	var rules []*MockRule
+	for _, path := range paths {
+		files := []string{path}
+		if builtin.IsFolderPathExists(path) {
+			files = nil
+			err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				if !info.IsDir() && builtin.Contains(mockFileExtensions, filepath.Ext(p)) {
+					files = append(files, p)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, errors.Wrapf(err, "walk mock folder %s failed", path)
+			}
+		}
+		for _, file := range files {
+			fileRules, err := loadMockFile(file)
+			if err != nil {
+				return nil, errors.Wrapf(err, "load mock rules from %s failed", file)
+			}
+			log.Info().Str("path", file).Int("rules", len(fileRules)).Msg("load mock rules")
+			rules = append(rules, fileRules...)
+		}
+	}
+	return rules, nil
+}
+
+func loadMockFile(path string) ([]*MockRule, error) {
+	if filepath.Ext(path) == ".har" {
+		caseHAR := new(convert.CaseHar)
+		if err := hrp.LoadFileObject(path, caseHAR); err != nil {
+			return nil, err
+		}
+		return mockRulesFromHAR(caseHAR)
+	}
+
+	var content map[string]interface{}
+	if err := hrp.LoadFileObject(path, &content); err != nil {
+		return nil, err
+	}
+	switch {
+	case content["mocks"] != nil:
+		var mockFile struct {
+			Mocks []*MockRule `json:"mocks" yaml:"mocks"`
+		}
+		if err := hrp.LoadFileObject(path, &mockFile); err != nil {
+			return nil, err
+		}
+		for _, rule := range mockFile.Mocks {
+			if rule.Request != nil {
+				rule.Request.Body = normalizeJSON(rule.Request.Body)
+			}
+			if rule.Response != nil {
+				rule.Response.Body = normalizeJSON(rule.Response.Body)
+			}
+		}
+		return mockFile.Mocks, nil
+	case content["openapi"] != nil || content["swagger"] != nil:
+		return mockRulesFromOpenAPI(normalizeJSON(content).(map[string]interface{}))
+	case content["teststeps"] != nil:
+		tc := new(hrp.TestCaseDef)
+		if err := hrp.LoadFileObject(path, tc); err != nil {
+			return nil, err
+		}
+		if err := hrp.ConvertCaseCompatibility(tc); err != nil {
+			return nil, err
+		}
+		return mockRulesFromTestCase(tc), nil
+	default:
+		return nil, errors.New("unsupported mock file, expect mocks, testcase, HAR or OpenAPI document")
+	}
+}
+
+var variableSegmentRegexp = regexp.MustCompile(`^\$\{?(\w+)\}?$`)
+
+// mockRulesFromTestCase stubs request steps, response is derived from
+// equality validators and extractors of status code, headers and body fields.
+func mockRulesFromTestCase(tc *hrp.TestCaseDef) []*MockRule {
+	var rules []*MockRule
+	for _, step := range tc.Steps {
+		if step.Request == nil {
+			continue
+		}
+		stepURL := step.Request.URL
+		if u, err := url.Parse(stepURL); err == nil && u.Host != "" {
+			stepURL = u.Path
+		}
+		stepURL = strings.SplitN(stepURL, "?", 2)[0]
+		// variables in path are converted to path params, e.g. /users/$uid -> /users/{uid}
+		segments := strings.Split(stepURL, "/")
+		for i, segment := range segments {
+			if matches := variableSegmentRegexp.FindStringSubmatch(segment); matches != nil {
+				segments[i] = "{" + matches[1] + "}"
+			}
+		}
+
+		resp := &MockResponse{Status: 200, Headers: map[string]string{}}
+		var body map[string]interface{}
+		for _, iValidator := range step.Validators {
+			validator, ok := iValidator.(hrp.Validator)
+			if !ok || (validator.Assert != "eq" && validator.Assert != "equals" && validator.Assert != "equal") {
+				continue
+			}
+			if s, ok := validator.Expect.(string); ok && strings.Contains(s, "$") {
+				continue // expected value references variables
+			}
+			switch {
+			case validator.Check == "status_code":
+				if status, err := strconv.Atoi(fmt.Sprint(validator.Expect)); err == nil {
+					resp.Status = status
+				}
+			case strings.HasPrefix(validator.Check, "headers."):
+				header := strings.Trim(strings.TrimPrefix(validator.Check, "headers."), `"`)
+				resp.Headers[header] = fmt.Sprint(validator.Expect)
+			case strings.HasPrefix(validator.Check, "body."):
+				body = setBodyField(body, strings.TrimPrefix(validator.Check, "body."), normalizeJSON(validator.Expect), true)
+			}
+		}
+		// extracted fields are filled with variable names as example values
+		names := make([]string, 0, len(step.Extract))
+		for name := range step.Extract {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if expr := step.Extract[name]; strings.HasPrefix(expr, "body.") {
+				body = setBodyField(body, strings.TrimPrefix(expr, "body."), name, false)
+			}
+		}
+		if body != nil {
+			resp.Body = body
+		}
+
+		rules = append(rules, &MockRule{
+			Name: step.StepName,
+			Request: &MockRequest{
+				Method: string(step.Request.Method),
+				Path:   strings.Join(segments, "/"),
+			},
+			Response: resp,
+		})
+	}
+	return rules
+}
+
+var fieldNameRegexp = regexp.MustCompile(`^\w+$`)
+
+// setBodyField sets value of dot separated field path, fields with index or functions are ignored
+func setBodyField(body map[string]interface{}, field string, value interface{}, override bool) map[string]interface{} {
+	keys := strings.Split(field, ".")
+	for _, key := range keys {
+		if !fieldNameRegexp.MatchString(key) {
+			return body
+		}
+	}
+	if body == nil {
+		body = make(map[string]interface{})
+	}
+	current := body
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := current[key].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			current[key] = next
+		}
+		current = next
+	}
+	last := keys[len(keys)-1]
+	if _, exists := current[last]; exists && !override {
+		return body
+	}
+	current[last] = value
+	return body
+}
+
+func mockRulesFromHAR(caseHAR *convert.CaseHar) ([]*MockRule, error) {
+	var rules []*MockRule
+	for _, entry := range caseHAR.Log.Entries {
+		u, err := url.Parse(entry.Request.URL)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse url %s failed", entry.Request.URL)
+		}
+		query := make(map[string]string)
+		for _, param := range entry.Request.QueryString {
+			query[param.Name] = param.Value
+		}
+		headers := make(map[string]string)
+		for _, header := range entry.Response.Headers {
+			if harSkippedHeaders[strings.ToLower(header.Name)] {
+				continue
+			}
+			headers[header.Name] = header.Value
+		}
+		var body interface{}
+		if text := entry.Response.Content.Text; text != "" {
+			if entry.Response.Content.Encoding == "base64" {
+				decoded, err := base64.StdEncoding.DecodeString(text)
+				if err != nil {
+					return nil, errors.Wrap(err, "decode base64 response content failed")
+				}
+				text = string(decoded)
+			}
+			body = text
+		}
+		rules = append(rules, &MockRule{
+			Name: fmt.Sprintf("%s %s", entry.Request.Method, u.Path),
+			Request: &MockRequest{
+				Method: entry.Request.Method,
+				Path:   u.Path,
+				Query:  query,
+			},
+			Response: &MockResponse{
+				Status:  entry.Response.Status,
+				Headers: headers,
+				Body:    body,
+			},
+		})
+	}
+	return rules, nil
+}
+
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch"}
+
+// mockRulesFromOpenAPI stubs operations with response examples of the first 2xx response
+func mockRulesFromOpenAPI(doc map[string]interface{}) ([]*MockRule, error) {
+	paths, ok := doc["paths"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("paths not found in OpenAPI document")
+	}
+
+	// path prefix from swagger basePath or OpenAPI servers url
+	var basePath string
+	if v, ok := doc["basePath"].(string); ok {
+		basePath = v
+	} else if servers, ok := doc["servers"].([]interface{}); ok && len(servers) > 0 {
+		if server, ok := servers[0].(map[string]interface{}); ok {
+			if u, err := url.Parse(fmt.Sprint(server["url"])); err == nil {
+				basePath = u.Path
+			}
+		}
+	}
+	basePath = strings.TrimSuffix(basePath, "/")
+
+	pathNames := make([]string, 0, len(paths))
+	for path := range paths {
+		pathNames = append(pathNames, path)
+	}
+	// static paths are matched before paths with params
+	sort.Slice(pathNames, func(i, j int) bool {
+		pi, pj := strings.Count(pathNames[i], "{"), strings.Count(pathNames[j], "{")
+		if pi != pj {
+			return pi < pj
+		}
+		return pathNames[i] < pathNames[j]
+	})
+
+	var rules []*MockRule
+	for _, path := range pathNames {
+		operations, ok := paths[path].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, method := range openAPIMethods {
+			operation, ok := operations[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			status, response := pickOpenAPIResponse(operation)
+			resp := &MockResponse{Status: status}
+			if response != nil {
+				contentType, example := openAPIExample(response)
+				if contentType != "" {
+					resp.Headers = map[string]string{"Content-Type": contentType}
+				}
+				resp.Body = example
+			}
+			name := fmt.Sprintf("%s %s", strings.ToUpper(method), path)
+			if operationID, ok := operation["operationId"].(string); ok {
+				name = operationID
+			}
+			rules = append(rules, &MockRule{
+				Name: name,
+				Request: &MockRequest{
+					Method: strings.ToUpper(method),
+					Path:   basePath + path,
+				},
+				Response: resp,
+			})
+		}
+	}
+	return rules, nil
+}
+
+func pickOpenAPIResponse(operation map[string]interface{}) (int, map[string]interface{}) {
+	responses, _ := operation["responses"].(map[string]interface{})
+	codes := make([]string, 0, len(responses))
+	for code := range responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		if strings.HasPrefix(code, "2") {
+			status, err := strconv.Atoi(code)
+			if err != nil {
+				status = 200
+			}
+			response, _ := responses[code].(map[string]interface{})
+			return status, response
+		}
+	}
+	if response, ok := responses["default"].(map[string]interface{}); ok {
+		return 200, response
+	}
+	return 200, nil
+}
+
+// openAPIExample returns content type and example of response, OpenAPI 3 content and swagger 2 examples are supported
+func openAPIExample(response map[string]interface{}) (string, interface{}) {
+	if content, ok := response["content"].(map[string]interface{}); ok {
+		for _, contentType := range sortedKeys(content) {
+			media, _ := content[contentType].(map[string]interface{})
+			if example, ok := media["example"]; ok {
+				return contentType, example
+			}
+			if examples, ok := media["examples"].(map[string]interface{}); ok {
+				for _, name := range sortedKeys(examples) {
+					if example, ok := examples[name].(map[string]interface{}); ok {
+						return contentType, example["value"]
+					}
+				}
+			}
+			if schema, ok := media["schema"].(map[string]interface{}); ok {
+				if example, ok := schema["example"]; ok {
+					return contentType, example
+				}
+			}
+			return contentType, nil
+		}
+	}
+	if examples, ok := response["examples"].(map[string]interface{}); ok {
+		for _, contentType := range sortedKeys(examples) {
+			return contentType, examples[contentType]
+		}
+	}
+	if schema, ok := response["schema"].(map[string]interface{}); ok {
+		if example, ok := schema["example"]; ok {
+			return "application/json", example
+		}
+	}
+	return "", nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,276 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMockTestServer(t *testing.T, options MockOptions, files map[string]string) *httptest.Server {
+	dir := t.TempDir()
+	for name, content := range files {
+		require.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	rules, err := LoadMockRules(dir)
+	require.Nil(t, err)
+	server := httptest.NewServer(NewMockServer(rules, options).Engine)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func doMockRequest(t *testing.T, method, url, body string, headers map[string]string) (*http.Response, string) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.Nil(t, err)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp, string(data)
+}
+
+func TestMockRulesFile(t *testing.T) {
+	server := newMockTestServer(t, MockOptions{}, map[string]string{
+		"mocks.yml": `
+mocks:
+  - name: admin user
+    request:
+      method: GET
+      path: /api/users/{id}
+      query: {role: admin}
+      headers: {X-Token: secret}
+    response:
+      body: {id: "$id", role: admin}
+      template: true
+  - name: get user
+    request:
+      method: GET
+      path: /api/users/:id
+    response:
+      headers: {X-Path: "$request_path"}
+      body: {id: "$id", method: "$request_method"}
+      template: true
+  - name: create vip
+    request:
+      method: POST
+      path: /api/users
+      body: {profile: {vip: true}}
+    response:
+      status: 201
+      body: vip created
+  - name: create user
+    request:
+      method: POST
+      path: /api/users
+    response:
+      status: 201
+      headers: {Content-Type: application/json}
+      body: {name: "${get_request_name($request_body)}"}
+      template: true
+`,
+	})
+
+	resp, body := doMockRequest(t, "GET", server.URL+"/api/users/7?role=admin", "",
+		map[string]string{"x-token": "secret"})
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.JSONEq(t, `{"id": "7", "role": "admin"}`, body)
+
+	resp, body = doMockRequest(t, "GET", server.URL+"/api/users/8/?role=admin", "", nil)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "/api/users/8/", resp.Header.Get("X-Path"))
+	assert.JSONEq(t, `{"id": "8", "method": "GET"}`, body)
+
+	resp, body = doMockRequest(t, "POST", server.URL+"/api/users",
+		`{"name": "bob", "profile": {"vip": true, "age": 18}}`, nil)
+	assert.Equal(t, 201, resp.StatusCode)
+	assert.Equal(t, "vip created", body)
+
+	// unregistered function fails to render template
+	resp, _ = doMockRequest(t, "POST", server.URL+"/api/users", `{"name": "bob"}`, nil)
+	assert.Equal(t, 500, resp.StatusCode)
+
+	resp, body = doMockRequest(t, "DELETE", server.URL+"/api/users/7", "", nil)
+	assert.Equal(t, 404, resp.StatusCode)
+	assert.Contains(t, body, "no mock rule matched")
+
+	// recorded requests
+	_, body = doMockRequest(t, "GET", server.URL+"/__mock/requests?method=post&path=/api/users", "", nil)
+	var result struct {
+		Result []*MockRecord `json:"result"`
+	}
+	require.Nil(t, json.Unmarshal([]byte(body), &result))
+	if assert.Len(t, result.Result, 2) {
+		assert.Equal(t, "create vip", result.Result[0].Rule)
+		assert.Equal(t, 201, result.Result[0].Status)
+		assert.Contains(t, result.Result[0].Body, "bob")
+		assert.Equal(t, "create user", result.Result[1].Rule)
+		assert.Equal(t, 500, result.Result[1].Status)
+	}
+
+	resp, _ = doMockRequest(t, "DELETE", server.URL+"/__mock/requests", "", nil)
+	assert.Equal(t, 200, resp.StatusCode)
+	_, body = doMockRequest(t, "GET", server.URL+"/__mock/requests", "", nil)
+	require.Nil(t, json.Unmarshal([]byte(body), &result))
+	assert.Empty(t, result.Result)
+}
+
+func TestMockFromTestCase(t *testing.T) {
+	server := newMockTestServer(t, MockOptions{}, map[string]string{
+		"testcase.json": `{
+	"config": {"name": "demo", "base_url": "https://postman-echo.com"},
+	"teststeps": [
+		{
+			"name": "get user",
+			"request": {"method": "GET", "url": "/users/$uid", "params": {"a": 1}},
+			"extract": {"token": "body.data.token"},
+			"validate": [
+				{"check": "status_code", "assert": "equals", "expect": 200},
+				{"check": "headers.\"Content-Type\"", "assert": "equals", "expect": "application/json"},
+				{"check": "body.data.name", "assert": "equals", "expect": "alice"},
+				{"check": "body.data.uid", "assert": "equals", "expect": "$uid"},
+				{"check": "body.data.age", "assert": "greater_than", "expect": 10}
+			]
+		},
+		{
+			"name": "create user",
+			"request": {"method": "POST", "url": "https://postman-echo.com/users", "json": {"name": "bob"}},
+			"validate": [{"eq": ["status_code", 201]}]
+		}
+	]
+}`,
+	})
+
+	resp, body := doMockRequest(t, "GET", server.URL+"/users/123", "", nil)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	assert.JSONEq(t, `{"data": {"name": "alice", "token": "token"}}`, body)
+
+	resp, _ = doMockRequest(t, "POST", server.URL+"/users", `{"name": "bob"}`, nil)
+	assert.Equal(t, 201, resp.StatusCode)
+}
+
+func TestMockFromHAR(t *testing.T) {
+	server := newMockTestServer(t, MockOptions{}, map[string]string{
+		"demo.har": `{"log": {"entries": [
+	{
+		"request": {
+			"method": "GET",
+			"url": "https://example.com/search?q=a",
+			"queryString": [{"name": "q", "value": "a"}]
+		},
+		"response": {
+			"status": 200,
+			"headers": [
+				{"name": "Content-Type", "value": "application/json"},
+				{"name": "Content-Length", "value": "999"}
+			],
+			"content": {"mimeType": "application/json", "text": "{\"result\": \"a\"}"}
+		}
+	},
+	{
+		"request": {
+			"method": "GET",
+			"url": "https://example.com/search?q=b",
+			"queryString": [{"name": "q", "value": "b"}]
+		},
+		"response": {
+			"status": 200,
+			"headers": [{"name": "Content-Type", "value": "text/plain"}],
+			"content": {"mimeType": "text/plain", "text": "cmVzdWx0IGI=", "encoding": "base64"}
+		}
+	}
+]}}`,
+	})
+
+	resp, body := doMockRequest(t, "GET", server.URL+"/search?q=a", "", nil)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	assert.JSONEq(t, `{"result": "a"}`, body)
+
+	_, body = doMockRequest(t, "GET", server.URL+"/search?q=b", "", nil)
+	assert.Equal(t, "result b", body)
+
+	resp, _ = doMockRequest(t, "GET", server.URL+"/search?q=c", "", nil)
+	assert.Equal(t, 404, resp.StatusCode)
+}
+
+func TestMockFromOpenAPI(t *testing.T) {
+	server := newMockTestServer(t, MockOptions{}, map[string]string{
+		"openapi.yaml": `
+openapi: 3.0.0
+servers:
+  - url: https://api.example.com/v1
+paths:
+  /pets/{petId}:
+    get:
+      operationId: showPetById
+      responses:
+        "200":
+          content:
+            application/json:
+              schema:
+                example: {id: 1, name: dog}
+        default:
+          content:
+            application/json:
+              example: {error: unexpected}
+  /pets/mine:
+    get:
+      responses:
+        "200":
+          content:
+            application/json:
+              examples:
+                cat: {value: {id: 2, name: cat}}
+  /pets:
+    post:
+      responses:
+        "201":
+          description: created
+`,
+	})
+
+	resp, body := doMockRequest(t, "GET", server.URL+"/v1/pets/1", "", nil)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.JSONEq(t, `{"id": 1, "name": "dog"}`, body)
+
+	// static path is matched before path with params
+	_, body = doMockRequest(t, "GET", server.URL+"/v1/pets/mine", "", nil)
+	assert.JSONEq(t, `{"id": 2, "name": "cat"}`, body)
+
+	resp, _ = doMockRequest(t, "POST", server.URL+"/v1/pets", "", nil)
+	assert.Equal(t, 201, resp.StatusCode)
+}
+
+func TestMockFaultAndLatency(t *testing.T) {
+	server := newMockTestServer(t, MockOptions{Latency: 100 * time.Millisecond}, map[string]string{
+		"mocks.json": `{"mocks": [
+	{"request": {"path": "/abort"}, "response": {"fault": "abort"}},
+	{"request": {"path": "/error"}, "response": {"fault": "error"}},
+	{"request": {"path": "/slow"}, "response": {"delay": 100, "body": "ok"}}
+]}`,
+	})
+
+	req, _ := http.NewRequest("GET", server.URL+"/abort", nil)
+	_, err := http.DefaultClient.Do(req)
+	assert.NotNil(t, err)
+
+	resp, _ := doMockRequest(t, "GET", server.URL+"/error", "", nil)
+	assert.Equal(t, 503, resp.StatusCode)
+
+	start := time.Now()
+	_, body := doMockRequest(t, "GET", server.URL+"/slow", "", nil)
+	assert.Equal(t, "ok", body)
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+}
@@ -0,0 +1,524 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	hrp "github.com/httprunner/httprunner/v5"
+	"github.com/httprunner/httprunner/v5/convert"
+)
+
+// hop-by-hop headers are not forwarded nor recorded
+var hopHeaders = []string{
+	"Connection", "Proxy-Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization",
+	"Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// RecordOptions configures traffic recording.
+type RecordOptions struct {
+	Target       string        // upstream url in reverse proxy mode, forward proxy if empty
+	Hosts        []string      // only record requests to hosts, *.example.com matches subdomains
+	Paths        []string      // only record requests with path prefixes
+	Dedup        bool          // skip requests with same method, url and body
+	Validate     bool          // generate validators from recorded status codes
+	ThinkTime    bool          // generate think time steps from gaps between requests
+	MinThinkTime time.Duration // ignore gaps shorter than it, default 1s
+	CACert       string        // CA cert file to sign certificates of intercepted https hosts
+	CAKey        string        // CA key file, CA is generated if not specified
+	Insecure     bool          // skip verifying certificates of upstream servers
+}
+
+type recordedEntry struct {
+	convert.Entry
+	start time.Time
+	end   time.Time
+}
+
+// Recorder is a HTTP(S) forward or reverse proxy which captures traffic into testcase.
+type Recorder struct {
+	options   RecordOptions
+	target    *url.URL
+	transport *http.Transport
+	ca        *tls.Certificate
+	caCert    *x509.Certificate
+	certs     sync.Map // host -> *tls.Certificate
+	mutex     sync.Mutex
+	entries   []*recordedEntry
+	seen      map[string]bool
+}
+
+func NewRecorder(options RecordOptions) (*Recorder, error) {
+	if options.MinThinkTime <= 0 {
+		options.MinThinkTime = time.Second
+	}
+	r := &Recorder{
+		options: options,
+		transport: &http.Transport{
+			Proxy:           nil,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: options.Insecure},
+		},
+		seen: make(map[string]bool),
+	}
+	if options.Target != "" {
+		target, err := url.Parse(options.Target)
+		if err != nil || target.Host == "" {
+			return nil, errors.Errorf("invalid record target %s", options.Target)
+		}
+		r.target = target
+	}
+
+	var err error
+	if options.CACert != "" || options.CAKey != "" {
+		ca, loadErr := tls.LoadX509KeyPair(options.CACert, options.CAKey)
+		if loadErr != nil {
+			return nil, errors.Wrap(loadErr, "load record CA failed")
+		}
+		r.ca = &ca
+	} else if r.ca, err = generateCA(); err != nil {
+		return nil, err
+	}
+	if r.caCert, err = x509.ParseCertificate(r.ca.Certificate[0]); err != nil {
+		return nil, errors.Wrap(err, "parse record CA failed")
+	}
+	return r, nil
+}
+
+// CACert returns CA certificate which should be trusted by clients to record https traffic.
+func (r *Recorder) CACert() *x509.Certificate {
+	return r.caCert
+}
+
+// CACertPEM returns CA certificate in PEM format.
+func (r *Recorder) CACertPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: r.caCert.Raw})
+}
+
+// Run starts recording proxy and blocks until interrupted.
+func (r *Recorder) Run(addr string) error {
+	server := &http.Server{
+		Addr:    addr,
+		Handler: r,
+	}
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+	errCh := make(chan error, 1)
+	go func() {
+		log.Info().Str("addr", addr).Str("target", r.options.Target).Msg("Starting hrp record proxy")
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			errCh <- err
+		}
+	}()
+
+	select {
+	case err := <-errCh:
+		return errors.Wrap(err, "record proxy failed to start")
+	case <-quit:
+	}
+	log.Info().Msg("Shutting down hrp record proxy...")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return server.Shutdown(ctx)
+}
+
+func (r *Recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method == http.MethodConnect {
+		r.handleConnect(w, req)
+		return
+	}
+
+	if r.target != nil {
+		req.URL.Scheme = r.target.Scheme
+		req.URL.Host = r.target.Host
+		req.URL.Path = singleJoiningSlash(r.target.Path, req.URL.Path)
+		req.Host = r.target.Host
+	} else if !req.URL.IsAbs() {
+		http.Error(w, "hrp record proxy expects absolute url in forward proxy mode", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := r.roundTrip(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	for key, values := range resp.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body)
+}
+
+// handleConnect intercepts https traffic with certificates signed by recorder CA
+func (r *Recorder) handleConnect(w http.ResponseWriter, req *http.Request) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "hijacking not supported", http.StatusInternalServerError)
+		return
+	}
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		log.Error().Err(err).Msg("hijack connect request failed")
+		return
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		return
+	}
+
+	host := req.Host
+	tlsConn := tls.Server(conn, &tls.Config{
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			name := hello.ServerName
+			if name == "" {
+				name, _, _ = net.SplitHostPort(host)
+			}
+			return r.certificate(name)
+		},
+	})
+	defer tlsConn.Close()
+	if err := tlsConn.Handshake(); err != nil {
+		log.Error().Err(err).Str("host", host).Msg("tls handshake with client failed")
+		return
+	}
+
+	reader := bufio.NewReader(tlsConn)
+	for {
+		tunnelReq, err := http.ReadRequest(reader)
+		if err != nil {
+			if err != io.EOF {
+				log.Debug().Err(err).Str("host", host).Msg("read tunneled request failed")
+			}
+			return
+		}
+		tunnelReq.URL.Scheme = "https"
+		tunnelReq.URL.Host = host
+		resp, err := r.roundTrip(tunnelReq)
+		if err != nil {
+			resp = &http.Response{
+				StatusCode: http.StatusBadGateway,
+				ProtoMajor: 1,
+				ProtoMinor: 1,
+				Header:     http.Header{},
+				Body:       io.NopCloser(strings.NewReader(err.Error())),
+			}
+		}
+		err = resp.Write(tlsConn)
+		resp.Body.Close()
+		if err != nil || tunnelReq.Close {
+			return
+		}
+	}
+}
+
+// roundTrip sends request to upstream and records request and response
+func (r *Recorder) roundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	var reqBody []byte
+	if req.Body != nil {
+		reqBody, _ = io.ReadAll(req.Body)
+		req.Body.Close()
+	}
+
+	outReq, err := http.NewRequestWithContext(req.Context(), req.Method, req.URL.String(), bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	outReq.Header = req.Header.Clone()
+	for _, header := range hopHeaders {
+		outReq.Header.Del(header)
+	}
+	// let transport handle compression, thus response body is recorded decoded
+	outReq.Header.Del("Accept-Encoding")
+	outReq.Host = req.Host
+
+	resp, err := r.transport.RoundTrip(outReq)
+	if err != nil {
+		log.Error().Err(err).Str("url", outReq.URL.String()).Msg("forward request failed")
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	for _, header := range hopHeaders {
+		resp.Header.Del(header)
+	}
+	resp.Header.Del("Content-Length")
+	resp.TransferEncoding = nil
+	resp.ContentLength = int64(len(respBody))
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	r.record(outReq, reqBody, resp, respBody, start)
+	return resp, nil
+}
+
+func (r *Recorder) record(req *http.Request, reqBody []byte, resp *http.Response, respBody []byte, start time.Time) {
+	if !r.shouldRecord(req.URL) {
+		return
+	}
+	if r.options.Dedup {
+		key := req.Method + " " + req.URL.String() + "\n" + string(reqBody)
+		r.mutex.Lock()
+		seen := r.seen[key]
+		r.seen[key] = true
+		r.mutex.Unlock()
+		if seen {
+			log.Info().Str("method", req.Method).Str("url", req.URL.String()).Msg("skip duplicated request")
+			return
+		}
+	}
+
+	entry := &recordedEntry{start: start, end: time.Now()}
+	entry.StartedDateTime = start.Format(time.RFC3339Nano)
+	entry.Time = float32(entry.end.Sub(start).Milliseconds())
+	entry.Request = convert.Request{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		HTTPVersion: req.Proto,
+		Headers:     []convert.NVP{},
+		QueryString: []convert.NVP{},
+		Cookies:     []convert.Cookie{},
+	}
+	for _, name := range sortedHeaderNames(req.Header) {
+		if strings.EqualFold(name, "Content-Length") {
+			continue
+		}
+		entry.Request.Headers = append(entry.Request.Headers, convert.NVP{Name: name, Value: req.Header.Get(name)})
+	}
+	query := req.URL.Query()
+	for _, name := range sortedHeaderNames(query) {
+		entry.Request.QueryString = append(entry.Request.QueryString, convert.NVP{Name: name, Value: query.Get(name)})
+	}
+	for _, cookie := range req.Cookies() {
+		entry.Request.Cookies = append(entry.Request.Cookies, convert.Cookie{Name: cookie.Name, Value: cookie.Value})
+	}
+	if len(reqBody) > 0 {
+		mimeType := req.Header.Get("Content-Type")
+		if mimeType == "" {
+			mimeType = "text/plain"
+		}
+		entry.Request.PostData = convert.PostData{MimeType: mimeType, Text: string(reqBody)}
+		if strings.HasPrefix(mimeType, "application/x-www-form-urlencoded") {
+			form, _ := url.ParseQuery(string(reqBody))
+			for _, name := range sortedHeaderNames(form) {
+				entry.Request.PostData.Params = append(entry.Request.PostData.Params,
+					convert.PostParam{Name: name, Value: form.Get(name)})
+			}
+		}
+	}
+
+	entry.Response = convert.Response{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		HTTPVersion: resp.Proto,
+		Headers:     []convert.NVP{},
+		Content: convert.Content{
+			Size:     len(respBody),
+			MimeType: resp.Header.Get("Content-Type"),
+		},
+	}
+	for _, name := range sortedHeaderNames(resp.Header) {
+		entry.Response.Headers = append(entry.Response.Headers, convert.NVP{Name: name, Value: resp.Header.Get(name)})
+	}
+	if utf8.Valid(respBody) {
+		entry.Response.Content.Text = string(respBody)
+	} else {
+		entry.Response.Content.Text = base64.StdEncoding.EncodeToString(respBody)
+		entry.Response.Content.Encoding = "base64"
+	}
+
+	log.Info().Str("method", req.Method).Str("url", req.URL.String()).
+		Int("status", resp.StatusCode).Msg("record request")
+	r.mutex.Lock()
+	r.entries = append(r.entries, entry)
+	r.mutex.Unlock()
+}
+
+func (r *Recorder) shouldRecord(u *url.URL) bool {
+	if len(r.options.Hosts) > 0 {
+		matched := false
+		for _, host := range r.options.Hosts {
+			if host == u.Host || host == u.Hostname() ||
+				(strings.HasPrefix(host, "*.") && strings.HasSuffix(u.Hostname(), host[1:])) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(r.options.Paths) > 0 {
+		for _, path := range r.options.Paths {
+			if strings.HasPrefix(u.Path, path) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// HAR returns recorded traffic in HAR format.
+func (r *Recorder) HAR() *convert.CaseHar {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	caseHAR := &convert.CaseHar{
+		Log: convert.Log{
+			Version: "1.2",
+			Creator: convert.Creator{Name: "hrp record", Version: hrp.Version()},
+			Entries: make([]convert.Entry, 0, len(r.entries)),
+		},
+	}
+	for _, entry := range r.entries {
+		caseHAR.Log.Entries = append(caseHAR.Log.Entries, entry.Entry)
+	}
+	return caseHAR
+}
+
+// TestCase converts recorded traffic to testcase with HAR converter.
+func (r *Recorder) TestCase(name string) (*hrp.TestCaseDef, error) {
+	caseHAR := r.HAR()
+	if len(caseHAR.Log.Entries) == 0 {
+		return nil, errors.New("no request recorded")
+	}
+	tCase, err := caseHAR.ToTestCase()
+	if err != nil {
+		return nil, err
+	}
+	tCase.Config.Name = name
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var steps []*hrp.TStep
+	for i, step := range tCase.Steps {
+		if r.options.ThinkTime && i > 0 {
+			gap := r.entries[i].start.Sub(r.entries[i-1].end)
+			if gap >= r.options.MinThinkTime {
+				steps = append(steps, &hrp.TStep{
+					StepConfig: hrp.StepConfig{StepName: "think time"},
+					ThinkTime:  &hrp.ThinkTime{Time: gap.Round(100 * time.Millisecond).Seconds()},
+				})
+			}
+		}
+		if u, err := url.Parse(step.Request.URL); err == nil {
+			step.StepName = fmt.Sprintf("%s %s", step.Request.Method, u.Path)
+		}
+		// only status code validator is kept
+		var validators []interface{}
+		if r.options.Validate {
+			for _, iValidator := range step.Validators {
+				if validator, ok := iValidator.(hrp.Validator); ok && validator.Check == "status_code" {
+					validators = append(validators, validator)
+				}
+			}
+		}
+		step.Validators = validators
+		steps = append(steps, step)
+	}
+	tCase.Steps = steps
+	return tCase, nil
+}
+
+func (r *Recorder) certificate(host string) (*tls.Certificate, error) {
+	if cert, ok := r.certs.Load(host); ok {
+		return cert.(*tls.Certificate), nil
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(365 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	} else {
+		template.DNSNames = []string{host}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, r.caCert, &key.PublicKey, r.ca.PrivateKey)
+	if err != nil {
+		return nil, errors.Wrapf(err, "sign certificate for %s failed", host)
+	}
+	cert := &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	r.certs.Store(host, cert)
+	return cert, nil
+}
+
+func generateCA() (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "generate record CA key failed")
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: "hrp record CA", Organization: []string{"HttpRunner"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, errors.Wrap(err, "generate record CA failed")
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+func sortedHeaderNames(values map[string][]string) []string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func singleJoiningSlash(a, b string) string {
+	aslash := strings.HasSuffix(a, "/")
+	bslash := strings.HasPrefix(b, "/")
+	switch {
+	case aslash && bslash:
+		return a + b[1:]
+	case !aslash && !bslash:
+		return a + "/" + b
+	}
+	return a + b
+}
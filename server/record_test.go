@@ -0,0 +1,147 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	hrp "github.com/httprunner/httprunner/v5"
+	"github.com/httprunner/httprunner/v5/convert"
+)
+
+func newRecordUpstream() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"path":  r.URL.Path,
+			"query": r.URL.RawQuery,
+			"body":  string(body),
+		})
+	})
+}
+
+func doRecordRequest(t *testing.T, client *http.Client, method, url, body string) *http.Response {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.Nil(t, err)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := client.Do(req)
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	_, _ = io.ReadAll(resp.Body)
+	return resp
+}
+
+func TestRecordForwardProxy(t *testing.T) {
+	upstream := httptest.NewServer(newRecordUpstream())
+	defer upstream.Close()
+
+	recorder, err := NewRecorder(RecordOptions{
+		Paths:        []string{"/api/"},
+		Dedup:        true,
+		Validate:     true,
+		ThinkTime:    true,
+		MinThinkTime: 200 * time.Millisecond,
+	})
+	require.Nil(t, err)
+	proxy := httptest.NewServer(recorder)
+	defer proxy.Close()
+
+	proxyURL, _ := url.Parse(proxy.URL)
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+
+	resp := doRecordRequest(t, client, "GET", upstream.URL+"/api/users?page=1", "")
+	assert.Equal(t, 200, resp.StatusCode)
+	doRecordRequest(t, client, "GET", upstream.URL+"/api/users?page=1", "") // duplicated
+	doRecordRequest(t, client, "GET", upstream.URL+"/static/app.js", "")    // filtered
+	time.Sleep(300 * time.Millisecond)
+	resp = doRecordRequest(t, client, "POST", upstream.URL+"/api/users", `{"name": "bob"}`)
+	assert.Equal(t, 201, resp.StatusCode)
+
+	tCase, err := recorder.TestCase("record demo")
+	require.Nil(t, err)
+	assert.Equal(t, "record demo", tCase.Config.Name)
+	require.Len(t, tCase.Steps, 3)
+	assert.Equal(t, "GET /api/users", tCase.Steps[0].StepName)
+	assert.Equal(t, "1", tCase.Steps[0].Request.Params["page"])
+	assert.Len(t, tCase.Steps[0].Validators, 1)
+	assert.NotNil(t, tCase.Steps[1].ThinkTime)
+	assert.Equal(t, map[string]interface{}{"name": "bob"}, tCase.Steps[2].Request.Body)
+	assert.Equal(t, 201, tCase.Steps[2].Validators[0].(hrp.Validator).Expect)
+
+	// dump testcase with converter and replay
+	outputFile, err := convert.NewConverter(t.TempDir(), "").
+		ConvertTestCase(tCase, "record_demo.har", convert.OutputTypeJSON)
+	require.Nil(t, err)
+	assert.Equal(t, "record_demo_test.json", filepath.Base(outputFile))
+	testcase := hrp.TestCasePath(outputFile)
+	assert.Nil(t, hrp.NewRunner(t).Run(&testcase))
+}
+
+func TestRecordHTTPS(t *testing.T) {
+	upstream := httptest.NewTLSServer(newRecordUpstream())
+	defer upstream.Close()
+
+	recorder, err := NewRecorder(RecordOptions{Insecure: true})
+	require.Nil(t, err)
+	proxy := httptest.NewServer(recorder)
+	defer proxy.Close()
+
+	// client trusts record CA
+	roots := x509.NewCertPool()
+	roots.AddCert(recorder.CACert())
+	proxyURL, _ := url.Parse(proxy.URL)
+	client := &http.Client{Transport: &http.Transport{
+		Proxy:           http.ProxyURL(proxyURL),
+		TLSClientConfig: &tls.Config{RootCAs: roots},
+	}}
+
+	resp := doRecordRequest(t, client, "PUT", upstream.URL+"/api/users/1", `{"name": "alice"}`)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	tCase, err := recorder.TestCase("record https")
+	require.Nil(t, err)
+	require.Len(t, tCase.Steps, 1)
+	assert.Equal(t, upstream.URL+"/api/users/1", tCase.Steps[0].Request.URL)
+	assert.Empty(t, tCase.Steps[0].Validators)
+}
+
+func TestRecordReverseProxy(t *testing.T) {
+	upstream := httptest.NewServer(newRecordUpstream())
+	defer upstream.Close()
+
+	recorder, err := NewRecorder(RecordOptions{Target: upstream.URL + "/v1"})
+	require.Nil(t, err)
+	proxy := httptest.NewServer(recorder)
+	defer proxy.Close()
+
+	req, _ := http.NewRequest("GET", proxy.URL+"/users?id=1", nil)
+	resp, err := http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Contains(t, string(body), `"path":"/v1/users"`)
+
+	caseHAR := recorder.HAR()
+	require.Len(t, caseHAR.Log.Entries, 1)
+	entry := caseHAR.Log.Entries[0]
+	assert.Equal(t, upstream.URL+"/v1/users?id=1", entry.Request.URL)
+	assert.Equal(t, 200, entry.Response.Status)
+	assert.Contains(t, entry.Response.Content.Text, "/v1/users")
+}
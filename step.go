@@ -15,12 +15,22 @@ const (
 	StepTypeRendezvous  StepType = "rendezvous"
 	StepTypeThinkTime   StepType = "thinktime"
 	StepTypeWebSocket   StepType = "websocket"
+	StepTypeGRPC        StepType = "grpc"
+	StepTypeTCP         StepType = "tcp"
+	StepTypeUDP         StepType = "udp"
+	StepTypeMQTT        StepType = "mqtt"
+	StepTypeSQL         StepType = "sql"
+	StepTypeRedis       StepType = "redis"
+	StepTypeGraphQL     StepType = "graphql"
+	StepTypeCookieJar   StepType = "cookie_jar"
 	StepTypeAndroid     StepType = "android"
 	StepTypeHarmony     StepType = "harmony"
 	StepTypeIOS         StepType = "ios"
 	stepTypeBrowser     StepType = "browser"
 	StepTypeShell       StepType = "shell"
 	StepTypeFunction    StepType = "function"
+	StepTypeWhile       StepType = "while"
+	StepTypeForeach     StepType = "foreach"
 
 	stepTypeSuffixExtraction StepType = "_extraction"
 	stepTypeSuffixValidation StepType = "_validation"
@@ -39,6 +49,10 @@ type StepConfig struct {
 	Loops             int                    `json:"loops,omitempty" yaml:"loops,omitempty"`
 	IgnorePopup       bool                   `json:"ignore_popup,omitempty" yaml:"ignore_popup,omitempty"`             // ignore popup for this step, keep for compatibility
 	AutoPopupHandler  bool                   `json:"auto_popup_handler,omitempty" yaml:"auto_popup_handler,omitempty"` // enable auto popup handler for this step
+	Retry             *StepRetry             `json:"retry,omitempty" yaml:"retry,omitempty"`                           // retry policy for this step
+	SkipIf            interface{}            `json:"skip_if,omitempty" yaml:"skip_if,omitempty"`                       // skip step if condition expression is true
+	RunIf             interface{}            `json:"run_if,omitempty" yaml:"run_if,omitempty"`                         // run step only if condition expression is true
+	ValidateMode      ValidateMode           `json:"validate_mode,omitempty" yaml:"validate_mode,omitempty"`           // first(default) or all, override testcase config
 }
 
 // define struct for teststep
@@ -51,11 +65,22 @@ type TStep struct {
 	Rendezvous  *Rendezvous      `json:"rendezvous,omitempty" yaml:"rendezvous,omitempty"`
 	ThinkTime   *ThinkTime       `json:"think_time,omitempty" yaml:"think_time,omitempty"`
 	WebSocket   *WebSocketAction `json:"websocket,omitempty" yaml:"websocket,omitempty"`
+	GRPC        *GRPC            `json:"grpc,omitempty" yaml:"grpc,omitempty"`
+	TCP         *SocketAction    `json:"tcp,omitempty" yaml:"tcp,omitempty"`
+	UDP         *SocketAction    `json:"udp,omitempty" yaml:"udp,omitempty"`
+	MQTT        *MQTT            `json:"mqtt,omitempty" yaml:"mqtt,omitempty"`
+	SQL         *SQL             `json:"sql,omitempty" yaml:"sql,omitempty"`
+	Redis       *Redis           `json:"redis,omitempty" yaml:"redis,omitempty"`
+	GraphQL     *GraphQL         `json:"graphql,omitempty" yaml:"graphql,omitempty"`
+	CookieJar   *CookieJar       `json:"cookie_jar,omitempty" yaml:"cookie_jar,omitempty"`
 	Android     *MobileUI        `json:"android,omitempty" yaml:"android,omitempty"`
 	Harmony     *MobileUI        `json:"harmony,omitempty" yaml:"harmony,omitempty"`
 	IOS         *MobileUI        `json:"ios,omitempty" yaml:"ios,omitempty"`
 	Browser     *MobileUI        `json:"browser,omitempty" yaml:"browser,omitempty"`
 	Shell       *Shell           `json:"shell,omitempty" yaml:"shell,omitempty"`
+	While       *While           `json:"while,omitempty" yaml:"while,omitempty"`
+	Foreach     *Foreach         `json:"foreach,omitempty" yaml:"foreach,omitempty"`
+	Steps       []*TStep         `json:"steps,omitempty" yaml:"steps,omitempty"` // child steps of while/foreach
 }
 
 // one step contains one or multiple actions
@@ -75,6 +100,7 @@ type StepResult struct {
 	StartTime   int64                  `json:"start_time" yaml:"time"`                               // step start time in millisecond(ms)
 	StepType    StepType               `json:"step_type" yaml:"step_type"`                           // step type, testcase/request/transaction/rendezvous
 	Success     bool                   `json:"success" yaml:"success"`                               // step execution result
+	Skipped     bool                   `json:"skipped,omitempty" yaml:"skipped,omitempty"`           // step is skipped by skip_if/run_if condition
 	Elapsed     int64                  `json:"elapsed_ms" yaml:"elapsed_ms"`                         // step execution time in millisecond(ms)
 	HttpStat    map[string]int64       `json:"httpstat,omitempty" yaml:"httpstat,omitempty"`         // httpstat in millisecond(ms)
 	Data        interface{}            `json:"data,omitempty" yaml:"data,omitempty"`                 // step data
@@ -82,12 +108,15 @@ type StepResult struct {
 	ExportVars  map[string]interface{} `json:"export_vars,omitempty" yaml:"export_vars,omitempty"`   // extract variables
 	Actions     []*ActionResult        `json:"actions,omitempty" yaml:"actions,omitempty"`           // store action execution info
 	Attachments interface{}            `json:"attachments,omitempty" yaml:"attachments,omitempty"`   // store extra step information, such as error message or screenshots
+	Attempts    []*StepAttempt         `json:"attempts,omitempty" yaml:"attempts,omitempty"`         // store all attempts if step has retry policy
+	TraceID     string                 `json:"trace_id,omitempty" yaml:"trace_id,omitempty"`         // OpenTelemetry trace id, set if telemetry is enabled
+	baseName    string                 // step name without suffixes of parameters and loops iterations
 }
 
 // IStep represents interface for all types for teststeps, includes:
 // StepRequest, StepRequestWithOptionalArgs, StepRequestValidation, StepRequestExtraction,
 // StepTestCaseWithOptionalArgs,
-// StepTransaction, StepRendezvous, StepWebSocket.
+// StepTransaction, StepRendezvous, StepWebSocket, StepGRPC, StepSocket, StepMQTT, StepSQL, StepRedis, StepGraphQL, StepCookieJar.
 type IStep interface {
 	Name() string
 	Type() StepType
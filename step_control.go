@@ -0,0 +1,272 @@
+package hrp
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/httprunner/httprunner/v5/code"
+)
+
+const defaultWhileMaxIterations = 100
+
+type While struct {
+	Condition     interface{} `json:"condition" yaml:"condition"`                               // run child steps while condition expression is true
+	MaxIterations int         `json:"max_iterations,omitempty" yaml:"max_iterations,omitempty"` // default 100, step fails if condition is still true
+}
+
+type Foreach struct {
+	Items interface{} `json:"items" yaml:"items"`               // list or variable reference of list, e.g. $ids
+	As    string      `json:"as,omitempty" yaml:"as,omitempty"` // variable name of current item, default item
+}
+
+// StepWhile implements IStep interface.
+type StepWhile struct {
+	StepConfig
+	While *While  `json:"while,omitempty" yaml:"while,omitempty"`
+	Steps []IStep `json:"steps,omitempty" yaml:"steps,omitempty"`
+}
+
+// WithMaxIterations sets the max iterations of while loop.
+func (s *StepWhile) WithMaxIterations(n int) *StepWhile {
+	s.While.MaxIterations = n
+	return s
+}
+
+func (s *StepWhile) Name() string {
+	return s.StepName
+}
+
+func (s *StepWhile) Type() StepType {
+	return StepTypeWhile
+}
+
+func (s *StepWhile) Config() *StepConfig {
+	return &s.StepConfig
+}
+
+func (s *StepWhile) Run(r *SessionRunner) (stepResult *StepResult, err error) {
+	start := time.Now()
+	stepResult = &StepResult{
+		Name:      s.Name(),
+		StepType:  s.Type(),
+		Success:   false,
+		StartTime: start.UnixMilli(),
+	}
+	var records []*StepResult
+	defer func() {
+		if err != nil {
+			stepResult.Attachments = err.Error()
+		}
+		stepResult.Data = records
+		stepResult.Elapsed = time.Since(start).Milliseconds()
+	}()
+
+	maxIterations := s.While.MaxIterations
+	if maxIterations <= 0 {
+		maxIterations = defaultWhileMaxIterations
+	}
+
+	for iteration := 1; ; iteration++ {
+		// condition is evaluated with the latest extracted variables
+		ok, err := r.evalCondition(s.While.Condition,
+			mergeVariables(r.sessionVariables, s.Variables))
+		if err != nil {
+			return stepResult, errors.Wrap(err, "evaluate while condition failed")
+		}
+		if !ok {
+			log.Info().Str("step", s.Name()).Int("iterations", iteration-1).Msg("while loop finished")
+			break
+		}
+		if iteration > maxIterations {
+			return stepResult, errors.Errorf("while condition %v is still true after %d iterations",
+				s.While.Condition, maxIterations)
+		}
+
+		results, err := r.runChildSteps(s.Steps, s.Variables, nil,
+			fmt.Sprintf("%s [while_%d]", s.Name(), iteration))
+		records = append(records, results...)
+		if err != nil {
+			return stepResult, err
+		}
+	}
+
+	stepResult.Success = true
+	for _, record := range records {
+		stepResult.Success = stepResult.Success && record.Success
+	}
+	return stepResult, nil
+}
+
+// StepForeach implements IStep interface.
+type StepForeach struct {
+	StepConfig
+	Foreach *Foreach `json:"foreach,omitempty" yaml:"foreach,omitempty"`
+	Steps   []IStep  `json:"steps,omitempty" yaml:"steps,omitempty"`
+}
+
+// As sets the variable name of current item, default item.
+func (s *StepForeach) As(name string) *StepForeach {
+	s.Foreach.As = name
+	return s
+}
+
+func (s *StepForeach) Name() string {
+	return s.StepName
+}
+
+func (s *StepForeach) Type() StepType {
+	return StepTypeForeach
+}
+
+func (s *StepForeach) Config() *StepConfig {
+	return &s.StepConfig
+}
+
+func (s *StepForeach) Run(r *SessionRunner) (stepResult *StepResult, err error) {
+	start := time.Now()
+	stepResult = &StepResult{
+		Name:      s.Name(),
+		StepType:  s.Type(),
+		Success:   false,
+		StartTime: start.UnixMilli(),
+	}
+	var records []*StepResult
+	defer func() {
+		if err != nil {
+			stepResult.Attachments = err.Error()
+		}
+		stepResult.Data = records
+		stepResult.Elapsed = time.Since(start).Milliseconds()
+	}()
+
+	items, err := r.caseRunner.parser.Parse(s.Foreach.Items,
+		mergeVariables(r.sessionVariables, s.Variables))
+	if err != nil {
+		return stepResult, errors.Wrap(err, "parse foreach items failed")
+	}
+	itemsValue := reflect.ValueOf(items)
+	if itemsValue.Kind() != reflect.Slice && itemsValue.Kind() != reflect.Array {
+		return stepResult, errors.Errorf("foreach items should be list, got %T", items)
+	}
+
+	itemName := s.Foreach.As
+	if itemName == "" {
+		itemName = "item"
+	}
+
+	for i := 0; i < itemsValue.Len(); i++ {
+		loopVariables := map[string]interface{}{
+			itemName: itemsValue.Index(i).Interface(),
+		}
+		results, err := r.runChildSteps(s.Steps, s.Variables, loopVariables,
+			fmt.Sprintf("%s [foreach_%d]", s.Name(), i+1))
+		records = append(records, results...)
+		if err != nil {
+			return stepResult, err
+		}
+	}
+
+	stepResult.Success = true
+	for _, record := range records {
+		stepResult.Success = stepResult.Success && record.Success
+	}
+	return stepResult, nil
+}
+
+// runChildSteps runs child steps of while/foreach in current session.
+// child steps are cloned for each iteration, thus they are parsed with the latest session variables.
+// variables priority: child step variables > loop variables > session variables > parent step variables
+func (r *SessionRunner) runChildSteps(steps []IStep, stepVariables, loopVariables map[string]interface{},
+	prefix string,
+) (records []*StepResult, err error) {
+	for _, step := range steps {
+		childStep, err := cloneStep(step)
+		if err != nil {
+			return records, errors.Wrap(err, "clone child step failed")
+		}
+		inheritedVariables := mergeVariables(loopVariables,
+			mergeVariables(r.sessionVariables, stepVariables))
+		childConfig := childStep.Config()
+		childConfig.Variables = mergeVariables(childConfig.Variables, inheritedVariables)
+
+		results, err := r.runStep(childStep)
+		for _, result := range results {
+			result.Name = fmt.Sprintf("%s - %s", prefix, result.Name)
+		}
+		records = append(records, results...)
+		if err == nil {
+			continue
+		}
+		// interrupted or timeout, abort running
+		if errors.Is(err, code.InterruptError) || errors.Is(err, code.TimeoutError) {
+			return records, err
+		}
+		if r.caseRunner.hrpRunner.failfast {
+			return records, errors.Wrap(err, "abort running child steps due to failfast setting")
+		}
+	}
+	return records, nil
+}
+
+// skipReason evaluates skip_if and run_if conditions of step,
+// returns non-empty reason if the step should be skipped.
+func (r *SessionRunner) skipReason(stepConfig *StepConfig) (string, error) {
+	if stepConfig.SkipIf != nil {
+		ok, err := r.evalCondition(stepConfig.SkipIf, stepConfig.Variables)
+		if err != nil {
+			return "", errors.Wrap(err, "evaluate skip_if condition failed")
+		}
+		if ok {
+			return fmt.Sprintf("skip_if condition %v is true", stepConfig.SkipIf), nil
+		}
+	}
+	if stepConfig.RunIf != nil {
+		ok, err := r.evalCondition(stepConfig.RunIf, stepConfig.Variables)
+		if err != nil {
+			return "", errors.Wrap(err, "evaluate run_if condition failed")
+		}
+		if !ok {
+			return fmt.Sprintf("run_if condition %v is false", stepConfig.RunIf), nil
+		}
+	}
+	return "", nil
+}
+
+// evalCondition parses condition expression with variables and checks if the result is true.
+func (r *SessionRunner) evalCondition(condition interface{}, variables map[string]interface{}) (bool, error) {
+	value, err := r.caseRunner.parser.Parse(condition, variables)
+	if err != nil {
+		return false, err
+	}
+	return isTruthy(value), nil
+}
+
+// isTruthy checks if value is true, e.g. true, "true", non-zero number, non-empty list or map.
+func isTruthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+			return b
+		}
+		return v != ""
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return rv.Len() > 0
+	case reflect.Ptr, reflect.Interface:
+		return !rv.IsNil()
+	}
+	return !rv.IsZero()
+}
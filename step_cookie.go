@@ -0,0 +1,695 @@
+package hrp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+type CookieJarAction string
+
+const (
+	CookieJarExport CookieJarAction = "export" // list cookies in jar, save to Netscape cookie file if file is set
+	CookieJarImport CookieJarAction = "import" // import cookies from variable or Netscape cookie file
+	CookieJarClear  CookieJarAction = "clear"  // remove all cookies in jar
+)
+
+type CookieJar struct {
+	Action  CookieJarAction `json:"action,omitempty" yaml:"action,omitempty"`   // export(default), import or clear
+	URL     string          `json:"url,omitempty" yaml:"url,omitempty"`         // limit cookies to url, could be relative to base_url
+	File    string          `json:"file,omitempty" yaml:"file,omitempty"`       // Netscape cookie file, relative to project root dir
+	Cookies interface{}     `json:"cookies,omitempty" yaml:"cookies,omitempty"` // cookies to import, map of name to value or list of cookie objects
+}
+
+// jarCookie is the cookie object exported from or imported into session cookie jar
+type jarCookie struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Domain   string `json:"domain,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Secure   bool   `json:"secure,omitempty"`
+	HttpOnly bool   `json:"http_only,omitempty"`
+	Expires  int64  `json:"expires,omitempty"` // unix timestamp in seconds, 0 for session cookie
+}
+
+type cookieJarRespObjMeta struct {
+	Cookies       map[string]string `json:"cookies"`        // cookie name to value
+	CookiesDetail []*jarCookie      `json:"cookies_detail"` // cookie list with domain and path
+}
+
+// sessionCookieJar wraps cookiejar.Jar and records the attributes of stored cookies,
+// since cookiejar.Jar does not support listing all its cookies.
+type sessionCookieJar struct {
+	mutex   sync.Mutex
+	jar     *cookiejar.Jar
+	cookies map[string]*storedCookie // keyed by domain, path and name
+	seq     int
+}
+
+// storedCookie is the cookie recorded with attributes from Set-Cookie header
+type storedCookie struct {
+	jarCookie
+	hostOnly bool
+	seq      int // creation order
+}
+
+func newSessionCookieJar() *sessionCookieJar {
+	jar, _ := cookiejar.New(nil)
+	return &sessionCookieJar{
+		jar:     jar,
+		cookies: make(map[string]*storedCookie),
+	}
+}
+
+func (j *sessionCookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.jar.SetCookies(u, cookies)
+	now := time.Now()
+	for _, cookie := range cookies {
+		stored := newStoredCookie(u, cookie, now)
+		key := stored.Domain + ";" + stored.Path + ";" + stored.Name
+		if cookie.MaxAge < 0 || (stored.Expires > 0 && stored.Expires <= now.Unix()) {
+			delete(j.cookies, key)
+			continue
+		}
+		if old, ok := j.cookies[key]; ok {
+			stored.seq = old.seq
+		} else {
+			j.seq++
+			stored.seq = j.seq
+		}
+		j.cookies[key] = stored
+	}
+}
+
+// newStoredCookie resolves cookie domain and path as RFC 6265 section 5.3
+func newStoredCookie(u *url.URL, cookie *http.Cookie, now time.Time) *storedCookie {
+	host := strings.ToLower(u.Hostname())
+	stored := &storedCookie{
+		jarCookie: jarCookie{
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Domain:   host,
+			Path:     cookie.Path,
+			Secure:   cookie.Secure,
+			HttpOnly: cookie.HttpOnly,
+		},
+		hostOnly: true,
+	}
+	domain := strings.ToLower(strings.TrimPrefix(cookie.Domain, "."))
+	if domain != "" && net.ParseIP(host) == nil {
+		stored.Domain = domain
+		stored.hostOnly = false
+	}
+	if stored.Path == "" || stored.Path[0] != '/' {
+		stored.Path = defaultCookiePath(u.Path)
+	}
+	if cookie.MaxAge > 0 {
+		stored.Expires = now.Add(time.Duration(cookie.MaxAge) * time.Second).Unix()
+	} else if !cookie.Expires.IsZero() {
+		stored.Expires = cookie.Expires.Unix()
+	}
+	return stored
+}
+
+// url returns the url which cookie is sent to
+func (c *storedCookie) url() *url.URL {
+	scheme := "http"
+	if c.Secure {
+		scheme = "https"
+	}
+	return &url.URL{Scheme: scheme, Host: c.Domain, Path: c.Path}
+}
+
+// match checks whether cookie is sent to the given url
+func (c *storedCookie) match(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	if c.hostOnly && host != c.Domain || !c.hostOnly && !domainMatch(host, c.Domain) {
+		return false
+	}
+	if c.Secure && u.Scheme != "https" {
+		return false
+	}
+	requestPath := u.Path
+	if requestPath == "" {
+		requestPath = "/"
+	}
+	return requestPath == c.Path || strings.HasPrefix(requestPath, strings.TrimSuffix(c.Path, "/")+"/")
+}
+
+func (j *sessionCookieJar) Cookies(u *url.URL) []*http.Cookie {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.jar.Cookies(u)
+}
+
+// Clear removes all cookies in jar.
+func (j *sessionCookieJar) Clear() {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.jar, _ = cookiejar.New(nil)
+	j.cookies = make(map[string]*storedCookie)
+}
+
+// list returns cookies in jar with attributes from Set-Cookie header,
+// limited to the given url if not nil. Domain cookies are listed with a leading dot.
+func (j *sessionCookieJar) list(scope *url.URL) []*jarCookie {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	stored := make([]*storedCookie, 0, len(j.cookies))
+	for _, cookie := range j.cookies {
+		stored = append(stored, cookie)
+	}
+	sort.Slice(stored, func(i, k int) bool {
+		if stored[i].Domain != stored[k].Domain {
+			return stored[i].Domain < stored[k].Domain
+		}
+		if len(stored[i].Path) != len(stored[k].Path) {
+			return len(stored[i].Path) < len(stored[k].Path)
+		}
+		return stored[i].seq < stored[k].seq
+	})
+
+	now := time.Now().Unix()
+	cookies := []*jarCookie{}
+	for _, cookie := range stored {
+		if cookie.Expires > 0 && cookie.Expires <= now {
+			continue
+		}
+		if scope != nil && !cookie.match(scope) {
+			continue
+		}
+		// skip cookies rejected by jar, e.g. domain not matching the request host
+		if !containsCookie(j.jar.Cookies(cookie.url()), cookie.Name, cookie.Value) {
+			continue
+		}
+		exported := cookie.jarCookie
+		if !cookie.hostOnly {
+			exported.Domain = "." + cookie.Domain
+		}
+		cookies = append(cookies, &exported)
+	}
+	return cookies
+}
+
+func containsCookie(cookies []*http.Cookie, name, value string) bool {
+	for _, cookie := range cookies {
+		if cookie.Name == name && cookie.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+// add inserts cookie into jar, cookie domain is required if defaultURL is nil.
+func (j *sessionCookieJar) add(cookie *jarCookie, defaultURL *url.URL) error {
+	if cookie.Name == "" {
+		return errors.New("cookie name is required")
+	}
+	host := strings.TrimPrefix(cookie.Domain, ".")
+	if host == "" {
+		if defaultURL == nil {
+			return errors.Errorf("cookie domain is required for %s", cookie.Name)
+		}
+		host = defaultURL.Hostname()
+	}
+	scheme := "http"
+	if cookie.Secure {
+		scheme = "https"
+	}
+	cookiePath := cookie.Path
+	if cookiePath == "" {
+		cookiePath = "/"
+	}
+	httpCookie := &http.Cookie{
+		Name:     cookie.Name,
+		Value:    cookie.Value,
+		Path:     cookiePath,
+		Secure:   cookie.Secure,
+		HttpOnly: cookie.HttpOnly,
+	}
+	// leading dot means the cookie is also sent to subdomains
+	if strings.HasPrefix(cookie.Domain, ".") && net.ParseIP(host) == nil {
+		httpCookie.Domain = host
+	}
+	if cookie.Expires > 0 {
+		httpCookie.Expires = time.Unix(cookie.Expires, 0)
+	}
+	j.SetCookies(&url.URL{Scheme: scheme, Host: host, Path: cookiePath}, []*http.Cookie{httpCookie})
+	return nil
+}
+
+// defaultCookiePath returns the directory of request path, as RFC 6265 section 5.1.4
+func defaultCookiePath(urlPath string) string {
+	if urlPath == "" || urlPath[0] != '/' {
+		return "/"
+	}
+	dir := path.Dir(urlPath)
+	if strings.HasSuffix(urlPath, "/") {
+		dir = strings.TrimSuffix(urlPath, "/")
+	}
+	if dir == "" || dir == "." {
+		return "/"
+	}
+	return dir
+}
+
+func domainMatch(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// readNetscapeCookies loads cookies from Netscape cookie file, which is used by curl and browsers.
+func readNetscapeCookies(path string) ([]*jarCookie, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "open cookie file failed")
+	}
+	defer file.Close()
+
+	var cookies []*jarCookie
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		httpOnly := false
+		if strings.HasPrefix(line, "#HttpOnly_") {
+			line = strings.TrimPrefix(line, "#HttpOnly_")
+			httpOnly = true
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 6 {
+			return nil, errors.Errorf("invalid cookie line %d: %s", lineNo, line)
+		}
+		if len(fields) == 6 {
+			fields = append(fields, "") // empty value
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid cookie expires at line %d", lineNo)
+		}
+		if expires > 0 && expires < time.Now().Unix() {
+			continue
+		}
+		domain := fields[0]
+		if strings.EqualFold(fields[1], "TRUE") && !strings.HasPrefix(domain, ".") {
+			domain = "." + domain
+		}
+		cookies = append(cookies, &jarCookie{
+			Domain:   domain,
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			Expires:  expires,
+			Name:     fields[5],
+			Value:    fields[6],
+			HttpOnly: httpOnly,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "read cookie file failed")
+	}
+	return cookies, nil
+}
+
+// writeNetscapeCookies saves cookies to Netscape cookie file.
+func writeNetscapeCookies(path string, cookies []*jarCookie) error {
+	var b strings.Builder
+	b.WriteString("# Netscape HTTP Cookie File\n")
+	for _, cookie := range cookies {
+		domain := cookie.Domain
+		if cookie.HttpOnly {
+			domain = "#HttpOnly_" + domain
+		}
+		fmt.Fprintf(&b, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			domain, netscapeBool(strings.HasPrefix(cookie.Domain, ".")), cookie.Path,
+			netscapeBool(cookie.Secure), cookie.Expires, cookie.Name, cookie.Value)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		return errors.Wrap(err, "write cookie file failed")
+	}
+	return nil
+}
+
+func netscapeBool(b bool) string {
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+// parseJarCookies converts parsed cookies variable to cookie list,
+// map of name to value or list of cookie objects are supported.
+func parseJarCookies(cookies interface{}) ([]*jarCookie, error) {
+	switch v := cookies.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		result := make([]*jarCookie, 0, len(v))
+		for _, name := range names {
+			result = append(result, &jarCookie{Name: name, Value: convertString(v[name])})
+		}
+		return result, nil
+	case map[string]string:
+		m := make(map[string]interface{}, len(v))
+		for name, value := range v {
+			m[name] = value
+		}
+		return parseJarCookies(m)
+	default:
+		content, err := json.Marshal(v)
+		if err != nil {
+			return nil, errors.Wrap(err, "marshal cookies failed")
+		}
+		var result []*jarCookie
+		if err := json.Unmarshal(content, &result); err != nil {
+			return nil, errors.Wrapf(err, "invalid cookies %v", v)
+		}
+		return result, nil
+	}
+}
+
+// StepCookieJar implements IStep interface.
+type StepCookieJar struct {
+	StepConfig
+	CookieJar *CookieJar `json:"cookie_jar,omitempty" yaml:"cookie_jar,omitempty"`
+}
+
+func (s *StepCookieJar) Name() string {
+	if s.StepName != "" {
+		return s.StepName
+	}
+	action := s.CookieJar.Action
+	if action == "" {
+		action = CookieJarExport
+	}
+	return fmt.Sprintf("cookie jar %s", action)
+}
+
+func (s *StepCookieJar) Type() StepType {
+	return StepTypeCookieJar
+}
+
+func (s *StepCookieJar) Config() *StepConfig {
+	return &s.StepConfig
+}
+
+func (s *StepCookieJar) Run(r *SessionRunner) (*StepResult, error) {
+	return runStepCookieJar(r, s)
+}
+
+// WithURL limits cookies to the given url, url could be relative to base_url.
+func (s *StepCookieJar) WithURL(url string) *StepCookieJar {
+	s.CookieJar.URL = url
+	return s
+}
+
+// Clear removes all cookies in session cookie jar.
+func (s *StepCookieJar) Clear() *StepCookieJar {
+	s.CookieJar.Action = CookieJarClear
+	return s
+}
+
+// Import imports cookies into session cookie jar, cookies could be map of name to value
+// which are set for url, or list of cookie objects exported before, variables could be referenced.
+func (s *StepCookieJar) Import(cookies interface{}) *StepCookieJar {
+	s.CookieJar.Action = CookieJarImport
+	s.CookieJar.Cookies = cookies
+	return s
+}
+
+// ImportFile imports cookies from Netscape cookie file.
+func (s *StepCookieJar) ImportFile(path string) *StepCookieJar {
+	s.CookieJar.Action = CookieJarImport
+	s.CookieJar.File = path
+	return s
+}
+
+// ExportFile saves cookies in session cookie jar to Netscape cookie file.
+func (s *StepCookieJar) ExportFile(path string) *StepCookieJar {
+	s.CookieJar.Action = CookieJarExport
+	s.CookieJar.File = path
+	return s
+}
+
+// Validate switches to step validation.
+func (s *StepCookieJar) Validate() *StepCookieJarValidation {
+	return &StepCookieJarValidation{
+		StepCookieJar: s,
+	}
+}
+
+// Extract switches to step extraction.
+func (s *StepCookieJar) Extract() *StepCookieJarExtraction {
+	s.StepConfig.Extract = make(map[string]string)
+	return &StepCookieJarExtraction{
+		StepCookieJar: s,
+	}
+}
+
+// StepCookieJarExtraction implements IStep interface.
+type StepCookieJarExtraction struct {
+	*StepCookieJar
+}
+
+// WithJmesPath sets the JMESPath expression to extract from the cookies.
+func (s *StepCookieJarExtraction) WithJmesPath(jmesPath string, varName string) *StepCookieJarExtraction {
+	s.StepConfig.Extract[varName] = jmesPath
+	return s
+}
+
+// Validate switches to step validation.
+func (s *StepCookieJarExtraction) Validate() *StepCookieJarValidation {
+	return &StepCookieJarValidation{
+		StepCookieJar: s.StepCookieJar,
+	}
+}
+
+func (s *StepCookieJarExtraction) Type() StepType {
+	return StepTypeCookieJar + stepTypeSuffixExtraction
+}
+
+func (s *StepCookieJarExtraction) Run(r *SessionRunner) (*StepResult, error) {
+	if s.CookieJar != nil {
+		return runStepCookieJar(r, s.StepCookieJar)
+	}
+	return nil, errors.New("unexpected protocol type")
+}
+
+// StepCookieJarValidation implements IStep interface.
+type StepCookieJarValidation struct {
+	*StepCookieJar
+}
+
+func (s *StepCookieJarValidation) Type() StepType {
+	return StepTypeCookieJar + stepTypeSuffixValidation
+}
+
+func (s *StepCookieJarValidation) Run(r *SessionRunner) (*StepResult, error) {
+	if s.CookieJar != nil {
+		return runStepCookieJar(r, s.StepCookieJar)
+	}
+	return nil, errors.New("unexpected protocol type")
+}
+
+func (s *StepCookieJarValidation) AssertEqual(jmesPath string, expected interface{}, msg string) *StepCookieJarValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  "equals",
+		Expect:  expected,
+		Message: msg,
+	}
+	s.Validators = append(s.Validators, v)
+	return s
+}
+
+func (s *StepCookieJarValidation) AssertNotEqual(jmesPath string, expected interface{}, msg string) *StepCookieJarValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  "not_equal",
+		Expect:  expected,
+		Message: msg,
+	}
+	s.Validators = append(s.Validators, v)
+	return s
+}
+
+func (s *StepCookieJarValidation) AssertContains(jmesPath string, expected interface{}, msg string) *StepCookieJarValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  "contains",
+		Expect:  expected,
+		Message: msg,
+	}
+	s.Validators = append(s.Validators, v)
+	return s
+}
+
+func (s *StepCookieJarValidation) AssertLengthEqual(jmesPath string, expected interface{}, msg string) *StepCookieJarValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  "length_equals",
+		Expect:  expected,
+		Message: msg,
+	}
+	s.Validators = append(s.Validators, v)
+	return s
+}
+
+func runStepCookieJar(r *SessionRunner, step *StepCookieJar) (stepResult *StepResult, err error) {
+	variables := step.Variables
+	start := time.Now()
+	stepResult = &StepResult{
+		Name:        step.Name(),
+		StepType:    step.Type(),
+		Success:     false,
+		ContentSize: 0,
+		StartTime:   start.UnixMilli(),
+	}
+	defer func() {
+		if err != nil {
+			stepResult.Attachments = err.Error()
+		}
+		stepResult.Elapsed = time.Since(start).Milliseconds()
+	}()
+
+	if r.cookieJar == nil {
+		return stepResult, errors.New("cookie jar is disabled by testcase config")
+	}
+	parser := r.caseRunner.parser
+	casePath := r.caseRunner.Config.Get().Path
+
+	// cookies scope, defaults to base_url
+	var scope *url.URL
+	rawURL, err := parser.ParseString(step.CookieJar.URL, variables)
+	if err != nil {
+		return stepResult, errors.Wrap(err, "parse cookie jar url failed")
+	}
+	var baseURL string
+	if variables["base_url"] != nil {
+		baseURL, _ = variables["base_url"].(string)
+	}
+	if convertString(rawURL) != "" || baseURL != "" {
+		scope = buildURL(baseURL, convertString(rawURL), nil)
+		if scope == nil || scope.Host == "" {
+			return stepResult, errors.Errorf("invalid cookie jar url %v", rawURL)
+		}
+	}
+	var file string
+	if step.CookieJar.File != "" {
+		var parsedFile interface{}
+		if parsedFile, err = parser.ParseString(step.CookieJar.File, variables); err != nil {
+			return stepResult, errors.Wrap(err, "parse cookie file failed")
+		}
+		file = resolveCaseFilePath(casePath, convertString(parsedFile))
+	}
+
+	action := step.CookieJar.Action
+	if action == "" {
+		action = CookieJarExport
+	}
+	requestMap := map[string]interface{}{
+		"action": action,
+		"file":   file,
+	}
+	if scope != nil {
+		requestMap["url"] = scope.String()
+	}
+	log.Info().Str("action", string(action)).Interface("url", requestMap["url"]).
+		Str("file", file).Msg("run cookie jar step")
+
+	switch action {
+	case CookieJarClear:
+		r.cookieJar.Clear()
+	case CookieJarImport:
+		if file == "" && step.CookieJar.Cookies == nil {
+			return stepResult, errors.New("cookies or cookie file is required for import")
+		}
+		var cookies, variableCookies []*jarCookie
+		if file != "" {
+			if cookies, err = readNetscapeCookies(file); err != nil {
+				return stepResult, err
+			}
+		}
+		var parsedCookies interface{}
+		if parsedCookies, err = parser.Parse(step.CookieJar.Cookies, variables); err != nil {
+			return stepResult, errors.Wrap(err, "parse cookies failed")
+		}
+		if variableCookies, err = parseJarCookies(parsedCookies); err != nil {
+			return stepResult, err
+		}
+		cookies = append(cookies, variableCookies...)
+		for _, cookie := range cookies {
+			if err = r.cookieJar.add(cookie, scope); err != nil {
+				return stepResult, err
+			}
+		}
+		requestMap["cookies"] = cookies
+	case CookieJarExport:
+	default:
+		return stepResult, errors.Errorf("unsupported cookie jar action %s", action)
+	}
+
+	// cookies in jar after action
+	respObjMeta := &cookieJarRespObjMeta{
+		Cookies:       make(map[string]string),
+		CookiesDetail: r.cookieJar.list(scope),
+	}
+	for _, cookie := range respObjMeta.CookiesDetail {
+		respObjMeta.Cookies[cookie.Name] = cookie.Value
+	}
+	if action == CookieJarExport && file != "" {
+		if err = writeNetscapeCookies(file, respObjMeta.CookiesDetail); err != nil {
+			return stepResult, err
+		}
+	}
+
+	respObj, err := convertToResponseObject(r.testingT(), parser, respObjMeta)
+	if err != nil {
+		err = errors.Wrap(err, "init ResponseObject error")
+		return
+	}
+	sessionData := &SessionData{
+		ReqResps: &ReqResps{
+			Request:  requestMap,
+			Response: respObj.respObjMeta,
+		},
+	}
+	stepResult.Data = sessionData
+
+	// extract variables from cookies
+	extractMapping := respObj.Extract(step.StepConfig.Extract, variables)
+	stepResult.ExportVars = extractMapping
+
+	// override step variables with extracted variables
+	variables = mergeVariables(variables, extractMapping)
+
+	// validate cookies
+	err = respObj.Validate(step.Validators, variables, r.validateMode(&step.StepConfig))
+	sessionData.Validators = respObj.validationResults
+	if err == nil {
+		stepResult.Success = true
+	}
+	return stepResult, err
+}
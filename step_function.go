@@ -3,6 +3,7 @@ package hrp
 import (
 	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -12,6 +13,8 @@ import (
 	"github.com/httprunner/httprunner/v5/uixt/option"
 )
 
+var functionEnvMutex sync.Mutex
+
 // StepFunction implements IStep interface.
 type StepFunction struct {
 	StepConfig
@@ -65,6 +68,10 @@ func runStepFunction(r *SessionRunner, step IStep) (stepResult *StepResult, err
 		stepResult.Elapsed = time.Since(start).Milliseconds()
 	}()
 
+	// config variables are exported as process environment variables for the function,
+	// lock is held until the function returns so that concurrent sessions do not overwrite them
+	functionEnvMutex.Lock()
+	defer functionEnvMutex.Unlock()
 	vars := r.caseRunner.Config.Get().Variables
 	for key, value := range vars {
 		os.Setenv(key, fmt.Sprintf("%v", value))
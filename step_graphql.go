@@ -0,0 +1,375 @@
+package hrp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+type GraphQL struct {
+	URL            string                 `json:"url" yaml:"url"`                                           // graphql endpoint, could be relative to base_url
+	Query          string                 `json:"query,omitempty" yaml:"query,omitempty"`                   // inline query document
+	QueryFile      string                 `json:"query_file,omitempty" yaml:"query_file,omitempty"`         // .graphql file, relative to project root dir
+	OperationName  string                 `json:"operation_name,omitempty" yaml:"operation_name,omitempty"` // required if query document contains multiple operations
+	Variables      map[string]interface{} `json:"variables,omitempty" yaml:"variables,omitempty"`           // graphql variables, parsed with step variables
+	Headers        map[string]string      `json:"headers,omitempty" yaml:"headers,omitempty"`
+	PersistedQuery bool                   `json:"persisted_query,omitempty" yaml:"persisted_query,omitempty"` // send sha256 hash of query first, and full query if not persisted
+	AllowErrors    bool                   `json:"allow_errors,omitempty" yaml:"allow_errors,omitempty"`       // do not fail step if response contains errors
+	Timeout        float64                `json:"timeout,omitempty" yaml:"timeout,omitempty"`                 // timeout in seconds
+}
+
+// loadQuery returns inline query or content of query file
+func (g *GraphQL) loadQuery(casePath string) (string, error) {
+	if g.Query != "" {
+		return g.Query, nil
+	}
+	if g.QueryFile == "" {
+		return "", errors.New("graphql query or query_file is required")
+	}
+	content, err := os.ReadFile(resolveCaseFilePath(casePath, g.QueryFile))
+	if err != nil {
+		return "", errors.Wrap(err, "read graphql query file failed")
+	}
+	return string(content), nil
+}
+
+// StepGraphQL implements IStep interface.
+type StepGraphQL struct {
+	StepConfig
+	GraphQL *GraphQL `json:"graphql,omitempty" yaml:"graphql,omitempty"`
+}
+
+func (s *StepGraphQL) Name() string {
+	if s.StepName != "" {
+		return s.StepName
+	}
+	if s.GraphQL.OperationName != "" {
+		return fmt.Sprintf("graphql %s", s.GraphQL.OperationName)
+	}
+	return fmt.Sprintf("graphql %s", s.GraphQL.URL)
+}
+
+func (s *StepGraphQL) Type() StepType {
+	return StepTypeGraphQL
+}
+
+func (s *StepGraphQL) Config() *StepConfig {
+	return &s.StepConfig
+}
+
+func (s *StepGraphQL) Run(r *SessionRunner) (*StepResult, error) {
+	return runStepGraphQL(r, s)
+}
+
+// WithQuery sets the inline query document.
+func (s *StepGraphQL) WithQuery(query string) *StepGraphQL {
+	s.GraphQL.Query = query
+	return s
+}
+
+// WithQueryFile loads query document from .graphql file.
+func (s *StepGraphQL) WithQueryFile(path string) *StepGraphQL {
+	s.GraphQL.QueryFile = path
+	return s
+}
+
+// WithOperationName sets the operation to execute.
+func (s *StepGraphQL) WithOperationName(name string) *StepGraphQL {
+	s.GraphQL.OperationName = name
+	return s
+}
+
+// WithQueryVariables sets the graphql variables, step variables could be referenced.
+func (s *StepGraphQL) WithQueryVariables(variables map[string]interface{}) *StepGraphQL {
+	s.GraphQL.Variables = variables
+	return s
+}
+
+// WithHeaders sets the HTTP request headers.
+func (s *StepGraphQL) WithHeaders(headers map[string]string) *StepGraphQL {
+	s.GraphQL.Headers = headers
+	return s
+}
+
+// WithPersistedQuery sends sha256 hash of query instead of the full query.
+func (s *StepGraphQL) WithPersistedQuery() *StepGraphQL {
+	s.GraphQL.PersistedQuery = true
+	return s
+}
+
+// AllowErrors does not fail the step if response contains errors.
+func (s *StepGraphQL) AllowErrors() *StepGraphQL {
+	s.GraphQL.AllowErrors = true
+	return s
+}
+
+// WithTimeout sets timeout for graphql request in seconds.
+func (s *StepGraphQL) WithTimeout(timeout float64) *StepGraphQL {
+	s.GraphQL.Timeout = timeout
+	return s
+}
+
+// Validate switches to step validation.
+func (s *StepGraphQL) Validate() *StepGraphQLValidation {
+	return &StepGraphQLValidation{
+		StepGraphQL: s,
+	}
+}
+
+// Extract switches to step extraction.
+func (s *StepGraphQL) Extract() *StepGraphQLExtraction {
+	s.StepConfig.Extract = make(map[string]string)
+	return &StepGraphQLExtraction{
+		StepGraphQL: s,
+	}
+}
+
+// StepGraphQLExtraction implements IStep interface.
+type StepGraphQLExtraction struct {
+	*StepGraphQL
+}
+
+// WithJmesPath sets the JMESPath expression to extract from the response.
+func (s *StepGraphQLExtraction) WithJmesPath(jmesPath string, varName string) *StepGraphQLExtraction {
+	s.StepConfig.Extract[varName] = jmesPath
+	return s
+}
+
+// Validate switches to step validation.
+func (s *StepGraphQLExtraction) Validate() *StepGraphQLValidation {
+	return &StepGraphQLValidation{
+		StepGraphQL: s.StepGraphQL,
+	}
+}
+
+func (s *StepGraphQLExtraction) Type() StepType {
+	return StepTypeGraphQL + stepTypeSuffixExtraction
+}
+
+func (s *StepGraphQLExtraction) Run(r *SessionRunner) (*StepResult, error) {
+	if s.GraphQL != nil {
+		return runStepGraphQL(r, s.StepGraphQL)
+	}
+	return nil, errors.New("unexpected protocol type")
+}
+
+// StepGraphQLValidation implements IStep interface.
+type StepGraphQLValidation struct {
+	*StepGraphQL
+}
+
+func (s *StepGraphQLValidation) Type() StepType {
+	return StepTypeGraphQL + stepTypeSuffixValidation
+}
+
+func (s *StepGraphQLValidation) Run(r *SessionRunner) (*StepResult, error) {
+	if s.GraphQL != nil {
+		return runStepGraphQL(r, s.StepGraphQL)
+	}
+	return nil, errors.New("unexpected protocol type")
+}
+
+func (s *StepGraphQLValidation) AssertEqual(jmesPath string, expected interface{}, msg string) *StepGraphQLValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  "equals",
+		Expect:  expected,
+		Message: msg,
+	}
+	s.Validators = append(s.Validators, v)
+	return s
+}
+
+func (s *StepGraphQLValidation) AssertNotEqual(jmesPath string, expected interface{}, msg string) *StepGraphQLValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  "not_equal",
+		Expect:  expected,
+		Message: msg,
+	}
+	s.Validators = append(s.Validators, v)
+	return s
+}
+
+func (s *StepGraphQLValidation) AssertContains(jmesPath string, expected interface{}, msg string) *StepGraphQLValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  "contains",
+		Expect:  expected,
+		Message: msg,
+	}
+	s.Validators = append(s.Validators, v)
+	return s
+}
+
+func (s *StepGraphQLValidation) AssertLengthEqual(jmesPath string, expected interface{}, msg string) *StepGraphQLValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  "length_equals",
+		Expect:  expected,
+		Message: msg,
+	}
+	s.Validators = append(s.Validators, v)
+	return s
+}
+
+var errPersistedQueryNotFound = errors.New("persisted query not found")
+
+// runStepGraphQL posts graphql query as HTTP request, response with errors fails the step unless allowed.
+func runStepGraphQL(r *SessionRunner, step *StepGraphQL) (stepResult *StepResult, err error) {
+	start := time.Now()
+	query, err := step.GraphQL.loadQuery(r.caseRunner.Config.Get().Path)
+	if err != nil {
+		return &StepResult{
+			Name:        step.Name(),
+			StepType:    step.Type(),
+			StartTime:   start.UnixMilli(),
+			Attachments: err.Error(),
+		}, err
+	}
+
+	var queryHash string
+	if step.GraphQL.PersistedQuery {
+		sum := sha256.Sum256([]byte(query))
+		queryHash = hex.EncodeToString(sum[:])
+	}
+
+	// persisted query is sent without query first, and resent with query if not found on server
+	sendQuery := !step.GraphQL.PersistedQuery
+	for {
+		delete(step.Variables, "hrp_step_response")
+		probe := !sendQuery
+		stepRequest := &StepRequestWithOptionalArgs{
+			StepRequest: &StepRequest{
+				StepConfig: step.StepConfig,
+				Request:    step.GraphQL.newRequest(query, queryHash, sendQuery),
+				checkResponse: func(respObjMeta interface{}) error {
+					// skip teardown hooks and validators if query is not persisted
+					if probe && isPersistedQueryNotFound(graphqlErrors(respObjMeta)) {
+						return errPersistedQueryNotFound
+					}
+					return nil
+				},
+			},
+		}
+		stepResult, err = runStepRequest(r, stepRequest)
+		stepResult.StepType = step.Type()
+		if errors.Is(err, errPersistedQueryNotFound) {
+			log.Info().Str("hash", queryHash).Msg("persisted query not found, resend with query")
+			sendQuery = true
+			continue
+		}
+
+		gqlErrors := graphqlErrors(stepRequest.Variables["hrp_step_response"])
+		if len(gqlErrors) == 0 || step.GraphQL.AllowErrors {
+			return stepResult, err
+		}
+
+		// record errors as failed validation
+		if sessionData, ok := stepResult.Data.(*SessionData); ok {
+			sessionData.Validators = append(sessionData.Validators, &ValidationResult{
+				Validator: Validator{
+					Check:   "body.errors",
+					Assert:  "length_equals",
+					Expect:  0,
+					Message: "graphql errors are not allowed",
+				},
+				CheckValue:  gqlErrors,
+				CheckResult: "fail",
+			})
+		}
+		stepResult.Success = false
+		errMsg := fmt.Sprintf("graphql response errors: %s", formatGraphQLErrors(gqlErrors))
+		if err != nil {
+			err = errors.Wrap(err, errMsg)
+		} else {
+			err = errors.New(errMsg)
+		}
+		stepResult.Attachments = err.Error()
+		return stepResult, err
+	}
+}
+
+// newRequest builds HTTP request with graphql payload,
+// $ in query is escaped since graphql variables share the same syntax with step variables
+func (g *GraphQL) newRequest(query, queryHash string, sendQuery bool) *Request {
+	payload := map[string]interface{}{}
+	if sendQuery {
+		payload["query"] = strings.ReplaceAll(query, "$", "$$")
+	}
+	if g.OperationName != "" {
+		payload["operationName"] = g.OperationName
+	}
+	if len(g.Variables) > 0 {
+		payload["variables"] = g.Variables
+	}
+	if queryHash != "" {
+		payload["extensions"] = map[string]interface{}{
+			"persistedQuery": map[string]interface{}{
+				"version":    1,
+				"sha256Hash": queryHash,
+			},
+		}
+	}
+
+	headers := map[string]string{"Content-Type": "application/json"}
+	for key, value := range g.Headers {
+		headers[key] = value
+	}
+	return &Request{
+		Method:  HTTP_POST,
+		URL:     g.URL,
+		Headers: headers,
+		Body:    payload,
+		Timeout: g.Timeout,
+	}
+}
+
+// graphqlErrors returns errors in graphql response body
+func graphqlErrors(respObjMeta interface{}) []interface{} {
+	resp, ok := respObjMeta.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	body, ok := resp["body"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	gqlErrors, _ := body["errors"].([]interface{})
+	return gqlErrors
+}
+
+func isPersistedQueryNotFound(gqlErrors []interface{}) bool {
+	for _, e := range gqlErrors {
+		gqlError, ok := e.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if gqlError["message"] == "PersistedQueryNotFound" {
+			return true
+		}
+		if extensions, ok := gqlError["extensions"].(map[string]interface{}); ok &&
+			extensions["code"] == "PERSISTED_QUERY_NOT_FOUND" {
+			return true
+		}
+	}
+	return false
+}
+
+func formatGraphQLErrors(gqlErrors []interface{}) string {
+	messages := make([]string, 0, len(gqlErrors))
+	for _, e := range gqlErrors {
+		if gqlError, ok := e.(map[string]interface{}); ok && gqlError["message"] != nil {
+			messages = append(messages, fmt.Sprintf("%v", gqlError["message"]))
+		} else {
+			messages = append(messages, fmt.Sprintf("%v", e))
+		}
+	}
+	return strings.Join(messages, "; ")
+}
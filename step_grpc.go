@@ -0,0 +1,649 @@
+package hrp
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/grpcreflect"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/httprunner/httprunner/v5/internal/builtin"
+	"github.com/httprunner/httprunner/v5/internal/json"
+)
+
+type GRPC struct {
+	URL         string            `json:"url" yaml:"url"`                                       // server address, e.g. localhost:50051
+	Method      string            `json:"method" yaml:"method"`                                 // full method name, e.g. helloworld.Greeter/SayHello
+	ProtoFiles  []string          `json:"proto_files,omitempty" yaml:"proto_files,omitempty"`   // use server reflection if not specified
+	ImportPaths []string          `json:"import_paths,omitempty" yaml:"import_paths,omitempty"` // default to dirs of proto files
+	Metadata    map[string]string `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Body        interface{}       `json:"body,omitempty" yaml:"body,omitempty"`       // request message, or list of messages for client/bidi streaming
+	Timeout     float64           `json:"timeout,omitempty" yaml:"timeout,omitempty"` // deadline in seconds
+	TLS         *GRPCTLS          `json:"tls,omitempty" yaml:"tls,omitempty"`         // use plaintext connection if not specified
+}
+
+type GRPCTLS struct {
+	CACert             string `json:"ca_cert,omitempty" yaml:"ca_cert,omitempty"` // CA certificate to verify server, default to system roots
+	Cert               string `json:"cert,omitempty" yaml:"cert,omitempty"`       // client certificate for mutual TLS
+	Key                string `json:"key,omitempty" yaml:"key,omitempty"`         // client private key for mutual TLS
+	ServerName         string `json:"server_name,omitempty" yaml:"server_name,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty" yaml:"insecure_skip_verify,omitempty"`
+}
+
+// StepGRPC implements IStep interface.
+type StepGRPC struct {
+	StepConfig
+	GRPC *GRPC `json:"grpc,omitempty" yaml:"grpc,omitempty"`
+}
+
+func (s *StepGRPC) Name() string {
+	if s.StepName != "" {
+		return s.StepName
+	}
+	return fmt.Sprintf("grpc %s", s.GRPC.Method)
+}
+
+func (s *StepGRPC) Type() StepType {
+	return StepTypeGRPC
+}
+
+func (s *StepGRPC) Config() *StepConfig {
+	return &s.StepConfig
+}
+
+func (s *StepGRPC) Run(r *SessionRunner) (*StepResult, error) {
+	return runStepGRPC(r, s)
+}
+
+// WithBody sets the request message, pass multiple messages for client or bidi streaming.
+func (s *StepGRPC) WithBody(messages ...interface{}) *StepGRPC {
+	if len(messages) == 1 {
+		s.GRPC.Body = messages[0]
+	} else {
+		s.GRPC.Body = messages
+	}
+	return s
+}
+
+// WithMetadata sets the request metadata.
+func (s *StepGRPC) WithMetadata(md map[string]string) *StepGRPC {
+	s.GRPC.Metadata = md
+	return s
+}
+
+// WithProtoFiles resolves method with local proto files instead of server reflection.
+func (s *StepGRPC) WithProtoFiles(files ...string) *StepGRPC {
+	s.GRPC.ProtoFiles = append(s.GRPC.ProtoFiles, files...)
+	return s
+}
+
+// WithImportPaths sets import paths for proto files.
+func (s *StepGRPC) WithImportPaths(paths ...string) *StepGRPC {
+	s.GRPC.ImportPaths = append(s.GRPC.ImportPaths, paths...)
+	return s
+}
+
+// WithTimeout sets the call deadline in seconds.
+func (s *StepGRPC) WithTimeout(timeout float64) *StepGRPC {
+	s.GRPC.Timeout = timeout
+	return s
+}
+
+// WithTLS uses TLS connection.
+func (s *StepGRPC) WithTLS(tlsConfig *GRPCTLS) *StepGRPC {
+	s.GRPC.TLS = tlsConfig
+	return s
+}
+
+// Validate switches to step validation.
+func (s *StepGRPC) Validate() *StepGRPCValidation {
+	return &StepGRPCValidation{
+		StepGRPC: s,
+	}
+}
+
+// Extract switches to step extraction.
+func (s *StepGRPC) Extract() *StepGRPCExtraction {
+	s.StepConfig.Extract = make(map[string]string)
+	return &StepGRPCExtraction{
+		StepGRPC: s,
+	}
+}
+
+// StepGRPCExtraction implements IStep interface.
+type StepGRPCExtraction struct {
+	*StepGRPC
+}
+
+// WithJmesPath sets the JMESPath expression to extract from the response.
+func (s *StepGRPCExtraction) WithJmesPath(jmesPath string, varName string) *StepGRPCExtraction {
+	s.StepConfig.Extract[varName] = jmesPath
+	return s
+}
+
+// Validate switches to step validation.
+func (s *StepGRPCExtraction) Validate() *StepGRPCValidation {
+	return &StepGRPCValidation{
+		StepGRPC: s.StepGRPC,
+	}
+}
+
+func (s *StepGRPCExtraction) Type() StepType {
+	return StepTypeGRPC + stepTypeSuffixExtraction
+}
+
+func (s *StepGRPCExtraction) Run(r *SessionRunner) (*StepResult, error) {
+	if s.GRPC != nil {
+		return runStepGRPC(r, s.StepGRPC)
+	}
+	return nil, errors.New("unexpected protocol type")
+}
+
+// StepGRPCValidation implements IStep interface.
+type StepGRPCValidation struct {
+	*StepGRPC
+}
+
+func (s *StepGRPCValidation) Type() StepType {
+	return StepTypeGRPC + stepTypeSuffixValidation
+}
+
+func (s *StepGRPCValidation) Run(r *SessionRunner) (*StepResult, error) {
+	if s.GRPC != nil {
+		return runStepGRPC(r, s.StepGRPC)
+	}
+	return nil, errors.New("unexpected protocol type")
+}
+
+func (s *StepGRPCValidation) AssertEqual(jmesPath string, expected interface{}, msg string) *StepGRPCValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  "equals",
+		Expect:  expected,
+		Message: msg,
+	}
+	s.Validators = append(s.Validators, v)
+	return s
+}
+
+func (s *StepGRPCValidation) AssertNotEqual(jmesPath string, expected interface{}, msg string) *StepGRPCValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  "not_equal",
+		Expect:  expected,
+		Message: msg,
+	}
+	s.Validators = append(s.Validators, v)
+	return s
+}
+
+func (s *StepGRPCValidation) AssertContains(jmesPath string, expected interface{}, msg string) *StepGRPCValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  "contains",
+		Expect:  expected,
+		Message: msg,
+	}
+	s.Validators = append(s.Validators, v)
+	return s
+}
+
+func (s *StepGRPCValidation) AssertLengthEqual(jmesPath string, expected interface{}, msg string) *StepGRPCValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  "length_equals",
+		Expect:  expected,
+		Message: msg,
+	}
+	s.Validators = append(s.Validators, v)
+	return s
+}
+
+// AssertStatusCode checks gRPC status code, e.g. codes.OK, codes.NotFound.
+func (s *StepGRPCValidation) AssertStatusCode(expected codes.Code, msg string) *StepGRPCValidation {
+	return s.AssertEqual("status_code", int(expected), msg)
+}
+
+// grpcFieldTags are searched only on grpc response, besides the common fieldTags
+var grpcFieldTags = []string{"trailers", "status_message"}
+
+type grpcRespObjMeta struct {
+	StatusCode    int               `json:"status_code"`    // gRPC status code, 0 for OK
+	StatusMessage string            `json:"status_message"` // gRPC status message
+	Headers       map[string]string `json:"headers"`
+	Trailers      map[string]string `json:"trailers"`
+	Body          interface{}       `json:"body"` // response message, or list of messages for server/bidi streaming
+}
+
+func newGRPCSession() *grpcSession {
+	return &grpcSession{
+		conns:    make(map[string]*grpc.ClientConn),
+		services: make(map[string]protoreflect.ServiceDescriptor),
+	}
+}
+
+type grpcSession struct {
+	conns    map[string]*grpc.ClientConn               // connections by target and tls config
+	services map[string]protoreflect.ServiceDescriptor // services resolved by server reflection
+}
+
+func (s *grpcSession) close() {
+	for target, conn := range s.conns {
+		if err := conn.Close(); err != nil {
+			log.Error().Err(err).Str("target", target).Msg("close grpc connection failed")
+		}
+	}
+	s.conns = make(map[string]*grpc.ClientConn)
+}
+
+func runStepGRPC(r *SessionRunner, step *StepGRPC) (stepResult *StepResult, err error) {
+	variables := step.Variables
+	start := time.Now()
+	stepResult = &StepResult{
+		Name:        step.Name(),
+		StepType:    step.Type(),
+		Success:     false,
+		ContentSize: 0,
+		StartTime:   start.UnixMilli(),
+	}
+	defer func() {
+		if err != nil {
+			stepResult.Attachments = err.Error()
+		}
+		stepResult.Elapsed = time.Since(start).Milliseconds()
+	}()
+
+	parser := r.caseRunner.parser
+	casePath := r.caseRunner.Config.Get().Path
+
+	// parse target, method, metadata and request body
+	target, err := parser.ParseString(step.GRPC.URL, variables)
+	if err != nil {
+		return stepResult, errors.Wrap(err, "parse grpc url failed")
+	}
+	method, err := parser.ParseString(step.GRPC.Method, variables)
+	if err != nil {
+		return stepResult, errors.Wrap(err, "parse grpc method failed")
+	}
+	serviceName, methodName, err := splitGRPCMethod(convertString(method))
+	if err != nil {
+		return stepResult, err
+	}
+	md := metadata.MD{}
+	for key, value := range step.GRPC.Metadata {
+		parsedValue, err := parser.ParseString(value, variables)
+		if err != nil {
+			return stepResult, errors.Wrapf(err, "parse grpc metadata %s failed", key)
+		}
+		md.Append(key, convertString(parsedValue))
+	}
+	body, err := parser.Parse(step.GRPC.Body, variables)
+	if err != nil {
+		return stepResult, errors.Wrap(err, "parse grpc body failed")
+	}
+
+	fullMethod := fmt.Sprintf("/%s/%s", serviceName, methodName)
+	requestMap := map[string]interface{}{
+		"url":      convertString(target),
+		"method":   fullMethod,
+		"metadata": md,
+		"body":     body,
+	}
+	variables["hrp_step_name"] = step.Name()
+	variables["hrp_step_request"] = requestMap
+
+	// deal with setup hooks
+	for _, setupHook := range step.SetupHooks {
+		_, err = parser.Parse(setupHook, variables)
+		if err != nil {
+			return stepResult, errors.Wrap(err, "run setup hooks failed")
+		}
+	}
+
+	conn, err := r.grpc.getConn(convertString(target), step.GRPC.TLS, casePath)
+	if err != nil {
+		return stepResult, err
+	}
+	methodDesc, err := r.grpc.resolveMethod(r.ctx, conn, step.GRPC, serviceName, methodName, casePath)
+	if err != nil {
+		return stepResult, err
+	}
+	requests, err := newGRPCRequests(methodDesc, body)
+	if err != nil {
+		return stepResult, err
+	}
+
+	ctx := metadata.NewOutgoingContext(r.ctx, md)
+	if step.GRPC.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(step.GRPC.Timeout*1000)*time.Millisecond)
+		defer cancel()
+	}
+
+	log.Info().Str("target", convertString(target)).Str("method", fullMethod).
+		Bool("client_streaming", methodDesc.IsStreamingClient()).
+		Bool("server_streaming", methodDesc.IsStreamingServer()).
+		Msg("call grpc method")
+	respObjMeta, contentSize := invokeGRPC(ctx, conn, methodDesc, fullMethod, requests)
+	if r.caseRunner.hrpRunner.requestsLogOn {
+		fmt.Printf("-------------------- grpc call: %s --------------------\n", fullMethod)
+		respBytes, _ := json.MarshalIndent(respObjMeta, "", "    ")
+		fmt.Println(string(respBytes))
+	}
+
+	respObj, err := convertToResponseObject(r.testingT(), parser, respObjMeta)
+	if err != nil {
+		err = errors.Wrap(err, "init ResponseObject error")
+		return
+	}
+	respObj.casePath = casePath
+	respObj.fieldTags = grpcFieldTags
+	variables["hrp_step_response"] = respObj.respObjMeta
+
+	// deal with teardown hooks
+	for _, teardownHook := range step.TeardownHooks {
+		_, err = parser.Parse(teardownHook, variables)
+		if err != nil {
+			return stepResult, errors.Wrap(err, "run teardown hooks failed")
+		}
+	}
+
+	sessionData := &SessionData{
+		ReqResps: &ReqResps{
+			Request:  requestMap,
+			Response: builtin.FormatResponse(respObj.respObjMeta),
+		},
+	}
+	stepResult.Data = sessionData
+	stepResult.ContentSize = contentSize
+
+	// extract variables from response
+	extractMapping := respObj.Extract(step.StepConfig.Extract, variables)
+	stepResult.ExportVars = extractMapping
+
+	// override step variables with extracted variables
+	variables = mergeVariables(variables, extractMapping)
+
+	// validate response
+	err = respObj.Validate(step.Validators, variables, r.validateMode(&step.StepConfig))
+	sessionData.Validators = respObj.validationResults
+	if err == nil {
+		stepResult.Success = true
+	}
+	return stepResult, err
+}
+
+// invokeGRPC calls unary or streaming method, non-OK status is kept in response for validation.
+func invokeGRPC(ctx context.Context, conn *grpc.ClientConn, methodDesc protoreflect.MethodDescriptor,
+	fullMethod string, requests []proto.Message,
+) (respObjMeta *grpcRespObjMeta, contentSize int64) {
+	streamDesc := &grpc.StreamDesc{
+		StreamName:    string(methodDesc.Name()),
+		ClientStreams: methodDesc.IsStreamingClient(),
+		ServerStreams: methodDesc.IsStreamingServer(),
+	}
+	respObjMeta = &grpcRespObjMeta{}
+	var responses []interface{}
+	var err error
+	defer func() {
+		st := status.Convert(err)
+		respObjMeta.StatusCode = int(st.Code())
+		respObjMeta.StatusMessage = st.Message()
+		if methodDesc.IsStreamingServer() {
+			respObjMeta.Body = responses
+		} else if len(responses) > 0 {
+			respObjMeta.Body = responses[0]
+		}
+	}()
+
+	stream, err := conn.NewStream(ctx, streamDesc, fullMethod)
+	if err != nil {
+		return
+	}
+	for _, request := range requests {
+		// the actual error is returned by RecvMsg if stream is broken
+		if err = stream.SendMsg(request); err != nil {
+			break
+		}
+	}
+	if err = stream.CloseSend(); err != nil {
+		return
+	}
+
+	for {
+		response := dynamicpb.NewMessage(methodDesc.Output())
+		err = stream.RecvMsg(response)
+		if err == io.EOF {
+			err = nil
+			break
+		}
+		if err != nil {
+			break
+		}
+		contentSize += int64(proto.Size(response))
+		var value interface{}
+		value, err = grpcMessageToValue(response)
+		if err != nil {
+			break
+		}
+		responses = append(responses, value)
+		if !methodDesc.IsStreamingServer() {
+			break
+		}
+	}
+
+	if header, e := stream.Header(); e == nil {
+		respObjMeta.Headers = flattenMetadata(header)
+	}
+	respObjMeta.Trailers = flattenMetadata(stream.Trailer())
+	return
+}
+
+// newGRPCRequests converts request body to messages of method input type
+func newGRPCRequests(methodDesc protoreflect.MethodDescriptor, body interface{}) ([]proto.Message, error) {
+	var items []interface{}
+	if list, ok := body.([]interface{}); ok {
+		items = list
+	} else {
+		items = []interface{}{body}
+	}
+	if !methodDesc.IsStreamingClient() && len(items) != 1 {
+		return nil, errors.Errorf("method %s expects one request message, got %d",
+			methodDesc.FullName(), len(items))
+	}
+
+	messages := make([]proto.Message, 0, len(items))
+	for _, item := range items {
+		message := dynamicpb.NewMessage(methodDesc.Input())
+		if item != nil {
+			itemBytes, err := json.Marshal(item)
+			if err != nil {
+				return nil, errors.Wrap(err, "marshal grpc request message failed")
+			}
+			if err := protojson.Unmarshal(itemBytes, message); err != nil {
+				return nil, errors.Wrapf(err, "convert %s to %s failed",
+					string(itemBytes), methodDesc.Input().FullName())
+			}
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// grpcMessageToValue converts message to json value with proto field names, zero values are kept
+func grpcMessageToValue(message proto.Message) (interface{}, error) {
+	messageBytes, err := protojson.MarshalOptions{
+		UseProtoNames:   true,
+		EmitUnpopulated: true,
+	}.Marshal(message)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal grpc response message failed")
+	}
+	var value interface{}
+	if err := json.Unmarshal(messageBytes, &value); err != nil {
+		return nil, errors.Wrap(err, "unmarshal grpc response message failed")
+	}
+	return value, nil
+}
+
+func flattenMetadata(md metadata.MD) map[string]string {
+	result := make(map[string]string, len(md))
+	for key, values := range md {
+		result[key] = strings.Join(values, ",")
+	}
+	return result
+}
+
+// splitGRPCMethod splits full method name into service and method name,
+// e.g. helloworld.Greeter/SayHello, /helloworld.Greeter/SayHello, helloworld.Greeter.SayHello
+func splitGRPCMethod(fullMethod string) (service, method string, err error) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	i := strings.LastIndex(fullMethod, "/")
+	if i < 0 {
+		i = strings.LastIndex(fullMethod, ".")
+	}
+	if i <= 0 || i == len(fullMethod)-1 {
+		return "", "", errors.Errorf("invalid grpc method %s, should be like package.Service/Method", fullMethod)
+	}
+	return fullMethod[:i], fullMethod[i+1:], nil
+}
+
+// getConn returns connection of target, connections are reused in the same session
+func (s *grpcSession) getConn(target string, tlsConfig *GRPCTLS, casePath string) (*grpc.ClientConn, error) {
+	key := target
+	if tlsConfig != nil {
+		key = fmt.Sprintf("%s|%+v", target, *tlsConfig)
+	}
+	if conn, ok := s.conns[key]; ok {
+		return conn, nil
+	}
+
+	creds := insecure.NewCredentials()
+	if tlsConfig != nil {
+		config, err := tlsConfig.load(casePath)
+		if err != nil {
+			return nil, err
+		}
+		creds = credentials.NewTLS(config)
+	}
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, errors.Wrapf(err, "create grpc connection to %s failed", target)
+	}
+	s.conns[key] = conn
+	return conn, nil
+}
+
+func (t *GRPCTLS) load(casePath string) (*tls.Config, error) {
+	return loadTLSConfig(casePath, t.CACert, t.Cert, t.Key, t.ServerName, t.InsecureSkipVerify)
+}
+
+// resolveMethod resolves method descriptor with proto files, or server reflection if proto files not specified
+func (s *grpcSession) resolveMethod(ctx context.Context, conn *grpc.ClientConn, g *GRPC,
+	serviceName, methodName, casePath string,
+) (protoreflect.MethodDescriptor, error) {
+	var serviceDesc protoreflect.ServiceDescriptor
+	if len(g.ProtoFiles) > 0 {
+		files, err := loadProtoFiles(g.ProtoFiles, g.ImportPaths, casePath)
+		if err != nil {
+			return nil, err
+		}
+		d, err := files.FindDescriptorByName(protoreflect.FullName(serviceName))
+		if err != nil {
+			return nil, errors.Wrapf(err, "service %s not found in proto files", serviceName)
+		}
+		var ok bool
+		if serviceDesc, ok = d.(protoreflect.ServiceDescriptor); !ok {
+			return nil, errors.Errorf("%s is not a service", serviceName)
+		}
+	} else {
+		var err error
+		serviceDesc, err = s.resolveService(ctx, conn, serviceName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	methodDesc := serviceDesc.Methods().ByName(protoreflect.Name(methodName))
+	if methodDesc == nil {
+		return nil, errors.Errorf("method %s not found in service %s", methodName, serviceName)
+	}
+	return methodDesc, nil
+}
+
+// resolveService resolves service descriptor with server reflection, services are cached in the same session
+func (s *grpcSession) resolveService(ctx context.Context, conn *grpc.ClientConn, serviceName string,
+) (protoreflect.ServiceDescriptor, error) {
+	key := conn.Target() + "/" + serviceName
+	if serviceDesc, ok := s.services[key]; ok {
+		return serviceDesc, nil
+	}
+	client := grpcreflect.NewClientAuto(ctx, conn)
+	defer client.Reset()
+	sd, err := client.ResolveService(serviceName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "resolve service %s with server reflection failed", serviceName)
+	}
+	s.services[key] = sd.UnwrapService()
+	return s.services[key], nil
+}
+
+var protoFilesCache sync.Map // proto files and import paths -> *protoregistry.Files
+
+// loadProtoFiles parses proto files, import paths default to dirs of proto files
+func loadProtoFiles(protoFiles, importPaths []string, casePath string) (*protoregistry.Files, error) {
+	var paths, files []string
+	for _, p := range importPaths {
+		paths = append(paths, resolveCaseFilePath(casePath, p))
+	}
+	for _, f := range protoFiles {
+		f = resolveCaseFilePath(casePath, f)
+		if len(importPaths) == 0 {
+			paths = append(paths, filepath.Dir(f))
+			f = filepath.Base(f)
+		}
+		files = append(files, f)
+	}
+
+	sortedFiles := append([]string{}, files...)
+	sort.Strings(sortedFiles)
+	key := strings.Join(sortedFiles, ",") + "|" + strings.Join(paths, ",")
+	if registry, ok := protoFilesCache.Load(key); ok {
+		return registry.(*protoregistry.Files), nil
+	}
+
+	parser := protoparse.Parser{ImportPaths: paths}
+	fds, err := parser.ParseFiles(files...)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse proto files failed")
+	}
+	registry, err := protodesc.NewFiles(desc.ToFileDescriptorSet(fds...))
+	if err != nil {
+		return nil, errors.Wrap(err, "build proto registry failed")
+	}
+	log.Info().Strs("files", files).Strs("import_paths", paths).Msg("load proto files")
+	protoFilesCache.Store(key, registry)
+	return registry, nil
+}
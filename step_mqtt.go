@@ -0,0 +1,677 @@
+package hrp
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/httprunner/httprunner/v5/internal/builtin"
+	"github.com/httprunner/httprunner/v5/internal/json"
+)
+
+type MQTTActionType string
+
+const (
+	mqttConnect     MQTTActionType = "connect"
+	mqttPublish     MQTTActionType = "publish"
+	mqttSubscribe   MQTTActionType = "subscribe"
+	mqttExpect      MQTTActionType = "expect"
+	mqttUnsubscribe MQTTActionType = "unsubscribe"
+	mqttDisconnect  MQTTActionType = "disconnect"
+)
+
+// MQTT is an action of mqtt client, clients are kept in session and identified by broker url and client id.
+type MQTT struct {
+	Type         MQTTActionType `json:"type" yaml:"type"`
+	URL          string         `json:"url" yaml:"url"`                                         // broker url, e.g. tcp://localhost:1883, ssl://localhost:8883, ws://localhost:8083/mqtt
+	ClientID     string         `json:"client_id,omitempty" yaml:"client_id,omitempty"`         // random client id is used when connecting if not specified
+	Username     string         `json:"username,omitempty" yaml:"username,omitempty"`           // connect credentials
+	Password     string         `json:"password,omitempty" yaml:"password,omitempty"`           // connect credentials
+	TLS          *MQTTTLS       `json:"tls,omitempty" yaml:"tls,omitempty"`                     // tls config for ssl:// or wss:// broker
+	CleanSession *bool          `json:"clean_session,omitempty" yaml:"clean_session,omitempty"` // default to true
+	KeepAlive    int64          `json:"keep_alive,omitempty" yaml:"keep_alive,omitempty"`       // keep alive in seconds, default to 30
+	Will         *MQTTWill      `json:"will,omitempty" yaml:"will,omitempty"`                   // last will sent by broker when client disconnects abnormally
+	Topic        string         `json:"topic,omitempty" yaml:"topic,omitempty"`                 // topic to publish, or topic filter with wildcards + and # to subscribe and expect
+	Payload      interface{}    `json:"payload,omitempty" yaml:"payload,omitempty"`             // payload to publish, non-string is encoded as json
+	QoS          byte           `json:"qos,omitempty" yaml:"qos,omitempty"`                     // 0, 1 or 2
+	Retain       bool           `json:"retain,omitempty" yaml:"retain,omitempty"`
+	Until        *Validator     `json:"until,omitempty" yaml:"until,omitempty"`     // condition of message to expect
+	Timeout      int64          `json:"timeout,omitempty" yaml:"timeout,omitempty"` // timeout in milliseconds for each action
+}
+
+type MQTTWill struct {
+	Topic   string      `json:"topic" yaml:"topic"`
+	Payload interface{} `json:"payload,omitempty" yaml:"payload,omitempty"`
+	QoS     byte        `json:"qos,omitempty" yaml:"qos,omitempty"`
+	Retain  bool        `json:"retain,omitempty" yaml:"retain,omitempty"`
+}
+
+type MQTTTLS struct {
+	CACert             string `json:"ca_cert,omitempty" yaml:"ca_cert,omitempty"` // CA certificate to verify broker, default to system roots
+	Cert               string `json:"cert,omitempty" yaml:"cert,omitempty"`       // client certificate for mutual TLS
+	Key                string `json:"key,omitempty" yaml:"key,omitempty"`         // client private key for mutual TLS
+	ServerName         string `json:"server_name,omitempty" yaml:"server_name,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty" yaml:"insecure_skip_verify,omitempty"`
+}
+
+func (t *MQTTTLS) load(casePath string) (*tls.Config, error) {
+	return loadTLSConfig(casePath, t.CACert, t.Cert, t.Key, t.ServerName, t.InsecureSkipVerify)
+}
+
+func (m *MQTT) GetTimeout() int64 {
+	if m.Timeout <= 0 {
+		return defaultTimeout
+	}
+	return m.Timeout
+}
+
+// StepMQTT implements IStep interface.
+type StepMQTT struct {
+	StepConfig
+	MQTT *MQTT `json:"mqtt,omitempty" yaml:"mqtt,omitempty"`
+}
+
+func (s *StepMQTT) Name() string {
+	if s.StepName != "" {
+		return s.StepName
+	}
+	return strings.TrimSpace(fmt.Sprintf("mqtt %s %s", s.MQTT.Type, s.MQTT.Topic))
+}
+
+func (s *StepMQTT) Type() StepType {
+	return StepTypeMQTT
+}
+
+func (s *StepMQTT) Config() *StepConfig {
+	return &s.StepConfig
+}
+
+func (s *StepMQTT) Run(r *SessionRunner) (*StepResult, error) {
+	return runStepMQTT(r, s)
+}
+
+// Connect connects to broker, the connected client is used by following steps with the same url and client id.
+func (s *StepMQTT) Connect() *StepMQTT {
+	s.MQTT.Type = mqttConnect
+	return s
+}
+
+// Publish publishes payload to topic.
+func (s *StepMQTT) Publish(topic string) *StepMQTT {
+	s.MQTT.Type = mqttPublish
+	s.MQTT.Topic = topic
+	return s
+}
+
+// Subscribe subscribes topic filter, e.g. devices/+/status, messages are collected in background.
+func (s *StepMQTT) Subscribe(topic string) *StepMQTT {
+	s.MQTT.Type = mqttSubscribe
+	s.MQTT.Topic = topic
+	return s
+}
+
+// Expect waits for collected message on topic filter within timeout, use WaitUntil to match message.
+func (s *StepMQTT) Expect(topic string) *StepMQTT {
+	s.MQTT.Type = mqttExpect
+	s.MQTT.Topic = topic
+	return s
+}
+
+func (s *StepMQTT) Unsubscribe(topic string) *StepMQTT {
+	s.MQTT.Type = mqttUnsubscribe
+	s.MQTT.Topic = topic
+	return s
+}
+
+func (s *StepMQTT) Disconnect() *StepMQTT {
+	s.MQTT.Type = mqttDisconnect
+	return s
+}
+
+func (s *StepMQTT) WithClientID(clientID string) *StepMQTT {
+	s.MQTT.ClientID = clientID
+	return s
+}
+
+func (s *StepMQTT) WithCredentials(username, password string) *StepMQTT {
+	s.MQTT.Username = username
+	s.MQTT.Password = password
+	return s
+}
+
+func (s *StepMQTT) WithTLS(tlsConfig *MQTTTLS) *StepMQTT {
+	s.MQTT.TLS = tlsConfig
+	return s
+}
+
+func (s *StepMQTT) WithCleanSession(clean bool) *StepMQTT {
+	s.MQTT.CleanSession = &clean
+	return s
+}
+
+func (s *StepMQTT) WithWill(topic string, payload interface{}, qos byte, retain bool) *StepMQTT {
+	s.MQTT.Will = &MQTTWill{
+		Topic:   topic,
+		Payload: payload,
+		QoS:     qos,
+		Retain:  retain,
+	}
+	return s
+}
+
+// WithPayload sets payload to publish, non-string payload is encoded as json.
+func (s *StepMQTT) WithPayload(payload interface{}) *StepMQTT {
+	s.MQTT.Payload = payload
+	return s
+}
+
+func (s *StepMQTT) WithQoS(qos byte) *StepMQTT {
+	s.MQTT.QoS = qos
+	return s
+}
+
+func (s *StepMQTT) WithRetain() *StepMQTT {
+	s.MQTT.Retain = true
+	return s
+}
+
+func (s *StepMQTT) WithTimeout(timeout int64) *StepMQTT {
+	s.MQTT.Timeout = timeout
+	return s
+}
+
+// WaitUntil sets the condition of message to expect, check is JMESPath on message object, e.g. body.status
+func (s *StepMQTT) WaitUntil(check string, assert string, expected interface{}, msg ...string) *StepMQTT {
+	s.MQTT.Until = &Validator{
+		Check:  check,
+		Assert: assert,
+		Expect: expected,
+	}
+	if len(msg) > 0 {
+		s.MQTT.Until.Message = msg[0]
+	}
+	return s
+}
+
+// Validate switches to step validation.
+func (s *StepMQTT) Validate() *StepMQTTValidation {
+	return &StepMQTTValidation{
+		StepMQTT: s,
+	}
+}
+
+// Extract switches to step extraction.
+func (s *StepMQTT) Extract() *StepMQTTExtraction {
+	s.StepConfig.Extract = make(map[string]string)
+	return &StepMQTTExtraction{
+		StepMQTT: s,
+	}
+}
+
+// StepMQTTExtraction implements IStep interface.
+type StepMQTTExtraction struct {
+	*StepMQTT
+}
+
+// WithJmesPath sets the JMESPath expression to extract from the response.
+func (s *StepMQTTExtraction) WithJmesPath(jmesPath string, varName string) *StepMQTTExtraction {
+	s.StepConfig.Extract[varName] = jmesPath
+	return s
+}
+
+// Validate switches to step validation.
+func (s *StepMQTTExtraction) Validate() *StepMQTTValidation {
+	return &StepMQTTValidation{
+		StepMQTT: s.StepMQTT,
+	}
+}
+
+func (s *StepMQTTExtraction) Type() StepType {
+	return StepTypeMQTT + stepTypeSuffixExtraction
+}
+
+func (s *StepMQTTExtraction) Run(r *SessionRunner) (*StepResult, error) {
+	if s.MQTT != nil {
+		return runStepMQTT(r, s.StepMQTT)
+	}
+	return nil, errors.New("unexpected protocol type")
+}
+
+// StepMQTTValidation implements IStep interface.
+type StepMQTTValidation struct {
+	*StepMQTT
+}
+
+func (s *StepMQTTValidation) Type() StepType {
+	return StepTypeMQTT + stepTypeSuffixValidation
+}
+
+func (s *StepMQTTValidation) Run(r *SessionRunner) (*StepResult, error) {
+	if s.MQTT != nil {
+		return runStepMQTT(r, s.StepMQTT)
+	}
+	return nil, errors.New("unexpected protocol type")
+}
+
+func (s *StepMQTTValidation) AssertEqual(jmesPath string, expected interface{}, msg string) *StepMQTTValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  "equals",
+		Expect:  expected,
+		Message: msg,
+	}
+	s.Validators = append(s.Validators, v)
+	return s
+}
+
+func (s *StepMQTTValidation) AssertContains(jmesPath string, expected interface{}, msg string) *StepMQTTValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  "contains",
+		Expect:  expected,
+		Message: msg,
+	}
+	s.Validators = append(s.Validators, v)
+	return s
+}
+
+func (s *StepMQTTValidation) AssertLengthEqual(jmesPath string, expected interface{}, msg string) *StepMQTTValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  "length_equals",
+		Expect:  expected,
+		Message: msg,
+	}
+	s.Validators = append(s.Validators, v)
+	return s
+}
+
+// mqttFieldTags are searched only on mqtt message, besides the common fieldTags
+var mqttFieldTags = []string{"topic", "qos", "retained", "raw_message", "elapsed_ms"}
+
+// mqttMessage is the message received by subscriptions of client
+type mqttMessage struct {
+	Topic      string      `json:"topic"`
+	Body       interface{} `json:"body"`        // parsed as JSON if possible
+	RawMessage string      `json:"raw_message"` // raw payload, could be matched with regex
+	QoS        byte        `json:"qos"`
+	Retained   bool        `json:"retained"`
+	ElapsedMs  int64       `json:"elapsed_ms"` // from connecting to receiving message
+}
+
+func newMQTTSession() *mqttSession {
+	return &mqttSession{
+		clients: make(map[string]*mqttClient),
+	}
+}
+
+type mqttSession struct {
+	clients map[string]*mqttClient // clients by broker url and client id
+}
+
+// mqttClient collects messages of all subscriptions in background
+type mqttClient struct {
+	mqtt.Client
+	start    time.Time
+	messages *messageCollector[*mqttMessage]
+}
+
+func mqttClientKey(url, clientID string) string {
+	return url + "#" + clientID
+}
+
+func (s *mqttSession) get(url, clientID string) (*mqttClient, error) {
+	client, ok := s.clients[mqttClientKey(url, clientID)]
+	if !ok {
+		return nil, errors.New("try to use existing client, but there is no connected client")
+	}
+	return client, nil
+}
+
+func (s *mqttSession) close() {
+	for key, client := range s.clients {
+		log.Info().Str("client", key).Msg("mqtt client disconnected")
+		client.Disconnect(250)
+	}
+	s.clients = make(map[string]*mqttClient)
+}
+
+func (s *mqttSession) connect(m *MQTT, url string, parser *Parser, variables map[string]interface{},
+	casePath string,
+) (*mqttClient, error) {
+	clientID := m.ClientID
+	if clientID == "" {
+		clientID = "hrp-" + uuid.NewString()[:8]
+	}
+	opts := mqtt.NewClientOptions().
+		AddBroker(url).
+		SetClientID(clientID).
+		SetAutoReconnect(false).
+		SetConnectTimeout(time.Duration(m.GetTimeout()) * time.Millisecond)
+	if m.Username != "" {
+		username, err := parser.ParseString(m.Username, variables)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse mqtt username failed")
+		}
+		opts.SetUsername(convertString(username))
+	}
+	if m.Password != "" {
+		password, err := parser.ParseString(m.Password, variables)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse mqtt password failed")
+		}
+		opts.SetPassword(convertString(password))
+	}
+	if m.TLS != nil {
+		tlsConfig, err := m.TLS.load(casePath)
+		if err != nil {
+			return nil, err
+		}
+		opts.SetTLSConfig(tlsConfig)
+	}
+	if m.CleanSession != nil {
+		opts.SetCleanSession(*m.CleanSession)
+	}
+	if m.KeepAlive > 0 {
+		opts.SetKeepAlive(time.Duration(m.KeepAlive) * time.Second)
+	}
+	if m.Will != nil {
+		payload, err := parseMQTTPayload(m.Will.Payload, parser, variables)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse mqtt will payload failed")
+		}
+		opts.SetBinaryWill(m.Will.Topic, payload, m.Will.QoS, m.Will.Retain)
+	}
+
+	client := &mqttClient{
+		start:    time.Now(),
+		messages: newMessageCollector[*mqttMessage](defaultMaxCollectedMessages),
+	}
+	// messages of all subscriptions are handled by default handler
+	opts.SetDefaultPublishHandler(func(_ mqtt.Client, msg mqtt.Message) {
+		client.add(msg)
+	})
+	client.Client = mqtt.NewClient(opts)
+	if err := waitMQTTToken(client.Connect(), m.GetTimeout()); err != nil {
+		return nil, errors.Wrap(err, "connect mqtt broker failed")
+	}
+	s.clients[mqttClientKey(url, m.ClientID)] = client
+	return client, nil
+}
+
+func (c *mqttClient) add(msg mqtt.Message) {
+	var body interface{}
+	if err := json.Unmarshal(msg.Payload(), &body); err != nil {
+		// payload is not json, use raw payload
+		body = string(msg.Payload())
+	}
+	log.Debug().Str("topic", msg.Topic()).Str("payload", string(msg.Payload())).Msg("receive mqtt message")
+	c.messages.add(&mqttMessage{
+		Topic:      msg.Topic(),
+		Body:       body,
+		RawMessage: string(msg.Payload()),
+		QoS:        msg.Qos(),
+		Retained:   msg.Retained(),
+		ElapsedMs:  time.Since(c.start).Milliseconds(),
+	})
+}
+
+// expect waits for message matching topic filter and condition within timeout, and consumes it
+func (c *mqttClient) expect(topicFilter string, matcher eventMatcher, timeout time.Duration) (
+	*mqttMessage, interface{}, error,
+) {
+	msg, checkValue, ok, err := c.messages.expect(func(msg *mqttMessage) (bool, interface{}, error) {
+		if !matchMQTTTopic(topicFilter, msg.Topic) {
+			return false, nil, nil
+		}
+		if matcher == nil {
+			return true, nil, nil
+		}
+		return matcher(msg)
+	}, timeout)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		return nil, nil, errors.New("expect timeout")
+	}
+	return msg, checkValue, nil
+}
+
+// matchMQTTTopic checks whether topic matches filter with wildcards, + for single level and # for multi levels
+func matchMQTTTopic(filter, topic string) bool {
+	if filter == "" || filter == "#" {
+		return true
+	}
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+	for i, level := range filterLevels {
+		if level == "#" {
+			return true
+		}
+		if i >= len(topicLevels) {
+			return false
+		}
+		if level != "+" && level != topicLevels[i] {
+			return false
+		}
+	}
+	return len(filterLevels) == len(topicLevels)
+}
+
+func waitMQTTToken(token mqtt.Token, timeout int64) error {
+	if !token.WaitTimeout(time.Duration(timeout) * time.Millisecond) {
+		return errors.New("timeout")
+	}
+	return token.Error()
+}
+
+func parseMQTTPayload(payload interface{}, parser *Parser, variables map[string]interface{}) ([]byte, error) {
+	if payload == nil {
+		return []byte{}, nil
+	}
+	parsedPayload, err := parser.Parse(payload, variables)
+	if err != nil {
+		return nil, err
+	}
+	switch p := parsedPayload.(type) {
+	case string:
+		return []byte(p), nil
+	case []byte:
+		return p, nil
+	default:
+		return json.Marshal(p)
+	}
+}
+
+func runStepMQTT(r *SessionRunner, step *StepMQTT) (stepResult *StepResult, err error) {
+	m := step.MQTT
+	variables := step.Variables
+	start := time.Now()
+	stepResult = &StepResult{
+		Name:        step.Name(),
+		StepType:    step.Type(),
+		Success:     false,
+		ContentSize: 0,
+		StartTime:   start.UnixMilli(),
+	}
+	defer func() {
+		if err != nil {
+			stepResult.Attachments = err.Error()
+		}
+		stepResult.Elapsed = time.Since(start).Milliseconds()
+	}()
+
+	parser := r.caseRunner.parser
+	casePath := r.caseRunner.Config.Get().Path
+	parsedURL, err := parser.ParseString(m.URL, variables)
+	if err != nil {
+		return stepResult, errors.Wrap(err, "parse mqtt url failed")
+	}
+	url := convertString(parsedURL)
+	parsedTopic, err := parser.ParseString(m.Topic, variables)
+	if err != nil {
+		return stepResult, errors.Wrap(err, "parse mqtt topic failed")
+	}
+	topic := convertString(parsedTopic)
+	requestMap := map[string]interface{}{
+		"type":      m.Type,
+		"url":       url,
+		"client_id": m.ClientID,
+		"topic":     topic,
+		"qos":       m.QoS,
+	}
+	var payload []byte
+	if m.Type == mqttPublish {
+		payload, err = parseMQTTPayload(m.Payload, parser, variables)
+		if err != nil {
+			return stepResult, errors.Wrap(err, "parse mqtt payload failed")
+		}
+		requestMap["payload"] = string(payload)
+		requestMap["retain"] = m.Retain
+	}
+	variables["hrp_step_name"] = step.Name()
+	variables["hrp_step_request"] = requestMap
+
+	// deal with setup hooks
+	for _, setupHook := range step.SetupHooks {
+		_, err = parser.Parse(setupHook, variables)
+		if err != nil {
+			return stepResult, errors.Wrap(err, "run setup hooks failed")
+		}
+	}
+
+	if r.caseRunner.hrpRunner.requestsLogOn {
+		fmt.Printf("-------------------- mqtt action: %v %s --------------------\n", m.Type, topic)
+	}
+	var msg *mqttMessage
+	var untilResult *ValidationResult
+	switch m.Type {
+	case mqttConnect:
+		log.Info().Str("url", url).Str("clientID", m.ClientID).Msg("connect mqtt broker")
+		// use the current client if existed
+		if _, e := r.mqtt.get(url, m.ClientID); e == nil {
+			break
+		}
+		if _, err = r.mqtt.connect(m, url, parser, variables, casePath); err != nil {
+			return stepResult, err
+		}
+	case mqttPublish, mqttSubscribe, mqttUnsubscribe, mqttExpect, mqttDisconnect:
+		var client *mqttClient
+		client, err = r.mqtt.get(url, m.ClientID)
+		if err != nil {
+			return stepResult, err
+		}
+		switch m.Type {
+		case mqttPublish:
+			log.Info().Str("topic", topic).Int("qos", int(m.QoS)).Bool("retain", m.Retain).Msg("publish mqtt message")
+			err = waitMQTTToken(client.Publish(topic, m.QoS, m.Retain, payload), m.GetTimeout())
+			if err != nil {
+				return stepResult, errors.Wrap(err, "publish message failed")
+			}
+		case mqttSubscribe:
+			log.Info().Str("topic", topic).Int("qos", int(m.QoS)).Msg("subscribe mqtt topic")
+			err = waitMQTTToken(client.Subscribe(topic, m.QoS, nil), m.GetTimeout())
+			if err != nil {
+				return stepResult, errors.Wrap(err, "subscribe topic failed")
+			}
+		case mqttUnsubscribe:
+			log.Info().Str("topic", topic).Msg("unsubscribe mqtt topic")
+			err = waitMQTTToken(client.Unsubscribe(topic), m.GetTimeout())
+			if err != nil {
+				return stepResult, errors.Wrap(err, "unsubscribe topic failed")
+			}
+		case mqttExpect:
+			log.Info().Str("topic", topic).Int64("timeout(ms)", m.GetTimeout()).Msg("expect mqtt message")
+			var matcher eventMatcher
+			if m.Until != nil {
+				untilResult, matcher, err = newEventMatcher(parser, m.Until, variables)
+				if err != nil {
+					return stepResult, err
+				}
+			}
+			var checkValue interface{}
+			msg, checkValue, err = client.expect(topic, matcher, time.Duration(m.GetTimeout())*time.Millisecond)
+			if err != nil {
+				if m.Until != nil {
+					err = errors.Wrapf(err, "no message matches until condition: %s %s %v",
+						m.Until.Check, m.Until.Assert, untilResult.Expect)
+				}
+				return stepResult, errors.Wrapf(err, "expect message on %s failed", topic)
+			}
+			if untilResult != nil {
+				untilResult.CheckValue = checkValue
+				untilResult.CheckResult = "pass"
+			}
+		case mqttDisconnect:
+			log.Info().Str("url", url).Str("clientID", m.ClientID).Msg("disconnect mqtt broker")
+			client.Disconnect(250)
+			delete(r.mqtt.clients, mqttClientKey(url, m.ClientID))
+		}
+	default:
+		return stepResult, errors.Errorf("unexpected mqtt action type: %v", m.Type)
+	}
+
+	var respObj *responseObject
+	if msg != nil {
+		if r.caseRunner.hrpRunner.requestsLogOn {
+			respBytes, _ := json.MarshalIndent(msg, "", "    ")
+			fmt.Println(string(respBytes))
+		}
+		respObj, err = convertToResponseObject(r.testingT(), parser, msg)
+		if err != nil {
+			err = errors.Wrap(err, "init ResponseObject error")
+			return
+		}
+		respObj.casePath = casePath
+		respObj.fieldTags = mqttFieldTags
+		// record until condition as validation result
+		if untilResult != nil {
+			respObj.validationResults = append(respObj.validationResults, untilResult)
+		}
+		variables["hrp_step_response"] = respObj.respObjMeta
+	}
+
+	// deal with teardown hooks
+	for _, teardownHook := range step.TeardownHooks {
+		_, err = parser.Parse(teardownHook, variables)
+		if err != nil {
+			return stepResult, errors.Wrap(err, "run teardown hooks failed")
+		}
+	}
+
+	if respObj == nil {
+		stepResult.Success = true
+		return stepResult, nil
+	}
+
+	sessionData := &SessionData{
+		ReqResps: &ReqResps{
+			Request:  requestMap,
+			Response: builtin.FormatResponse(respObj.respObjMeta),
+		},
+	}
+	stepResult.Data = sessionData
+	stepResult.ContentSize = int64(len(msg.RawMessage))
+
+	// extract variables from response
+	extractMapping := respObj.Extract(step.StepConfig.Extract, variables)
+	stepResult.ExportVars = extractMapping
+
+	// override step variables with extracted variables
+	variables = mergeVariables(variables, extractMapping)
+
+	// validate response
+	err = respObj.Validate(step.Validators, variables, r.validateMode(&step.StepConfig))
+	sessionData.Validators = respObj.validationResults
+	if err == nil {
+		stepResult.Success = true
+	}
+	return stepResult, err
+}
@@ -34,7 +34,7 @@ func (s *StepRendezvous) Run(r *SessionRunner) (*StepResult, error) {
 	log.Info().
 		Str("name", rendezvous.Name).
 		Float32("percent", rendezvous.Percent).
-		Int64("number", rendezvous.Number).
+		Int64("number", atomic.LoadInt64(&rendezvous.Number)).
 		Int64("timeout", rendezvous.Timeout).
 		Msg("rendezvous")
 
@@ -50,14 +50,15 @@ func (s *StepRendezvous) Run(r *SessionRunner) (*StepResult, error) {
 	}
 
 	// activate the rendezvous only once during each cycle
-	rendezvous.once.Do(func() {
-		close(rendezvous.activateChan)
+	activateChan, releaseChan, once := rendezvous.cycle()
+	once.Do(func() {
+		close(activateChan)
 	})
 
 	// check current cnt using double check lock before updating to avoid negative WaitGroup counter
-	if atomic.LoadInt64(&rendezvous.cnt) < rendezvous.Number {
+	if atomic.LoadInt64(&rendezvous.cnt) < atomic.LoadInt64(&rendezvous.Number) {
 		rendezvous.lock.Lock()
-		if atomic.LoadInt64(&rendezvous.cnt) < rendezvous.Number {
+		if atomic.LoadInt64(&rendezvous.cnt) < atomic.LoadInt64(&rendezvous.Number) {
 			atomic.AddInt64(&rendezvous.cnt, 1)
 			rendezvous.wg.Done()
 			rendezvous.timerResetChan <- struct{}{}
@@ -66,7 +67,7 @@ func (s *StepRendezvous) Run(r *SessionRunner) (*StepResult, error) {
 	}
 
 	// block until current rendezvous released
-	<-rendezvous.releaseChan
+	<-releaseChan
 	return stepResult, nil
 }
 
@@ -127,12 +128,15 @@ type Rendezvous struct {
 	releaseChan    chan struct{}
 	once           *sync.Once
 	lock           sync.Mutex
+	cycleLock      sync.RWMutex // guards channels and once which are renewed every cycle
 }
 
 func (r *Rendezvous) reset() {
-	r.cnt = 0
-	r.releasedFlag = 0
-	r.wg.Add(int(r.Number))
+	r.cycleLock.Lock()
+	defer r.cycleLock.Unlock()
+	atomic.StoreInt64(&r.cnt, 0)
+	atomic.StoreUint32(&r.releasedFlag, 0)
+	r.wg.Add(int(atomic.LoadInt64(&r.Number)))
 	// timerResetChan channel will not be closed, thus init only once
 	if r.timerResetChan == nil {
 		r.timerResetChan = make(chan struct{})
@@ -142,6 +146,13 @@ func (r *Rendezvous) reset() {
 	r.once = new(sync.Once)
 }
 
+// cycle returns the channels and once of current rendezvous cycle
+func (r *Rendezvous) cycle() (activateChan, releaseChan chan struct{}, once *sync.Once) {
+	r.cycleLock.RLock()
+	defer r.cycleLock.RUnlock()
+	return r.activateChan, r.releaseChan, r.once
+}
+
 func (r *Rendezvous) isSpawnDone() bool {
 	return atomic.LoadUint32(&r.spawnDoneFlag) == 1
 }
@@ -165,8 +176,8 @@ func (r *Rendezvous) updateRendezvousNumber(number int64) {
 func InitRendezvous(testcase *TestCase, total int64) []*Rendezvous {
 	var rendezvousList []*Rendezvous
 	for _, s := range testcase.TestSteps {
-		step := s.(*StepRendezvous)
-		if step.Rendezvous == nil {
+		step, ok := s.(*StepRendezvous)
+		if !ok || step.Rendezvous == nil {
 			continue
 		}
 		rendezvous := step.Rendezvous
@@ -208,7 +219,8 @@ func WaitRendezvous(rendezvousList []*Rendezvous, b IBoomer) {
 func waitSingleRendezvous(rendezvous *Rendezvous, rendezvousList []*Rendezvous, lastRendezvous *Rendezvous, b IBoomer) {
 	for {
 		// cycle start: block current checking until current rendezvous activated
-		<-rendezvous.activateChan
+		activateChan, releaseChan, _ := rendezvous.cycle()
+		<-activateChan
 		stop := make(chan struct{})
 		timeout := time.Duration(rendezvous.Timeout) * time.Millisecond
 		timer := time.NewTimer(timeout)
@@ -222,24 +234,24 @@ func waitSingleRendezvous(rendezvous *Rendezvous, rendezvousList []*Rendezvous,
 				timer.Reset(timeout)
 			case <-stop:
 				rendezvous.setReleased()
-				close(rendezvous.releaseChan)
+				close(releaseChan)
 				log.Info().
 					Str("name", rendezvous.Name).
 					Float32("percent", rendezvous.Percent).
-					Int64("number", rendezvous.Number).
+					Int64("number", atomic.LoadInt64(&rendezvous.Number)).
 					Int64("timeout(ms)", rendezvous.Timeout).
-					Int64("cnt", rendezvous.cnt).
+					Int64("cnt", atomic.LoadInt64(&rendezvous.cnt)).
 					Str("reason", "rendezvous release condition satisfied").
 					Msg("rendezvous released")
 			case <-timer.C:
 				rendezvous.setReleased()
-				close(rendezvous.releaseChan)
+				close(releaseChan)
 				log.Info().
 					Str("name", rendezvous.Name).
 					Float32("percent", rendezvous.Percent).
-					Int64("number", rendezvous.Number).
+					Int64("number", atomic.LoadInt64(&rendezvous.Number)).
 					Int64("timeout(ms)", rendezvous.Timeout).
-					Int64("cnt", rendezvous.cnt).
+					Int64("cnt", atomic.LoadInt64(&rendezvous.cnt)).
 					Str("reason", "time's up").
 					Msg("rendezvous released")
 			}
@@ -253,7 +265,8 @@ func waitSingleRendezvous(rendezvous *Rendezvous, rendezvousList []*Rendezvous,
 				r.updateRendezvousNumber(int64(b.GetSpawnCount()))
 			}
 		} else {
-			<-lastRendezvous.releaseChan
+			_, lastReleaseChan, _ := lastRendezvous.cycle()
+			<-lastReleaseChan
 		}
 	}
 }
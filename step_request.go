@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"compress/gzip"
 	"compress/zlib"
+	"context"
 	"crypto/tls"
 	"fmt"
 	"io"
@@ -42,19 +43,27 @@ const (
 // Request represents HTTP request data structure.
 // This is used for teststep.
 type Request struct {
-	Method         HTTPMethod             `json:"method" yaml:"method"` // required
-	URL            string                 `json:"url" yaml:"url"`       // required
-	HTTP2          bool                   `json:"http2,omitempty" yaml:"http2,omitempty"`
-	Params         map[string]interface{} `json:"params,omitempty" yaml:"params,omitempty"`
-	Headers        map[string]string      `json:"headers,omitempty" yaml:"headers,omitempty"`
-	Cookies        map[string]string      `json:"cookies,omitempty" yaml:"cookies,omitempty"`
-	Body           interface{}            `json:"body,omitempty" yaml:"body,omitempty"`
-	Json           interface{}            `json:"json,omitempty" yaml:"json,omitempty"`
-	Data           interface{}            `json:"data,omitempty" yaml:"data,omitempty"`
-	Timeout        float64                `json:"timeout,omitempty" yaml:"timeout,omitempty"` // timeout in seconds
-	AllowRedirects bool                   `json:"allow_redirects,omitempty" yaml:"allow_redirects,omitempty"`
-	Verify         bool                   `json:"verify,omitempty" yaml:"verify,omitempty"`
-	Upload         map[string]interface{} `json:"upload,omitempty" yaml:"upload,omitempty"`
+	Method           HTTPMethod             `json:"method" yaml:"method"` // required
+	URL              string                 `json:"url" yaml:"url"`       // required
+	HTTP2            bool                   `json:"http2,omitempty" yaml:"http2,omitempty"`
+	Params           map[string]interface{} `json:"params,omitempty" yaml:"params,omitempty"`
+	Headers          map[string]string      `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Cookies          map[string]string      `json:"cookies,omitempty" yaml:"cookies,omitempty"`
+	Body             interface{}            `json:"body,omitempty" yaml:"body,omitempty"`
+	Json             interface{}            `json:"json,omitempty" yaml:"json,omitempty"`
+	Data             interface{}            `json:"data,omitempty" yaml:"data,omitempty"`
+	Timeout          float64                `json:"timeout,omitempty" yaml:"timeout,omitempty"`                     // timeout in seconds
+	AllowRedirects   bool                   `json:"allow_redirects,omitempty" yaml:"allow_redirects,omitempty"`     // enable redirects, override testcase config
+	DisableRedirects bool                   `json:"disable_redirects,omitempty" yaml:"disable_redirects,omitempty"` // disable redirects, override testcase config
+	MaxRedirects     int                    `json:"max_redirects,omitempty" yaml:"max_redirects,omitempty"`         // override testcase config, default 10
+	Verify           bool                   `json:"verify,omitempty" yaml:"verify,omitempty"`                       // enable verification, override testcase config
+	SkipVerify       bool                   `json:"skip_verify,omitempty" yaml:"skip_verify,omitempty"`             // disable verification unless CA bundle is set
+	TLS              *RequestTLS            `json:"tls,omitempty" yaml:"tls,omitempty"`                             // override testcase config
+	Proxies          map[string]string      `json:"proxies,omitempty" yaml:"proxies,omitempty"`                     // override testcase config
+	Auth             *HTTPAuth              `json:"auth,omitempty" yaml:"auth,omitempty"`                           // override testcase config
+	Upload           map[string]interface{} `json:"upload,omitempty" yaml:"upload,omitempty"`
+	Stream           *RequestStream         `json:"stream,omitempty" yaml:"stream,omitempty"` // read response body as events
+	Sign             *RequestSign           `json:"sign,omitempty" yaml:"sign,omitempty"`     // override testcase config
 }
 
 func newRequestBuilder(parser *Parser, config *TConfig, stepRequest *Request) *requestBuilder {
@@ -89,6 +98,8 @@ type requestBuilder struct {
 	parser      *Parser
 	config      *TConfig
 	requestMap  map[string]interface{}
+	oauth2      *oauth2Session // inject oauth2 token into headers if set
+	oauth2Token *oauth2Token   // injected oauth2 token
 }
 
 func (r *requestBuilder) prepareHeaders(stepVariables map[string]interface{}) error {
@@ -132,13 +143,59 @@ func (r *requestBuilder) prepareHeaders(stepVariables map[string]interface{}) er
 		})
 	}
 
-	// update header
+	// inject oauth2 token unless step request specifies its own authorization
+	if r.oauth2 != nil && r.req.Header.Get("Authorization") == "" && r.stepRequest.Auth == nil {
+		token, err := r.oauth2.getToken(stepVariables)
+		if err != nil {
+			return errors.Wrap(err, "get oauth2 token failed")
+		}
+		r.req.Header.Set("Authorization", token.header())
+		r.oauth2Token = token
+	}
+
+	r.updateRequestMapHeaders()
+	return nil
+}
+
+// resendWithNewToken refreshes the oauth2 token rejected by server and resends the request once
+func (r *requestBuilder) resendWithNewToken(client *http.Client, resp *http.Response,
+	stepVariables map[string]interface{},
+) (*http.Response, error) {
+	r.oauth2.invalidate(r.oauth2Token)
+	token, err := r.oauth2.getToken(stepVariables)
+	if err != nil {
+		log.Warn().Err(err).Msg("refresh oauth2 token failed")
+		return resp, nil
+	}
+	if token.AccessToken == r.oauth2Token.AccessToken || (r.req.Body != nil && r.req.GetBody == nil) {
+		return resp, nil
+	}
+	log.Info().Msg("oauth2 token is rejected, resend request with new token")
+	resp.Body.Close()
+
+	req := r.req.Clone(r.req.Context())
+	if r.req.GetBody != nil {
+		if req.Body, err = r.req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	req.Header.Set("Authorization", token.header())
+	r.req = req
+	r.oauth2Token = token
+	r.updateRequestMapHeaders()
+	// signature may cover authorization header
+	if err = r.signRequest(stepVariables); err != nil {
+		return nil, err
+	}
+	return client.Do(r.req)
+}
+
+func (r *requestBuilder) updateRequestMapHeaders() {
 	headers := make(map[string]string)
 	for key, value := range r.req.Header {
 		headers[key] = value[0]
 	}
 	r.requestMap["headers"] = headers
-	return nil
 }
 
 func (r *requestBuilder) prepareUrlParams(stepVariables map[string]interface{}) error {
@@ -248,6 +305,10 @@ func (r *requestBuilder) prepareBody(stepVariables map[string]interface{}) error
 
 	r.req.Body = io.NopCloser(bytes.NewReader(dataBytes))
 	r.req.ContentLength = int64(len(dataBytes))
+	// body could be resent in redirects and digest auth
+	r.req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(dataBytes)), nil
+	}
 
 	return nil
 }
@@ -277,6 +338,14 @@ func prepareUpload(parser *Parser, stepRequest *StepRequest, stepVariables map[s
 	return
 }
 
+// withClientTimeout returns a shallow copy of client with the given timeout,
+// transport is still shared.
+func withClientTimeout(client *http.Client, timeout time.Duration) *http.Client {
+	c := *client
+	c.Timeout = timeout
+	return &c
+}
+
 func runStepRequest(r *SessionRunner, step IStep) (stepResult *StepResult, err error) {
 	stepRequest := step.(*StepRequestWithOptionalArgs)
 	start := time.Now()
@@ -308,6 +377,7 @@ func runStepRequest(r *SessionRunner, step IStep) (stepResult *StepResult, err e
 	config := r.caseRunner.Config.Get()
 
 	rb := newRequestBuilder(parser, config, stepRequest.Request)
+	rb.oauth2 = r.oauth2
 	rb.req.Method = strings.ToUpper(string(stepRequest.Request.Method))
 
 	err = rb.prepareUrlParams(stepRequest.Variables)
@@ -319,6 +389,7 @@ func runStepRequest(r *SessionRunner, step IStep) (stepResult *StepResult, err e
 	if err != nil {
 		return
 	}
+	rb.injectTraceContext(r.ctx)
 
 	err = rb.prepareBody(stepRequest.Variables)
 	if err != nil {
@@ -338,6 +409,11 @@ func runStepRequest(r *SessionRunner, step IStep) (stepResult *StepResult, err e
 		}
 	}
 
+	// sign request after url, headers and body are fully built
+	if err = rb.signRequest(stepRequest.Variables); err != nil {
+		return
+	}
+
 	// log & print request
 	if r.caseRunner.hrpRunner.requestsLogOn {
 		if err := printRequest(rb.req); err != nil {
@@ -345,6 +421,18 @@ func runStepRequest(r *SessionRunner, step IStep) (stepResult *StepResult, err e
 		}
 	}
 
+	// abort request when session is cancelled
+	rb.req = rb.req.WithContext(r.ctx)
+
+	// streaming request could be cancelled to stop reading after duration
+	cancelRequest := func() {}
+	if stepRequest.Request.Stream != nil {
+		var ctx context.Context
+		ctx, cancelRequest = context.WithCancel(r.ctx)
+		defer cancelRequest()
+		rb.req = rb.req.WithContext(ctx)
+	}
+
 	// stat HTTP request
 	var httpStat httpstat.Stat
 	if r.caseRunner.hrpRunner.httpStatOn {
@@ -360,13 +448,37 @@ func runStepRequest(r *SessionRunner, step IStep) (stepResult *StepResult, err e
 		client = r.caseRunner.hrpRunner.httpClient
 	}
 
-	// set step timeout
+	// apply TLS, proxies, redirects and auth settings, step settings override testcase config
+	transport := newRequestTransport(config, stepRequest.Request)
+	if err = transport.parse(parser, stepRequest.Variables); err != nil {
+		return
+	}
+	if err = transport.applyAuth(rb.req); err != nil {
+		return
+	}
+	client, err = transport.client(r.caseRunner.hrpRunner, client, stepRequest.Request.HTTP2, config.Path)
+	if err != nil {
+		return stepResult, errors.Wrap(err, "init http client failed")
+	}
+	// store and send cookies with session cookie jar, jar is nil if cookies are disabled
+	if r.cookieJar != nil {
+		client.Jar = r.cookieJar
+	}
+
+	// set request timeout, priority: step timeout > testcase config timeout > global timeout
+	// the shared client is copied instead of updated in place since sessions may run concurrently
 	if stepRequest.Request.Timeout != 0 {
-		client.Timeout = time.Duration(stepRequest.Request.Timeout*1000) * time.Millisecond
+		client = withClientTimeout(client, time.Duration(stepRequest.Request.Timeout*1000)*time.Millisecond)
+	} else if config.RequestTimeout != 0 {
+		client = withClientTimeout(client, time.Duration(config.RequestTimeout*1000)*time.Millisecond)
 	}
 
 	// do request action
+	requestStart := time.Now()
 	resp, err := client.Do(rb.req)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized && rb.oauth2Token != nil {
+		resp, err = rb.resendWithNewToken(client, resp, stepRequest.Variables)
+	}
 	if err != nil {
 		return stepResult, errors.Wrap(err, "do request failed")
 	}
@@ -381,19 +493,30 @@ func runStepRequest(r *SessionRunner, step IStep) (stepResult *StepResult, err e
 	}
 	defer resp.Body.Close()
 
-	// log & print response
-	if r.caseRunner.hrpRunner.requestsLogOn {
-		if err := printResponse(resp); err != nil {
-			return stepResult, err
+	// new response object, streaming response is read as events
+	var respObj *responseObject
+	if stepRequest.Request.Stream != nil {
+		respObj, err = newHttpStreamResponseObject(r.testingT(), parser, resp, requestStart,
+			stepRequest.Request.Stream, stepRequest.Variables, cancelRequest)
+	} else {
+		// log & print response
+		if r.caseRunner.hrpRunner.requestsLogOn {
+			if err := printResponse(resp); err != nil {
+				return stepResult, err
+			}
 		}
+		respObj, err = newHttpResponseObject(r.testingT(), parser, resp, requestStart)
 	}
-
-	// new response object
-	respObj, err := newHttpResponseObject(r.caseRunner.hrpRunner.t, parser, resp)
 	if err != nil {
 		err = errors.Wrap(err, "init ResponseObject error")
 		return
 	}
+	respObj.casePath = r.caseRunner.Config.Get().Path
+	if stepRequest.Request.Stream != nil && r.caseRunner.hrpRunner.requestsLogOn {
+		if err := printStreamResponse(resp, respObj.streamEvents); err != nil {
+			return stepResult, err
+		}
+	}
 
 	if r.caseRunner.hrpRunner.httpStatOn {
 		// resp.Body has been ReadAll
@@ -406,6 +529,12 @@ func runStepRequest(r *SessionRunner, step IStep) (stepResult *StepResult, err e
 	stepRequest.Variables["hrp_step_response"] = respObj.respObjMeta
 	stepRequest.Variables["response"] = respObj.respObjMeta
 
+	if stepRequest.checkResponse != nil {
+		if err := stepRequest.checkResponse(respObj.respObjMeta); err != nil {
+			return stepResult, err
+		}
+	}
+
 	// deal with teardown hooks
 	for _, teardownHook := range stepRequest.TeardownHooks {
 		_, err := parser.Parse(teardownHook, stepRequest.Variables)
@@ -426,7 +555,11 @@ func runStepRequest(r *SessionRunner, step IStep) (stepResult *StepResult, err e
 	stepRequest.Variables = mergeVariables(stepRequest.Variables, extractMapping)
 
 	// validate response
-	err = respObj.Validate(stepRequest.Validators, stepRequest.Variables)
+	err = respObj.Validate(stepRequest.Validators, stepRequest.Variables,
+		r.validateMode(&stepRequest.StepConfig))
+	if respObj.streamErr != nil {
+		err = respObj.streamErr
+	}
 	sessionData.Validators = respObj.validationResults
 	if err == nil {
 		stepResult.Success = true
@@ -535,6 +668,10 @@ func NewStep(name string) *StepRequest {
 type StepRequest struct {
 	StepConfig
 	Request *Request `json:"request,omitempty" yaml:"request,omitempty"`
+
+	// checkResponse is called before teardown hooks, extraction and validation,
+	// returning error aborts the step, used by protocols built on HTTP request
+	checkResponse func(respObjMeta interface{}) error
 }
 
 // WithVariables sets variables for current teststep.
@@ -583,6 +720,18 @@ func (s *StepRequest) Loop(times int) *StepRequest {
 	return s
 }
 
+// SkipIf skips step if condition expression is true, e.g. $skip or ${eq($status, done)}.
+func (s *StepRequest) SkipIf(condition interface{}) *StepRequest {
+	s.StepConfig.SkipIf = condition
+	return s
+}
+
+// RunIf runs step only if condition expression is true.
+func (s *StepRequest) RunIf(condition interface{}) *StepRequest {
+	s.StepConfig.RunIf = condition
+	return s
+}
+
 // GET makes a HTTP GET request.
 func (s *StepRequest) GET(url string) *StepRequestWithOptionalArgs {
 	if s.Request != nil {
@@ -770,6 +919,78 @@ func (s *StepRequest) WebSocket() *StepWebSocket {
 	}
 }
 
+// GRPC creates a new grpc step to call method of target,
+// method is full method name, e.g. helloworld.Greeter/SayHello
+func (s *StepRequest) GRPC(url, method string) *StepGRPC {
+	return &StepGRPC{
+		StepConfig: s.StepConfig,
+		GRPC: &GRPC{
+			URL:    url,
+			Method: method,
+		},
+	}
+}
+
+// TCP creates a new raw tcp socket step
+func (s *StepRequest) TCP() *StepSocket {
+	return &StepSocket{
+		StepConfig: s.StepConfig,
+		TCP:        &SocketAction{},
+	}
+}
+
+// UDP creates a new raw udp socket step
+func (s *StepRequest) UDP() *StepSocket {
+	return &StepSocket{
+		StepConfig: s.StepConfig,
+		UDP:        &SocketAction{},
+	}
+}
+
+// MQTT creates a new mqtt step, url is the broker url, e.g. tcp://localhost:1883
+func (s *StepRequest) MQTT(url string) *StepMQTT {
+	return &StepMQTT{
+		StepConfig: s.StepConfig,
+		MQTT: &MQTT{
+			URL: url,
+		},
+	}
+}
+
+// SQL creates a new database step, database defaults to environs SQL_DRIVER and SQL_DSN
+func (s *StepRequest) SQL() *StepSQL {
+	return &StepSQL{
+		StepConfig: s.StepConfig,
+		SQL:        &SQL{},
+	}
+}
+
+// Redis creates a new redis step, connection is configured in testcase config
+func (s *StepRequest) Redis() *StepRedis {
+	return &StepRedis{
+		StepConfig: s.StepConfig,
+		Redis:      &Redis{},
+	}
+}
+
+// GraphQL creates a new graphql step, url could be relative to base_url
+func (s *StepRequest) GraphQL(url string) *StepGraphQL {
+	return &StepGraphQL{
+		StepConfig: s.StepConfig,
+		GraphQL: &GraphQL{
+			URL: url,
+		},
+	}
+}
+
+// CookieJar creates a new step to manage session cookie jar, cookies in jar are exported by default.
+func (s *StepRequest) CookieJar() *StepCookieJar {
+	return &StepCookieJar{
+		StepConfig: s.StepConfig,
+		CookieJar:  &CookieJar{},
+	}
+}
+
 // MobileUI creates a new mobile step session
 func (s *StepRequest) MobileUI() *StepMobile {
 	return &StepMobile{
@@ -833,6 +1054,29 @@ func (s *StepRequest) Shell(content string) *StepShell {
 	}
 }
 
+// While creates a new while step, which runs child steps repeatedly while condition is true.
+func (s *StepRequest) While(condition interface{}, steps ...IStep) *StepWhile {
+	return &StepWhile{
+		StepConfig: s.StepConfig,
+		While: &While{
+			Condition: condition,
+		},
+		Steps: steps,
+	}
+}
+
+// Foreach creates a new foreach step, which runs child steps for each item in list.
+// items could be list or variable reference of list extracted from previous steps, e.g. $ids
+func (s *StepRequest) Foreach(items interface{}, steps ...IStep) *StepForeach {
+	return &StepForeach{
+		StepConfig: s.StepConfig,
+		Foreach: &Foreach{
+			Items: items,
+		},
+		Steps: steps,
+	}
+}
+
 // Function creates a new function step session
 func (s *StepRequest) Function(fn func()) *StepFunction {
 	return &StepFunction{
@@ -850,6 +1094,13 @@ type StepRequestWithOptionalArgs struct {
 func (s *StepRequestWithOptionalArgs) SetVerify(verify bool) *StepRequestWithOptionalArgs {
 	log.Info().Bool("verify", verify).Msg("set step request verify")
 	s.Request.Verify = verify
+	s.Request.SkipVerify = !verify
+	return s
+}
+
+// SetTLS sets CA bundle, client certificate and SNI for current HTTP request.
+func (s *StepRequestWithOptionalArgs) SetTLS(tls *RequestTLS) *StepRequestWithOptionalArgs {
+	s.Request.TLS = tls
 	return s
 }
 
@@ -860,10 +1111,29 @@ func (s *StepRequestWithOptionalArgs) SetTimeout(timeout time.Duration) *StepReq
 	return s
 }
 
-// SetProxies sets proxies for current HTTP request.
+// SetStream reads response of current HTTP request as events, e.g. SSE, NDJSON.
+func (s *StepRequestWithOptionalArgs) SetStream(stream *RequestStream) *StepRequestWithOptionalArgs {
+	s.Request.Stream = stream
+	return s
+}
+
+// SetRetry sets retry policy for current HTTP request.
+func (s *StepRequestWithOptionalArgs) SetRetry(retry *StepRetry) *StepRequestWithOptionalArgs {
+	s.Retry = retry
+	return s
+}
+
+// SetValidateMode sets validate mode for current HTTP request,
+// ValidateModeAll evaluates all validators and reports all failures.
+func (s *StepRequestWithOptionalArgs) SetValidateMode(mode ValidateMode) *StepRequestWithOptionalArgs {
+	s.ValidateMode = mode
+	return s
+}
+
+// SetProxies sets proxies for current HTTP request, keyed by scheme, e.g. http, https or all.
 func (s *StepRequestWithOptionalArgs) SetProxies(proxies map[string]string) *StepRequestWithOptionalArgs {
 	log.Info().Interface("proxies", proxies).Msg("set step request proxies")
-	// TODO
+	s.Request.Proxies = proxies
 	return s
 }
 
@@ -871,13 +1141,32 @@ func (s *StepRequestWithOptionalArgs) SetProxies(proxies map[string]string) *Ste
 func (s *StepRequestWithOptionalArgs) SetAllowRedirects(allowRedirects bool) *StepRequestWithOptionalArgs {
 	log.Info().Bool("allowRedirects", allowRedirects).Msg("set step request allowRedirects")
 	s.Request.AllowRedirects = allowRedirects
+	s.Request.DisableRedirects = !allowRedirects
+	return s
+}
+
+// SetMaxRedirects sets max redirect hops for current HTTP request.
+func (s *StepRequestWithOptionalArgs) SetMaxRedirects(maxRedirects int) *StepRequestWithOptionalArgs {
+	s.Request.MaxRedirects = maxRedirects
 	return s
 }
 
-// SetAuth sets auth for current HTTP request.
+// SetSign sets signing of current HTTP request, overrides testcase config.
+func (s *StepRequestWithOptionalArgs) SetSign(sign *RequestSign) *StepRequestWithOptionalArgs {
+	s.Request.Sign = sign
+	return s
+}
+
+// SetAuth sets auth for current HTTP request, e.g. {"type": "basic", "username": "u", "password": "p"},
+// type could be basic, digest or bearer, and token is required for bearer auth.
 func (s *StepRequestWithOptionalArgs) SetAuth(auth map[string]string) *StepRequestWithOptionalArgs {
-	log.Info().Interface("auth", auth).Msg("set step request auth")
-	// TODO
+	log.Info().Str("type", auth["type"]).Msg("set step request auth")
+	s.Request.Auth = &HTTPAuth{
+		Type:     HTTPAuthType(auth["type"]),
+		Username: auth["username"],
+		Password: auth["password"],
+		Token:    auth["token"],
+	}
 	return s
 }
 
@@ -964,6 +1253,23 @@ func (s *StepRequestExtraction) WithJmesPath(jmesPath string, varName string) *S
 	return s
 }
 
+// WithXPath sets the XPath expression to extract from XML response body.
+func (s *StepRequestExtraction) WithXPath(xpath string, varName string) *StepRequestExtraction {
+	s.StepConfig.Extract[varName] = fmt.Sprintf("xpath(%s)", xpath)
+	return s
+}
+
+// WithCSS sets the CSS selector to extract from HTML response body,
+// text of the matched element is extracted if attr is empty.
+func (s *StepRequestExtraction) WithCSS(selector string, attr string, varName string) *StepRequestExtraction {
+	expr := fmt.Sprintf("css(%s)", selector)
+	if attr != "" {
+		expr += "@" + attr
+	}
+	s.StepConfig.Extract[varName] = expr
+	return s
+}
+
 // Validate switches to step validation.
 func (s *StepRequestExtraction) Validate() *StepRequestValidation {
 	return &StepRequestValidation{
@@ -1228,6 +1534,31 @@ func (s *StepRequestValidation) AssertLengthGreaterOrEquals(jmesPath string, exp
 	return s
 }
 
+// AssertJSONSchema validates the value of jmesPath against JSON schema, schema could be inline schema object,
+// schema file path or reference to OpenAPI schema component, e.g. openapi.yaml#/components/schemas/User
+func (s *StepRequestValidation) AssertJSONSchema(jmesPath string, schema interface{}, msg string) *StepRequestValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  assertJSONSchema,
+		Expect:  schema,
+		Message: msg,
+	}
+	s.Validators = append(s.Validators, v)
+	return s
+}
+
+// AssertOpenAPI validates the whole response against the matching operation in OpenAPI 3 spec file.
+func (s *StepRequestValidation) AssertOpenAPI(specPath string, msg string) *StepRequestValidation {
+	v := Validator{
+		Check:   "body",
+		Assert:  assertOpenAPI,
+		Expect:  specPath,
+		Message: msg,
+	}
+	s.Validators = append(s.Validators, v)
+	return s
+}
+
 // Validator represents validator for one HTTP response.
 type Validator struct {
 	Check   string      `json:"check" yaml:"check"` // get value with jmespath
@@ -0,0 +1,306 @@
+package hrp
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/httprunner/httprunner/v5/internal/builtin"
+)
+
+type OAuth2GrantType string
+
+const (
+	OAuth2ClientCredentials OAuth2GrantType = "client_credentials"
+	OAuth2Password          OAuth2GrantType = "password"
+	OAuth2RefreshToken      OAuth2GrantType = "refresh_token"
+)
+
+// OAuth2Config configures token provider of testcase, the access token is fetched once per session,
+// injected into request headers, and refreshed on expiry or when the server responds 401.
+// fields could reference variables.
+type OAuth2Config struct {
+	GrantType    OAuth2GrantType   `json:"grant_type,omitempty" yaml:"grant_type,omitempty"` // client_credentials(default), password or refresh_token
+	TokenURL     string            `json:"token_url,omitempty" yaml:"token_url,omitempty"`   // token endpoint, could be relative to base_url
+	Issuer       string            `json:"issuer,omitempty" yaml:"issuer,omitempty"`         // OIDC issuer, token endpoint is discovered if token_url is not set
+	ClientID     string            `json:"client_id,omitempty" yaml:"client_id,omitempty"`
+	ClientSecret string            `json:"client_secret,omitempty" yaml:"client_secret,omitempty"`
+	ClientAuth   string            `json:"client_auth,omitempty" yaml:"client_auth,omitempty"` // basic(default) or body, how client credentials are sent
+	Username     string            `json:"username,omitempty" yaml:"username,omitempty"`       // password grant
+	Password     string            `json:"password,omitempty" yaml:"password,omitempty"`       // password grant
+	RefreshToken string            `json:"refresh_token,omitempty" yaml:"refresh_token,omitempty"`
+	Scope        string            `json:"scope,omitempty" yaml:"scope,omitempty"`
+	Params       map[string]string `json:"params,omitempty" yaml:"params,omitempty"`               // extra token request params, e.g. audience
+	Function     string            `json:"function,omitempty" yaml:"function,omitempty"`           // custom token provider in debugtalk, e.g. ${get_token($user)}
+	ExpiryLeeway float64           `json:"expiry_leeway,omitempty" yaml:"expiry_leeway,omitempty"` // refresh token seconds before it expires
+}
+
+type oauth2Token struct {
+	AccessToken  string
+	TokenType    string
+	RefreshToken string
+	Expiry       time.Time // zero if token does not expire
+}
+
+func (t *oauth2Token) header() string {
+	if t.TokenType == "" || strings.EqualFold(t.TokenType, "bearer") {
+		return "Bearer " + t.AccessToken
+	}
+	return t.TokenType + " " + t.AccessToken
+}
+
+// oauth2Session caches the access token for session runner
+type oauth2Session struct {
+	mutex  sync.Mutex
+	config *OAuth2Config
+	runner *SessionRunner
+	token  *oauth2Token
+}
+
+func newOAuth2Session(r *SessionRunner, config *OAuth2Config) *oauth2Session {
+	return &oauth2Session{
+		config: config,
+		runner: r,
+	}
+}
+
+// getToken returns the cached token, or fetches a new one if it is missing or expired.
+func (s *oauth2Session) getToken(variables map[string]interface{}) (*oauth2Token, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.token != nil && s.token.AccessToken != "" {
+		leeway := time.Duration(s.config.ExpiryLeeway*1000) * time.Millisecond
+		if s.token.Expiry.IsZero() || time.Now().Add(leeway).Before(s.token.Expiry) {
+			return s.token, nil
+		}
+		log.Info().Time("expiry", s.token.Expiry).Msg("oauth2 token expired")
+	}
+
+	// refresh with refresh token first, and fall back to the configured grant
+	if s.token != nil && s.token.RefreshToken != "" && s.config.Function == "" {
+		token, err := s.fetchToken(variables, OAuth2RefreshToken, s.token.RefreshToken)
+		if err == nil {
+			s.token = token
+			return token, nil
+		}
+		log.Warn().Err(err).Msg("refresh oauth2 token failed, request new token")
+	}
+	token, err := s.fetchToken(variables, "", "")
+	if err != nil {
+		return nil, err
+	}
+	s.token = token
+	return token, nil
+}
+
+// invalidate marks the token as expired if it is rejected by server, refresh token is kept.
+func (s *oauth2Session) invalidate(token *oauth2Token) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.token != nil && s.token.AccessToken == token.AccessToken {
+		s.token = &oauth2Token{RefreshToken: s.token.RefreshToken}
+	}
+}
+
+func (s *oauth2Session) fetchToken(variables map[string]interface{},
+	grantType OAuth2GrantType, refreshToken string,
+) (*oauth2Token, error) {
+	parser := s.runner.caseRunner.parser
+
+	// token is provided by custom function in plugin
+	if s.config.Function != "" {
+		result, err := parser.Parse(s.config.Function, variables)
+		if err != nil {
+			return nil, errors.Wrap(err, "call oauth2 token function failed")
+		}
+		if accessToken, ok := result.(string); ok {
+			return &oauth2Token{AccessToken: accessToken}, nil
+		}
+		resultMap, ok := result.(map[string]interface{})
+		if !ok {
+			return nil, errors.Errorf("oauth2 token function should return string or map, got %v", result)
+		}
+		return newOAuth2Token(resultMap)
+	}
+
+	fields, err := parser.ParseHeaders(map[string]string{
+		"token_url":     s.config.TokenURL,
+		"issuer":        s.config.Issuer,
+		"client_id":     s.config.ClientID,
+		"client_secret": s.config.ClientSecret,
+		"username":      s.config.Username,
+		"password":      s.config.Password,
+		"refresh_token": s.config.RefreshToken,
+		"scope":         s.config.Scope,
+	}, variables)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse oauth2 config failed")
+	}
+	var params map[string]string
+	if len(s.config.Params) > 0 {
+		params, err = parser.ParseHeaders(s.config.Params, variables)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse oauth2 params failed")
+		}
+	}
+
+	if grantType == "" {
+		grantType = s.config.GrantType
+		if grantType == "" {
+			grantType = OAuth2ClientCredentials
+		}
+	}
+	form := url.Values{}
+	form.Set("grant_type", string(grantType))
+	switch grantType {
+	case OAuth2ClientCredentials:
+	case OAuth2Password:
+		form.Set("username", fields["username"])
+		form.Set("password", fields["password"])
+	case OAuth2RefreshToken:
+		if refreshToken == "" {
+			refreshToken = fields["refresh_token"]
+		}
+		if refreshToken == "" {
+			return nil, errors.New("oauth2 refresh token is required")
+		}
+		form.Set("refresh_token", refreshToken)
+	default:
+		return nil, errors.Errorf("unsupported oauth2 grant type %s", grantType)
+	}
+	if fields["scope"] != "" {
+		form.Set("scope", fields["scope"])
+	}
+	for key, value := range params {
+		form.Set(key, value)
+	}
+	if s.config.ClientAuth == "body" {
+		form.Set("client_id", fields["client_id"])
+		if fields["client_secret"] != "" {
+			form.Set("client_secret", fields["client_secret"])
+		}
+	}
+
+	client, err := s.httpClient(variables)
+	if err != nil {
+		return nil, err
+	}
+	tokenURL := fields["token_url"]
+	if tokenURL == "" {
+		if fields["issuer"] == "" {
+			return nil, errors.New("oauth2 token_url or issuer is required")
+		}
+		tokenURL, err = discoverTokenEndpoint(client, fields["issuer"])
+		if err != nil {
+			return nil, err
+		}
+	} else if baseURL, ok := variables["base_url"].(string); ok && !strings.Contains(tokenURL, "://") {
+		tokenURL = buildURL(baseURL, tokenURL, nil).String()
+	}
+
+	req, err := http.NewRequestWithContext(s.runner.ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, errors.Wrap(err, "create oauth2 token request failed")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if s.config.ClientAuth != "body" && fields["client_id"] != "" {
+		req.SetBasicAuth(url.QueryEscape(fields["client_id"]), url.QueryEscape(fields["client_secret"]))
+	}
+
+	log.Info().Str("token_url", tokenURL).Str("grant_type", string(grantType)).Msg("fetch oauth2 token")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "request oauth2 token failed")
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "read oauth2 token response failed")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("request oauth2 token failed, status code: %d, body: %s", resp.StatusCode, body)
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, errors.Wrapf(err, "invalid oauth2 token response: %s", body)
+	}
+	token, err := newOAuth2Token(result)
+	if err != nil {
+		return nil, err
+	}
+	// refresh token may not be rotated
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	return token, nil
+}
+
+// httpClient returns client for token endpoint, TLS and proxies settings of testcase are applied
+func (s *oauth2Session) httpClient(variables map[string]interface{}) (*http.Client, error) {
+	config := s.runner.caseRunner.Config.Get()
+	hrpRunner := s.runner.caseRunner.hrpRunner
+	transport := newRequestTransport(config, &Request{})
+	if err := transport.parse(s.runner.caseRunner.parser, variables); err != nil {
+		return nil, err
+	}
+	client, err := transport.client(hrpRunner, hrpRunner.httpClient, false, config.Path)
+	if err != nil {
+		return nil, errors.Wrap(err, "init oauth2 http client failed")
+	}
+	if config.RequestTimeout != 0 {
+		client = withClientTimeout(client, time.Duration(config.RequestTimeout*1000)*time.Millisecond)
+	}
+	return client, nil
+}
+
+// discoverTokenEndpoint gets token endpoint from OIDC discovery document
+func discoverTokenEndpoint(client *http.Client, issuer string) (string, error) {
+	discoveryURL := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	resp, err := client.Get(discoveryURL)
+	if err != nil {
+		return "", errors.Wrap(err, "request oidc discovery document failed")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("request oidc discovery document failed, status code: %d", resp.StatusCode)
+	}
+	var document struct {
+		TokenEndpoint string `json:"token_endpoint"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&document); err != nil {
+		return "", errors.Wrap(err, "decode oidc discovery document failed")
+	}
+	if document.TokenEndpoint == "" {
+		return "", errors.New("token_endpoint not found in oidc discovery document")
+	}
+	return document.TokenEndpoint, nil
+}
+
+func newOAuth2Token(result map[string]interface{}) (*oauth2Token, error) {
+	accessToken, _ := result["access_token"].(string)
+	if accessToken == "" {
+		return nil, errors.Errorf("access_token not found in oauth2 token response: %v", result)
+	}
+	token := &oauth2Token{AccessToken: accessToken}
+	token.TokenType, _ = result["token_type"].(string)
+	token.RefreshToken, _ = result["refresh_token"].(string)
+	if expiresIn, ok := result["expires_in"]; ok && expiresIn != nil {
+		seconds, err := builtin.Interface2Float64(expiresIn)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid oauth2 expires_in %v", expiresIn)
+		}
+		if seconds > 0 {
+			token.Expiry = time.Now().Add(time.Duration(seconds*1000) * time.Millisecond)
+		}
+	}
+	return token, nil
+}
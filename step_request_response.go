@@ -9,7 +9,10 @@ import (
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/PuerkitoBio/goquery"
+	"github.com/antchfx/xmlquery"
 	"github.com/jmespath/go-jmespath"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
@@ -20,20 +23,60 @@ import (
 
 var fieldTags = []string{"proto", "status_code", "headers", "cookies", "body", textExtractorSubRegexp}
 
+// httpFieldTags are searched only on http response, besides the common fieldTags
+var httpFieldTags = []string{"elapsed_ms", "content_length", "final_url", "redirects"}
+
 type httpRespObjMeta struct {
-	Proto      string            `json:"proto"`
-	StatusCode int               `json:"status_code"`
-	Headers    map[string]string `json:"headers"`
-	Cookies    map[string]string `json:"cookies"`
-	Body       interface{}       `json:"body"`
+	Proto         string              `json:"proto"`
+	StatusCode    int                 `json:"status_code"`
+	Headers       map[string]string   `json:"headers"`     // first value of each header
+	HeadersAll    map[string][]string `json:"headers_all"` // all values of each header, e.g. repeated Set-Cookie
+	Cookies       map[string]string   `json:"cookies"`
+	Body          interface{}         `json:"body"`
+	ElapsedMs     int64               `json:"elapsed_ms"`     // from sending request to reading whole response body
+	ContentLength int64               `json:"content_length"` // fallback to length of decoded body if unknown
+	FinalURL      string              `json:"final_url"`      // request url after redirects
+	Redirects     []*redirectHop      `json:"redirects"`      // redirect responses before final response
 }
 
-func newHttpResponseObject(t *testing.T, parser *Parser, resp *http.Response) (*responseObject, error) {
+// newHttpResponseObject reads response body and builds response object,
+// requestStart is the time the request is sent, used to calculate elapsed_ms.
+func newHttpResponseObject(t *testing.T, parser *Parser, resp *http.Response, requestStart time.Time) (*responseObject, error) {
+	// read response body
+	respBodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// parse response body
+	var body interface{}
+	if err := json.Unmarshal(respBodyBytes, &body); err != nil {
+		// response body is not json, use raw body
+		body = string(respBodyBytes)
+	}
+
+	respObjMeta := newHttpRespObjMeta(resp, body, int64(len(respBodyBytes)), requestStart)
+	respObj, err := convertToResponseObject(t, parser, respObjMeta)
+	if err != nil {
+		return nil, err
+	}
+	// keep raw response for validating against OpenAPI spec and searching with XPath/CSS selector
+	respObj.httpResp = resp
+	respObj.httpRespBody = respBodyBytes
+	respObj.fieldTags = httpFieldTags
+	return respObj, nil
+}
+
+// newHttpRespObjMeta builds response meta with body which has been read,
+// bodyLength is used as content_length if unknown.
+func newHttpRespObjMeta(resp *http.Response, body interface{}, bodyLength int64, requestStart time.Time) httpRespObjMeta {
 	// prepare response headers
 	headers := make(map[string]string)
+	headersAll := make(map[string][]string)
 	for k, v := range resp.Header {
 		if len(v) > 0 {
 			headers[k] = v[0]
+			headersAll[k] = v
 		}
 	}
 
@@ -43,28 +86,31 @@ func newHttpResponseObject(t *testing.T, parser *Parser, resp *http.Response) (*
 		cookies[cookie.Name] = cookie.Value
 	}
 
-	// read response body
-	respBodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	var elapsedMs int64
+	if !requestStart.IsZero() {
+		elapsedMs = time.Since(requestStart).Milliseconds()
 	}
-
-	// parse response body
-	var body interface{}
-	if err := json.Unmarshal(respBodyBytes, &body); err != nil {
-		// response body is not json, use raw body
-		body = string(respBodyBytes)
+	contentLength := resp.ContentLength
+	if contentLength < 0 {
+		contentLength = bodyLength
 	}
-
-	respObjMeta := httpRespObjMeta{
-		Proto:      resp.Proto,
-		StatusCode: resp.StatusCode,
-		Headers:    headers,
-		Cookies:    cookies,
-		Body:       body,
+	var finalURL string
+	if resp.Request != nil && resp.Request.URL != nil {
+		finalURL = resp.Request.URL.String()
 	}
 
-	return convertToResponseObject(t, parser, respObjMeta)
+	return httpRespObjMeta{
+		Proto:         resp.Proto,
+		StatusCode:    resp.StatusCode,
+		Headers:       headers,
+		HeadersAll:    headersAll,
+		Cookies:       cookies,
+		Body:          body,
+		ElapsedMs:     elapsedMs,
+		ContentLength: contentLength,
+		FinalURL:      finalURL,
+		Redirects:     redirectChain(resp),
+	}
 }
 
 type wsCloseRespObject struct {
@@ -119,6 +165,14 @@ type responseObject struct {
 	parser            *Parser
 	respObjMeta       interface{}
 	validationResults []*ValidationResult
+	httpResp          *http.Response
+	httpRespBody      []byte
+	casePath          string            // testcase file path, used to locate schema files
+	xmlDoc            *xmlquery.Node    // parsed lazily for XPath search
+	htmlDoc           *goquery.Document // parsed lazily for CSS selector search
+	streamEvents      []*streamEvent    // received events of streaming response
+	streamErr         error             // set if no event matches until condition of streaming response
+	fieldTags         []string          // field tags of protocol response object, checked besides the common fieldTags
 }
 
 const textExtractorSubRegexp string = `(.*)`
@@ -133,8 +187,16 @@ func (v *responseObject) searchField(field string, variablesMapping map[string]i
 			log.Error().Str("field name", field).Err(err).Msg("fail to parse field before search")
 		}
 	}
+	parsedField, ok := result.(string)
+	if !ok {
+		return result
+	}
+	// search body with XPath or CSS selector, e.g. xpath(//title), css(a.next)@href
+	if selected, ok := v.searchSelector(parsedField); ok {
+		return selected
+	}
 	// search field using jmespath or regex if parsed field is still string and contains specified fieldTags
-	if parsedField, ok := result.(string); ok && checkSearchField(parsedField) {
+	if v.checkSearchField(parsedField) {
 		if strings.Contains(field, textExtractorSubRegexp) {
 			result = v.searchRegexp(parsedField)
 		} else {
@@ -160,7 +222,28 @@ func (v *responseObject) Extract(extractors map[string]string, variablesMapping
 	return extractMapping
 }
 
-func (v *responseObject) Validate(iValidators []interface{}, variablesMapping map[string]interface{}) (err error) {
+type ValidateMode string
+
+const (
+	ValidateModeFirst ValidateMode = "first" // stop at the first failed validator, default
+	ValidateModeAll   ValidateMode = "all"   // evaluate all validators and report all failures
+)
+
+// validateMode returns validate mode of step, step setting overrides testcase config.
+func (r *SessionRunner) validateMode(stepConfig *StepConfig) ValidateMode {
+	if stepConfig.ValidateMode != "" {
+		return stepConfig.ValidateMode
+	}
+	if mode := r.caseRunner.Config.Get().ValidateMode; mode != "" {
+		return mode
+	}
+	return ValidateModeFirst
+}
+
+func (v *responseObject) Validate(iValidators []interface{}, variablesMapping map[string]interface{},
+	mode ValidateMode,
+) (err error) {
+	var failures []string
 	for _, iValidator := range iValidators {
 		validator, ok := iValidator.(Validator)
 		if !ok {
@@ -173,12 +256,12 @@ func (v *responseObject) Validate(iValidators []interface{}, variablesMapping ma
 		// get assert method
 		assertMethod := validator.Assert
 		assertFunc, ok := builtin.Assertions[assertMethod]
-		if !ok {
+		if !ok && !isSchemaAssertion(assertMethod) {
 			return errors.New(fmt.Sprintf("unexpected assertMethod: %v", assertMethod))
 		}
 
 		// parse expected value
-		expectValue, err := v.parser.Parse(validator.Expect, variablesMapping)
+		expectValue, err := parseValidatorExpect(v.parser, validator, variablesMapping)
 		if err != nil {
 			return err
 		}
@@ -194,7 +277,17 @@ func (v *responseObject) Validate(iValidators []interface{}, variablesMapping ma
 		}
 
 		// do assertion
-		result := assertFunc(v.t, checkValue, expectValue)
+		var result bool
+		if schemaAssert, ok := schemaAssertions[assertMethod]; ok {
+			violations, err := schemaAssert(v, checkValue, expectValue)
+			if err != nil {
+				return errors.Wrapf(err, "%s assertion failed", assertMethod)
+			}
+			validResult.Violations = violations
+			result = len(violations) == 0
+		} else {
+			result = assertFunc(v.t, checkValue, expectValue)
+		}
 		if result {
 			validResult.CheckResult = "pass"
 		}
@@ -210,6 +303,11 @@ func (v *responseObject) Validate(iValidators []interface{}, variablesMapping ma
 			Msgf("validate %s", checkItem)
 		if !result {
 			v.t.Fail()
+			for _, violation := range validResult.Violations {
+				log.Error().Str("pointer", violation.Pointer).
+					Str("keyword", violation.Keyword).
+					Msg(violation.Message)
+			}
 			log.Error().
 				Str("checkExpr", validator.Check).
 				Str("assertMethod", assertMethod).
@@ -218,14 +316,54 @@ func (v *responseObject) Validate(iValidators []interface{}, variablesMapping ma
 				Interface("expectValue", expectValue).
 				Str("expectValueType", builtin.InterfaceType(expectValue)).
 				Msg("assert failed")
-			return errors.New("step validation failed")
+			if mode != ValidateModeAll {
+				return errors.New("step validation failed")
+			}
+			failures = append(failures, validResult.failureMessage())
 		}
 	}
+
+	if len(failures) > 0 {
+		return errors.Errorf("step validation failed, %d of %d validators failed:\n%s",
+			len(failures), len(v.validationResults), strings.Join(failures, "\n"))
+	}
 	return nil
 }
 
+// failureMessage describes the failed check, e.g. body.id equal 1, got 2 (check id)
+func (r *ValidationResult) failureMessage() string {
+	msg := fmt.Sprintf("%s %s %v, got %v", r.Check, r.Assert, r.Expect, r.CheckValue)
+	if len(r.Violations) > 0 {
+		msg = fmt.Sprintf("%s %s, %d violations", r.Check, r.Assert, len(r.Violations))
+	}
+	if r.Message != "" {
+		msg += fmt.Sprintf(" (%s)", r.Message)
+	}
+	for _, violation := range r.Violations {
+		msg += "\n    " + violation.String()
+	}
+	return msg
+}
+
+// parseValidatorExpect parses expected value of validator with variables,
+// schema objects of schema assertions are kept as they are, e.g. $ref and $schema keywords
+func parseValidatorExpect(parser *Parser, validator Validator, variablesMapping map[string]interface{}) (interface{}, error) {
+	if _, ok := validator.Expect.(string); !ok && isSchemaAssertion(validator.Assert) {
+		return validator.Expect, nil
+	}
+	return parser.Parse(validator.Expect, variablesMapping)
+}
+
 func checkSearchField(expr string) bool {
-	for _, t := range fieldTags {
+	return containsFieldTag(expr, fieldTags)
+}
+
+func (v *responseObject) checkSearchField(expr string) bool {
+	return checkSearchField(expr) || containsFieldTag(expr, v.fieldTags)
+}
+
+func containsFieldTag(expr string, tags []string) bool {
+	for _, t := range tags {
 		if strings.Contains(expr, t) {
 			return true
 		}
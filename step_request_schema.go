@@ -0,0 +1,308 @@
+package hrp
+
+import (
+	"context"
+	builtinJSON "encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	assertJSONSchema = "json_schema" // validate check value against inline schema or schema file
+	assertOpenAPI    = "openapi"     // validate the whole response against the matching operation in OpenAPI 3 spec
+)
+
+// schemaAssertions validate response against schema and report every violation
+var schemaAssertions = map[string]func(v *responseObject, checkValue, expectValue interface{}) ([]*SchemaViolation, error){
+	assertJSONSchema: (*responseObject).validateJSONSchema,
+	assertOpenAPI:    (*responseObject).validateOpenAPI,
+}
+
+func isSchemaAssertion(assert string) bool {
+	_, ok := schemaAssertions[assert]
+	return ok
+}
+
+// SchemaViolation describes one violation of JSON schema or OpenAPI response contract.
+type SchemaViolation struct {
+	Pointer string `json:"pointer" yaml:"pointer"` // JSON pointer to the invalid value, e.g. /data/0/id
+	Keyword string `json:"keyword" yaml:"keyword"` // violated schema keyword, e.g. type, required
+	Message string `json:"message" yaml:"message"`
+}
+
+func (v *SchemaViolation) String() string {
+	pointer := v.Pointer
+	if pointer == "" {
+		pointer = "(root)"
+	}
+	return fmt.Sprintf("%s [%s] %s", pointer, v.Keyword, v.Message)
+}
+
+// validateJSONSchema validates check value against schema, expect value could be
+// inline schema, schema file path, or reference to OpenAPI component, e.g. openapi.yaml#/components/schemas/User
+func (v *responseObject) validateJSONSchema(checkValue, expectValue interface{}) ([]*SchemaViolation, error) {
+	schema, err := v.loadSchema(expectValue)
+	if err != nil {
+		return nil, errors.Wrap(err, "load json schema failed")
+	}
+
+	// convert check value to standard json types, e.g. json.Number to float64
+	valueBytes, err := builtinJSON.Marshal(checkValue)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal check value failed")
+	}
+	var value interface{}
+	if err := builtinJSON.Unmarshal(valueBytes, &value); err != nil {
+		return nil, errors.Wrap(err, "unmarshal check value failed")
+	}
+
+	err = schema.VisitJSON(value, openapi3.MultiErrors())
+	return collectSchemaViolations(err, ""), nil
+}
+
+// validateOpenAPI validates status code, headers and body of HTTP response
+// against the operation matching the request method and path in OpenAPI 3 spec.
+func (v *responseObject) validateOpenAPI(checkValue, expectValue interface{}) ([]*SchemaViolation, error) {
+	if v.httpResp == nil || v.httpResp.Request == nil {
+		return nil, errors.New("openapi assertion only supports HTTP response")
+	}
+	specPath, ok := expectValue.(string)
+	if !ok {
+		return nil, errors.Errorf("openapi assertion expects spec file path, got %v", expectValue)
+	}
+	spec, err := loadOpenAPISpec(v.resolvePath(specPath))
+	if err != nil {
+		return nil, err
+	}
+
+	req := v.httpResp.Request
+	route, pathParams, err := spec.findRoute(req)
+	if err != nil {
+		return []*SchemaViolation{{
+			Keyword: "paths",
+			Message: fmt.Sprintf("%s %s: %v", req.Method, req.URL.Path, err),
+		}}, nil
+	}
+
+	input := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: &openapi3filter.RequestValidationInput{
+			Request:    req,
+			PathParams: pathParams,
+			Route:      route,
+		},
+		Status: v.httpResp.StatusCode,
+		Header: v.httpResp.Header,
+		Options: &openapi3filter.Options{
+			MultiError:            true,
+			IncludeResponseStatus: true,
+		},
+	}
+	input.SetBodyBytes(v.httpRespBody)
+	err = openapi3filter.ValidateResponse(context.Background(), input)
+	return collectSchemaViolations(err, ""), nil
+}
+
+// loadSchema loads schema from inline schema object or schema reference
+func (v *responseObject) loadSchema(expectValue interface{}) (*openapi3.Schema, error) {
+	var ref string
+	switch expect := expectValue.(type) {
+	case string:
+		ref = expect
+	case map[string]interface{}:
+		if r, ok := expect["$ref"].(string); ok && len(expect) == 1 {
+			ref = r
+		} else {
+			return newSchemaFromObject(expect)
+		}
+	default:
+		return nil, errors.Errorf("json schema should be object or reference, got %v", expectValue)
+	}
+
+	path, fragment, _ := strings.Cut(ref, "#")
+	path = v.resolvePath(path)
+	if fragment == "" {
+		if schema, ok := schemaCache.Load(path); ok {
+			return schema.(*openapi3.Schema), nil
+		}
+		var obj map[string]interface{}
+		if err := LoadFileObject(path, &obj); err != nil {
+			return nil, err
+		}
+		schema, err := newSchemaFromObject(obj)
+		if err != nil {
+			return nil, err
+		}
+		schemaCache.Store(path, schema)
+		return schema, nil
+	}
+
+	// reference to schema component in OpenAPI spec
+	spec, err := loadOpenAPISpec(path)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimPrefix(fragment, "/components/schemas/")
+	if name == fragment || spec.doc.Components == nil {
+		return nil, errors.Errorf("unsupported schema reference %s", ref)
+	}
+	schemaRef, ok := spec.doc.Components.Schemas[name]
+	if !ok || schemaRef.Value == nil {
+		return nil, errors.Errorf("schema %s not found in %s", name, path)
+	}
+	return schemaRef.Value, nil
+}
+
+// resolvePath resolves relative schema path based on project root dir, fallback to current dir
+func (v *responseObject) resolvePath(path string) string {
+	return resolveCaseFilePath(v.casePath, path)
+}
+
+func newSchemaFromObject(obj map[string]interface{}) (*openapi3.Schema, error) {
+	schemaBytes, err := builtinJSON.Marshal(obj)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal json schema failed")
+	}
+	schema := openapi3.NewSchema()
+	if err := builtinJSON.Unmarshal(schemaBytes, schema); err != nil {
+		return nil, errors.Wrap(err, "unmarshal json schema failed")
+	}
+	return schema, nil
+}
+
+var (
+	schemaCache  sync.Map // schema file path -> *openapi3.Schema
+	openapiCache sync.Map // spec file path -> *openapiSpec
+)
+
+type openapiSpec struct {
+	doc       *openapi3.T
+	router    routers.Router
+	basePaths []string // path of servers, e.g. /v1
+}
+
+func loadOpenAPISpec(path string) (*openapiSpec, error) {
+	if spec, ok := openapiCache.Load(path); ok {
+		return spec.(*openapiSpec), nil
+	}
+
+	loader := openapi3.NewLoader()
+	loader.IsExternalRefsAllowed = true
+	doc, err := loader.LoadFromFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "load openapi spec %s failed", path)
+	}
+
+	// match operations by path only, servers are used to strip base paths
+	spec := &openapiSpec{doc: doc}
+	for _, server := range doc.Servers {
+		serverURL := server.URL
+		if i := strings.Index(serverURL, "://"); i >= 0 {
+			serverURL = serverURL[i+3:]
+			if j := strings.Index(serverURL, "/"); j >= 0 {
+				serverURL = serverURL[j:]
+			} else {
+				serverURL = ""
+			}
+		}
+		basePath := strings.TrimRight(serverURL, "/")
+		if basePath != "" && !strings.Contains(basePath, "{") {
+			spec.basePaths = append(spec.basePaths, basePath)
+		}
+	}
+	routerDoc := *doc
+	routerDoc.Servers = nil
+	spec.router, err = legacy.NewRouter(&routerDoc)
+	if err != nil {
+		return nil, errors.Wrapf(err, "create router for openapi spec %s failed", path)
+	}
+
+	log.Info().Str("path", path).Int("paths", len(doc.Paths)).Msg("load openapi spec")
+	openapiCache.Store(path, spec)
+	return spec, nil
+}
+
+// findRoute finds the operation matching request method and path,
+// trailing slash appended to request url is ignored.
+func (s *openapiSpec) findRoute(req *http.Request) (*routers.Route, map[string]string, error) {
+	paths := []string{req.URL.Path}
+	for _, basePath := range s.basePaths {
+		if strings.HasPrefix(req.URL.Path, basePath+"/") {
+			paths = append(paths, strings.TrimPrefix(req.URL.Path, basePath))
+		}
+	}
+
+	var err error
+	for _, path := range paths {
+		candidates := []string{path}
+		if trimmed := strings.TrimRight(path, "/"); trimmed != "" && trimmed != path {
+			candidates = append(candidates, trimmed)
+		}
+		for _, candidate := range candidates {
+			var route *routers.Route
+			var pathParams map[string]string
+			route, pathParams, err = s.router.FindRoute(&http.Request{
+				Method: req.Method,
+				URL:    &url.URL{Path: candidate},
+			})
+			if err == nil {
+				return route, pathParams, nil
+			}
+		}
+	}
+	return nil, nil, err
+}
+
+// collectSchemaViolations converts schema validation errors to violations
+func collectSchemaViolations(err error, reason string) (violations []*SchemaViolation) {
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case openapi3.MultiError:
+		for _, item := range e {
+			violations = append(violations, collectSchemaViolations(item, reason)...)
+		}
+	case *openapi3filter.ResponseError:
+		if e.Err == nil {
+			return []*SchemaViolation{{Keyword: "responses", Message: e.Reason}}
+		}
+		return collectSchemaViolations(e.Err, e.Reason)
+	case *openapi3.SchemaError:
+		message := e.Reason
+		if reason != "" {
+			message = fmt.Sprintf("%s: %s", reason, message)
+		}
+		return []*SchemaViolation{{
+			Pointer: toJSONPointer(e.JSONPointer()),
+			Keyword: e.SchemaField,
+			Message: message,
+		}}
+	default:
+		message := err.Error()
+		if reason != "" && !strings.Contains(message, reason) {
+			message = fmt.Sprintf("%s: %s", reason, message)
+		}
+		return []*SchemaViolation{{Message: message}}
+	}
+	return violations
+}
+
+// toJSONPointer converts path tokens to JSON pointer defined in RFC 6901
+func toJSONPointer(tokens []string) string {
+	var pointer strings.Builder
+	for _, token := range tokens {
+		token = strings.ReplaceAll(token, "~", "~0")
+		token = strings.ReplaceAll(token, "/", "~1")
+		pointer.WriteString("/" + token)
+	}
+	return pointer.String()
+}
@@ -0,0 +1,155 @@
+package hrp
+
+import (
+	"bytes"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// xpath(//book[@id="1"]/title), xpath(count(//book))
+	xpathFieldRegexp = regexp.MustCompile(`^xpath\((.+)\)$`)
+	// css(div.title), css(a.next)@href
+	cssFieldRegexp = regexp.MustCompile(`^css\((.+)\)(?:@([\w:.-]+))?$`)
+)
+
+// searchSelector searches XML body with XPath or HTML body with CSS selector,
+// returns false if field is not a selector expression.
+func (v *responseObject) searchSelector(field string) (interface{}, bool) {
+	if match := xpathFieldRegexp.FindStringSubmatch(field); match != nil {
+		return v.searchXPath(match[1]), true
+	}
+	if match := cssFieldRegexp.FindStringSubmatch(field); match != nil {
+		return v.searchCSS(match[1], match[2]), true
+	}
+	return nil, false
+}
+
+// searchXPath evaluates XPath expression on XML response body.
+// node set result is converted to text of the matched node, or list of texts if multiple nodes matched,
+// while number, string and boolean results of XPath functions are returned as they are.
+func (v *responseObject) searchXPath(expr string) interface{} {
+	doc, err := v.loadXMLDoc()
+	if err != nil {
+		log.Error().Str("xpath", expr).Err(err).Msg("search xpath failed")
+		return expr
+	}
+	compiled, err := xpath.Compile(expr)
+	if err != nil {
+		log.Error().Str("xpath", expr).Err(err).Msg("compile xpath failed")
+		return expr
+	}
+
+	switch result := compiled.Evaluate(xmlquery.CreateXPathNavigator(doc)).(type) {
+	case *xpath.NodeIterator:
+		var texts []string
+		for result.MoveNext() {
+			texts = append(texts, strings.TrimSpace(result.Current().Value()))
+		}
+		return selectorResult(texts)
+	case float64:
+		if result == math.Trunc(result) && !math.IsInf(result, 0) {
+			return int64(result)
+		}
+		return result
+	default:
+		return result
+	}
+}
+
+// searchCSS finds elements in HTML response body with CSS selector,
+// returns text of the matched element, or the specified attribute if attr is not empty.
+func (v *responseObject) searchCSS(selector, attr string) interface{} {
+	doc, err := v.loadHTMLDoc()
+	if err != nil {
+		log.Error().Str("selector", selector).Err(err).Msg("search css selector failed")
+		return selector
+	}
+
+	matcher, err := cascadia.Compile(selector)
+	if err != nil {
+		log.Error().Str("selector", selector).Err(err).Msg("compile css selector failed")
+		return selector
+	}
+
+	var texts []string
+	doc.FindMatcher(matcher).Each(func(_ int, s *goquery.Selection) {
+		if attr == "" {
+			texts = append(texts, strings.TrimSpace(s.Text()))
+		} else if value, ok := s.Attr(attr); ok {
+			texts = append(texts, value)
+		}
+	})
+	return selectorResult(texts)
+}
+
+// selectorResult returns nil if nothing matched, single text for one match, and list for multiple matches.
+func selectorResult(texts []string) interface{} {
+	switch len(texts) {
+	case 0:
+		return nil
+	case 1:
+		return texts[0]
+	}
+	list := make([]interface{}, len(texts))
+	for i, text := range texts {
+		list[i] = text
+	}
+	return list
+}
+
+func (v *responseObject) loadXMLDoc() (*xmlquery.Node, error) {
+	if v.xmlDoc != nil {
+		return v.xmlDoc, nil
+	}
+	body, err := v.rawBody()
+	if err != nil {
+		return nil, err
+	}
+	doc, err := xmlquery.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, "parse xml body failed")
+	}
+	v.xmlDoc = doc
+	return doc, nil
+}
+
+func (v *responseObject) loadHTMLDoc() (*goquery.Document, error) {
+	if v.htmlDoc != nil {
+		return v.htmlDoc, nil
+	}
+	body, err := v.rawBody()
+	if err != nil {
+		return nil, err
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, "parse html body failed")
+	}
+	v.htmlDoc = doc
+	return doc, nil
+}
+
+// rawBody returns raw HTTP response body, or text body of other responses, e.g. websocket message
+func (v *responseObject) rawBody() ([]byte, error) {
+	if v.httpRespBody != nil {
+		return v.httpRespBody, nil
+	}
+	respMap, ok := v.respObjMeta.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("convert respObjMeta to map failed")
+	}
+	bodyStr, ok := respMap["body"].(string)
+	if !ok {
+		return nil, errors.New("response body is not text")
+	}
+	return []byte(bodyStr), nil
+}
@@ -0,0 +1,333 @@
+package hrp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+type RequestSignType string
+
+const (
+	SignHMACSHA256 RequestSignType = "hmac_sha256"
+	SignAWSSigV4   RequestSignType = "aws_sigv4"
+	SignFunction   RequestSignType = "function"
+	SignNone       RequestSignType = "none" // disable signing of testcase config for step
+)
+
+const (
+	defaultSignCanonicalString = "{method}\n{path}\n{query}\n{timestamp}\n{nonce}\n{body_sha256}"
+	defaultSignHeader          = "X-Signature"
+	signTimestampHeader        = "X-Timestamp"
+	signNonceHeader            = "X-Nonce"
+)
+
+// RequestSign configures signing of HTTP requests, the request is signed after url, headers and body
+// are fully built. fields except templates could reference variables.
+type RequestSign struct {
+	Type            RequestSignType `json:"type" yaml:"type"`                                             // hmac_sha256, aws_sigv4, function or none
+	KeyID           string          `json:"key_id,omitempty" yaml:"key_id,omitempty"`                     // hmac key id, or aws access key id
+	Secret          string          `json:"secret,omitempty" yaml:"secret,omitempty"`                     // hmac secret, or aws secret access key
+	SessionToken    string          `json:"session_token,omitempty" yaml:"session_token,omitempty"`       // aws session token of temporary credentials
+	Region          string          `json:"region,omitempty" yaml:"region,omitempty"`                     // aws region, e.g. us-east-1
+	Service         string          `json:"service,omitempty" yaml:"service,omitempty"`                   // aws service, e.g. execute-api
+	CanonicalString string          `json:"canonical_string,omitempty" yaml:"canonical_string,omitempty"` // hmac string to sign template, e.g. {method}\n{path}\n{header:X-Date}
+	Header          string          `json:"header,omitempty" yaml:"header,omitempty"`                     // hmac signature header, default X-Signature
+	HeaderFormat    string          `json:"header_format,omitempty" yaml:"header_format,omitempty"`       // hmac signature header value template, default {signature}
+	Encoding        string          `json:"encoding,omitempty" yaml:"encoding,omitempty"`                 // hmac signature encoding, hex(default) or base64
+	Function        string          `json:"function,omitempty" yaml:"function,omitempty"`                 // plugin function returns headers to set, e.g. ${sign($hrp_sign_request)}
+}
+
+var signPlaceholderRegexp = regexp.MustCompile(`\{(header:[^{}]+|[a-z0-9_]+)\}`)
+
+// signRequest signs the fully built request, signed headers are updated to request map for report.
+func (r *requestBuilder) signRequest(stepVariables map[string]interface{}) error {
+	sign := r.config.Sign
+	if r.stepRequest.Sign != nil {
+		sign = r.stepRequest.Sign
+	}
+	if sign == nil || sign.Type == SignNone {
+		return nil
+	}
+
+	fields, err := r.parser.ParseHeaders(map[string]string{
+		"key_id":        sign.KeyID,
+		"secret":        sign.Secret,
+		"session_token": sign.SessionToken,
+		"region":        sign.Region,
+		"service":       sign.Service,
+	}, stepVariables)
+	if err != nil {
+		return errors.Wrap(err, "parse sign config failed")
+	}
+	body, err := r.bodyBytes()
+	if err != nil {
+		return err
+	}
+
+	switch sign.Type {
+	case SignHMACSHA256:
+		err = r.signHMAC(sign, fields, body)
+	case SignAWSSigV4:
+		err = r.signAWSV4(fields, body, time.Now().UTC())
+	case SignFunction:
+		err = r.signWithFunction(sign, stepVariables, body)
+	default:
+		err = errors.Errorf("unsupported sign type %s", sign.Type)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "sign request with %s failed", sign.Type)
+	}
+	log.Info().Str("type", string(sign.Type)).Msg("request signed")
+	r.updateRequestMapHeaders()
+	return nil
+}
+
+// bodyBytes returns request body without consuming it
+func (r *requestBuilder) bodyBytes() ([]byte, error) {
+	if r.req.Body == nil || r.req.GetBody == nil {
+		return nil, nil
+	}
+	body, err := r.req.GetBody()
+	if err != nil {
+		return nil, errors.Wrap(err, "get request body failed")
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+func (r *requestBuilder) signHMAC(sign *RequestSign, fields map[string]string, body []byte) error {
+	if fields["secret"] == "" {
+		return errors.New("hmac secret is required")
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	r.req.Header.Set(signTimestampHeader, strconv.FormatInt(time.Now().Unix(), 10))
+	r.req.Header.Set(signNonceHeader, hex.EncodeToString(nonce))
+
+	bodyHash := sha256.Sum256(body)
+	values := map[string]string{
+		"method":      r.req.Method,
+		"host":        r.req.Host,
+		"path":        r.req.URL.EscapedPath(),
+		"query":       r.req.URL.Query().Encode(), // sorted by key
+		"body":        string(body),
+		"body_sha256": hex.EncodeToString(bodyHash[:]),
+		"timestamp":   r.req.Header.Get(signTimestampHeader),
+		"nonce":       r.req.Header.Get(signNonceHeader),
+		"key_id":      fields["key_id"],
+	}
+	canonicalString := sign.CanonicalString
+	if canonicalString == "" {
+		canonicalString = defaultSignCanonicalString
+	}
+	stringToSign := expandSignTemplate(canonicalString, values, r.req.Header)
+
+	mac := hmac.New(sha256.New, []byte(fields["secret"]))
+	mac.Write([]byte(stringToSign))
+	switch sign.Encoding {
+	case "", "hex":
+		values["signature"] = hex.EncodeToString(mac.Sum(nil))
+	case "base64":
+		values["signature"] = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	default:
+		return errors.Errorf("unsupported signature encoding %s", sign.Encoding)
+	}
+
+	header := sign.Header
+	if header == "" {
+		header = defaultSignHeader
+	}
+	headerFormat := sign.HeaderFormat
+	if headerFormat == "" {
+		headerFormat = "{signature}"
+	}
+	r.req.Header.Set(header, expandSignTemplate(headerFormat, values, r.req.Header))
+	return nil
+}
+
+// expandSignTemplate replaces placeholders in template, e.g. {method}, {header:Content-Type}
+func expandSignTemplate(template string, values map[string]string, header http.Header) string {
+	template = strings.NewReplacer(`\n`, "\n").Replace(template)
+	return signPlaceholderRegexp.ReplaceAllStringFunc(template, func(placeholder string) string {
+		name := placeholder[1 : len(placeholder)-1]
+		if strings.HasPrefix(name, "header:") {
+			return header.Get(strings.TrimPrefix(name, "header:"))
+		}
+		if value, ok := values[name]; ok {
+			return value
+		}
+		return placeholder
+	})
+}
+
+// signAWSV4 signs request with AWS Signature Version 4, X-Amz-Date in request headers is respected.
+func (r *requestBuilder) signAWSV4(fields map[string]string, body []byte, now time.Time) error {
+	if fields["key_id"] == "" || fields["secret"] == "" {
+		return errors.New("aws access key id and secret access key are required")
+	}
+	if fields["region"] == "" || fields["service"] == "" {
+		return errors.New("aws region and service are required")
+	}
+	amzDate := r.req.Header.Get("X-Amz-Date")
+	if amzDate == "" {
+		amzDate = now.Format("20060102T150405Z")
+		r.req.Header.Set("X-Amz-Date", amzDate)
+	}
+	if len(amzDate) < 8 {
+		return errors.Errorf("invalid X-Amz-Date %s", amzDate)
+	}
+	if fields["session_token"] != "" {
+		r.req.Header.Set("X-Amz-Security-Token", fields["session_token"])
+	}
+	bodyHash := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(bodyHash[:])
+	if fields["service"] == "s3" {
+		r.req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	// canonical headers: host, content-type and x-amz-* headers
+	headers := map[string]string{"host": r.req.Host}
+	for key, values := range r.req.Header {
+		name := strings.ToLower(key)
+		if name == "content-type" || strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.Join(strings.Fields(strings.Join(values, ",")), " ")
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalURI := r.req.URL.EscapedPath()
+	if canonicalURI == "" {
+		canonicalURI = "/"
+	}
+	if fields["service"] != "s3" {
+		// path segments are encoded twice except for s3
+		segments := strings.Split(canonicalURI, "/")
+		for i, segment := range segments {
+			segments[i] = awsURIEncode(segment)
+		}
+		canonicalURI = strings.Join(segments, "/")
+	}
+	canonicalRequest := strings.Join([]string{
+		r.req.Method,
+		canonicalURI,
+		awsCanonicalQuery(r.req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	date := amzDate[:8]
+	scope := strings.Join([]string{date, fields["region"], fields["service"], "aws4_request"}, "/")
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(canonicalHash[:]),
+	}, "\n")
+
+	key := []byte("AWS4" + fields["secret"])
+	for _, part := range []string{date, fields["region"], fields["service"], "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	r.req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		fields["key_id"], scope, signedHeaders, signature))
+	return nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsURIEncode encodes all characters except unreserved ones in RFC 3986
+func awsURIEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// awsCanonicalQuery sorts query parameters by encoded key, then by encoded value for repeated keys
+func awsCanonicalQuery(query url.Values) string {
+	pairs := make([][2]string, 0, len(query))
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, [2]string{awsURIEncode(key), awsURIEncode(value)})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	encoded := make([]string, len(pairs))
+	for i, pair := range pairs {
+		encoded[i] = pair[0] + "=" + pair[1]
+	}
+	return strings.Join(encoded, "&")
+}
+
+// signWithFunction calls plugin function with the built request, returned headers are set to request.
+func (r *requestBuilder) signWithFunction(sign *RequestSign, stepVariables map[string]interface{}, body []byte) error {
+	if sign.Function == "" {
+		return errors.New("sign function is required")
+	}
+	headers := make(map[string]string)
+	for key := range r.req.Header {
+		headers[key] = r.req.Header.Get(key)
+	}
+	variables := mergeVariables(map[string]interface{}{
+		"hrp_sign_request": map[string]interface{}{
+			"method":  r.req.Method,
+			"url":     r.req.URL.String(),
+			"headers": headers,
+			"body":    string(body),
+		},
+	}, stepVariables)
+	result, err := r.parser.Parse(sign.Function, variables)
+	if err != nil {
+		return errors.Wrap(err, "call sign function failed")
+	}
+	signedHeaders, ok := result.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("sign function should return headers map, got %v", result)
+	}
+	for key, value := range signedHeaders {
+		r.req.Header.Set(key, convertString(value))
+	}
+	return nil
+}
@@ -0,0 +1,409 @@
+package hrp
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/httprunner/httprunner/v5/internal/builtin"
+	"github.com/httprunner/httprunner/v5/internal/json"
+)
+
+type StreamFormat string
+
+const (
+	StreamFormatSSE     StreamFormat = "sse"     // text/event-stream
+	StreamFormatNDJSON  StreamFormat = "ndjson"  // one JSON object per line
+	StreamFormatChunked StreamFormat = "chunked" // raw chunks
+)
+
+const (
+	streamEndEOF       = "eof"
+	streamEndMaxEvents = "max_events"
+	streamEndDuration  = "duration"
+	streamEndUntil     = "until"
+)
+
+// defaultStreamMaxRawSize limits raw body kept in memory for long-running streams, events are not limited
+const defaultStreamMaxRawSize = 1 << 20
+
+// RequestStream reads response body incrementally as events instead of a single blob.
+type RequestStream struct {
+	Format     StreamFormat `json:"format,omitempty" yaml:"format,omitempty"`             // detected by response Content-Type if empty
+	MaxEvents  int          `json:"max_events,omitempty" yaml:"max_events,omitempty"`     // stop reading after receiving max events
+	Duration   float64      `json:"duration,omitempty" yaml:"duration,omitempty"`         // stop reading after duration in seconds
+	Until      *Validator   `json:"until,omitempty" yaml:"until,omitempty"`               // stop reading once an event matches, step fails if none matched
+	MaxRawSize int          `json:"max_raw_size,omitempty" yaml:"max_raw_size,omitempty"` // max bytes of raw body kept as body, default 1MB, the rest is dropped
+}
+
+// NewRequestStream returns stream options which reads response body as events.
+func NewRequestStream() *RequestStream {
+	return &RequestStream{}
+}
+
+// WithFormat sets the stream format instead of detecting by Content-Type.
+func (s *RequestStream) WithFormat(format StreamFormat) *RequestStream {
+	s.Format = format
+	return s
+}
+
+// WithMaxEvents stops reading after receiving max events.
+func (s *RequestStream) WithMaxEvents(maxEvents int) *RequestStream {
+	s.MaxEvents = maxEvents
+	return s
+}
+
+// WithDuration stops reading after duration.
+func (s *RequestStream) WithDuration(duration time.Duration) *RequestStream {
+	s.Duration = duration.Seconds()
+	return s
+}
+
+// WithMaxRawSize sets max bytes of raw body kept as body, the rest is dropped while events are still parsed.
+func (s *RequestStream) WithMaxRawSize(size int) *RequestStream {
+	s.MaxRawSize = size
+	return s
+}
+
+// WaitUntil stops reading once an event matches the assertion, check is JMESPath expression against the event,
+// e.g. data.done, event. The step fails if stream ends without any matched event.
+func (s *RequestStream) WaitUntil(check string, assert string, expected interface{}, msg ...string) *RequestStream {
+	s.Until = &Validator{
+		Check:  check,
+		Assert: assert,
+		Expect: expected,
+	}
+	if len(msg) > 0 {
+		s.Until.Message = msg[0]
+	}
+	return s
+}
+
+type streamEvent struct {
+	ID        string      `json:"id,omitempty"`
+	Event     string      `json:"event,omitempty"`
+	Data      interface{} `json:"data"` // parsed as JSON if possible
+	Retry     int         `json:"retry,omitempty"`
+	ElapsedMs int64       `json:"elapsed_ms"` // from sending request to receiving event
+}
+
+// streamFieldTags are searched only on streaming response, besides the common fieldTags
+var streamFieldTags = append([]string{"events", "ttfb_ms", "event_latencies_ms", "end_reason"}, httpFieldTags...)
+
+type httpStreamRespObjMeta struct {
+	httpRespObjMeta
+	Events           []*streamEvent `json:"events"`
+	TTFBMs           int64          `json:"ttfb_ms"`            // from sending request to receiving first byte of body
+	EventLatenciesMs []int64        `json:"event_latencies_ms"` // intervals between adjacent events
+	EndReason        string         `json:"end_reason"`         // eof, max_events, duration or until
+}
+
+// streamReader records time of the first byte read, and keeps at most maxRawSize bytes of raw body
+type streamReader struct {
+	io.Reader
+	firstByte  time.Time
+	raw        bytes.Buffer
+	maxRawSize int
+	size       int64 // total bytes read
+}
+
+func (r *streamReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if n > 0 {
+		if r.firstByte.IsZero() {
+			r.firstByte = time.Now()
+		}
+		r.size += int64(n)
+		if remaining := r.maxRawSize - r.raw.Len(); remaining > 0 {
+			r.raw.Write(p[:min(n, remaining)])
+		}
+	}
+	return n, err
+}
+
+// eventMatcher checks whether the event matches until condition, e.g. stream event or websocket message
+type eventMatcher func(event interface{}) (matched bool, checkValue interface{}, err error)
+
+// newHttpStreamResponseObject reads response body as events until stream ends or any stop condition is met,
+// cancelRequest is called to interrupt reading after duration.
+func newHttpStreamResponseObject(t *testing.T, parser *Parser, resp *http.Response, requestStart time.Time,
+	stream *RequestStream, variablesMapping map[string]interface{}, cancelRequest func(),
+) (*responseObject, error) {
+	var timeout atomic.Bool
+	if stream.Duration > 0 {
+		timer := time.AfterFunc(time.Duration(stream.Duration*1000)*time.Millisecond, func() {
+			timeout.Store(true)
+			cancelRequest()
+		})
+		defer timer.Stop()
+	}
+
+	var untilResult *ValidationResult
+	var matcher eventMatcher
+	if stream.Until != nil {
+		var err error
+		untilResult, matcher, err = newEventMatcher(parser, stream.Until, variablesMapping)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	format := stream.Format
+	if format == "" {
+		format = detectStreamFormat(resp.Header.Get("Content-Type"))
+	}
+	maxRawSize := stream.MaxRawSize
+	if maxRawSize <= 0 {
+		maxRawSize = defaultStreamMaxRawSize
+	}
+	reader := &streamReader{Reader: resp.Body, maxRawSize: maxRawSize}
+	meta := httpStreamRespObjMeta{
+		Events:           []*streamEvent{},
+		EventLatenciesMs: []int64{},
+	}
+
+	var lastEvent time.Time
+	onEvent := func(event *streamEvent) (bool, error) {
+		now := time.Now()
+		event.ElapsedMs = now.Sub(requestStart).Milliseconds()
+		if !lastEvent.IsZero() {
+			meta.EventLatenciesMs = append(meta.EventLatenciesMs, now.Sub(lastEvent).Milliseconds())
+		}
+		lastEvent = now
+		meta.Events = append(meta.Events, event)
+		log.Debug().Interface("event", event).Msg("receive stream event")
+
+		if matcher != nil {
+			matched, checkValue, err := matcher(event)
+			if err != nil {
+				return true, err
+			}
+			if matched {
+				untilResult.CheckValue = checkValue
+				untilResult.CheckResult = "pass"
+				meta.EndReason = streamEndUntil
+				return true, nil
+			}
+		}
+		if stream.MaxEvents > 0 && len(meta.Events) >= stream.MaxEvents {
+			meta.EndReason = streamEndMaxEvents
+			return true, nil
+		}
+		return false, nil
+	}
+
+	var err error
+	switch format {
+	case StreamFormatSSE:
+		err = readSSEEvents(reader, onEvent)
+	case StreamFormatNDJSON:
+		err = readNDJSONEvents(reader, onEvent)
+	default:
+		err = readChunkedEvents(reader, onEvent)
+	}
+	if err != nil && timeout.Load() {
+		meta.EndReason = streamEndDuration
+	} else if err != nil {
+		return nil, errors.Wrap(err, "read stream failed")
+	} else if meta.EndReason == "" {
+		meta.EndReason = streamEndEOF
+	}
+
+	respBodyBytes := reader.raw.Bytes()
+	if reader.size > int64(len(respBodyBytes)) {
+		log.Warn().Int64("size", reader.size).Int("maxRawSize", maxRawSize).Msg("stream raw body truncated")
+	}
+	meta.httpRespObjMeta = newHttpRespObjMeta(resp, string(respBodyBytes), reader.size, requestStart)
+	if !reader.firstByte.IsZero() {
+		meta.TTFBMs = reader.firstByte.Sub(requestStart).Milliseconds()
+	}
+	log.Info().Str("format", string(format)).Int("events", len(meta.Events)).
+		Str("endReason", meta.EndReason).Int64("ttfb(ms)", meta.TTFBMs).Msg("read stream done")
+
+	respObj, err := convertToResponseObject(t, parser, meta)
+	if err != nil {
+		return nil, err
+	}
+	respObj.httpResp = resp
+	respObj.httpRespBody = respBodyBytes
+	respObj.streamEvents = meta.Events
+	respObj.fieldTags = streamFieldTags
+
+	// record until condition as validation result
+	if untilResult != nil {
+		respObj.validationResults = append(respObj.validationResults, untilResult)
+		if untilResult.CheckResult != "pass" {
+			t.Fail()
+			respObj.streamErr = errors.Errorf("no stream event matches until condition: %s %s %v",
+				stream.Until.Check, stream.Until.Assert, untilResult.Expect)
+		}
+	}
+	return respObj, nil
+}
+
+func detectStreamFormat(contentType string) StreamFormat {
+	switch {
+	case strings.HasPrefix(contentType, "text/event-stream"):
+		return StreamFormatSSE
+	case strings.HasPrefix(contentType, "application/x-ndjson"),
+		strings.HasPrefix(contentType, "application/ndjson"),
+		strings.HasPrefix(contentType, "application/jsonl"),
+		strings.HasPrefix(contentType, "application/stream+json"):
+		return StreamFormatNDJSON
+	default:
+		return StreamFormatChunked
+	}
+}
+
+// newEventMatcher parses until condition with variables, and returns matcher against each event
+func newEventMatcher(parser *Parser, until *Validator, variablesMapping map[string]interface{}) (
+	*ValidationResult, eventMatcher, error,
+) {
+	assertFunc, ok := builtin.Assertions[until.Assert]
+	if !ok {
+		return nil, nil, errors.New(fmt.Sprintf("unexpected assertMethod: %v", until.Assert))
+	}
+	expectValue, err := parser.Parse(until.Expect, variablesMapping)
+	if err != nil {
+		return nil, nil, err
+	}
+	result := &ValidationResult{
+		Validator: Validator{
+			Check:   "until " + until.Check,
+			Assert:  until.Assert,
+			Expect:  expectValue,
+			Message: until.Message,
+		},
+		CheckResult: "fail",
+	}
+	matcher := func(event interface{}) (bool, interface{}, error) {
+		eventObj, err := convertToResponseObject(&testing.T{}, parser, event)
+		if err != nil {
+			return false, nil, err
+		}
+		checkValue := eventObj.searchJmespath(until.Check)
+		// assertion on unmatched events should not fail the test
+		return assertFunc(&testing.T{}, checkValue, expectValue), checkValue, nil
+	}
+	return result, matcher, nil
+}
+
+// readSSEEvents parses server-sent events, reference: https://html.spec.whatwg.org/multipage/server-sent-events.html
+func readSSEEvents(r io.Reader, onEvent func(*streamEvent) (bool, error)) error {
+	scanner := newStreamScanner(r)
+	event := &streamEvent{}
+	var dataLines []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			// blank line dispatches the event
+			if dataLines == nil {
+				event = &streamEvent{}
+				continue
+			}
+			if event.Event == "" {
+				event.Event = "message"
+			}
+			event.Data = parseStreamData(strings.Join(dataLines, "\n"))
+			if stop, err := onEvent(event); stop || err != nil {
+				return err
+			}
+			event = &streamEvent{}
+			dataLines = nil
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue // comment
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event.Event = value
+		case "data":
+			dataLines = append(dataLines, value)
+		case "id":
+			event.ID = value
+		case "retry":
+			if retry, err := strconv.Atoi(value); err == nil {
+				event.Retry = retry
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// readNDJSONEvents parses each non-empty line as an event
+func readNDJSONEvents(r io.Reader, onEvent func(*streamEvent) (bool, error)) error {
+	scanner := newStreamScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if stop, err := onEvent(&streamEvent{Data: parseStreamData(line)}); stop || err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// readChunkedEvents takes each read of response body as an event
+func readChunkedEvents(r io.Reader, onEvent func(*streamEvent) (bool, error)) error {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if stop, err := onEvent(&streamEvent{Data: string(buf[:n])}); stop || err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func newStreamScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	return scanner
+}
+
+func parseStreamData(data string) interface{} {
+	var value interface{}
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		return data
+	}
+	return value
+}
+
+// printStreamResponse prints response headers and received events
+func printStreamResponse(resp *http.Response, events []*streamEvent) error {
+	fmt.Println("==================== response ====================")
+	respDump, err := httputil.DumpResponse(resp, false)
+	if err != nil {
+		return errors.Wrap(err, "dump response failed")
+	}
+	fmt.Println(string(respDump))
+	for _, event := range events {
+		eventBytes, _ := json.Marshal(event)
+		fmt.Println(string(eventBytes))
+	}
+	fmt.Println("--------------------------------------------------")
+	return nil
+}
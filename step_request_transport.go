@@ -0,0 +1,439 @@
+package hrp
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/http2"
+)
+
+const defaultMaxRedirects = 10 // same as net/http
+
+// RequestTLS configures TLS of HTTP requests, files are located relative to project root dir.
+type RequestTLS struct {
+	CACert     string `json:"ca_cert,omitempty" yaml:"ca_cert,omitempty"`         // PEM encoded CA bundle to verify server certificate
+	Cert       string `json:"cert,omitempty" yaml:"cert,omitempty"`               // PEM encoded client certificate for mutual TLS
+	Key        string `json:"key,omitempty" yaml:"key,omitempty"`                 // PEM encoded client private key for mutual TLS
+	ServerName string `json:"server_name,omitempty" yaml:"server_name,omitempty"` // override SNI and the name to verify
+}
+
+type HTTPAuthType string
+
+const (
+	HTTPAuthBasic  HTTPAuthType = "basic"
+	HTTPAuthDigest HTTPAuthType = "digest"
+	HTTPAuthBearer HTTPAuthType = "bearer"
+)
+
+// HTTPAuth configures authentication of HTTP requests, fields could reference variables.
+type HTTPAuth struct {
+	Type     HTTPAuthType `json:"type" yaml:"type"` // basic, digest or bearer
+	Username string       `json:"username,omitempty" yaml:"username,omitempty"`
+	Password string       `json:"password,omitempty" yaml:"password,omitempty"`
+	Token    string       `json:"token,omitempty" yaml:"token,omitempty"` // bearer token
+}
+
+type redirectHop struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+	Location   string `json:"location"`
+}
+
+// redirectChain returns redirect responses before the final response
+func redirectChain(resp *http.Response) []*redirectHop {
+	chain := []*redirectHop{}
+	if resp.Request == nil {
+		return chain
+	}
+	for redirect := resp.Request.Response; redirect != nil; redirect = redirect.Request.Response {
+		hop := &redirectHop{
+			StatusCode: redirect.StatusCode,
+			Location:   redirect.Header.Get("Location"),
+		}
+		if redirect.Request != nil && redirect.Request.URL != nil {
+			hop.URL = redirect.Request.URL.String()
+		}
+		chain = append([]*redirectHop{hop}, chain...)
+		if redirect.Request == nil {
+			break
+		}
+	}
+	return chain
+}
+
+// requestTransport holds effective transport settings of HTTP request,
+// priority: step request > testcase config > defaults
+type requestTransport struct {
+	verify         *bool // explicitly enabled or disabled, nil if unset
+	tls            RequestTLS
+	proxies        map[string]string
+	allowRedirects bool
+	maxRedirects   int
+	auth           *HTTPAuth
+}
+
+func newRequestTransport(config *TConfig, request *Request) *requestTransport {
+	t := &requestTransport{
+		proxies:        config.Proxies,
+		allowRedirects: true,
+		maxRedirects:   defaultMaxRedirects,
+		auth:           config.Auth,
+	}
+	if config.Verify {
+		t.verify = &config.Verify
+	} else if config.SkipVerify {
+		t.verify = new(bool)
+	}
+	if config.TLS != nil {
+		t.tls = *config.TLS
+	}
+	if config.AllowRedirects != nil {
+		t.allowRedirects = *config.AllowRedirects
+	}
+	if config.MaxRedirects > 0 {
+		t.maxRedirects = config.MaxRedirects
+	}
+
+	// override with step request settings
+	if request.Verify {
+		t.verify = &request.Verify
+	} else if request.SkipVerify {
+		t.verify = new(bool)
+	}
+	if request.TLS != nil {
+		if request.TLS.CACert != "" {
+			t.tls.CACert = request.TLS.CACert
+		}
+		if request.TLS.Cert != "" || request.TLS.Key != "" {
+			t.tls.Cert = request.TLS.Cert
+			t.tls.Key = request.TLS.Key
+		}
+		if request.TLS.ServerName != "" {
+			t.tls.ServerName = request.TLS.ServerName
+		}
+	}
+	if len(request.Proxies) > 0 {
+		t.proxies = request.Proxies
+	}
+	if request.AllowRedirects {
+		t.allowRedirects = true
+	} else if request.DisableRedirects {
+		t.allowRedirects = false
+	}
+	if request.MaxRedirects > 0 {
+		t.maxRedirects = request.MaxRedirects
+	}
+	if request.Auth != nil {
+		t.auth = request.Auth
+	}
+	return t
+}
+
+// verifyServer returns true if server certificate should be verified,
+// a CA bundle always enables verification, other TLS settings enable it unless disabled explicitly
+func (t *requestTransport) verifyServer() bool {
+	if t.tls.CACert != "" {
+		return true
+	}
+	if t.verify != nil {
+		return *t.verify
+	}
+	return t.tls != RequestTLS{}
+}
+
+// customized returns true if the shared transport of runner could not be used
+func (t *requestTransport) customized() bool {
+	return t.verifyServer() || t.tls != RequestTLS{} || len(t.proxies) > 0
+}
+
+func (t *requestTransport) checkRedirect(req *http.Request, via []*http.Request) error {
+	if !t.allowRedirects {
+		return http.ErrUseLastResponse
+	}
+	if len(via) >= t.maxRedirects {
+		return errors.Errorf("stopped after %d redirects", t.maxRedirects)
+	}
+	return nil
+}
+
+// parse parses proxies and auth with variables
+func (t *requestTransport) parse(parser *Parser, variablesMapping map[string]interface{}) error {
+	if len(t.proxies) > 0 {
+		proxies, err := parser.ParseHeaders(t.proxies, variablesMapping)
+		if err != nil {
+			return errors.Wrap(err, "parse proxies failed")
+		}
+		t.proxies = proxies
+	}
+	if t.auth != nil {
+		fields, err := parser.ParseHeaders(map[string]string{
+			"username": t.auth.Username,
+			"password": t.auth.Password,
+			"token":    t.auth.Token,
+		}, variablesMapping)
+		if err != nil {
+			return errors.Wrap(err, "parse auth failed")
+		}
+		t.auth = &HTTPAuth{
+			Type:     t.auth.Type,
+			Username: fields["username"],
+			Password: fields["password"],
+			Token:    fields["token"],
+		}
+	}
+	return nil
+}
+
+// client returns a shallow copy of base client with transport settings applied,
+// customized transports are cached in runner to reuse connections
+func (t *requestTransport) client(r *HRPRunner, base *http.Client, useHTTP2 bool, casePath string) (*http.Client, error) {
+	client := *base
+	if t.customized() {
+		key := fmt.Sprintf("%v|%+v|%v|%v|%s", t.verifyServer(), t.tls, t.proxies, useHTTP2, casePath)
+		if transport, ok := r.transports.Load(key); ok {
+			client.Transport = transport.(http.RoundTripper)
+		} else {
+			transport, err := t.newTransport(base.Transport, useHTTP2, casePath)
+			if err != nil {
+				return nil, err
+			}
+			actual, _ := r.transports.LoadOrStore(key, transport)
+			client.Transport = actual.(http.RoundTripper)
+		}
+	}
+	client.CheckRedirect = t.checkRedirect
+	if t.auth != nil && t.auth.Type == HTTPAuthDigest {
+		client.Transport = &digestAuthTransport{
+			base:     client.Transport,
+			username: t.auth.Username,
+			password: t.auth.Password,
+		}
+	}
+	return &client, nil
+}
+
+func (t *requestTransport) newTransport(base http.RoundTripper, useHTTP2 bool, casePath string) (http.RoundTripper, error) {
+	tlsConfig, err := loadTLSConfig(casePath, t.tls.CACert, t.tls.Cert, t.tls.Key, t.tls.ServerName, !t.verifyServer())
+	if err != nil {
+		return nil, err
+	}
+	if useHTTP2 && len(t.proxies) == 0 {
+		return &http2.Transport{TLSClientConfig: tlsConfig}, nil
+	}
+
+	var transport *http.Transport
+	if baseTransport, ok := base.(*http.Transport); ok {
+		transport = baseTransport.Clone()
+	} else {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+	transport.TLSClientConfig = tlsConfig
+	transport.ForceAttemptHTTP2 = useHTTP2
+	if len(t.proxies) > 0 {
+		proxy, err := proxyFunc(t.proxies)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = proxy
+	}
+	log.Info().Bool("verify", t.verifyServer()).Interface("tls", t.tls).
+		Interface("proxies", t.proxies).Msg("init http transport")
+	return transport, nil
+}
+
+// applyAuth sets Authorization header for basic and bearer auth, digest auth is handled in transport
+func (t *requestTransport) applyAuth(req *http.Request) error {
+	if t.auth == nil {
+		return nil
+	}
+	switch t.auth.Type {
+	case HTTPAuthBasic:
+		req.SetBasicAuth(t.auth.Username, t.auth.Password)
+	case HTTPAuthBearer:
+		req.Header.Set("Authorization", "Bearer "+t.auth.Token)
+	case HTTPAuthDigest:
+	default:
+		return errors.Errorf("unsupported auth type: %s", t.auth.Type)
+	}
+	return nil
+}
+
+// proxyFunc selects proxy by request scheme, e.g. {"http": "...", "https": "..."}, "all" for any scheme
+func proxyFunc(proxies map[string]string) (func(*http.Request) (*url.URL, error), error) {
+	proxyURLs := make(map[string]*url.URL, len(proxies))
+	for scheme, rawURL := range proxies {
+		proxyURL, err := url.Parse(rawURL)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid proxy url %s", rawURL)
+		}
+		proxyURLs[strings.ToLower(scheme)] = proxyURL
+	}
+	return func(req *http.Request) (*url.URL, error) {
+		if proxyURL, ok := proxyURLs[req.URL.Scheme]; ok {
+			return proxyURL, nil
+		}
+		return proxyURLs["all"], nil
+	}, nil
+}
+
+// loadTLSConfig loads CA bundle and client certificate, relative paths are located in project root dir
+func loadTLSConfig(casePath, caCert, cert, key, serverName string, insecureSkipVerify bool) (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: insecureSkipVerify,
+	}
+	if caCert != "" {
+		content, err := os.ReadFile(resolveCaseFilePath(casePath, caCert))
+		if err != nil {
+			return nil, errors.Wrap(err, "read ca cert failed")
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(content) {
+			return nil, errors.Errorf("invalid ca cert %s", caCert)
+		}
+	}
+	if cert != "" || key != "" {
+		certificate, err := tls.LoadX509KeyPair(
+			resolveCaseFilePath(casePath, cert), resolveCaseFilePath(casePath, key))
+		if err != nil {
+			return nil, errors.Wrap(err, "load client cert failed")
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+	return config, nil
+}
+
+// digestAuthTransport resends request with digest authorization if challenged,
+// reference: https://datatracker.ietf.org/doc/html/rfc7616
+type digestAuthTransport struct {
+	base     http.RoundTripper
+	username string
+	password string
+}
+
+func (d *digestAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := d.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	challenge := parseDigestChallenge(resp.Header.Get("WWW-Authenticate"))
+	if challenge == nil || (req.Body != nil && req.GetBody == nil) {
+		return resp, nil
+	}
+	authorization, err := challenge.authorize(d.username, d.password, req.Method, req.URL.RequestURI())
+	if err != nil {
+		return resp, nil
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	retry.Header.Set("Authorization", authorization)
+	return base.RoundTrip(retry)
+}
+
+type digestChallenge map[string]string
+
+// parseDigestChallenge parses WWW-Authenticate header, e.g. Digest realm="x", qop="auth,auth-int", nonce="y"
+func parseDigestChallenge(header string) digestChallenge {
+	scheme, params, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, "digest") {
+		return nil
+	}
+	challenge := make(digestChallenge)
+	for params != "" {
+		var name, value string
+		name, params, _ = strings.Cut(strings.TrimLeft(params, " ,"), "=")
+		if strings.HasPrefix(params, `"`) {
+			end := strings.Index(params[1:], `"`)
+			if end < 0 {
+				return nil
+			}
+			value, params = params[1:end+1], params[end+2:]
+		} else {
+			value, params, _ = strings.Cut(params, ",")
+		}
+		challenge[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(value)
+	}
+	if challenge["nonce"] == "" {
+		return nil
+	}
+	return challenge
+}
+
+func (c digestChallenge) authorize(username, password, method, uri string) (string, error) {
+	algorithm := c["algorithm"]
+	if algorithm == "" {
+		algorithm = "MD5"
+	}
+	var newHash func() hash.Hash
+	switch strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS") {
+	case "MD5":
+		newHash = md5.New
+	case "SHA-256":
+		newHash = sha256.New
+	default:
+		return "", errors.Errorf("unsupported digest algorithm %s", algorithm)
+	}
+	h := func(data string) string {
+		hasher := newHash()
+		hasher.Write([]byte(data))
+		return hex.EncodeToString(hasher.Sum(nil))
+	}
+
+	cnonceBytes := make([]byte, 8)
+	_, _ = rand.Read(cnonceBytes)
+	cnonce := hex.EncodeToString(cnonceBytes)
+	nc := "00000001"
+
+	ha1 := h(username + ":" + c["realm"] + ":" + password)
+	if strings.HasSuffix(strings.ToUpper(algorithm), "-SESS") {
+		ha1 = h(ha1 + ":" + c["nonce"] + ":" + cnonce)
+	}
+	ha2 := h(method + ":" + uri)
+
+	var qop string
+	for _, q := range strings.Split(c["qop"], ",") {
+		if strings.TrimSpace(q) == "auth" {
+			qop = "auth"
+		}
+	}
+	var response string
+	if qop != "" {
+		response = h(strings.Join([]string{ha1, c["nonce"], nc, cnonce, qop, ha2}, ":"))
+	} else {
+		response = h(ha1 + ":" + c["nonce"] + ":" + ha2)
+	}
+
+	authorization := fmt.Sprintf(`Digest username="%s", realm="%s", nonce="%s", uri="%s", algorithm=%s, response="%s"`,
+		username, c["realm"], c["nonce"], uri, algorithm, response)
+	if qop != "" {
+		authorization += fmt.Sprintf(`, qop=%s, nc=%s, cnonce="%s"`, qop, nc, cnonce)
+	}
+	if c["opaque"] != "" {
+		authorization += fmt.Sprintf(`, opaque="%s"`, c["opaque"])
+	}
+	return authorization, nil
+}
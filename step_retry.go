@@ -0,0 +1,279 @@
+package hrp
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/httprunner/httprunner/v5/code"
+)
+
+type RetryBackoff string
+
+const (
+	RetryBackoffFixed       RetryBackoff = "fixed"
+	RetryBackoffExponential RetryBackoff = "exponential"
+)
+
+const defaultRetryInterval float64 = 1 // seconds
+
+// StepRetry defines the retry policy of teststep.
+// without any retry conditions, step is retried whenever it fails;
+// otherwise step is retried only when one of the conditions is met.
+type StepRetry struct {
+	MaxAttempts     int           `json:"max_attempts" yaml:"max_attempts"`                           // max attempts, including the first run
+	Interval        float64       `json:"interval,omitempty" yaml:"interval,omitempty"`               // interval between attempts in seconds, default 1s
+	Backoff         RetryBackoff  `json:"backoff,omitempty" yaml:"backoff,omitempty"`                 // fixed(default) or exponential
+	MaxInterval     float64       `json:"max_interval,omitempty" yaml:"max_interval,omitempty"`       // max interval in seconds for exponential backoff
+	Jitter          float64       `json:"jitter,omitempty" yaml:"jitter,omitempty"`                   // random ratio in [0, 1] added to interval
+	OnStatusCodes   []int         `json:"on_status_codes,omitempty" yaml:"on_status_codes,omitempty"` // retry when response status code is in list
+	OnValidators    []string      `json:"on_validators,omitempty" yaml:"on_validators,omitempty"`     // retry when validators with these check expressions fail
+	UntilValidators []interface{} `json:"until,omitempty" yaml:"until,omitempty"`                     // retry until all these validators pass, used for polling
+}
+
+// NewStepRetry returns a retry policy which runs step at most maxAttempts times.
+func NewStepRetry(maxAttempts int) *StepRetry {
+	return &StepRetry{
+		MaxAttempts: maxAttempts,
+	}
+}
+
+// WithInterval sets the interval between attempts.
+func (r *StepRetry) WithInterval(interval time.Duration) *StepRetry {
+	r.Interval = interval.Seconds()
+	return r
+}
+
+// WithExponentialBackoff doubles the interval after each attempt, limited by maxInterval.
+func (r *StepRetry) WithExponentialBackoff(maxInterval time.Duration) *StepRetry {
+	r.Backoff = RetryBackoffExponential
+	r.MaxInterval = maxInterval.Seconds()
+	return r
+}
+
+// WithJitter adds a random duration of [0, ratio*interval) to each interval.
+func (r *StepRetry) WithJitter(ratio float64) *StepRetry {
+	r.Jitter = ratio
+	return r
+}
+
+// WhenStatusCodes retries step when response status code is one of codes.
+func (r *StepRetry) WhenStatusCodes(codes ...int) *StepRetry {
+	r.OnStatusCodes = append(r.OnStatusCodes, codes...)
+	return r
+}
+
+// WhenValidatorsFail retries step when validators with the given check expressions fail.
+func (r *StepRetry) WhenValidatorsFail(checks ...string) *StepRetry {
+	r.OnValidators = append(r.OnValidators, checks...)
+	return r
+}
+
+// Until retries step until the validator passes, check could be jmespath or extracted variable.
+func (r *StepRetry) Until(check, assert string, expected interface{}, msg ...string) *StepRetry {
+	v := Validator{
+		Check:  check,
+		Assert: assert,
+		Expect: expected,
+	}
+	if len(msg) > 0 {
+		v.Message = msg[0]
+	}
+	r.UntilValidators = append(r.UntilValidators, v)
+	return r
+}
+
+// UntilEqual retries step until the check value equals expected value.
+func (r *StepRetry) UntilEqual(check string, expected interface{}, msg ...string) *StepRetry {
+	return r.Until(check, "equal", expected, msg...)
+}
+
+func (r *StepRetry) hasConditions() bool {
+	return len(r.OnStatusCodes) > 0 || len(r.OnValidators) > 0 || len(r.UntilValidators) > 0
+}
+
+// interval returns the waiting duration before the next attempt.
+func (r *StepRetry) interval(attempt int) time.Duration {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = defaultRetryInterval
+	}
+	if r.Backoff == RetryBackoffExponential {
+		interval = interval * math.Pow(2, float64(attempt-1))
+		if r.MaxInterval > 0 && interval > r.MaxInterval {
+			interval = r.MaxInterval
+		}
+	}
+	if r.Jitter > 0 {
+		interval += interval * r.Jitter * rand.Float64()
+	}
+	return time.Duration(interval*1000) * time.Millisecond
+}
+
+// retryReason checks whether the step should be retried, returns empty string if not.
+func (r *StepRetry) retryReason(stepResult *StepResult, err error) string {
+	if !r.hasConditions() {
+		if err != nil {
+			return err.Error()
+		}
+		if stepResult != nil && !stepResult.Success {
+			return "step failed"
+		}
+		return ""
+	}
+
+	sessionData, ok := stepResult.getSessionData()
+	if !ok {
+		return ""
+	}
+
+	if len(r.OnStatusCodes) > 0 && sessionData.ReqResps != nil {
+		if resp, ok := sessionData.ReqResps.Response.(map[string]interface{}); ok {
+			statusCode := fmt.Sprintf("%v", resp["status_code"])
+			for _, c := range r.OnStatusCodes {
+				if statusCode == fmt.Sprintf("%d", c) {
+					return fmt.Sprintf("status code %s", statusCode)
+				}
+			}
+		}
+	}
+
+	checks := append([]string{}, r.OnValidators...)
+	for _, iValidator := range r.UntilValidators {
+		if validator, ok := iValidator.(Validator); ok {
+			checks = append(checks, validator.Check)
+		}
+	}
+	for _, result := range sessionData.Validators {
+		if result.CheckResult != "fail" {
+			continue
+		}
+		for _, check := range checks {
+			if result.Check == check {
+				return fmt.Sprintf("validator %s %s %v failed, got %v",
+					result.Check, result.Assert, result.Expect, result.CheckValue)
+			}
+		}
+	}
+	return ""
+}
+
+func (s *StepResult) getSessionData() (*SessionData, bool) {
+	if s == nil {
+		return nil, false
+	}
+	sessionData, ok := s.Data.(*SessionData)
+	return sessionData, ok && sessionData != nil
+}
+
+// StepAttempt records one attempt of the step running with retry policy.
+type StepAttempt struct {
+	Attempt     int    `json:"attempt" yaml:"attempt"`                               // attempt number, starts from 1
+	StartTime   int64  `json:"start_time" yaml:"start_time"`                         // attempt start time in millisecond(ms)
+	Elapsed     int64  `json:"elapsed_ms" yaml:"elapsed_ms"`                         // attempt execution time in millisecond(ms)
+	Success     bool   `json:"success" yaml:"success"`                               // attempt execution result
+	Error       string `json:"error,omitempty" yaml:"error,omitempty"`               // error message of failed attempt
+	RetryReason string `json:"retry_reason,omitempty" yaml:"retry_reason,omitempty"` // reason for running next attempt
+}
+
+// executeStepWithRetry executes step with retry policy in step config,
+// every attempt is recorded in the step result of the last attempt.
+func (r *SessionRunner) executeStepWithRetry(step IStep, stepName string, parameters map[string]interface{}) (stepResult *StepResult, err error) {
+	retry := step.Config().Retry
+	if retry == nil || retry.MaxAttempts <= 1 {
+		return r.executeStepWithVariables(step, stepName, parameters)
+	}
+
+	// until validators are checked ahead of step validators
+	stepConfig := step.Config()
+	originalValidators := stepConfig.Validators
+	if len(retry.UntilValidators) > 0 {
+		validators := make([]interface{}, 0, len(retry.UntilValidators)+len(originalValidators))
+		for _, iValidator := range retry.UntilValidators {
+			validator, ok := iValidator.(Validator)
+			if !ok {
+				return nil, errors.Wrap(code.InvalidCaseError, "retry until validator type error")
+			}
+			validator.Expect, err = r.caseRunner.parser.Parse(validator.Expect, stepConfig.Variables)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to parse retry until validator expect")
+			}
+			validators = append(validators, validator)
+		}
+		stepConfig.Validators = append(validators, originalValidators...)
+		defer func() {
+			stepConfig.Validators = originalValidators
+		}()
+	}
+
+	var attempts []*StepAttempt
+	defer func() {
+		r.retrying = false
+		if stepResult != nil {
+			stepResult.Attempts = attempts
+		}
+	}()
+
+	for attempt := 1; ; attempt++ {
+		// assertion failures of attempts to be retried should not fail the test
+		r.retrying = attempt < retry.MaxAttempts
+
+		start := time.Now()
+		stepResult, err = r.executeStepWithVariables(step, stepName, parameters)
+		stepAttempt := &StepAttempt{
+			Attempt:   attempt,
+			StartTime: start.UnixMilli(),
+			Elapsed:   time.Since(start).Milliseconds(),
+			Success:   err == nil && stepResult != nil && stepResult.Success,
+		}
+		if err != nil {
+			stepAttempt.Error = err.Error()
+		}
+		attempts = append(attempts, stepAttempt)
+
+		reason := retry.retryReason(stepResult, err)
+		if reason == "" {
+			return stepResult, err
+		}
+		if attempt >= retry.MaxAttempts {
+			log.Error().Str("step", stepName).Int("attempts", attempt).
+				Str("reason", reason).Msg("step retry exhausted")
+			if stepResult != nil {
+				stepResult.Success = false
+			}
+			return stepResult, errors.Wrapf(code.MaxRetryError,
+				"step still failed after %d attempts: %s", attempt, reason)
+		}
+
+		stepAttempt.RetryReason = reason
+		interval := retry.interval(attempt)
+		log.Warn().Str("step", stepName).Int("attempt", attempt).
+			Str("reason", reason).Dur("interval", interval).Msg("retry step")
+
+		// wait before next attempt, abort when interrupted or cancelled
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+		case <-r.caseRunner.hrpRunner.interruptSignal:
+			timer.Stop()
+			return stepResult, errors.Wrap(code.InterruptError, "step retry interrupted")
+		case <-r.ctx.Done():
+			timer.Stop()
+			return stepResult, errors.Wrap(code.InterruptError, "step retry cancelled")
+		}
+	}
+}
+
+// testingT returns testing.T used in assertions,
+// assertion failures of the attempts to be retried are not reported.
+func (r *SessionRunner) testingT() *testing.T {
+	if r.retrying {
+		return &testing.T{}
+	}
+	return r.caseRunner.hrpRunner.t
+}
@@ -1,11 +1,13 @@
 package hrp
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/exec"
+	"runtime"
 	"time"
 
-	"github.com/httprunner/funplugin/myexec"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 )
@@ -101,12 +103,15 @@ func runStepShell(r *SessionRunner, step IStep) (stepResult *StepResult, err err
 		stepResult.Elapsed = time.Since(start).Milliseconds()
 	}()
 
+	// pass config variables to shell command as environment variables,
+	// os.Setenv is not used since sessions may run concurrently
 	vars := r.caseRunner.Config.Get().Variables
+	env := make([]string, 0, len(vars))
 	for key, value := range vars {
-		os.Setenv(key, fmt.Sprintf("%v", value))
+		env = append(env, fmt.Sprintf("%s=%v", key, value))
 	}
 
-	exitCode, err := myexec.RunShell(shell.String)
+	exitCode, err := runShell(r.ctx, shell.String, env)
 	if err != nil {
 		if exitCode == shell.ExpectExitCode {
 			// get expected error
@@ -131,3 +136,27 @@ func runStepShell(r *SessionRunner, step IStep) (stepResult *StepResult, err err
 	stepResult.Success = true
 	return stepResult, nil
 }
+
+// runShell executes shell string with extra environment variables and returns exit code
+func runShell(ctx context.Context, shellString string, env []string) (exitCode int, err error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", shellString)
+	} else {
+		cmd = exec.CommandContext(ctx, "bash", "-c", shellString)
+	}
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	log.Info().Str("content", cmd.String()).Msg("exec shell string")
+
+	err = cmd.Run()
+	if err != nil {
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok {
+			return 1, errors.Wrap(err, "start running command failed")
+		}
+		return exitErr.ExitCode(), err
+	}
+	return 0, nil
+}
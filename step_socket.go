@@ -0,0 +1,615 @@
+package hrp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/httprunner/httprunner/v5/internal/builtin"
+	"github.com/httprunner/httprunner/v5/internal/json"
+)
+
+type SocketActionType string
+
+const (
+	socketOpen         SocketActionType = "open"
+	socketWriteAndRead SocketActionType = "wr"
+	socketRead         SocketActionType = "r"
+	socketWrite        SocketActionType = "w"
+	socketClose        SocketActionType = "close"
+)
+
+// SocketAction is an action on raw tcp or udp socket connection, connections are kept in session
+// and identified by network and address.
+type SocketAction struct {
+	Type      SocketActionType `json:"type" yaml:"type"`
+	URL       string           `json:"url" yaml:"url"`                                   // remote address, e.g. localhost:9000
+	Text      interface{}      `json:"text,omitempty" yaml:"text,omitempty"`             // text payload, non-string is encoded as json
+	Hex       string           `json:"hex,omitempty" yaml:"hex,omitempty"`               // hex payload, whitespaces are ignored
+	Base64    string           `json:"base64,omitempty" yaml:"base64,omitempty"`         // base64 payload
+	Binary    []*BinaryField   `json:"binary,omitempty" yaml:"binary,omitempty"`         // payload encoded with struct-like template
+	Decode    []*BinaryField   `json:"decode,omitempty" yaml:"decode,omitempty"`         // template to decode read frame into body
+	ByteOrder string           `json:"byte_order,omitempty" yaml:"byte_order,omitempty"` // byte order of binary templates, big(default) or little
+	Framing   *SocketFraming   `json:"framing,omitempty" yaml:"framing,omitempty"`       // framing set when opening is used by the connection by default
+	Timeout   int64            `json:"timeout,omitempty" yaml:"timeout,omitempty"`       // timeout in milliseconds for each action
+}
+
+func (a *SocketAction) GetTimeout() int64 {
+	if a.Timeout <= 0 {
+		return defaultTimeout
+	}
+	return a.Timeout
+}
+
+// payload encodes message to write, check priority: text > hex > base64 > binary
+func (a *SocketAction) payload(parser *Parser, variables map[string]interface{}) ([]byte, error) {
+	switch {
+	case a.Text != nil:
+		parsedText, err := parser.Parse(a.Text, variables)
+		if err != nil {
+			return nil, err
+		}
+		if text, ok := parsedText.(string); ok {
+			return []byte(text), nil
+		}
+		return json.Marshal(parsedText)
+	case a.Hex != "":
+		parsedHex, err := parser.ParseString(a.Hex, variables)
+		if err != nil {
+			return nil, err
+		}
+		return decodeHex(convertString(parsedHex))
+	case a.Base64 != "":
+		parsedBase64, err := parser.ParseString(a.Base64, variables)
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.DecodeString(convertString(parsedBase64))
+	case len(a.Binary) > 0:
+		return encodeBinary(a.Binary, byteOrder(a.ByteOrder), parser, variables)
+	default:
+		return []byte{}, nil
+	}
+}
+
+// StepSocket implements IStep interface.
+type StepSocket struct {
+	StepConfig
+	TCP *SocketAction `json:"tcp,omitempty" yaml:"tcp,omitempty"`
+	UDP *SocketAction `json:"udp,omitempty" yaml:"udp,omitempty"`
+}
+
+// network returns network and action of step
+func (s *StepSocket) network() (string, *SocketAction) {
+	if s.UDP != nil {
+		return "udp", s.UDP
+	}
+	return "tcp", s.TCP
+}
+
+func (s *StepSocket) action() *SocketAction {
+	_, action := s.network()
+	return action
+}
+
+func (s *StepSocket) Name() string {
+	if s.StepName != "" {
+		return s.StepName
+	}
+	network, action := s.network()
+	return fmt.Sprintf("%s %s %s", network, action.Type, action.URL)
+}
+
+func (s *StepSocket) Type() StepType {
+	if s.UDP != nil {
+		return StepTypeUDP
+	}
+	return StepTypeTCP
+}
+
+func (s *StepSocket) Config() *StepConfig {
+	return &s.StepConfig
+}
+
+func (s *StepSocket) Run(r *SessionRunner) (*StepResult, error) {
+	return runStepSocket(r, s)
+}
+
+func (s *StepSocket) withAction(actionType SocketActionType, addr string) *StepSocket {
+	action := s.action()
+	action.Type = actionType
+	action.URL = addr
+	return s
+}
+
+// OpenConnection connects to remote address, e.g. localhost:9000
+func (s *StepSocket) OpenConnection(addr string) *StepSocket {
+	return s.withAction(socketOpen, addr)
+}
+
+func (s *StepSocket) WriteAndRead(addr string) *StepSocket {
+	return s.withAction(socketWriteAndRead, addr)
+}
+
+func (s *StepSocket) Read(addr string) *StepSocket {
+	return s.withAction(socketRead, addr)
+}
+
+func (s *StepSocket) Write(addr string) *StepSocket {
+	return s.withAction(socketWrite, addr)
+}
+
+func (s *StepSocket) CloseConnection(addr string) *StepSocket {
+	return s.withAction(socketClose, addr)
+}
+
+// WithText sets text payload, non-string message is encoded as json.
+func (s *StepSocket) WithText(message interface{}) *StepSocket {
+	s.action().Text = message
+	return s
+}
+
+// WithHex sets payload in hex, e.g. "01 02 ff".
+func (s *StepSocket) WithHex(message string) *StepSocket {
+	s.action().Hex = message
+	return s
+}
+
+// WithBase64 sets payload in base64.
+func (s *StepSocket) WithBase64(message string) *StepSocket {
+	s.action().Base64 = message
+	return s
+}
+
+// WithBinary sets payload encoded with struct-like template.
+func (s *StepSocket) WithBinary(fields ...*BinaryField) *StepSocket {
+	s.action().Binary = fields
+	return s
+}
+
+// DecodeWith decodes read frame into body with struct-like template.
+func (s *StepSocket) DecodeWith(fields ...*BinaryField) *StepSocket {
+	s.action().Decode = fields
+	return s
+}
+
+// WithLittleEndian uses little endian for binary templates, default to big endian.
+func (s *StepSocket) WithLittleEndian() *StepSocket {
+	s.action().ByteOrder = byteOrderLittle
+	return s
+}
+
+func (s *StepSocket) framing() *SocketFraming {
+	action := s.action()
+	if action.Framing == nil {
+		action.Framing = &SocketFraming{}
+	}
+	return action.Framing
+}
+
+// WithDelimiter splits frames by delimiter, e.g. "\n".
+func (s *StepSocket) WithDelimiter(delimiter string) *StepSocket {
+	s.framing().Delimiter = delimiter
+	return s
+}
+
+// WithLengthPrefix prefixes frames with payload length in 1, 2, 4 or 8 bytes.
+func (s *StepSocket) WithLengthPrefix(size int) *StepSocket {
+	s.framing().LengthPrefix = size
+	return s
+}
+
+// WithFixedSize reads and writes frames in fixed size.
+func (s *StepSocket) WithFixedSize(size int) *StepSocket {
+	s.framing().FixedSize = size
+	return s
+}
+
+// WithMaxFrameSize limits payload size of frames to read, default 16 MiB.
+func (s *StepSocket) WithMaxFrameSize(size int) *StepSocket {
+	s.framing().MaxSize = size
+	return s
+}
+
+func (s *StepSocket) WithTimeout(timeout int64) *StepSocket {
+	s.action().Timeout = timeout
+	return s
+}
+
+// Validate switches to step validation.
+func (s *StepSocket) Validate() *StepSocketValidation {
+	return &StepSocketValidation{
+		StepSocket: s,
+	}
+}
+
+// Extract switches to step extraction.
+func (s *StepSocket) Extract() *StepSocketExtraction {
+	s.StepConfig.Extract = make(map[string]string)
+	return &StepSocketExtraction{
+		StepSocket: s,
+	}
+}
+
+// StepSocketExtraction implements IStep interface.
+type StepSocketExtraction struct {
+	*StepSocket
+}
+
+// WithJmesPath sets the JMESPath expression to extract from the response.
+func (s *StepSocketExtraction) WithJmesPath(jmesPath string, varName string) *StepSocketExtraction {
+	s.StepConfig.Extract[varName] = jmesPath
+	return s
+}
+
+// Validate switches to step validation.
+func (s *StepSocketExtraction) Validate() *StepSocketValidation {
+	return &StepSocketValidation{
+		StepSocket: s.StepSocket,
+	}
+}
+
+func (s *StepSocketExtraction) Type() StepType {
+	return s.StepSocket.Type() + stepTypeSuffixExtraction
+}
+
+func (s *StepSocketExtraction) Run(r *SessionRunner) (*StepResult, error) {
+	if s.TCP != nil || s.UDP != nil {
+		return runStepSocket(r, s.StepSocket)
+	}
+	return nil, errors.New("unexpected protocol type")
+}
+
+// StepSocketValidation implements IStep interface.
+type StepSocketValidation struct {
+	*StepSocket
+}
+
+func (s *StepSocketValidation) Type() StepType {
+	return s.StepSocket.Type() + stepTypeSuffixValidation
+}
+
+func (s *StepSocketValidation) Run(r *SessionRunner) (*StepResult, error) {
+	if s.TCP != nil || s.UDP != nil {
+		return runStepSocket(r, s.StepSocket)
+	}
+	return nil, errors.New("unexpected protocol type")
+}
+
+func (s *StepSocketValidation) AssertEqual(jmesPath string, expected interface{}, msg string) *StepSocketValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  "equals",
+		Expect:  expected,
+		Message: msg,
+	}
+	s.Validators = append(s.Validators, v)
+	return s
+}
+
+func (s *StepSocketValidation) AssertContains(jmesPath string, expected interface{}, msg string) *StepSocketValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  "contains",
+		Expect:  expected,
+		Message: msg,
+	}
+	s.Validators = append(s.Validators, v)
+	return s
+}
+
+func (s *StepSocketValidation) AssertStartsWith(jmesPath string, expected interface{}, msg string) *StepSocketValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  "startswith",
+		Expect:  expected,
+		Message: msg,
+	}
+	s.Validators = append(s.Validators, v)
+	return s
+}
+
+func (s *StepSocketValidation) AssertLengthEqual(jmesPath string, expected interface{}, msg string) *StepSocketValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  "length_equals",
+		Expect:  expected,
+		Message: msg,
+	}
+	s.Validators = append(s.Validators, v)
+	return s
+}
+
+// AssertFrameHex checks read frame in hex, whitespaces and case of expected hex are ignored, e.g. "CA FE 01".
+func (s *StepSocketValidation) AssertFrameHex(expected string, msg string) *StepSocketValidation {
+	return s.AssertEqual("frame_hex", normalizeHex(expected), msg)
+}
+
+// socketFieldTags are searched only on socket frame, besides the common fieldTags
+var socketFieldTags = []string{"frame_hex", "frame_base64", "frame_size", "elapsed_ms"}
+
+type socketRespObjMeta struct {
+	Body        interface{} `json:"body"`         // decoded fields with template, json or text, hex for other binary
+	FrameHex    string      `json:"frame_hex"`    // frame payload in hex, without length prefix or delimiter
+	FrameBase64 string      `json:"frame_base64"` // frame payload in base64
+	FrameSize   int         `json:"frame_size"`
+	ElapsedMs   int64       `json:"elapsed_ms"` // from starting action to reading whole frame
+}
+
+func newSocketSession() *socketSession {
+	return &socketSession{
+		conns: make(map[string]*socketConn),
+	}
+}
+
+type socketSession struct {
+	conns map[string]*socketConn // connections by network and address, e.g. tcp://localhost:9000
+}
+
+type socketConn struct {
+	net.Conn
+	network string
+	reader  *bufio.Reader
+	framing *SocketFraming // default framing set when opening
+}
+
+func (s *socketSession) open(network, addr string, framing *SocketFraming, timeout time.Duration) (*socketConn, error) {
+	conn, err := net.DialTimeout(network, addr, timeout)
+	if err != nil {
+		return nil, errors.Wrapf(err, "dial %s failed", network)
+	}
+	c := &socketConn{
+		Conn:    conn,
+		network: network,
+		reader:  bufio.NewReader(conn),
+		framing: framing,
+	}
+	s.conns[network+"://"+addr] = c
+	return c, nil
+}
+
+func (s *socketSession) get(network, addr string) (*socketConn, error) {
+	conn, ok := s.conns[network+"://"+addr]
+	if !ok {
+		return nil, errors.New("try to use existing connection, but there is no connection")
+	}
+	return conn, nil
+}
+
+func (s *socketSession) closeConn(network, addr string) error {
+	key := network + "://" + addr
+	conn, ok := s.conns[key]
+	if !ok {
+		return errors.New("no connection needs to be closed")
+	}
+	delete(s.conns, key)
+	return conn.Close()
+}
+
+func (s *socketSession) close() {
+	for key, conn := range s.conns {
+		if err := conn.Close(); err != nil {
+			log.Error().Err(err).Str("conn", key).Msg("close socket connection failed")
+		}
+	}
+	s.conns = make(map[string]*socketConn)
+}
+
+func (c *socketConn) write(payload []byte, framing *SocketFraming, timeout time.Duration) error {
+	if framing == nil {
+		framing = c.framing
+	}
+	frame, err := framing.encode(payload)
+	if err != nil {
+		return err
+	}
+	if err := c.SetWriteDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+	_, err = c.Write(frame)
+	return err
+}
+
+func (c *socketConn) read(framing *SocketFraming, timeout time.Duration) ([]byte, error) {
+	if framing == nil {
+		framing = c.framing
+	}
+	if err := c.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+	if c.network != "udp" {
+		return framing.read(c.reader)
+	}
+	// each read returns one datagram, frame is parsed within the datagram
+	buf := make([]byte, 65535)
+	n, err := c.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	if framing.isEmpty() {
+		return buf[:n], nil
+	}
+	return framing.read(bufio.NewReader(bytes.NewReader(buf[:n])))
+}
+
+func runStepSocket(r *SessionRunner, step *StepSocket) (stepResult *StepResult, err error) {
+	network, action := step.network()
+	if action == nil {
+		return nil, errors.New("unexpected protocol type")
+	}
+	variables := step.Variables
+	start := time.Now()
+	stepResult = &StepResult{
+		Name:        step.Name(),
+		StepType:    step.Type(),
+		Success:     false,
+		ContentSize: 0,
+		StartTime:   start.UnixMilli(),
+	}
+	defer func() {
+		if err != nil {
+			stepResult.Attachments = err.Error()
+		}
+		stepResult.Elapsed = time.Since(start).Milliseconds()
+	}()
+
+	parser := r.caseRunner.parser
+	parsedAddr, err := parser.ParseString(action.URL, variables)
+	if err != nil {
+		return stepResult, errors.Wrap(err, "parse socket url failed")
+	}
+	addr := convertString(parsedAddr)
+	requestMap := map[string]interface{}{
+		"network": network,
+		"url":     addr,
+		"type":    action.Type,
+	}
+	var payload []byte
+	if action.Type == socketWrite || action.Type == socketWriteAndRead {
+		payload, err = action.payload(parser, variables)
+		if err != nil {
+			return stepResult, errors.Wrap(err, "encode socket payload failed")
+		}
+		requestMap["hex"] = hex.EncodeToString(payload)
+	}
+	variables["hrp_step_name"] = step.Name()
+	variables["hrp_step_request"] = requestMap
+
+	// deal with setup hooks
+	for _, setupHook := range step.SetupHooks {
+		_, err = parser.Parse(setupHook, variables)
+		if err != nil {
+			return stepResult, errors.Wrap(err, "run setup hooks failed")
+		}
+	}
+
+	if r.caseRunner.hrpRunner.requestsLogOn {
+		fmt.Printf("-------------------- %s action: %v %s --------------------\n", network, action.Type, addr)
+	}
+	timeout := time.Duration(action.GetTimeout()) * time.Millisecond
+	var frame []byte
+	switch action.Type {
+	case socketOpen:
+		log.Info().Str("network", network).Str("url", addr).Msg("open socket connection")
+		// use the current connection if existed
+		if _, e := r.socket.get(network, addr); e == nil {
+			break
+		}
+		if _, err = r.socket.open(network, addr, action.Framing, timeout); err != nil {
+			return stepResult, errors.Wrap(err, "open connection failed")
+		}
+	case socketWrite, socketWriteAndRead, socketRead:
+		var conn *socketConn
+		conn, err = r.socket.get(network, addr)
+		if err != nil {
+			return stepResult, err
+		}
+		if action.Type != socketRead {
+			log.Info().Str("network", network).Str("url", addr).Int("size", len(payload)).Msg("write socket frame")
+			if err = conn.write(payload, action.Framing, timeout); err != nil {
+				return stepResult, errors.Wrap(err, "write frame failed")
+			}
+		}
+		if action.Type != socketWrite {
+			log.Info().Str("network", network).Str("url", addr).Msg("read socket frame")
+			frame, err = conn.read(action.Framing, timeout)
+			if err != nil {
+				return stepResult, errors.Wrap(err, "read frame failed")
+			}
+		}
+	case socketClose:
+		log.Info().Str("network", network).Str("url", addr).Msg("close socket connection")
+		if err = r.socket.closeConn(network, addr); err != nil {
+			return stepResult, errors.Wrap(err, "close connection failed")
+		}
+	default:
+		return stepResult, errors.Errorf("unexpected socket action type: %v", action.Type)
+	}
+
+	var respObj *responseObject
+	if frame != nil {
+		respObjMeta := &socketRespObjMeta{
+			FrameHex:    hex.EncodeToString(frame),
+			FrameBase64: base64.StdEncoding.EncodeToString(frame),
+			FrameSize:   len(frame),
+			ElapsedMs:   time.Since(start).Milliseconds(),
+		}
+		respObjMeta.Body, err = decodeSocketFrame(frame, action)
+		if err != nil {
+			return stepResult, errors.Wrap(err, "decode frame failed")
+		}
+		if r.caseRunner.hrpRunner.requestsLogOn {
+			respBytes, _ := json.MarshalIndent(respObjMeta, "", "    ")
+			fmt.Println(string(respBytes))
+		}
+		respObj, err = convertToResponseObject(r.testingT(), parser, respObjMeta)
+		if err != nil {
+			err = errors.Wrap(err, "init ResponseObject error")
+			return
+		}
+		respObj.casePath = r.caseRunner.Config.Get().Path
+		respObj.fieldTags = socketFieldTags
+		variables["hrp_step_response"] = respObj.respObjMeta
+	}
+
+	// deal with teardown hooks
+	for _, teardownHook := range step.TeardownHooks {
+		_, err = parser.Parse(teardownHook, variables)
+		if err != nil {
+			return stepResult, errors.Wrap(err, "run teardown hooks failed")
+		}
+	}
+
+	if respObj == nil {
+		stepResult.Success = true
+		return stepResult, nil
+	}
+
+	sessionData := &SessionData{
+		ReqResps: &ReqResps{
+			Request:  requestMap,
+			Response: builtin.FormatResponse(respObj.respObjMeta),
+		},
+	}
+	stepResult.Data = sessionData
+	stepResult.ContentSize = int64(len(frame))
+
+	// extract variables from response
+	extractMapping := respObj.Extract(step.StepConfig.Extract, variables)
+	stepResult.ExportVars = extractMapping
+
+	// override step variables with extracted variables
+	variables = mergeVariables(variables, extractMapping)
+
+	// validate response
+	err = respObj.Validate(step.Validators, variables, r.validateMode(&step.StepConfig))
+	sessionData.Validators = respObj.validationResults
+	if err == nil {
+		stepResult.Success = true
+	}
+	return stepResult, err
+}
+
+// decodeSocketFrame decodes frame with template, or as json or text, other binary frame is kept in hex
+func decodeSocketFrame(frame []byte, action *SocketAction) (interface{}, error) {
+	if len(action.Decode) > 0 {
+		return decodeBinary(frame, action.Decode, byteOrder(action.ByteOrder))
+	}
+	if !utf8.Valid(frame) {
+		return hex.EncodeToString(frame), nil
+	}
+	var body interface{}
+	if err := json.Unmarshal(frame, &body); err != nil {
+		// frame is not json, use text
+		return string(frame), nil
+	}
+	return body, nil
+}
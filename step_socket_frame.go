@@ -0,0 +1,357 @@
+package hrp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	byteOrderBig    = "big"
+	byteOrderLittle = "little"
+)
+
+const defaultSocketMaxFrameSize = 16 << 20 // 16 MiB
+
+func byteOrder(order string) binary.ByteOrder {
+	if strings.EqualFold(order, byteOrderLittle) {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}
+
+// SocketFraming splits byte stream into frames, check priority: length prefix > fixed size > delimiter,
+// frame is read as whatever arrives if no framing is specified, i.e. one datagram for udp.
+type SocketFraming struct {
+	Delimiter    string `json:"delimiter,omitempty" yaml:"delimiter,omitempty"`         // frame ends with delimiter, e.g. "\n"
+	LengthPrefix int    `json:"length_prefix,omitempty" yaml:"length_prefix,omitempty"` // frame starts with payload length in 1, 2, 4 or 8 bytes
+	FixedSize    int    `json:"fixed_size,omitempty" yaml:"fixed_size,omitempty"`       // frame has fixed size, payload is padded with zeros
+	ByteOrder    string `json:"byte_order,omitempty" yaml:"byte_order,omitempty"`       // byte order of length prefix, big(default) or little
+	MaxSize      int    `json:"max_size,omitempty" yaml:"max_size,omitempty"`           // max payload size of frames to read, default 16 MiB
+}
+
+func (f *SocketFraming) isEmpty() bool {
+	return f == nil || (f.LengthPrefix <= 0 && f.FixedSize <= 0 && f.Delimiter == "")
+}
+
+func (f *SocketFraming) maxSize() int {
+	if f == nil || f.MaxSize <= 0 {
+		return defaultSocketMaxFrameSize
+	}
+	return f.MaxSize
+}
+
+// encode builds frame of payload to write
+func (f *SocketFraming) encode(payload []byte) ([]byte, error) {
+	switch {
+	case f.isEmpty():
+		return payload, nil
+	case f.LengthPrefix > 0:
+		prefix, err := f.putLength(len(payload))
+		if err != nil {
+			return nil, err
+		}
+		return append(prefix, payload...), nil
+	case f.FixedSize > 0:
+		if len(payload) > f.FixedSize {
+			return nil, errors.Errorf("payload size %d exceeds fixed frame size %d", len(payload), f.FixedSize)
+		}
+		frame := make([]byte, f.FixedSize)
+		copy(frame, payload)
+		return frame, nil
+	default:
+		return append(payload, f.Delimiter...), nil
+	}
+}
+
+// read reads one frame and returns its payload without length prefix or delimiter
+func (f *SocketFraming) read(r *bufio.Reader) ([]byte, error) {
+	switch {
+	case f.isEmpty():
+		buf := make([]byte, 65535)
+		n, err := r.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case f.LengthPrefix > 0:
+		prefix := make([]byte, f.LengthPrefix)
+		if _, err := io.ReadFull(r, prefix); err != nil {
+			return nil, err
+		}
+		length, err := f.getLength(prefix)
+		if err != nil {
+			return nil, err
+		}
+		// length is read from wire, reject it before allocating
+		if length > uint64(f.maxSize()) {
+			return nil, errors.Errorf("frame size %d exceeds max size %d", length, f.maxSize())
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return nil, err
+		}
+		return payload, nil
+	case f.FixedSize > 0:
+		frame := make([]byte, f.FixedSize)
+		if _, err := io.ReadFull(r, frame); err != nil {
+			return nil, err
+		}
+		return frame, nil
+	default:
+		delimiter := []byte(f.Delimiter)
+		var frame []byte
+		for {
+			b, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			frame = append(frame, b)
+			if bytes.HasSuffix(frame, delimiter) {
+				return frame[:len(frame)-len(delimiter)], nil
+			}
+			if len(frame) >= f.maxSize()+len(delimiter) {
+				return nil, errors.Errorf("frame size exceeds max size %d before delimiter", f.maxSize())
+			}
+		}
+	}
+}
+
+func (f *SocketFraming) putLength(length int) ([]byte, error) {
+	order := byteOrder(f.ByteOrder)
+	prefix := make([]byte, f.LengthPrefix)
+	switch f.LengthPrefix {
+	case 1:
+		if length > math.MaxUint8 {
+			return nil, errors.Errorf("payload size %d overflows 1 byte length prefix", length)
+		}
+		prefix[0] = byte(length)
+	case 2:
+		if length > math.MaxUint16 {
+			return nil, errors.Errorf("payload size %d overflows 2 bytes length prefix", length)
+		}
+		order.PutUint16(prefix, uint16(length))
+	case 4:
+		order.PutUint32(prefix, uint32(length))
+	case 8:
+		order.PutUint64(prefix, uint64(length))
+	default:
+		return nil, errors.Errorf("unsupported length prefix size: %d", f.LengthPrefix)
+	}
+	return prefix, nil
+}
+
+func (f *SocketFraming) getLength(prefix []byte) (uint64, error) {
+	order := byteOrder(f.ByteOrder)
+	switch f.LengthPrefix {
+	case 1:
+		return uint64(prefix[0]), nil
+	case 2:
+		return uint64(order.Uint16(prefix)), nil
+	case 4:
+		return uint64(order.Uint32(prefix)), nil
+	case 8:
+		return order.Uint64(prefix), nil
+	default:
+		return 0, errors.Errorf("unsupported length prefix size: %d", f.LengthPrefix)
+	}
+}
+
+// BinaryField is a field of struct-like binary template, fields are encoded and decoded in order.
+type BinaryField struct {
+	Name  string      `json:"name,omitempty" yaml:"name,omitempty"`   // key in decoded body, field is skipped when decoding if empty
+	Type  string      `json:"type" yaml:"type"`                       // int8/uint8/int16/uint16/int32/uint32/int64/uint64/float32/float64/bool/string/bytes
+	Value interface{} `json:"value,omitempty" yaml:"value,omitempty"` // value to encode, bytes value is in hex
+	Size  int         `json:"size,omitempty" yaml:"size,omitempty"`   // size of string or bytes, default to value size or remaining bytes when decoding
+}
+
+// fixedSize returns byte size of numeric and bool types, 0 for string and bytes
+func (f *BinaryField) fixedSize() (int, error) {
+	switch f.Type {
+	case "int8", "uint8", "bool":
+		return 1, nil
+	case "int16", "uint16":
+		return 2, nil
+	case "int32", "uint32", "float32":
+		return 4, nil
+	case "int64", "uint64", "float64":
+		return 8, nil
+	case "string", "bytes":
+		return 0, nil
+	default:
+		return 0, errors.Errorf("unsupported binary field type: %s", f.Type)
+	}
+}
+
+func encodeBinary(fields []*BinaryField, order binary.ByteOrder, parser *Parser,
+	variables map[string]interface{},
+) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, field := range fields {
+		value, err := parser.Parse(field.Value, variables)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse binary field %s failed", field.Name)
+		}
+		data, err := field.encode(value, order)
+		if err != nil {
+			return nil, errors.Wrapf(err, "encode binary field %s failed", field.Name)
+		}
+		buf.Write(data)
+	}
+	return buf.Bytes(), nil
+}
+
+func (f *BinaryField) encode(value interface{}, order binary.ByteOrder) ([]byte, error) {
+	size, err := f.fixedSize()
+	if err != nil {
+		return nil, err
+	}
+	raw := convertString(value)
+	data := make([]byte, size)
+	switch f.Type {
+	case "int8", "int16", "int32", "int64":
+		v, err := strconv.ParseInt(raw, 0, size*8)
+		if err != nil {
+			return nil, err
+		}
+		putUint(data, uint64(v), order)
+	case "uint8", "uint16", "uint32", "uint64":
+		v, err := strconv.ParseUint(raw, 0, size*8)
+		if err != nil {
+			return nil, err
+		}
+		putUint(data, v, order)
+	case "float32":
+		v, err := strconv.ParseFloat(raw, 32)
+		if err != nil {
+			return nil, err
+		}
+		order.PutUint32(data, math.Float32bits(float32(v)))
+	case "float64":
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, err
+		}
+		order.PutUint64(data, math.Float64bits(v))
+	case "bool":
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, err
+		}
+		if v {
+			data[0] = 1
+		}
+	case "string", "bytes":
+		if value == nil {
+			raw = ""
+		}
+		data = []byte(raw)
+		if f.Type == "bytes" {
+			if data, err = decodeHex(raw); err != nil {
+				return nil, err
+			}
+		}
+		if f.Size > 0 {
+			if len(data) > f.Size {
+				return nil, errors.Errorf("value size %d exceeds field size %d", len(data), f.Size)
+			}
+			// pad with zeros
+			data = append(data, make([]byte, f.Size-len(data))...)
+		}
+	}
+	return data, nil
+}
+
+func decodeBinary(data []byte, fields []*BinaryField, order binary.ByteOrder) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	offset := 0
+	for _, field := range fields {
+		size, err := field.fixedSize()
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			size = field.Size
+			if size <= 0 {
+				// use the remaining bytes
+				size = len(data) - offset
+			}
+		}
+		if offset+size > len(data) {
+			return nil, errors.Errorf("frame size %d is too short for field %s", len(data), field.Name)
+		}
+		chunk := data[offset : offset+size]
+		offset += size
+		if field.Name == "" {
+			continue
+		}
+		result[field.Name] = field.decode(chunk, order)
+	}
+	return result, nil
+}
+
+func (f *BinaryField) decode(chunk []byte, order binary.ByteOrder) interface{} {
+	switch f.Type {
+	case "int8":
+		return int8(chunk[0])
+	case "uint8":
+		return chunk[0]
+	case "int16":
+		return int16(order.Uint16(chunk))
+	case "uint16":
+		return order.Uint16(chunk)
+	case "int32":
+		return int32(order.Uint32(chunk))
+	case "uint32":
+		return order.Uint32(chunk)
+	case "int64":
+		return int64(order.Uint64(chunk))
+	case "uint64":
+		return order.Uint64(chunk)
+	case "float32":
+		return math.Float32frombits(order.Uint32(chunk))
+	case "float64":
+		return math.Float64frombits(order.Uint64(chunk))
+	case "bool":
+		return chunk[0] != 0
+	case "string":
+		return string(bytes.TrimRight(chunk, "\x00"))
+	default:
+		return hex.EncodeToString(chunk)
+	}
+}
+
+func putUint(data []byte, v uint64, order binary.ByteOrder) {
+	switch len(data) {
+	case 1:
+		data[0] = byte(v)
+	case 2:
+		order.PutUint16(data, uint16(v))
+	case 4:
+		order.PutUint32(data, uint32(v))
+	case 8:
+		order.PutUint64(data, v)
+	}
+}
+
+// normalizeHex removes whitespaces and 0x prefix of hex string, and converts to lower case
+func normalizeHex(s string) string {
+	s = strings.Join(strings.Fields(s), "")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	return strings.ToLower(s)
+}
+
+func decodeHex(s string) ([]byte, error) {
+	data, err := hex.DecodeString(normalizeHex(s))
+	if err != nil {
+		return nil, errors.Wrap(err, "decode hex failed")
+	}
+	return data, nil
+}
@@ -0,0 +1,605 @@
+package hrp
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	_ "modernc.org/sqlite" // pure go, works with CGO_ENABLED=0
+
+	"github.com/httprunner/httprunner/v5/internal/builtin"
+	"github.com/httprunner/httprunner/v5/internal/json"
+)
+
+type SQLActionType string
+
+const (
+	sqlQuery    SQLActionType = "query"    // run statement and return rows
+	sqlExec     SQLActionType = "exec"     // run statement and return affected rows
+	sqlBegin    SQLActionType = "begin"    // begin transaction, following statements on the same database run in it
+	sqlCommit   SQLActionType = "commit"   // commit the current transaction
+	sqlRollback SQLActionType = "rollback" // rollback the current transaction
+)
+
+const (
+	// environs keys of default database, e.g. set in .env file
+	envSQLDriver = "SQL_DRIVER"
+	envSQLDSN    = "SQL_DSN"
+)
+
+// SQL is a database action, databases are kept in session and identified by driver and dsn.
+// Transaction not committed is rolled back when session resources are released.
+type SQL struct {
+	Type    SQLActionType `json:"type,omitempty" yaml:"type,omitempty"`       // default to query
+	Driver  string        `json:"driver,omitempty" yaml:"driver,omitempty"`   // sqlite, mysql or postgres, default to environs SQL_DRIVER
+	DSN     string        `json:"dsn,omitempty" yaml:"dsn,omitempty"`         // data source name, default to environs SQL_DSN
+	Query   string        `json:"query,omitempty" yaml:"query,omitempty"`     // statement with placeholders of driver, e.g. ? for sqlite and mysql, $1 for postgres
+	Args    []interface{} `json:"args,omitempty" yaml:"args,omitempty"`       // arguments of placeholders
+	Timeout int64         `json:"timeout,omitempty" yaml:"timeout,omitempty"` // timeout in milliseconds
+}
+
+func (s *SQL) GetTimeout() int64 {
+	if s.Timeout <= 0 {
+		return defaultTimeout
+	}
+	return s.Timeout
+}
+
+// StepSQL implements IStep interface.
+type StepSQL struct {
+	StepConfig
+	SQL *SQL `json:"sql,omitempty" yaml:"sql,omitempty"`
+}
+
+func (s *StepSQL) Name() string {
+	if s.StepName != "" {
+		return s.StepName
+	}
+	if s.SQL.Query != "" {
+		return s.SQL.Query
+	}
+	return fmt.Sprintf("sql %s", s.SQL.Type)
+}
+
+func (s *StepSQL) Type() StepType {
+	return StepTypeSQL
+}
+
+func (s *StepSQL) Config() *StepConfig {
+	return &s.StepConfig
+}
+
+func (s *StepSQL) Run(r *SessionRunner) (*StepResult, error) {
+	return runStepSQL(r, s)
+}
+
+// WithDB sets driver and dsn of database, which could be referenced from environs, e.g. $DB_DSN
+func (s *StepSQL) WithDB(driver, dsn string) *StepSQL {
+	s.SQL.Driver = driver
+	s.SQL.DSN = dsn
+	return s
+}
+
+// Query runs statement and returns rows, e.g. SELECT or statement with RETURNING clause.
+func (s *StepSQL) Query(query string, args ...interface{}) *StepSQL {
+	s.SQL.Type = sqlQuery
+	s.SQL.Query = query
+	s.SQL.Args = args
+	return s
+}
+
+// Exec runs statement and returns affected rows, e.g. INSERT, UPDATE or DELETE.
+func (s *StepSQL) Exec(query string, args ...interface{}) *StepSQL {
+	s.SQL.Type = sqlExec
+	s.SQL.Query = query
+	s.SQL.Args = args
+	return s
+}
+
+func (s *StepSQL) Begin() *StepSQL {
+	s.SQL.Type = sqlBegin
+	return s
+}
+
+func (s *StepSQL) Commit() *StepSQL {
+	s.SQL.Type = sqlCommit
+	return s
+}
+
+func (s *StepSQL) Rollback() *StepSQL {
+	s.SQL.Type = sqlRollback
+	return s
+}
+
+func (s *StepSQL) WithTimeout(timeout int64) *StepSQL {
+	s.SQL.Timeout = timeout
+	return s
+}
+
+// Validate switches to step validation.
+func (s *StepSQL) Validate() *StepSQLValidation {
+	return &StepSQLValidation{
+		StepSQL: s,
+	}
+}
+
+// Extract switches to step extraction.
+func (s *StepSQL) Extract() *StepSQLExtraction {
+	s.StepConfig.Extract = make(map[string]string)
+	return &StepSQLExtraction{
+		StepSQL: s,
+	}
+}
+
+// StepSQLExtraction implements IStep interface.
+type StepSQLExtraction struct {
+	*StepSQL
+}
+
+// WithJmesPath sets the JMESPath expression to extract from the response.
+func (s *StepSQLExtraction) WithJmesPath(jmesPath string, varName string) *StepSQLExtraction {
+	s.StepConfig.Extract[varName] = jmesPath
+	return s
+}
+
+// Validate switches to step validation.
+func (s *StepSQLExtraction) Validate() *StepSQLValidation {
+	return &StepSQLValidation{
+		StepSQL: s.StepSQL,
+	}
+}
+
+func (s *StepSQLExtraction) Type() StepType {
+	return StepTypeSQL + stepTypeSuffixExtraction
+}
+
+func (s *StepSQLExtraction) Run(r *SessionRunner) (*StepResult, error) {
+	if s.SQL != nil {
+		return runStepSQL(r, s.StepSQL)
+	}
+	return nil, errors.New("unexpected protocol type")
+}
+
+// StepSQLValidation implements IStep interface.
+type StepSQLValidation struct {
+	*StepSQL
+}
+
+func (s *StepSQLValidation) Type() StepType {
+	return StepTypeSQL + stepTypeSuffixValidation
+}
+
+func (s *StepSQLValidation) Run(r *SessionRunner) (*StepResult, error) {
+	if s.SQL != nil {
+		return runStepSQL(r, s.StepSQL)
+	}
+	return nil, errors.New("unexpected protocol type")
+}
+
+func (s *StepSQLValidation) AssertEqual(jmesPath string, expected interface{}, msg string) *StepSQLValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  "equals",
+		Expect:  expected,
+		Message: msg,
+	}
+	s.Validators = append(s.Validators, v)
+	return s
+}
+
+func (s *StepSQLValidation) AssertContains(jmesPath string, expected interface{}, msg string) *StepSQLValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  "contains",
+		Expect:  expected,
+		Message: msg,
+	}
+	s.Validators = append(s.Validators, v)
+	return s
+}
+
+func (s *StepSQLValidation) AssertLengthEqual(jmesPath string, expected interface{}, msg string) *StepSQLValidation {
+	v := Validator{
+		Check:   jmesPath,
+		Assert:  "length_equals",
+		Expect:  expected,
+		Message: msg,
+	}
+	s.Validators = append(s.Validators, v)
+	return s
+}
+
+// sqlFieldTags are searched only on sql response, besides the common fieldTags
+var sqlFieldTags = []string{"rows", "row_count", "columns", "last_insert_id", "elapsed_ms"}
+
+// sqlRespObjMeta is the response of sql step, rows are list of maps keyed by column names
+type sqlRespObjMeta struct {
+	Rows         []map[string]interface{} `json:"rows"`
+	RowCount     int                      `json:"row_count"`
+	Columns      []string                 `json:"columns"`
+	RowsAffected int64                    `json:"rows_affected"`
+	LastInsertID int64                    `json:"last_insert_id"`
+	ElapsedMs    int64                    `json:"elapsed_ms"`
+}
+
+// sqlQueryer is implemented by both *sql.DB and *sql.Tx
+type sqlQueryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func newSQLSession() *sqlSession {
+	return &sqlSession{
+		dbs: make(map[string]*sqlDB),
+	}
+}
+
+type sqlSession struct {
+	dbs map[string]*sqlDB // databases by driver and dsn
+}
+
+type sqlDB struct {
+	*sql.DB
+	tx *sql.Tx // current transaction, nil if not in transaction
+}
+
+// queryer returns the current transaction if existed
+func (d *sqlDB) queryer() sqlQueryer {
+	if d.tx != nil {
+		return d.tx
+	}
+	return d.DB
+}
+
+// normalizeSQLDriver converts driver aliases to registered driver names
+func normalizeSQLDriver(driver string) string {
+	switch strings.ToLower(driver) {
+	case "sqlite", "sqlite3":
+		return "sqlite"
+	case "postgres", "postgresql", "pg":
+		return "postgres"
+	default:
+		return strings.ToLower(driver)
+	}
+}
+
+func (s *sqlSession) get(driver, dsn string) (*sqlDB, error) {
+	key := driver + "|" + dsn
+	if db, ok := s.dbs[key]; ok {
+		return db, nil
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, errors.Wrapf(err, "open %s database failed", driver)
+	}
+	s.dbs[key] = &sqlDB{DB: db}
+	return s.dbs[key], nil
+}
+
+// close rolls back transactions not committed and closes databases
+func (s *sqlSession) close() {
+	for _, db := range s.dbs {
+		if db.tx != nil {
+			log.Info().Msg("rollback sql transaction not committed")
+			if err := db.tx.Rollback(); err != nil {
+				log.Error().Err(err).Msg("rollback sql transaction failed")
+			}
+			db.tx = nil
+		}
+		if err := db.Close(); err != nil {
+			log.Error().Err(err).Msg("close database failed")
+		}
+	}
+	s.dbs = make(map[string]*sqlDB)
+}
+
+// readSQLRows reads all rows as list of maps, values returned as bytes are converted by column types
+func readSQLRows(rows *sql.Rows) ([]string, []map[string]interface{}, error) {
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, nil, err
+	}
+	result := make([]map[string]interface{}, 0)
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, nil, err
+		}
+		row := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			row[column] = convertSQLValue(values[i], columnTypes[i])
+		}
+		result = append(result, row)
+	}
+	return columns, result, rows.Err()
+}
+
+// convertSQLValue converts bytes to the type of column, e.g. mysql returns bytes for all columns
+// in text protocol, so that the same validators work across drivers. Bytes of other columns are
+// converted to string.
+func convertSQLValue(value interface{}, columnType *sql.ColumnType) interface{} {
+	b, ok := value.([]byte)
+	if !ok {
+		return value
+	}
+	raw := string(b)
+	switch sqlColumnKind(columnType) {
+	case reflect.Int64:
+		if v, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			return v
+		}
+	case reflect.Uint64:
+		if v, err := strconv.ParseUint(raw, 10, 64); err == nil {
+			return v
+		}
+	case reflect.Float64:
+		if v, err := strconv.ParseFloat(raw, 64); err == nil {
+			return v
+		}
+	case reflect.Bool:
+		if v, err := strconv.ParseBool(raw); err == nil {
+			return v
+		}
+	}
+	return raw
+}
+
+var (
+	sqlNullIntTypes = map[reflect.Type]bool{
+		reflect.TypeOf(sql.NullInt64{}): true,
+		reflect.TypeOf(sql.NullInt32{}): true,
+		reflect.TypeOf(sql.NullInt16{}): true,
+		reflect.TypeOf(sql.NullByte{}):  true,
+	}
+	sqlNullFloatType = reflect.TypeOf(sql.NullFloat64{})
+	sqlNullBoolType  = reflect.TypeOf(sql.NullBool{})
+)
+
+// sqlColumnKind returns kind of column value by scan type, or by database type name
+// if scan type is not specific, e.g. decimal of mysql and numeric of postgres.
+func sqlColumnKind(columnType *sql.ColumnType) reflect.Kind {
+	if scanType := columnType.ScanType(); scanType != nil {
+		switch {
+		case sqlNullIntTypes[scanType]:
+			return reflect.Int64
+		case scanType == sqlNullFloatType:
+			return reflect.Float64
+		case scanType == sqlNullBoolType:
+			return reflect.Bool
+		}
+		switch scanType.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return reflect.Int64
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return reflect.Uint64
+		case reflect.Float32, reflect.Float64:
+			return reflect.Float64
+		case reflect.Bool:
+			return reflect.Bool
+		}
+	}
+	name := strings.ToUpper(columnType.DatabaseTypeName())
+	unsigned := strings.HasPrefix(name, "UNSIGNED ")
+	switch strings.TrimPrefix(name, "UNSIGNED ") {
+	case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "INTEGER", "BIGINT", "INT2", "INT4", "INT8", "YEAR":
+		if unsigned {
+			return reflect.Uint64
+		}
+		return reflect.Int64
+	case "DECIMAL", "NUMERIC", "FLOAT", "DOUBLE", "REAL", "FLOAT4", "FLOAT8":
+		return reflect.Float64
+	case "BOOL", "BOOLEAN":
+		return reflect.Bool
+	}
+	return reflect.String
+}
+
+func runStepSQL(r *SessionRunner, step *StepSQL) (stepResult *StepResult, err error) {
+	s := step.SQL
+	variables := step.Variables
+	start := time.Now()
+	stepResult = &StepResult{
+		Name:        step.Name(),
+		StepType:    step.Type(),
+		Success:     false,
+		ContentSize: 0,
+		StartTime:   start.UnixMilli(),
+	}
+	defer func() {
+		if err != nil {
+			stepResult.Attachments = err.Error()
+		}
+		stepResult.Elapsed = time.Since(start).Milliseconds()
+	}()
+
+	parser := r.caseRunner.parser
+	actionType := s.Type
+	if actionType == "" {
+		actionType = sqlQuery
+	}
+	driver, dsn := s.Driver, s.DSN
+	environs := r.caseRunner.Config.Get().Environs
+	if driver == "" {
+		driver = environs[envSQLDriver]
+	}
+	if dsn == "" {
+		dsn = environs[envSQLDSN]
+	}
+	parsedDriver, err := parser.ParseString(driver, variables)
+	if err != nil {
+		return stepResult, errors.Wrap(err, "parse sql driver failed")
+	}
+	driver = normalizeSQLDriver(convertString(parsedDriver))
+	parsedDSN, err := parser.ParseString(dsn, variables)
+	if err != nil {
+		return stepResult, errors.Wrap(err, "parse sql dsn failed")
+	}
+	dsn = convertString(parsedDSN)
+	if driver == "" || dsn == "" {
+		return stepResult, errors.Errorf("missing sql driver or dsn, set in step or environs %s and %s",
+			envSQLDriver, envSQLDSN)
+	}
+	parsedQuery, err := parser.ParseString(s.Query, variables)
+	if err != nil {
+		return stepResult, errors.Wrap(err, "parse sql query failed")
+	}
+	query := convertString(parsedQuery)
+	args := make([]interface{}, 0, len(s.Args))
+	for _, arg := range s.Args {
+		parsedArg, err := parser.Parse(arg, variables)
+		if err != nil {
+			return stepResult, errors.Wrap(err, "parse sql args failed")
+		}
+		args = append(args, parsedArg)
+	}
+	requestMap := map[string]interface{}{
+		"type":   actionType,
+		"driver": driver,
+		"query":  query,
+		"args":   args,
+	}
+	variables["hrp_step_name"] = step.Name()
+	variables["hrp_step_request"] = requestMap
+
+	// deal with setup hooks
+	for _, setupHook := range step.SetupHooks {
+		_, err = parser.Parse(setupHook, variables)
+		if err != nil {
+			return stepResult, errors.Wrap(err, "run setup hooks failed")
+		}
+	}
+
+	if r.caseRunner.hrpRunner.requestsLogOn {
+		fmt.Printf("-------------------- sql %v: %s --------------------\n", actionType, query)
+	}
+	db, err := r.sql.get(driver, dsn)
+	if err != nil {
+		return stepResult, err
+	}
+	ctx, cancel := context.WithTimeout(r.ctx, time.Duration(s.GetTimeout())*time.Millisecond)
+	defer cancel()
+
+	var respObjMeta *sqlRespObjMeta
+	switch actionType {
+	case sqlQuery:
+		log.Info().Str("driver", driver).Str("query", query).Msg("run sql query")
+		var rows *sql.Rows
+		rows, err = db.queryer().QueryContext(ctx, query, args...)
+		if err != nil {
+			return stepResult, errors.Wrap(err, "run sql query failed")
+		}
+		respObjMeta = &sqlRespObjMeta{}
+		respObjMeta.Columns, respObjMeta.Rows, err = readSQLRows(rows)
+		if err != nil {
+			return stepResult, errors.Wrap(err, "read sql rows failed")
+		}
+		respObjMeta.RowCount = len(respObjMeta.Rows)
+	case sqlExec:
+		log.Info().Str("driver", driver).Str("query", query).Msg("run sql exec")
+		var result sql.Result
+		result, err = db.queryer().ExecContext(ctx, query, args...)
+		if err != nil {
+			return stepResult, errors.Wrap(err, "run sql exec failed")
+		}
+		respObjMeta = &sqlRespObjMeta{Rows: []map[string]interface{}{}}
+		// ignore errors of drivers not supporting affected rows or last insert id, e.g. postgres
+		respObjMeta.RowsAffected, _ = result.RowsAffected()
+		respObjMeta.LastInsertID, _ = result.LastInsertId()
+	case sqlBegin:
+		log.Info().Str("driver", driver).Msg("begin sql transaction")
+		if db.tx != nil {
+			return stepResult, errors.New("sql transaction already begun")
+		}
+		// transaction should not be bound to the step timeout
+		db.tx, err = db.BeginTx(r.ctx, nil)
+		if err != nil {
+			return stepResult, errors.Wrap(err, "begin sql transaction failed")
+		}
+	case sqlCommit, sqlRollback:
+		log.Info().Str("driver", driver).Msgf("%s sql transaction", actionType)
+		if db.tx == nil {
+			return stepResult, errors.Errorf("%s without sql transaction", actionType)
+		}
+		if actionType == sqlCommit {
+			err = db.tx.Commit()
+		} else {
+			err = db.tx.Rollback()
+		}
+		db.tx = nil
+		if err != nil {
+			return stepResult, errors.Wrapf(err, "%s sql transaction failed", actionType)
+		}
+	default:
+		return stepResult, errors.Errorf("unexpected sql action type: %v", actionType)
+	}
+
+	var respObj *responseObject
+	if respObjMeta != nil {
+		respObjMeta.ElapsedMs = time.Since(start).Milliseconds()
+		if r.caseRunner.hrpRunner.requestsLogOn {
+			respBytes, _ := json.MarshalIndent(respObjMeta, "", "    ")
+			fmt.Println(string(respBytes))
+		}
+		respObj, err = convertToResponseObject(r.testingT(), parser, respObjMeta)
+		if err != nil {
+			err = errors.Wrap(err, "init ResponseObject error")
+			return
+		}
+		respObj.casePath = r.caseRunner.Config.Get().Path
+		respObj.fieldTags = sqlFieldTags
+		variables["hrp_step_response"] = respObj.respObjMeta
+	}
+
+	// deal with teardown hooks
+	for _, teardownHook := range step.TeardownHooks {
+		_, err = parser.Parse(teardownHook, variables)
+		if err != nil {
+			return stepResult, errors.Wrap(err, "run teardown hooks failed")
+		}
+	}
+
+	if respObj == nil {
+		stepResult.Success = true
+		return stepResult, nil
+	}
+
+	sessionData := &SessionData{
+		ReqResps: &ReqResps{
+			Request:  requestMap,
+			Response: builtin.FormatResponse(respObj.respObjMeta),
+		},
+	}
+	stepResult.Data = sessionData
+
+	// extract variables from response
+	extractMapping := respObj.Extract(step.StepConfig.Extract, variables)
+	stepResult.ExportVars = extractMapping
+
+	// override step variables with extracted variables
+	variables = mergeVariables(variables, extractMapping)
+
+	// validate response
+	err = respObj.Validate(step.Validators, variables, r.validateMode(&step.StepConfig))
+	sessionData.Validators = respObj.validationResults
+	if err == nil {
+		stepResult.Success = true
+	}
+	return stepResult, err
+}
@@ -86,6 +86,10 @@ func (s *StepTestCaseWithOptionalArgs) Run(r *SessionRunner) (stepResult *StepRe
 		return stepResult, err
 	}
 	sessionRunner := caseRunner.NewSession()
+	// share cookies with referenced testcase, e.g. login in referenced testcase
+	if sessionRunner.cookieJar != nil && r.cookieJar != nil {
+		sessionRunner.cookieJar = r.cookieJar
+	}
 
 	var summary *TestCaseSummary
 	// run referenced testcase with step variables
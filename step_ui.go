@@ -934,7 +934,7 @@ func runStepMobileUI(s *SessionRunner, step IStep) (stepResult *StepResult, err
 	// run actions
 	for _, action := range mobileStep.Actions {
 		select {
-		case <-s.caseRunner.hrpRunner.caseTimeoutTimer.C:
+		case <-s.caseTimeoutTimer.C:
 			log.Warn().Msg("case timeout in mobile UI runner, abort running")
 			return stepResult, errors.Wrap(code.TimeoutError, "mobile UI runner case timeout")
 		case <-s.caseRunner.hrpRunner.interruptSignal:
@@ -1018,7 +1018,7 @@ func runStepMobileUI(s *SessionRunner, step IStep) (stepResult *StepResult, err
 				case <-s.caseRunner.hrpRunner.interruptSignal:
 					log.Warn().Msg("cancelling action due to interrupt signal")
 					cancel(code.InterruptError)
-				case <-s.caseRunner.hrpRunner.caseTimeoutTimer.C:
+				case <-s.caseTimeoutTimer.C:
 					log.Warn().Msg("cancelling action due to case timeout")
 					cancel(code.TimeoutError)
 				case <-ctx.Done():
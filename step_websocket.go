@@ -3,7 +3,10 @@ package hrp
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"net/http"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 	"unsafe"
@@ -19,17 +22,45 @@ import (
 func newWSSession() *wsSession {
 	return &wsSession{
 		wsConnMap:         make(map[string]*websocket.Conn),
+		wsHeaderMap:       make(map[string]http.Header),
+		reconnections:     make(map[string]*wsReconnection),
+		remoteClosed:      make(map[*websocket.Conn]bool),
+		subscriptions:     make(map[string]*wsSubscription),
 		pongResponseChan:  make(chan string, 1),
 		closeResponseChan: make(chan *wsCloseRespObject, 1),
 	}
 }
 
 type wsSession struct {
+	mutex             sync.Mutex
 	wsConnMap         map[string]*websocket.Conn // save all websocket connections
+	wsHeaderMap       map[string]http.Header     // handshake headers of connections, used for reconnection
+	reconnections     map[string]*wsReconnection // only one reconnection of each url at a time
+	remoteClosed      map[*websocket.Conn]bool   // connections which received close message from remote server
+	releasing         bool                       // resources are being released, connections are not reconnected
+	subscriptions     map[string]*wsSubscription // background message collectors of connections
 	pongResponseChan  chan string                // channel used to receive pong response message
 	closeResponseChan chan *wsCloseRespObject    // channel used to receive close response message
 }
 
+// wsReconnection serializes reconnections of the same url, and records the connection failed to reconnect
+type wsReconnection struct {
+	sync.Mutex
+	failed *websocket.Conn
+	err    error
+}
+
+func (s *wsSession) getReconnection(urlStr string) *wsReconnection {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	rc, ok := s.reconnections[urlStr]
+	if !ok {
+		rc = &wsReconnection{}
+		s.reconnections[urlStr] = rc
+	}
+	return rc
+}
+
 const (
 	wsOpen         WSActionType = "open"
 	wsPing         WSActionType = "ping"
@@ -37,6 +68,9 @@ const (
 	wsRead         WSActionType = "r"
 	wsWrite        WSActionType = "w"
 	wsClose        WSActionType = "close"
+	wsSubscribe    WSActionType = "subscribe"
+	wsWait         WSActionType = "wait"
+	wsMessages     WSActionType = "messages"
 )
 
 const (
@@ -61,6 +95,12 @@ func (at WSActionType) toString() string {
 		return "write only"
 	case wsClose:
 		return "close current connection"
+	case wsSubscribe:
+		return "collect messages in background"
+	case wsWait:
+		return "wait for collected message"
+	case wsMessages:
+		return "get collected messages"
 	default:
 		return "unexpected action type"
 	}
@@ -89,7 +129,8 @@ func (mt MessageType) toString() string {
 	}
 }
 
-// WebSocketConfig TODO: support reconnection ability
+// WebSocketConfig configures websocket connections of testcase, connection closed by remote server
+// is reconnected with the same url and headers for at most ReconnectionTimes.
 type WebSocketConfig struct {
 	ReconnectionTimes    int64 `json:"reconnection_times,omitempty" yaml:"reconnection_times,omitempty"`       // maximum reconnection times when the connection closed by remote server
 	ReconnectionInterval int64 `json:"reconnection_interval,omitempty" yaml:"reconnection_interval,omitempty"` // interval between each reconnection in milliseconds
@@ -184,6 +225,44 @@ func (s *StepWebSocket) CloseConnection(url ...string) *StepWebSocket {
 	return s.withUrl(url...)
 }
 
+// Subscribe collects messages of connection in background, connection is opened if not existed.
+func (s *StepWebSocket) Subscribe(url ...string) *StepWebSocket {
+	s.WebSocket.Type = wsSubscribe
+	return s.withUrl(url...)
+}
+
+// WaitMessage waits for the next collected message within timeout, use WaitUntil or WaitMatch
+// to skip messages until the condition is met.
+func (s *StepWebSocket) WaitMessage(url ...string) *StepWebSocket {
+	s.WebSocket.Type = wsWait
+	return s.withUrl(url...)
+}
+
+// Messages gets all collected messages, which could be asserted on counts and order.
+func (s *StepWebSocket) Messages(url ...string) *StepWebSocket {
+	s.WebSocket.Type = wsMessages
+	return s.withUrl(url...)
+}
+
+// WaitUntil sets the condition of message to wait for, check is JMESPath on message object,
+// e.g. body.event
+func (s *StepWebSocket) WaitUntil(check string, assert string, expected interface{}, msg ...string) *StepWebSocket {
+	s.WebSocket.Until = &Validator{
+		Check:  check,
+		Assert: assert,
+		Expect: expected,
+	}
+	if len(msg) > 0 {
+		s.WebSocket.Until.Message = msg[0]
+	}
+	return s
+}
+
+// WaitMatch waits for message whose raw content matches the regular expression.
+func (s *StepWebSocket) WaitMatch(regex string, msg ...string) *StepWebSocket {
+	return s.WaitUntil("raw_message", "regex_match", regex, msg...)
+}
+
 func (s *StepWebSocket) WithParams(params map[string]interface{}) *StepWebSocket {
 	s.WebSocket.Params = params
 	return s
@@ -354,6 +433,7 @@ type WebSocketAction struct {
 	BinaryMessage   interface{}            `json:"binary,omitempty" yaml:"binary,omitempty"`
 	CloseStatusCode int64                  `json:"close_status,omitempty" yaml:"close_status,omitempty"`
 	Timeout         int64                  `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	Until           *Validator             `json:"until,omitempty" yaml:"until,omitempty"` // condition of message to wait for
 }
 
 func (w *WebSocketAction) GetTimeout() int64 {
@@ -404,6 +484,7 @@ func runStepWebSocket(r *SessionRunner, step IStep) (stepResult *StepResult, err
 		Headers: webSocket.Headers,
 	}
 	rb := newRequestBuilder(parser, config, dummyReq)
+	rb.oauth2 = r.oauth2
 
 	err = rb.prepareUrlParams(variables)
 	if err != nil {
@@ -414,6 +495,7 @@ func runStepWebSocket(r *SessionRunner, step IStep) (stepResult *StepResult, err
 	if err != nil {
 		return
 	}
+	rb.injectTraceContext(r.ctx)
 	parsedURL := rb.req.URL.String()
 	parsedHeader := rb.req.Header
 
@@ -430,6 +512,7 @@ func runStepWebSocket(r *SessionRunner, step IStep) (stepResult *StepResult, err
 	}
 
 	var resp interface{}
+	var untilResult *ValidationResult
 
 	// do websocket action
 	if r.caseRunner.hrpRunner.requestsLogOn {
@@ -486,6 +569,28 @@ func runStepWebSocket(r *SessionRunner, step IStep) (stepResult *StepResult, err
 		if err != nil {
 			return stepResult, errors.Wrap(err, "write message failed")
 		}
+	case wsSubscribe:
+		log.Info().Int64("timeout(ms)", webSocket.GetTimeout()).Str("url", parsedURL).Msg("subscribe messages")
+		// open connection if not existed
+		if getWsClient(r, parsedURL) == nil {
+			resp, err = openWithTimeout(parsedURL, parsedHeader, r, stepWebSocket)
+			if err != nil {
+				return stepResult, errors.Wrap(err, "open connection failed")
+			}
+		}
+		r.subscribeWebSocket(parsedURL)
+	case wsWait:
+		log.Info().Int64("timeout(ms)", webSocket.GetTimeout()).Str("url", parsedURL).Msg("wait for message")
+		resp, untilResult, err = waitMessageWithTimeout(parsedURL, r, stepWebSocket, variables)
+		if err != nil {
+			return stepResult, errors.Wrap(err, "wait message failed")
+		}
+	case wsMessages:
+		log.Info().Str("url", parsedURL).Msg("get collected messages")
+		resp, err = getCollectedMessages(parsedURL, r)
+		if err != nil {
+			return stepResult, errors.Wrap(err, "get messages failed")
+		}
 	case wsClose:
 		log.Info().Int64("timeout(ms)", webSocket.GetTimeout()).Str("url", parsedURL).Msg("close webSocket connection")
 		resp, err = closeWithTimeout(parsedURL, r, stepWebSocket, variables)
@@ -502,13 +607,18 @@ func runStepWebSocket(r *SessionRunner, step IStep) (stepResult *StepResult, err
 		}
 	}
 
-	respObj, err := getResponseObject(r.caseRunner.hrpRunner.t, r.caseRunner.parser, resp)
+	respObj, err := getResponseObject(r.testingT(), r.caseRunner.parser, resp)
 	if err != nil {
 		err = errors.Wrap(err, "get response object error")
 		return
 	}
 
 	if respObj != nil {
+		respObj.casePath = r.caseRunner.Config.Get().Path
+		// record wait condition as validation result
+		if untilResult != nil {
+			respObj.validationResults = append(respObj.validationResults, untilResult)
+		}
 		// add response object to step variables, could be used in teardown hooks
 		variables["hrp_step_response"] = respObj.respObjMeta
 	}
@@ -534,7 +644,8 @@ func runStepWebSocket(r *SessionRunner, step IStep) (stepResult *StepResult, err
 		variables = mergeVariables(variables, extractMapping)
 
 		// validate response
-		err = respObj.Validate(stepWebSocket.Validators, variables)
+		err = respObj.Validate(stepWebSocket.Validators, variables,
+			r.validateMode(&stepWebSocket.StepConfig))
 		sessionData.Validators = respObj.validationResults
 		if err == nil {
 			stepResult.Success = true
@@ -549,6 +660,8 @@ func runStepWebSocket(r *SessionRunner, step IStep) (stepResult *StepResult, err
 }
 
 func getWsClient(r *SessionRunner, url string) *websocket.Conn {
+	r.ws.mutex.Lock()
+	defer r.ws.mutex.Unlock()
 	if client, ok := r.ws.wsConnMap[url]; ok {
 		return client
 	}
@@ -577,6 +690,10 @@ func printWebSocketResponse(resp interface{}) error {
 		}
 	case *wsCloseRespObject:
 		fmt.Printf("close status code: %v\r\nmessage: %v\r\n", r.StatusCode, r.Text)
+	case *wsMessage:
+		fmt.Printf("message type: %v\r\nmessage: %s\r\n", r.Type, r.Raw)
+	case *wsMessagesRespObject:
+		fmt.Printf("messages count: %v\r\nreconnections: %v\r\n", r.Count, r.Reconnections)
 	case string:
 		fmt.Println(r)
 	default:
@@ -587,38 +704,16 @@ func printWebSocketResponse(resp interface{}) error {
 }
 
 func openWithTimeout(urlStr string, requestHeader http.Header, r *SessionRunner, step *StepWebSocket) (*http.Response, error) {
-	openResponseChan := make(chan *http.Response)
-	errorChan := make(chan error)
+	openResponseChan := make(chan *http.Response, 1)
+	errorChan := make(chan error, 1)
 	go func() {
-		conn, resp, err := r.caseRunner.hrpRunner.wsDialer.Dial(urlStr, requestHeader)
+		_, resp, err := r.dialWebSocket(urlStr, requestHeader)
 		if err != nil {
-			errorChan <- errors.Wrap(err, "dial tcp failed")
+			errorChan <- err
 			return
 		}
 		// handshake end here
 		defer resp.Body.Close()
-
-		// the following handlers handle and transport control message from server
-		conn.SetPongHandler(func(appData string) error {
-			r.ws.pongResponseChan <- appData
-			return nil
-		})
-		conn.SetCloseHandler(func(code int, text string) error {
-			message := websocket.FormatCloseMessage(code, "")
-			conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(defaultWriteWait))
-			select {
-			case r.ws.closeResponseChan <- &wsCloseRespObject{
-				StatusCode: code,
-				Text:       text,
-			}:
-			default:
-				log.Warn().Msg("close response channel is block, drop the response")
-			}
-
-			return nil
-		})
-
-		r.ws.wsConnMap[urlStr] = conn
 		openResponseChan <- resp
 	}()
 
@@ -634,15 +729,160 @@ func openWithTimeout(urlStr string, requestHeader http.Header, r *SessionRunner,
 	}
 }
 
+var errWebSocketReleased = errors.New("websocket session resources released")
+
+// dialWebSocket dials websocket connection, and saves the connection with its handshake headers
+// after registering control message handlers.
+func (r *SessionRunner) dialWebSocket(urlStr string, requestHeader http.Header) (*websocket.Conn, *http.Response, error) {
+	dialer := *r.caseRunner.hrpRunner.wsDialer
+	if r.cookieJar != nil {
+		dialer.Jar = r.cookieJar
+	}
+	conn, resp, err := dialer.Dial(urlStr, requestHeader)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "dial tcp failed")
+	}
+
+	// the following handlers handle and transport control message from server
+	conn.SetPongHandler(func(appData string) error {
+		r.ws.pongResponseChan <- appData
+		return nil
+	})
+	conn.SetCloseHandler(func(code int, text string) error {
+		r.ws.mutex.Lock()
+		r.ws.remoteClosed[conn] = true
+		r.ws.mutex.Unlock()
+		message := websocket.FormatCloseMessage(code, "")
+		conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(defaultWriteWait))
+		select {
+		case r.ws.closeResponseChan <- &wsCloseRespObject{
+			StatusCode: code,
+			Text:       text,
+		}:
+		default:
+			log.Warn().Msg("close response channel is block, drop the response")
+		}
+
+		return nil
+	})
+
+	r.ws.mutex.Lock()
+	defer r.ws.mutex.Unlock()
+	if r.ws.releasing {
+		conn.Close()
+		resp.Body.Close()
+		return nil, nil, errWebSocketReleased
+	}
+	if previous := r.ws.wsConnMap[urlStr]; previous != nil {
+		delete(r.ws.remoteClosed, previous)
+	}
+	r.ws.wsConnMap[urlStr] = conn
+	r.ws.wsHeaderMap[urlStr] = requestHeader
+	return conn, resp, nil
+}
+
+// isRemoteClosed checks whether the error is caused by remote server closing the connection,
+// errors of local close and deadline are not.
+func isRemoteClosed(err error) bool {
+	var closeErr *websocket.CloseError
+	if errors.As(err, &closeErr) {
+		return true
+	}
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE)
+}
+
+// reconnectWebSocket reconnects the broken connection closed by remote server with the same url and headers,
+// cause is returned if reconnection is disabled in config, the connection is not closed by remote server,
+// or session resources are being released. Concurrent callers of the same url wait for
+// the ongoing reconnection and reuse its result instead of dialing again.
+func (r *SessionRunner) reconnectWebSocket(urlStr string, broken *websocket.Conn, cause error) (*websocket.Conn, error) {
+	setting := r.caseRunner.Config.Get().WebSocketSetting
+	if setting == nil || setting.ReconnectionTimes <= 0 {
+		return nil, cause
+	}
+	rc := r.ws.getReconnection(urlStr)
+	rc.Lock()
+	defer rc.Unlock()
+
+	r.ws.mutex.Lock()
+	requestHeader := r.ws.wsHeaderMap[urlStr]
+	current := r.ws.wsConnMap[urlStr]
+	releasing := r.ws.releasing
+	remoteClosed := r.ws.remoteClosed[broken]
+	r.ws.mutex.Unlock()
+	if releasing {
+		return nil, cause
+	}
+	if current != broken {
+		// reconnected by another caller
+		log.Info().Str("url", urlStr).Msg("reuse reconnected websocket")
+		return current, nil
+	}
+	if rc.failed == broken {
+		return nil, rc.err
+	}
+	// writing to connection closed by remote server fails with ErrCloseSent after close message is replied
+	if !remoteClosed && !isRemoteClosed(cause) {
+		return nil, cause
+	}
+	if current != nil {
+		current.Close()
+	}
+
+	err := cause
+	for i := int64(1); i <= setting.ReconnectionTimes; i++ {
+		time.Sleep(time.Duration(setting.ReconnectionInterval) * time.Millisecond)
+		log.Warn().Err(err).Str("url", urlStr).Int64("attempt", i).Msg("reconnect websocket")
+		var conn *websocket.Conn
+		var resp *http.Response
+		conn, resp, err = r.dialWebSocket(urlStr, requestHeader)
+		if errors.Is(err, errWebSocketReleased) {
+			return nil, cause
+		} else if err != nil {
+			continue
+		}
+		resp.Body.Close()
+		// drop close response of the previous connection
+		select {
+		case <-r.ws.closeResponseChan:
+		default:
+		}
+		log.Info().Str("url", urlStr).Int64("attempt", i).Msg("websocket reconnected")
+		return conn, nil
+	}
+	rc.failed = broken
+	rc.err = errors.Wrapf(err, "reconnect failed after %d attempts", setting.ReconnectionTimes)
+	return nil, rc.err
+}
+
 func readMessageWithTimeout(urlString string, r *SessionRunner, step *StepWebSocket) (*wsReadRespObject, error) {
+	// messages of subscribed connection are read in background
+	if sub := r.ws.getSubscription(urlString); sub != nil && sub.isActive() {
+		msg, _, err := sub.next(nil, time.Duration(step.WebSocket.GetTimeout())*time.Millisecond)
+		if err != nil {
+			return nil, err
+		}
+		return &wsReadRespObject{
+			messageType: msg.messageType,
+			Message:     msg.data,
+		}, nil
+	}
 	wsConn := getWsClient(r, urlString)
 	if wsConn == nil {
 		return nil, errors.New("try to use existing connection, but there is no connection")
 	}
-	readResponseChan := make(chan *wsReadRespObject)
-	errorChan := make(chan error)
+	readResponseChan := make(chan *wsReadRespObject, 1)
+	errorChan := make(chan error, 1)
 	go func() {
 		messageType, message, err := wsConn.ReadMessage()
+		if err != nil {
+			// read again if connection closed by remote server is reconnected
+			var conn *websocket.Conn
+			if conn, err = r.reconnectWebSocket(urlString, wsConn, err); err == nil {
+				messageType, message, err = conn.ReadMessage()
+			}
+		}
 		if err != nil {
 			errorChan <- err
 		} else {
@@ -670,32 +910,37 @@ func writeWebSocket(urlString string, r *SessionRunner, step *StepWebSocket, ste
 		return errors.New("try to use existing connection, but there is no connection")
 	}
 	// check priority: text message > binary message
+	var write func(c *websocket.Conn) error
 	if step.WebSocket.TextMessage != nil {
 		parsedMessage, parseErr := r.caseRunner.parser.Parse(step.WebSocket.TextMessage, stepVariables)
 		if parseErr != nil {
 			return parseErr
 		}
-		writeErr := writeWithType(wsConn, step, websocket.TextMessage, parsedMessage)
-		if writeErr != nil {
-			return writeErr
+		write = func(c *websocket.Conn) error {
+			return writeWithType(c, step, websocket.TextMessage, parsedMessage)
 		}
 	} else if step.WebSocket.BinaryMessage != nil {
 		parsedMessage, parseErr := r.caseRunner.parser.Parse(step.WebSocket.BinaryMessage, stepVariables)
 		if parseErr != nil {
 			return parseErr
 		}
-		writeErr := writeWithType(wsConn, step, websocket.BinaryMessage, parsedMessage)
-		if writeErr != nil {
-			return writeErr
+		write = func(c *websocket.Conn) error {
+			return writeWithType(c, step, websocket.BinaryMessage, parsedMessage)
 		}
 	} else {
 		log.Info().Msg("step with empty message")
-		err := writeWithAction(wsConn, step, websocket.BinaryMessage, []byte{})
-		if err != nil {
-			return err
+		write = func(c *websocket.Conn) error {
+			return writeWithAction(c, step, websocket.BinaryMessage, []byte{})
 		}
 	}
-	return nil
+	err := write(wsConn)
+	if err != nil && step.WebSocket.Type != wsClose {
+		// write again if connection closed by remote server is reconnected
+		if wsConn, err = r.reconnectWebSocket(urlString, wsConn, err); err == nil {
+			err = write(wsConn)
+		}
+	}
+	return err
 }
 
 func writeWithType(c *websocket.Conn, step *StepWebSocket, messageType int, message interface{}) error {
@@ -735,13 +980,24 @@ func closeWithTimeout(urlString string, r *SessionRunner, step *StepWebSocket, s
 	if wsConn == nil {
 		return nil, errors.New("no connection needs to be closed")
 	}
-	errorChan := make(chan error)
+	sub := r.ws.getSubscription(urlString)
+	if sub != nil && !sub.isActive() {
+		sub = nil
+	}
+	if sub != nil {
+		// stop reconnecting, close response is received by subscription in background
+		sub.setClosing()
+	}
+	errorChan := make(chan error, 1)
 	go func() {
 		err := writeWebSocket(urlString, r, step, stepVariables)
 		if err != nil {
 			errorChan <- errors.Wrap(err, "send close message failed")
 			return
 		}
+		if sub != nil {
+			return
+		}
 		// discard redundant message left in the connection before close
 		var mt int
 		var message []byte
@@ -782,11 +1038,18 @@ func getResponseObject(t *testing.T, parser *Parser, resp interface{}) (*respons
 	}
 	switch r := resp.(type) {
 	case *http.Response:
-		return newHttpResponseObject(t, parser, r)
+		return newHttpResponseObject(t, parser, r, time.Time{})
 	case *wsReadRespObject:
 		return newWsReadResponseObject(t, parser, r)
 	case *wsCloseRespObject:
 		return newWsCloseResponseObject(t, parser, r)
+	case *wsMessage, *wsMessagesRespObject:
+		respObj, err := convertToResponseObject(t, parser, r)
+		if err != nil {
+			return nil, err
+		}
+		respObj.fieldTags = wsFieldTags
+		return respObj, nil
 	default:
 		return nil, errors.New("unxexpected reponse type")
 	}
@@ -800,13 +1063,20 @@ func getContentSize(resp interface{}) int64 {
 		return int64(unsafe.Sizeof(r.Message))
 	case *wsCloseRespObject:
 		return int64(unsafe.Sizeof(r.Text))
+	case *wsMessage:
+		return int64(len(r.data))
 	default:
 		return -1
 	}
 }
 
 func (r *SessionRunner) ReleaseResources() {
-	// close websocket connections
+	// stop collecting messages and close websocket connections
+	r.ws.mutex.Lock()
+	r.ws.releasing = true
+	for _, sub := range r.ws.subscriptions {
+		sub.setClosing()
+	}
 	for _, wsConn := range r.ws.wsConnMap {
 		if wsConn != nil {
 			log.Info().Str("testcase", r.caseRunner.Config.Get().Name).Msg("websocket disconnected")
@@ -816,4 +1086,15 @@ func (r *SessionRunner) ReleaseResources() {
 			}
 		}
 	}
+	r.ws.mutex.Unlock()
+	// close grpc connections
+	r.grpc.close()
+	// close tcp and udp connections
+	r.socket.close()
+	// disconnect mqtt clients
+	r.mqtt.close()
+	// rollback transactions not committed and close databases
+	r.sql.close()
+	// stop redis subscriptions and close connections
+	r.redis.close()
 }
@@ -0,0 +1,261 @@
+package hrp
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/httprunner/httprunner/v5/internal/json"
+)
+
+// wsFieldTags are searched only on messages collected by subscription, besides the common fieldTags
+var wsFieldTags = []string{"message_type", "raw_message", "message_count", "reconnections", "elapsed_ms"}
+
+// wsMessage is the message collected by subscription
+type wsMessage struct {
+	Type        string      `json:"message_type"` // text or binary
+	Body        interface{} `json:"body"`         // parsed as JSON if possible
+	Raw         string      `json:"raw_message"`  // raw content, could be matched with regex
+	ElapsedMs   int64       `json:"elapsed_ms"`   // from subscribing to receiving message
+	messageType int
+	data        []byte
+}
+
+func newWsMessage(messageType int, data []byte, elapsed time.Duration) *wsMessage {
+	var body interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		// message is not json, use raw content
+		body = string(data)
+	}
+	return &wsMessage{
+		Type:        MessageType(messageType).toString(),
+		Body:        body,
+		Raw:         string(data),
+		ElapsedMs:   elapsed.Milliseconds(),
+		messageType: messageType,
+		data:        data,
+	}
+}
+
+// wsMessagesRespObject is the response of messages action
+type wsMessagesRespObject struct {
+	Messages      []interface{} `json:"body"` // bodies of collected messages in receiving order
+	Count         int           `json:"message_count"`
+	Reconnections int           `json:"reconnections"`
+}
+
+// wsSubscription collects messages of connection in background
+type wsSubscription struct {
+	mutex         sync.Mutex
+	start         time.Time
+	messages      []*wsMessage
+	cursor        int // index of the next message for read and wait
+	reconnections int
+	closing       bool          // connection is being closed locally, no reconnection
+	err           error         // reason why collecting stopped
+	notify        chan struct{} // closed when new message arrives or collecting stops
+}
+
+func newWsSubscription() *wsSubscription {
+	return &wsSubscription{
+		start:  time.Now(),
+		notify: make(chan struct{}),
+	}
+}
+
+func (s *wsSubscription) add(messageType int, data []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.messages = append(s.messages, newWsMessage(messageType, data, time.Since(s.start)))
+	close(s.notify)
+	s.notify = make(chan struct{})
+}
+
+func (s *wsSubscription) stop(err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.err = err
+	close(s.notify)
+	s.notify = make(chan struct{})
+}
+
+func (s *wsSubscription) reconnected() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.reconnections++
+}
+
+func (s *wsSubscription) setClosing() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.closing = true
+}
+
+func (s *wsSubscription) isClosing() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.closing
+}
+
+func (s *wsSubscription) isActive() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.err == nil
+}
+
+// scan searches unconsumed messages for the first one matching condition, all scanned messages are consumed
+func (s *wsSubscription) scan(matcher eventMatcher) (
+	msg *wsMessage, checkValue interface{}, notify chan struct{}, stopped error, err error,
+) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for s.cursor < len(s.messages) {
+		msg := s.messages[s.cursor]
+		s.cursor++
+		if matcher == nil {
+			return msg, nil, nil, nil, nil
+		}
+		matched, checkValue, err := matcher(msg)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		if matched {
+			return msg, checkValue, nil, nil, nil
+		}
+	}
+	return nil, nil, s.notify, s.err, nil
+}
+
+// next waits for the next message matching condition within timeout, matcher could be nil to match any message
+func (s *wsSubscription) next(matcher eventMatcher, timeout time.Duration) (*wsMessage, interface{}, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		msg, checkValue, notify, stopped, err := s.scan(matcher)
+		if err != nil {
+			return nil, nil, err
+		}
+		if msg != nil {
+			return msg, checkValue, nil
+		}
+		if stopped != nil {
+			return nil, nil, errors.Wrap(stopped, "subscription stopped")
+		}
+		select {
+		case <-notify:
+		case <-timer.C:
+			return nil, nil, errors.New("wait timeout")
+		}
+	}
+}
+
+func (s *wsSubscription) snapshot() *wsMessagesRespObject {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	resp := &wsMessagesRespObject{
+		Messages:      make([]interface{}, 0, len(s.messages)),
+		Count:         len(s.messages),
+		Reconnections: s.reconnections,
+	}
+	for _, msg := range s.messages {
+		resp.Messages = append(resp.Messages, msg.Body)
+	}
+	return resp
+}
+
+func (ws *wsSession) getSubscription(url string) *wsSubscription {
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+	return ws.subscriptions[url]
+}
+
+// subscribeWebSocket starts collecting messages of connection in background until it is closed locally,
+// connection closed by remote server is reconnected if enabled in config.
+func (r *SessionRunner) subscribeWebSocket(urlStr string) *wsSubscription {
+	r.ws.mutex.Lock()
+	if sub, ok := r.ws.subscriptions[urlStr]; ok && sub.isActive() {
+		r.ws.mutex.Unlock()
+		return sub
+	}
+	sub := newWsSubscription()
+	r.ws.subscriptions[urlStr] = sub
+	r.ws.mutex.Unlock()
+
+	go func() {
+		for {
+			conn := getWsClient(r, urlStr)
+			if conn == nil {
+				sub.stop(errors.New("no connection"))
+				return
+			}
+			messageType, message, err := conn.ReadMessage()
+			if err == nil {
+				log.Debug().Str("url", urlStr).Str("type", MessageType(messageType).toString()).
+					Str("msg", string(message)).Msg("collect websocket message")
+				sub.add(messageType, message)
+				continue
+			}
+			if sub.isClosing() {
+				sub.stop(errors.New("connection closed"))
+				return
+			}
+			conn, err = r.reconnectWebSocket(urlStr, conn, err)
+			if err != nil {
+				log.Error().Err(err).Str("url", urlStr).Msg("stop collecting websocket messages")
+				sub.stop(err)
+				return
+			}
+			if sub.isClosing() {
+				// resources released during reconnection
+				conn.Close()
+				sub.stop(errors.New("connection closed"))
+				return
+			}
+			sub.reconnected()
+		}
+	}()
+	return sub
+}
+
+// waitMessageWithTimeout waits for the next collected message meeting until condition of step
+func waitMessageWithTimeout(urlStr string, r *SessionRunner, step *StepWebSocket,
+	stepVariables map[string]interface{},
+) (*wsMessage, *ValidationResult, error) {
+	sub := r.ws.getSubscription(urlStr)
+	if sub == nil {
+		return nil, nil, errors.New("messages are not subscribed on the connection")
+	}
+	until := step.WebSocket.Until
+	var untilResult *ValidationResult
+	var matcher eventMatcher
+	if until != nil {
+		var err error
+		untilResult, matcher, err = newEventMatcher(r.caseRunner.parser, until, stepVariables)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	msg, checkValue, err := sub.next(matcher, time.Duration(step.WebSocket.GetTimeout())*time.Millisecond)
+	if err != nil {
+		if until != nil {
+			return nil, nil, errors.Wrapf(err, "no message matches until condition: %s %s %v",
+				until.Check, until.Assert, untilResult.Expect)
+		}
+		return nil, nil, err
+	}
+	if untilResult != nil {
+		untilResult.CheckValue = checkValue
+		untilResult.CheckResult = "pass"
+	}
+	return msg, untilResult, nil
+}
+
+func getCollectedMessages(urlStr string, r *SessionRunner) (*wsMessagesRespObject, error) {
+	sub := r.ws.getSubscription(urlStr)
+	if sub == nil {
+		return nil, errors.New("messages are not subscribed on the connection")
+	}
+	return sub.snapshot(), nil
+}
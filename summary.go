@@ -61,6 +61,7 @@ func (s *Summary) AddCaseSummary(caseSummary *TestCaseSummary) {
 	}
 	s.Stat.TestSteps.Successes += caseSummary.Stat.Successes
 	s.Stat.TestSteps.Failures += caseSummary.Stat.Failures
+	s.Stat.TestSteps.Skipped += caseSummary.Stat.Skipped
 	s.Details = append(s.Details, caseSummary)
 
 	// specify output reports dir
@@ -136,6 +137,7 @@ type TestStepStat struct {
 	Total     int                       `json:"total" yaml:"total"`
 	Successes int                       `json:"successes" yaml:"successes"`
 	Failures  int                       `json:"failures" yaml:"failures"`
+	Skipped   int                       `json:"skipped" yaml:"skipped"`
 	Actions   map[option.ActionName]int `json:"actions" yaml:"actions"` // record action stats
 }
 
@@ -193,6 +195,19 @@ func (s *TestCaseSummary) AddStepResult(stepResult *StepResult) {
 		for _, result := range records {
 			s.addSingleStepResult(result)
 		}
+	case StepTypeWhile, StepTypeForeach:
+		// record child steps of control-flow step, child steps may be nested control-flow steps
+		records, ok := stepResult.Data.([]*StepResult)
+		if !ok {
+			log.Warn().
+				Interface("data", stepResult.Data).
+				Msgf("get unexpected %s step data", stepResult.StepType)
+			return
+		}
+		s.Success = s.Success && stepResult.Success
+		for _, result := range records {
+			s.AddStepResult(result)
+		}
 	default:
 		s.addSingleStepResult(stepResult)
 	}
@@ -201,7 +216,9 @@ func (s *TestCaseSummary) AddStepResult(stepResult *StepResult) {
 func (s *TestCaseSummary) addSingleStepResult(stepResult *StepResult) {
 	s.Success = s.Success && stepResult.Success
 	s.Stat.Total += 1
-	if stepResult.Success {
+	if stepResult.Skipped {
+		s.Stat.Skipped += 1
+	} else if stepResult.Success {
 		s.Stat.Successes += 1
 	} else {
 		s.Stat.Failures += 1
@@ -236,5 +253,6 @@ type ValidationResult struct {
 	Validator
 	CheckValue  interface{}             `json:"check_value" yaml:"check_value"`
 	CheckResult string                  `json:"check_result" yaml:"check_result"`
-	AIResult    *uixt.AIExecutionResult `json:"ai_result,omitempty" yaml:"ai_result,omitempty"` // store AI assertion result for displaying in report
+	AIResult    *uixt.AIExecutionResult `json:"ai_result,omitempty" yaml:"ai_result,omitempty"`   // store AI assertion result for displaying in report
+	Violations  []*SchemaViolation      `json:"violations,omitempty" yaml:"violations,omitempty"` // store json_schema/openapi assertion violations
 }
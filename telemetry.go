@@ -0,0 +1,218 @@
+package hrp
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/httprunner/httprunner/v5/internal/version"
+)
+
+const telemetryScope = "github.com/httprunner/httprunner/v5"
+
+var (
+	noopTracer             = noop.NewTracerProvider().Tracer(telemetryScope)
+	traceContextPropagator = propagation.TraceContext{}
+)
+
+// telemetry exports traces and metrics of test runs over OTLP/HTTP,
+// each testcase session is one trace and each step is a child span.
+type telemetry struct {
+	tracerProvider *sdktrace.TracerProvider
+	meterProvider  *sdkmetric.MeterProvider
+	tracer         trace.Tracer
+	stepDuration   metric.Float64Histogram
+	stepCount      metric.Int64Counter
+}
+
+func newTelemetry(endpoint string) (*telemetry, error) {
+	endpoint = strings.TrimRight(endpoint, "/")
+	if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+		endpoint = "http://" + endpoint
+	}
+	res := resource.NewSchemaless(
+		attribute.String("service.name", "httprunner"),
+		attribute.String("service.version", version.VERSION),
+	)
+
+	ctx := context.Background()
+	traceExporter, err := otlptracehttp.New(ctx,
+		otlptracehttp.WithEndpointURL(endpoint+"/v1/traces"))
+	if err != nil {
+		return nil, errors.Wrap(err, "create otlp trace exporter failed")
+	}
+	metricExporter, err := otlpmetrichttp.New(ctx,
+		otlpmetrichttp.WithEndpointURL(endpoint+"/v1/metrics"))
+	if err != nil {
+		return nil, errors.Wrap(err, "create otlp metric exporter failed")
+	}
+
+	t := &telemetry{
+		tracerProvider: sdktrace.NewTracerProvider(
+			sdktrace.WithBatcher(traceExporter),
+			sdktrace.WithResource(res),
+		),
+		meterProvider: sdkmetric.NewMeterProvider(
+			sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter)),
+			sdkmetric.WithResource(res),
+		),
+	}
+	t.tracer = t.tracerProvider.Tracer(telemetryScope, trace.WithInstrumentationVersion(version.VERSION))
+	meter := t.meterProvider.Meter(telemetryScope, metric.WithInstrumentationVersion(version.VERSION))
+	t.stepDuration, err = meter.Float64Histogram("hrp.step.duration",
+		metric.WithUnit("ms"), metric.WithDescription("duration of test steps"))
+	if err != nil {
+		return nil, errors.Wrap(err, "create step duration histogram failed")
+	}
+	t.stepCount, err = meter.Int64Counter("hrp.step.count",
+		metric.WithDescription("number of executed test steps"))
+	if err != nil {
+		return nil, errors.Wrap(err, "create step count counter failed")
+	}
+	return t, nil
+}
+
+// recordStep records step duration and success metrics.
+func (t *telemetry) recordStep(ctx context.Context, testCase string, stepResult *StepResult) {
+	attrs := metric.WithAttributes(
+		attribute.String("testcase", testCase),
+		attribute.String("step_type", string(stepResult.StepType)),
+		attribute.Bool("success", stepResult.Success),
+	)
+	t.stepDuration.Record(ctx, float64(stepResult.Elapsed), attrs)
+	t.stepCount.Add(ctx, 1, attrs)
+}
+
+// shutdown exports all pending spans and metrics, and stops tracer and meter providers,
+// the first error is returned if any of them fails.
+func (t *telemetry) shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var shutdownErr error
+	if err := t.tracerProvider.Shutdown(ctx); err != nil {
+		log.Error().Err(err).Msg("shutdown tracer provider failed")
+		shutdownErr = errors.Wrap(err, "shutdown tracer provider failed")
+	}
+	if err := t.meterProvider.Shutdown(ctx); err != nil {
+		log.Error().Err(err).Msg("shutdown meter provider failed")
+		if shutdownErr == nil {
+			shutdownErr = errors.Wrap(err, "shutdown meter provider failed")
+		}
+	}
+	return shutdownErr
+}
+
+// SetTelemetry enables OpenTelemetry traces and metrics export to OTLP/HTTP endpoint,
+// e.g. http://localhost:4318, W3C traceparent header is injected into outgoing requests.
+// Telemetry is shut down when the run ends, thus it should be set for each run.
+func (r *HRPRunner) SetTelemetry(endpoint string) *HRPRunner {
+	log.Info().Str("endpoint", endpoint).Msg("[init] SetTelemetry")
+	t, err := newTelemetry(endpoint)
+	if err != nil {
+		log.Error().Err(err).Str("endpoint", endpoint).Msg("[init] init telemetry failed")
+		return r
+	}
+	r.telemetry = t
+	return r
+}
+
+func (r *HRPRunner) tracer() trace.Tracer {
+	if r.telemetry == nil {
+		return noopTracer
+	}
+	return r.telemetry.tracer
+}
+
+// startCaseSpan starts root span for testcase session, session context is replaced
+// with testcase context until the returned end function is called.
+func (r *SessionRunner) startCaseSpan(config *TConfig) func(summary *TestCaseSummary, err error) {
+	parentCtx := r.ctx
+	ctx, span := r.caseRunner.hrpRunner.tracer().Start(parentCtx, config.Name,
+		trace.WithAttributes(attribute.String("hrp.testcase.path", config.Path)))
+	r.ctx = ctx
+
+	return func(summary *TestCaseSummary, err error) {
+		r.ctx = parentCtx
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+		} else if summary != nil && !summary.Success {
+			span.SetStatus(codes.Error, "testcase failed")
+		}
+		span.End()
+	}
+}
+
+// startStepSpan starts child span of testcase span for step, session context is replaced
+// with step context until the returned end function is called.
+func (r *SessionRunner) startStepSpan(stepName string, stepType StepType) func(stepResult *StepResult, err error) {
+	parentCtx := r.ctx
+	ctx, span := r.caseRunner.hrpRunner.tracer().Start(parentCtx, stepName,
+		trace.WithAttributes(attribute.String("hrp.step.type", string(stepType))))
+	r.ctx = ctx
+
+	return func(stepResult *StepResult, err error) {
+		r.ctx = parentCtx
+		if stepResult == nil {
+			span.End()
+			return
+		}
+		if span.SpanContext().IsValid() {
+			stepResult.TraceID = span.SpanContext().TraceID().String()
+		}
+		if len(stepResult.HttpStat) > 0 {
+			attrs := make([]attribute.KeyValue, 0, len(stepResult.HttpStat))
+			for key, value := range stepResult.HttpStat {
+				attrs = append(attrs, attribute.Int64("httpstat."+key, value))
+			}
+			span.AddEvent("httpstat", trace.WithAttributes(attrs...))
+		}
+		// UI actions are recorded as child spans of step with their own timings
+		for _, action := range stepResult.Actions {
+			start := time.UnixMilli(action.StartTime)
+			_, actionSpan := r.caseRunner.hrpRunner.tracer().Start(ctx, string(action.Method),
+				trace.WithTimestamp(start))
+			if action.Error != "" {
+				actionSpan.SetStatus(codes.Error, action.Error)
+			}
+			actionSpan.End(trace.WithTimestamp(start.Add(time.Duration(action.Elapsed) * time.Millisecond)))
+		}
+		span.SetAttributes(
+			attribute.Bool("hrp.step.success", stepResult.Success),
+			attribute.Bool("hrp.step.skipped", stepResult.Skipped),
+		)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+		} else if !stepResult.Success {
+			span.SetStatus(codes.Error, "step failed")
+		}
+		span.End()
+
+		if t := r.caseRunner.hrpRunner.telemetry; t != nil {
+			t.recordStep(parentCtx, r.caseRunner.TestCase.Config.Get().Name, stepResult)
+		}
+	}
+}
+
+// injectTraceContext injects W3C traceparent header of current span into request,
+// traceparent specified in step headers is kept.
+func (r *requestBuilder) injectTraceContext(ctx context.Context) {
+	if !trace.SpanContextFromContext(ctx).IsValid() || r.req.Header.Get("traceparent") != "" {
+		return
+	}
+	traceContextPropagator.Inject(ctx, propagation.HeaderCarrier(r.req.Header))
+	r.updateRequestMapHeaders()
+}
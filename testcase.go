@@ -236,6 +236,42 @@ func (tc *TestCaseDef) loadISteps() (*TestCase, error) {
 				StepConfig: step.StepConfig,
 				WebSocket:  step.WebSocket,
 			})
+		} else if step.GRPC != nil {
+			testCase.TestSteps = append(testCase.TestSteps, &StepGRPC{
+				StepConfig: step.StepConfig,
+				GRPC:       step.GRPC,
+			})
+		} else if step.TCP != nil || step.UDP != nil {
+			testCase.TestSteps = append(testCase.TestSteps, &StepSocket{
+				StepConfig: step.StepConfig,
+				TCP:        step.TCP,
+				UDP:        step.UDP,
+			})
+		} else if step.MQTT != nil {
+			testCase.TestSteps = append(testCase.TestSteps, &StepMQTT{
+				StepConfig: step.StepConfig,
+				MQTT:       step.MQTT,
+			})
+		} else if step.SQL != nil {
+			testCase.TestSteps = append(testCase.TestSteps, &StepSQL{
+				StepConfig: step.StepConfig,
+				SQL:        step.SQL,
+			})
+		} else if step.Redis != nil {
+			testCase.TestSteps = append(testCase.TestSteps, &StepRedis{
+				StepConfig: step.StepConfig,
+				Redis:      step.Redis,
+			})
+		} else if step.GraphQL != nil {
+			testCase.TestSteps = append(testCase.TestSteps, &StepGraphQL{
+				StepConfig: step.StepConfig,
+				GraphQL:    step.GraphQL,
+			})
+		} else if step.CookieJar != nil {
+			testCase.TestSteps = append(testCase.TestSteps, &StepCookieJar{
+				StepConfig: step.StepConfig,
+				CookieJar:  step.CookieJar,
+			})
 		} else if step.IOS != nil {
 			if len(step.Validators) > 0 {
 				testCase.TestSteps = append(testCase.TestSteps, &StepMobileUIValidation{
@@ -301,6 +337,29 @@ func (tc *TestCaseDef) loadISteps() (*TestCase, error) {
 				StepConfig: step.StepConfig,
 				Shell:      step.Shell,
 			})
+		} else if step.While != nil || step.Foreach != nil {
+			// load child steps of control-flow step
+			childCase := &TestCaseDef{
+				Config: tc.Config,
+				Steps:  step.Steps,
+			}
+			children, err := childCase.loadISteps()
+			if err != nil {
+				return nil, errors.Wrapf(err, "load child steps of %s failed", step.StepName)
+			}
+			if step.While != nil {
+				testCase.TestSteps = append(testCase.TestSteps, &StepWhile{
+					StepConfig: step.StepConfig,
+					While:      step.While,
+					Steps:      children.TestSteps,
+				})
+			} else {
+				testCase.TestSteps = append(testCase.TestSteps, &StepForeach{
+					StepConfig: step.StepConfig,
+					Foreach:    step.Foreach,
+					Steps:      children.TestSteps,
+				})
+			}
 		} else {
 			log.Warn().Interface("step", step).Msg("[convertTestCase] unexpected step")
 		}
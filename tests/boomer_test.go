@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	hrp "github.com/httprunner/httprunner/v5"
+)
+
+func newBoomTestServer(hits *int64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(hits, 1)
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/fail/" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		_, _ = w.Write([]byte(`{"user":"` + r.URL.Query().Get("user") + `"}`))
+	}))
+}
+
+func TestBoomerRunTestCases(t *testing.T) {
+	var hits int64
+	server := newBoomTestServer(&hits)
+	defer server.Close()
+
+	testcase1 := &hrp.TestCase{
+		Config: hrp.NewConfig("TestCase1").
+			SetBaseURL(server.URL).
+			WithParameters(map[string]interface{}{"user": []interface{}{"a", "b"}}).
+			SetWeight(3),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("rendezvous").SetRendezvous("rendezvous1").WithTimeout(100),
+			hrp.NewStep("start").StartTransaction("tx"),
+			hrp.NewStep("get user").
+				GET("/get").
+				WithParams(map[string]interface{}{"user": "$user"}).
+				Extract().
+				WithJmesPath("body.user", "varUser").
+				Validate().
+				AssertEqual("status_code", 200, "check status code").
+				AssertEqual("body.user", "$user", "check user"),
+			hrp.NewStep("end").EndTransaction("tx"),
+		},
+	}
+	testcase2 := &hrp.TestCase{
+		Config: hrp.NewConfig("TestCase2").
+			SetBaseURL(server.URL).
+			SetWeight(1),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("fail").
+				GET("/fail").
+				Validate().
+				AssertEqual("status_code", 200, "check status code"),
+		},
+	}
+
+	b := hrp.NewBoomer(4, 100)
+	b.SetLoopCount(40)
+	b.SetReportInterval(100 * time.Millisecond)
+	err := b.Run(testcase1, testcase2)
+	assert.Nil(t, err)
+
+	report := b.GetReport()
+	case1 := report.GetEntry("testcase", "TestCase1")
+	case2 := report.GetEntry("testcase", "TestCase2")
+	if !assert.NotNil(t, case1) || !assert.NotNil(t, case2) {
+		t.FailNow()
+	}
+	assert.Equal(t, int64(40), case1.NumRequests+case2.NumRequests)
+	assert.Greater(t, case1.NumRequests, case2.NumRequests)
+	assert.Equal(t, int64(0), case1.NumFailures)
+	assert.Equal(t, case2.NumRequests, case2.NumFailures)
+	assert.Equal(t, int64(40), atomic.LoadInt64(&hits))
+
+	step1 := report.GetEntry("request-GET", "get user")
+	if assert.NotNil(t, step1) {
+		assert.Equal(t, case1.NumRequests, step1.NumRequests)
+		assert.Equal(t, int64(0), step1.NumFailures)
+	}
+	tx := report.GetEntry("transaction", "tx")
+	if assert.NotNil(t, tx) {
+		assert.Equal(t, case1.NumRequests, tx.NumRequests)
+	}
+	step2 := report.GetEntry("request-GET", "fail")
+	if assert.NotNil(t, step2) {
+		assert.Equal(t, step2.NumRequests, step2.NumFailures)
+	}
+	assert.Nil(t, report.GetEntry("rendezvous", "rendezvous1"))
+}
@@ -0,0 +1,198 @@
+package tests
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	hrp "github.com/httprunner/httprunner/v5"
+)
+
+// eventRecorder collects run events in order
+type eventRecorder struct {
+	mutex  sync.Mutex
+	events []*hrp.RunEvent
+}
+
+func (r *eventRecorder) handle(event *hrp.RunEvent) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *eventRecorder) types() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var types []string
+	for _, event := range r.events {
+		types = append(types, string(event.Type)+":"+event.Step)
+	}
+	return types
+}
+
+func newListenerTestCase(t *testing.T) *hrp.TestCase {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name": "alice"}`))
+	}))
+	t.Cleanup(server.Close)
+	return &hrp.TestCase{
+		Config: hrp.NewConfig("listener demo").SetBaseURL(server.URL),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("get user").
+				GET("/api/users/1").
+				Validate().
+				AssertEqual("body.name", "alice", "check name"),
+			hrp.NewStep("skipped step").
+				SkipIf(true).
+				GET("/api/users/2"),
+			hrp.NewStep("failed step").
+				GET("/api/users/1").
+				Validate().
+				AssertEqual("body.name", "bob", "check name"),
+		},
+	}
+}
+
+var expectedEventTypes = []string{
+	"run_start:", "case_start:",
+	"step_start:get user", "step_end:get user",
+	"step_end:skipped step",
+	"step_start:failed step", "step_end:failed step",
+	"case_end:", "run_end:",
+}
+
+func TestRunListenerEvents(t *testing.T) {
+	recorder := &eventRecorder{}
+	_ = hrp.NewRunner(nil).SetFailfast(false).
+		AddListeners(hrp.RunEventHandler(recorder.handle)).
+		Run(newListenerTestCase(t))
+
+	assert.Equal(t, expectedEventTypes, recorder.types())
+	events := recorder.events
+	assert.Equal(t, []string{"listener demo"}, events[0].Data)
+	assert.Equal(t, "listener demo", events[1].Case)
+	assert.NotEmpty(t, events[1].SessionID)
+	assert.Equal(t, events[1].SessionID, events[3].SessionID)
+	assert.True(t, *events[3].Success)
+	assert.Contains(t, string(events[2].StepType), string(hrp.StepTypeRequest))
+	assert.True(t, events[4].Data.(*hrp.StepResult).Skipped)
+	assert.False(t, *events[6].Success)
+	assert.False(t, *events[7].Success)
+	assert.False(t, *events[8].Success)
+}
+
+func TestRunListenerNDJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events", "run.ndjson")
+	listener, err := hrp.NewNDJSONListener(path)
+	require.Nil(t, err)
+	_ = hrp.NewRunner(nil).SetFailfast(false).AddListeners(listener).Run(newListenerTestCase(t))
+	require.Nil(t, listener.Close())
+
+	file, err := os.Open(path)
+	require.Nil(t, err)
+	defer file.Close()
+	var types []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for scanner.Scan() {
+		var event hrp.RunEvent
+		require.Nil(t, json.Unmarshal(scanner.Bytes(), &event))
+		types = append(types, string(event.Type)+":"+event.Step)
+	}
+	assert.Equal(t, expectedEventTypes, types)
+}
+
+func TestRunListenerWebhook(t *testing.T) {
+	recorder := &eventRecorder{}
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "token", r.Header.Get("X-Token"))
+		var event hrp.RunEvent
+		_ = json.NewDecoder(r.Body).Decode(&event)
+		recorder.handle(&event)
+	}))
+	defer webhook.Close()
+
+	listener := hrp.NewWebhookListener(webhook.URL, map[string]string{"X-Token": "token"})
+	_ = hrp.NewRunner(nil).SetFailfast(false).AddListeners(listener).Run(newListenerTestCase(t))
+	require.Nil(t, listener.Close())
+	assert.Equal(t, expectedEventTypes, recorder.types())
+}
+
+func TestRunListenerSlowWebhook(t *testing.T) {
+	recorder := &eventRecorder{}
+	delay := 100 * time.Millisecond
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		var event hrp.RunEvent
+		_ = json.NewDecoder(r.Body).Decode(&event)
+		recorder.handle(&event)
+	}))
+	defer webhook.Close()
+
+	// events are posted in background, slow webhook does not block running
+	listener := hrp.NewWebhookListener(webhook.URL, nil)
+	start := time.Now()
+	_ = hrp.NewRunner(nil).SetFailfast(false).AddListeners(listener).Run(newListenerTestCase(t))
+	assert.Less(t, time.Since(start), time.Duration(len(expectedEventTypes))*delay/2)
+
+	// queued events are flushed on close
+	require.Nil(t, listener.Close())
+	assert.Equal(t, expectedEventTypes, recorder.types())
+}
+
+func TestRunListenerEventStream(t *testing.T) {
+	server := hrp.NewEventStreamServer()
+	require.Nil(t, server.Start("127.0.0.1:0"))
+	defer server.Close()
+
+	// websocket client subscribes before running
+	conn, _, err := websocket.DefaultDialer.Dial("ws://"+server.Addr()+"/ws", nil)
+	require.Nil(t, err)
+	defer conn.Close()
+
+	_ = hrp.NewRunner(nil).SetFailfast(false).AddListeners(server).Run(newListenerTestCase(t))
+
+	var wsTypes []string
+	for len(wsTypes) < len(expectedEventTypes) {
+		_, data, err := conn.ReadMessage()
+		require.Nil(t, err)
+		var event hrp.RunEvent
+		require.Nil(t, json.Unmarshal(data, &event))
+		wsTypes = append(wsTypes, string(event.Type)+":"+event.Step)
+	}
+	assert.Equal(t, expectedEventTypes, wsTypes)
+
+	// SSE client joins after running, history events are replayed
+	resp, err := http.Get("http://" + server.Addr() + "/events")
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	var sseTypes []string
+	reader := bufio.NewReader(resp.Body)
+	for len(sseTypes) < len(expectedEventTypes) {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		if strings.HasPrefix(line, "data: ") {
+			var event hrp.RunEvent
+			require.Nil(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event))
+			sseTypes = append(sseTypes, string(event.Type)+":"+event.Step)
+		}
+	}
+	assert.Equal(t, expectedEventTypes, sseTypes)
+}
@@ -0,0 +1,188 @@
+package tests
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	hrp "github.com/httprunner/httprunner/v5"
+)
+
+// gatherMetric sums values of metric samples matching the given labels
+func gatherMetric(t *testing.T, metrics *hrp.PrometheusMetrics, name string, labels map[string]string) float64 {
+	families, err := metrics.Registry().Gather()
+	require.Nil(t, err)
+	var value float64
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	samples:
+		for _, sample := range family.GetMetric() {
+			for _, pair := range sample.GetLabel() {
+				if expected, ok := labels[pair.GetName()]; ok && expected != pair.GetValue() {
+					continue samples
+				}
+			}
+			switch {
+			case sample.Counter != nil:
+				value += sample.Counter.GetValue()
+			case sample.Gauge != nil:
+				value += sample.Gauge.GetValue()
+			case sample.Histogram != nil:
+				value += float64(sample.Histogram.GetSampleCount())
+			}
+		}
+	}
+	return value
+}
+
+func newMetricsTestCase(serverURL string) *hrp.TestCase {
+	return &hrp.TestCase{
+		Config: hrp.NewConfig("metrics demo").SetBaseURL(serverURL),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("start").StartTransaction("tx"),
+			hrp.NewStep("get user").
+				GET("/get").
+				Validate().
+				AssertEqual("status_code", 200, "check status code"),
+			hrp.NewStep("end").EndTransaction("tx"),
+			hrp.NewStep("fail").
+				GET("/fail/").
+				Validate().
+				AssertEqual("status_code", 200, "check status code"),
+		},
+	}
+}
+
+func TestPrometheusMetricsEndpoint(t *testing.T) {
+	var hits int64
+	server := newBoomTestServer(&hits)
+	defer server.Close()
+
+	metrics := hrp.NewPrometheusMetrics()
+	require.Nil(t, metrics.Start("127.0.0.1:0"))
+	defer metrics.Close()
+
+	err := hrp.NewRunner(nil).SetFailfast(false).SetHTTPStatOn().
+		AddListeners(metrics).
+		Run(newMetricsTestCase(server.URL))
+	require.Nil(t, err)
+
+	assert.Equal(t, float64(2), gatherMetric(t, metrics, "hrp_step_requests_total", nil))
+	assert.Equal(t, float64(1), gatherMetric(t, metrics, "hrp_step_failures_total",
+		map[string]string{"step": "fail"}))
+	assert.Equal(t, float64(0), gatherMetric(t, metrics, "hrp_step_failures_total",
+		map[string]string{"step": "get user"}))
+	assert.Equal(t, float64(2), gatherMetric(t, metrics, "hrp_step_duration_seconds", nil))
+	assert.Equal(t, float64(2), gatherMetric(t, metrics, "hrp_httpstat_duration_seconds",
+		map[string]string{"phase": "ServerProcessing"}))
+	assert.Equal(t, float64(1), gatherMetric(t, metrics, "hrp_transactions_total",
+		map[string]string{"transaction": "tx", "success": "true"}))
+	assert.Equal(t, float64(1), gatherMetric(t, metrics, "hrp_transaction_duration_seconds", nil))
+	assert.Equal(t, float64(0), gatherMetric(t, metrics, "hrp_active_users", nil))
+
+	resp, err := http.Get("http://" + metrics.Addr() + "/metrics")
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.Nil(t, err)
+	assert.Contains(t, string(body), `hrp_step_failures_total{step="fail",step_type="request-GET`)
+	assert.Contains(t, string(body), `hrp_transactions_total{success="true",testcase="metrics demo",transaction="tx"} 1`)
+	assert.Contains(t, string(body), "hrp_active_users 0")
+}
+
+func TestPrometheusMetricsIterationLabels(t *testing.T) {
+	var hits int64
+	server := newBoomTestServer(&hits)
+	defer server.Close()
+
+	metrics := hrp.NewPrometheusMetrics()
+	testcase := &hrp.TestCase{
+		Config: hrp.NewConfig("metrics iterations").SetBaseURL(server.URL),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("get with parameters").
+				WithParameters(map[string]interface{}{"user": []interface{}{"a", "b"}}).
+				GET("/get").
+				WithParams(map[string]interface{}{"user": "$user"}),
+			hrp.NewStep("get with loops").
+				Loop(3).
+				GET("/get"),
+		},
+	}
+	err := hrp.NewRunner(nil).AddListeners(metrics).Run(testcase)
+	require.Nil(t, err)
+
+	// iterations of parameters and loops are labeled with the step name
+	assert.Equal(t, float64(2), gatherMetric(t, metrics, "hrp_step_requests_total",
+		map[string]string{"step": "get with parameters"}))
+	assert.Equal(t, float64(3), gatherMetric(t, metrics, "hrp_step_requests_total",
+		map[string]string{"step": "get with loops"}))
+	assert.Equal(t, float64(5), gatherMetric(t, metrics, "hrp_step_requests_total", nil))
+}
+
+func TestPrometheusMetricsPushgateway(t *testing.T) {
+	var hits int64
+	server := newBoomTestServer(&hits)
+	defer server.Close()
+
+	// fake pushgateway records pushed metrics
+	var mutex sync.Mutex
+	var paths []string
+	var lastPush string
+	pushgateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mutex.Lock()
+		defer mutex.Unlock()
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		lastPush = string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer pushgateway.Close()
+
+	metrics := hrp.NewPrometheusMetrics()
+	metrics.StartPush(pushgateway.URL, "soak", 20*time.Millisecond)
+	err := hrp.NewRunner(nil).SetFailfast(false).
+		AddListeners(metrics).
+		Run(newMetricsTestCase(server.URL))
+	require.Nil(t, err)
+	time.Sleep(50 * time.Millisecond)
+	require.Nil(t, metrics.Close())
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	require.GreaterOrEqual(t, len(paths), 2)
+	assert.True(t, strings.HasPrefix(paths[0], "PUT /metrics/job/soak/instance/"))
+	// the final push contains metrics of all steps, protobuf encoded
+	assert.Contains(t, lastPush, "hrp_step_requests_total")
+	assert.Contains(t, lastPush, "hrp_transactions_total")
+}
+
+func TestPrometheusMetricsBoomer(t *testing.T) {
+	var hits int64
+	server := newBoomTestServer(&hits)
+	defer server.Close()
+
+	metrics := hrp.NewPrometheusMetrics()
+	b := hrp.NewBoomer(2, 100)
+	b.SetLoopCount(10)
+	b.SetReportInterval(100 * time.Millisecond)
+	b.SetFailfast(false)
+	b.AddListeners(metrics)
+	require.Nil(t, b.Run(newMetricsTestCase(server.URL)))
+
+	assert.Equal(t, float64(10), gatherMetric(t, metrics, "hrp_step_requests_total",
+		map[string]string{"step": "get user"}))
+	assert.Equal(t, float64(10), gatherMetric(t, metrics, "hrp_step_failures_total",
+		map[string]string{"step": "fail"}))
+	assert.Equal(t, float64(10), gatherMetric(t, metrics, "hrp_transactions_total",
+		map[string]string{"transaction": "tx"}))
+	assert.Equal(t, float64(0), gatherMetric(t, metrics, "hrp_active_users", nil))
+}
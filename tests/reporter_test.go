@@ -0,0 +1,188 @@
+package tests
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	hrp "github.com/httprunner/httprunner/v5"
+)
+
+// newReporterSummary runs testcase with one passed, one failed and one skipped step
+func newReporterSummary(t *testing.T) *hrp.Summary {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name": "alice"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	testcase := hrp.TestCase{
+		Config: hrp.NewConfig("reporter demo").SetBaseURL(server.URL),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("get user").
+				GET("/api/users/1").
+				Validate().
+				AssertEqual("status_code", 200, "check status code"),
+			hrp.NewStep("check user name").
+				GET("/api/users/1").
+				Validate().
+				AssertEqual("body.name", "bob", "check name"),
+			hrp.NewStep("skipped step").
+				SkipIf(true).
+				GET("/api/users/2"),
+		},
+	}
+	caseRunner, err := hrp.NewCaseRunner(testcase, hrp.NewRunner(nil).SetFailfast(false))
+	require.Nil(t, err)
+	caseSummary, _ := caseRunner.NewSession().Start(nil)
+	require.NotNil(t, caseSummary)
+
+	summary := hrp.NewSummary()
+	summary.AddCaseSummary(caseSummary)
+	return summary
+}
+
+func TestReporterJUnit(t *testing.T) {
+	summary := newReporterSummary(t)
+	resultsDir := t.TempDir()
+	require.Nil(t, hrp.GenerateReports(summary, resultsDir, "junit"))
+
+	data, err := os.ReadFile(filepath.Join(resultsDir, "junit.xml"))
+	require.Nil(t, err)
+	var suites struct {
+		Tests    int `xml:"tests,attr"`
+		Failures int `xml:"failures,attr"`
+		Skipped  int `xml:"skipped,attr"`
+		Suites   []struct {
+			Name  string `xml:"name,attr"`
+			Cases []struct {
+				Name    string `xml:"name,attr"`
+				Failure *struct {
+					Message string `xml:"message,attr"`
+				} `xml:"failure"`
+				SystemOut string `xml:"system-out"`
+			} `xml:"testcase"`
+		} `xml:"testsuite"`
+	}
+	require.Nil(t, xml.Unmarshal(data, &suites))
+	assert.Equal(t, 3, suites.Tests)
+	assert.Equal(t, 1, suites.Failures)
+	assert.Equal(t, 1, suites.Skipped)
+	require.Len(t, suites.Suites, 1)
+	assert.Equal(t, "reporter demo", suites.Suites[0].Name)
+	cases := suites.Suites[0].Cases
+	require.Len(t, cases, 3)
+	assert.Nil(t, cases[0].Failure)
+	assert.Contains(t, cases[0].SystemOut, "/api/users/1")
+	require.NotNil(t, cases[1].Failure)
+	assert.Contains(t, cases[1].Failure.Message, "body.name")
+}
+
+func TestReporterAllure(t *testing.T) {
+	summary := newReporterSummary(t)
+	resultsDir := t.TempDir()
+	require.Nil(t, hrp.GenerateReports(summary, resultsDir, "allure"))
+
+	allureDir := filepath.Join(resultsDir, "allure-results")
+	files, err := filepath.Glob(filepath.Join(allureDir, "*-result.json"))
+	require.Nil(t, err)
+	require.Len(t, files, 1)
+
+	data, err := os.ReadFile(files[0])
+	require.Nil(t, err)
+	var result struct {
+		Name   string `json:"name"`
+		Status string `json:"status"`
+		Steps  []struct {
+			Status      string `json:"status"`
+			Attachments []struct {
+				Name   string `json:"name"`
+				Source string `json:"source"`
+			} `json:"attachments"`
+		} `json:"steps"`
+	}
+	require.Nil(t, json.Unmarshal(data, &result))
+	assert.Equal(t, "reporter demo", result.Name)
+	assert.Equal(t, "failed", result.Status)
+	require.Len(t, result.Steps, 3)
+	assert.Equal(t, "passed", result.Steps[0].Status)
+	assert.Equal(t, "failed", result.Steps[1].Status)
+	assert.Equal(t, "skipped", result.Steps[2].Status)
+	require.Len(t, result.Steps[0].Attachments, 2)
+	assert.Equal(t, "request", result.Steps[0].Attachments[0].Name)
+	assert.FileExists(t, filepath.Join(allureDir, result.Steps[0].Attachments[0].Source))
+}
+
+func TestReporterCTRFFromFiles(t *testing.T) {
+	summary := newReporterSummary(t)
+	resultsDir := t.TempDir()
+
+	// reports are generated from summary file in existing results folder
+	summaryFile := filepath.Join(resultsDir, "hrp_summary.json")
+	data, err := json.Marshal(summary)
+	require.Nil(t, err)
+	require.Nil(t, os.WriteFile(summaryFile, data, 0o644))
+	require.Nil(t, hrp.GenerateReportsFromFiles(summaryFile, "ctrf", "junit"))
+	assert.FileExists(t, filepath.Join(resultsDir, "junit.xml"))
+
+	data, err = os.ReadFile(filepath.Join(resultsDir, "ctrf-report.json"))
+	require.Nil(t, err)
+	var report struct {
+		Results struct {
+			Tool    struct{ Name string }
+			Summary struct {
+				Tests   int `json:"tests"`
+				Passed  int `json:"passed"`
+				Failed  int `json:"failed"`
+				Skipped int `json:"skipped"`
+			} `json:"summary"`
+			Tests []struct {
+				Name    string `json:"name"`
+				Status  string `json:"status"`
+				Suite   string `json:"suite"`
+				Message string `json:"message"`
+			} `json:"tests"`
+		} `json:"results"`
+	}
+	require.Nil(t, json.Unmarshal(data, &report))
+	assert.Equal(t, "httprunner", report.Results.Tool.Name)
+	assert.Equal(t, 3, report.Results.Summary.Tests)
+	assert.Equal(t, 1, report.Results.Summary.Passed)
+	assert.Equal(t, 1, report.Results.Summary.Failed)
+	assert.Equal(t, 1, report.Results.Summary.Skipped)
+	require.Len(t, report.Results.Tests, 3)
+	assert.Equal(t, "reporter demo", report.Results.Tests[1].Suite)
+	assert.True(t, strings.Contains(report.Results.Tests[1].Message, "bob"))
+}
+
+type customReporter struct {
+	generated bool
+}
+
+func (r *customReporter) Name() string {
+	return "custom"
+}
+
+func (r *customReporter) Generate(summary *hrp.Summary, resultsDir string) (string, error) {
+	r.generated = true
+	return resultsDir, nil
+}
+
+func TestReporterRegister(t *testing.T) {
+	reporter := &customReporter{}
+	hrp.RegisterReporter(reporter)
+	assert.Contains(t, hrp.ReporterNames(), "custom")
+	assert.Nil(t, hrp.GenerateReports(hrp.NewSummary(), t.TempDir(), "custom"))
+	assert.True(t, reporter.generated)
+
+	err := hrp.GenerateReports(hrp.NewSummary(), t.TempDir(), "unknown")
+	assert.ErrorContains(t, err, "reporter unknown not found")
+}
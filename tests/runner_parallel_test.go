@@ -0,0 +1,170 @@
+package tests
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	hrp "github.com/httprunner/httprunner/v5"
+	"github.com/httprunner/httprunner/v5/code"
+)
+
+// newDelayServer returns a server which responds after the delay specified in query,
+// and records the max number of requests being processed concurrently.
+func newDelayServer(maxConcurrency *int64) *httptest.Server {
+	var current int64
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&current, 1)
+		defer atomic.AddInt64(&current, -1)
+		for {
+			m := atomic.LoadInt64(maxConcurrency)
+			if n <= m || atomic.CompareAndSwapInt64(maxConcurrency, m, n) {
+				break
+			}
+		}
+
+		delay, _ := time.ParseDuration(r.URL.Query().Get("delay"))
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+		if strings.HasPrefix(r.URL.Path, "/status/500") {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"user":"` + r.URL.Query().Get("user") + `"}`))
+	}))
+}
+
+func TestRunParallel(t *testing.T) {
+	var maxConcurrency int64
+	server := newDelayServer(&maxConcurrency)
+	defer server.Close()
+
+	testcase := &hrp.TestCase{
+		Config: hrp.NewConfig("parallel").
+			SetBaseURL(server.URL).
+			WithVariables(map[string]interface{}{"foo": "bar"}).
+			WithParameters(map[string]interface{}{
+				"user": []interface{}{"a", "b", "c", "d", "e", "f", "g", "h"},
+			}),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("get user $user").
+				GET("/get").
+				WithParams(map[string]interface{}{"user": "$user", "delay": "200ms"}).
+				Extract().
+				WithJmesPath("body.user", "varUser").
+				Validate().
+				AssertEqual("status_code", 200, "check status code").
+				AssertEqual("body.user", "$user", "check user"),
+			hrp.NewStep("check extracted variable").
+				GET("/get").
+				WithParams(map[string]interface{}{"user": "$varUser"}).
+				Validate().
+				AssertEqual("body.user", "$user", "check session variable"),
+			hrp.NewStep("check config variable").
+				Shell(`test "$foo" = "bar"`),
+		},
+	}
+
+	start := time.Now()
+	err := hrp.NewRunner(t).SetParallelism(4).Run(testcase)
+	assert.Nil(t, err)
+	assert.Less(t, time.Since(start), 8*200*time.Millisecond)
+	assert.Equal(t, int64(4), atomic.LoadInt64(&maxConcurrency))
+}
+
+func TestRunParallelFailfast(t *testing.T) {
+	var maxConcurrency int64
+	server := newDelayServer(&maxConcurrency)
+	defer server.Close()
+
+	slowCase := &hrp.TestCase{
+		Config: hrp.NewConfig("slow").SetBaseURL(server.URL),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("slow request").
+				GET("/get").
+				WithParams(map[string]interface{}{"delay": "3s"}),
+		},
+	}
+	failedCase := &hrp.TestCase{
+		Config: hrp.NewConfig("failed").SetBaseURL(server.URL),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("failed request").
+				GET("/status/500").
+				Validate().
+				AssertEqual("status_code", 200, "check status code"),
+		},
+	}
+
+	start := time.Now()
+	err := hrp.NewRunner(nil).SetParallelism(2).Run(slowCase, failedCase, slowCase)
+	assert.NotNil(t, err)
+	assert.False(t, errors.Is(err, code.InterruptError))
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestRunStepTimeoutNotShared(t *testing.T) {
+	var maxConcurrency int64
+	server := newDelayServer(&maxConcurrency)
+	defer server.Close()
+
+	testcase := &hrp.TestCase{
+		Config: hrp.NewConfig("step timeout").SetBaseURL(server.URL),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("request with step timeout").
+				GET("/get").
+				WithParams(map[string]interface{}{"delay": "300ms"}).
+				SetTimeout(100 * time.Millisecond),
+			hrp.NewStep("request without step timeout").
+				GET("/get").
+				WithParams(map[string]interface{}{"delay": "300ms"}).
+				Validate().
+				AssertEqual("status_code", 200, "check status code"),
+		},
+	}
+
+	caseRunner, err := hrp.NewCaseRunner(*testcase, hrp.NewRunner(t).SetFailfast(false))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	summary, err := caseRunner.NewSession().Start(nil)
+	assert.Nil(t, err)
+	if assert.Len(t, summary.Records, 2) {
+		assert.False(t, summary.Records[0].Success)
+		assert.True(t, summary.Records[1].Success)
+	}
+}
+
+func TestRunParallelFunctionSteps(t *testing.T) {
+	var mismatched int64
+	newTestCase := func(name string) *hrp.TestCase {
+		fn := func() {
+			// config variables of current testcase are not overwritten while function runs
+			before := os.Getenv("HRP_FUNCTION_CASE")
+			time.Sleep(50 * time.Millisecond)
+			if before != name || os.Getenv("HRP_FUNCTION_CASE") != name {
+				atomic.AddInt64(&mismatched, 1)
+			}
+		}
+		return &hrp.TestCase{
+			Config: hrp.NewConfig(name).
+				WithVariables(map[string]interface{}{"HRP_FUNCTION_CASE": name}),
+			TestSteps: []hrp.IStep{
+				hrp.NewStep("function").Function(fn),
+			},
+		}
+	}
+	err := hrp.NewRunner(t).SetParallelism(4).
+		Run(newTestCase("a"), newTestCase("b"), newTestCase("c"), newTestCase("d"))
+	assert.Nil(t, err)
+	assert.Zero(t, atomic.LoadInt64(&mismatched))
+}
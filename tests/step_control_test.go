@@ -0,0 +1,233 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	hrp "github.com/httprunner/httprunner/v5"
+)
+
+// newControlFlowServer returns a server for control-flow steps:
+// /items responds with a list of ids, /item echoes the requested id,
+// /poll responds with pending status until the third request.
+func newControlFlowServer(hits *int64) *httptest.Server {
+	var polls int64
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(hits, 1)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/items"):
+			_, _ = w.Write([]byte(`{"ids":[101,102,103]}`))
+		case strings.HasPrefix(r.URL.Path, "/item"):
+			_, _ = w.Write([]byte(`{"id":` + r.URL.Query().Get("id") + `}`))
+		case strings.HasPrefix(r.URL.Path, "/poll"):
+			n := atomic.AddInt64(&polls, 1)
+			status := "pending"
+			if n >= 3 {
+				status = "done"
+			}
+			_, _ = w.Write([]byte(fmt.Sprintf(`{"status":"%s","pending":%v}`, status, n < 3)))
+		default:
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+}
+
+func TestStepSkipIfAndRunIf(t *testing.T) {
+	var hits int64
+	server := newControlFlowServer(&hits)
+	defer server.Close()
+
+	testcase := hrp.TestCase{
+		Config: hrp.NewConfig("skip steps").
+			SetBaseURL(server.URL).
+			WithVariables(map[string]interface{}{"env": "prod"}),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("get status").
+				GET("/poll").
+				Extract().
+				WithJmesPath("body.status", "status"),
+			hrp.NewStep("skipped by skip_if").
+				SkipIf("${eq($env, prod)}").
+				GET("/items"),
+			hrp.NewStep("skipped by run_if").
+				RunIf("${eq($status, done)}").
+				GET("/items"),
+			hrp.NewStep("run by run_if").
+				RunIf("${ne($status, done)}").
+				GET("/items"),
+		},
+	}
+	caseRunner, err := hrp.NewCaseRunner(testcase, hrp.NewRunner(t))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	summary, err := caseRunner.NewSession().Start(nil)
+	assert.Nil(t, err)
+	assert.True(t, summary.Success)
+	assert.Equal(t, int64(2), atomic.LoadInt64(&hits))
+	assert.Equal(t, 4, summary.Stat.Total)
+	assert.Equal(t, 2, summary.Stat.Successes)
+	assert.Equal(t, 2, summary.Stat.Skipped)
+	if assert.Len(t, summary.Records, 4) {
+		assert.False(t, summary.Records[0].Skipped)
+		assert.True(t, summary.Records[1].Skipped)
+		assert.True(t, summary.Records[2].Skipped)
+		assert.Equal(t, "run_if condition ${eq($status, done)} is false", summary.Records[2].Attachments)
+		assert.False(t, summary.Records[3].Skipped)
+	}
+}
+
+func TestStepWhile(t *testing.T) {
+	var hits int64
+	server := newControlFlowServer(&hits)
+	defer server.Close()
+
+	testcase := hrp.TestCase{
+		Config: hrp.NewConfig("while step").SetBaseURL(server.URL),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("poll until done").
+				WithVariables(map[string]interface{}{"status": "init"}).
+				While("${ne($status, done)}",
+					hrp.NewStep("poll").
+						GET("/poll").
+						Extract().
+						WithJmesPath("body.status", "status"),
+				).
+				WithMaxIterations(5),
+			hrp.NewStep("check status").
+				GET("/item").
+				WithParams(map[string]interface{}{"id": 1}).
+				Validate().
+				AssertEqual("$status", "done", "check extracted status"),
+		},
+	}
+	caseRunner, err := hrp.NewCaseRunner(testcase, hrp.NewRunner(t))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	summary, err := caseRunner.NewSession().Start(nil)
+	assert.Nil(t, err)
+	assert.True(t, summary.Success)
+	assert.Equal(t, int64(4), atomic.LoadInt64(&hits))
+	if assert.Len(t, summary.Records, 4) {
+		assert.Equal(t, "poll until done [while_1] - poll", summary.Records[0].Name)
+		assert.Equal(t, "poll until done [while_3] - poll", summary.Records[2].Name)
+		assert.Equal(t, "check status", summary.Records[3].Name)
+	}
+}
+
+func TestStepWhileExceedMaxIterations(t *testing.T) {
+	var hits int64
+	server := newControlFlowServer(&hits)
+	defer server.Close()
+
+	testcase := hrp.TestCase{
+		Config: hrp.NewConfig("while step").SetBaseURL(server.URL),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("poll forever").
+				WithVariables(map[string]interface{}{"pending": true}).
+				While("$pending",
+					hrp.NewStep("poll").
+						GET("/items"),
+				).
+				WithMaxIterations(2),
+		},
+	}
+	caseRunner, err := hrp.NewCaseRunner(testcase, hrp.NewRunner(nil))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	summary, err := caseRunner.NewSession().Start(nil)
+	assert.NotNil(t, err)
+	assert.False(t, summary.Success)
+	assert.Equal(t, int64(2), atomic.LoadInt64(&hits))
+}
+
+func TestStepForeach(t *testing.T) {
+	var hits int64
+	server := newControlFlowServer(&hits)
+	defer server.Close()
+
+	testcase := hrp.TestCase{
+		Config: hrp.NewConfig("foreach step").SetBaseURL(server.URL),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("get items").
+				GET("/items").
+				Extract().
+				WithJmesPath("body.ids", "ids"),
+			hrp.NewStep("get each item").
+				Foreach("$ids",
+					hrp.NewStep("get item $id").
+						GET("/item").
+						WithParams(map[string]interface{}{"id": "$id"}).
+						Validate().
+						AssertEqual("body.id", "$id", "check item id"),
+				).
+				As("id"),
+		},
+	}
+	caseRunner, err := hrp.NewCaseRunner(testcase, hrp.NewRunner(t))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	summary, err := caseRunner.NewSession().Start(nil)
+	assert.Nil(t, err)
+	assert.True(t, summary.Success)
+	assert.Equal(t, int64(4), atomic.LoadInt64(&hits))
+	if assert.Len(t, summary.Records, 4) {
+		assert.Equal(t, "get each item [foreach_1] - get item 101", summary.Records[1].Name)
+		assert.Equal(t, "get each item [foreach_3] - get item 103", summary.Records[3].Name)
+	}
+}
+
+func TestStepControlFlowLoadFromJSON(t *testing.T) {
+	var hits int64
+	server := newControlFlowServer(&hits)
+	defer server.Close()
+
+	content := `{
+	"config": {"name": "control flow", "base_url": "` + server.URL + `", "variables": {"skip": true}},
+	"teststeps": [
+		{
+			"name": "get items",
+			"request": {"method": "GET", "url": "/items"},
+			"extract": {"ids": "body.ids"}
+		},
+		{
+			"name": "skipped",
+			"skip_if": "$skip",
+			"request": {"method": "GET", "url": "/items"}
+		},
+		{
+			"name": "get each item",
+			"foreach": {"items": "$ids"},
+			"steps": [{
+				"name": "get item",
+				"request": {"method": "GET", "url": "/item", "params": {"id": "$item"}},
+				"validate": [{"eq": ["body.id", "$item"]}]
+			}]
+		}
+	]
+}`
+	tc := loadJSONTestCase(t, "", content)
+	if assert.Len(t, tc.TestSteps, 3) {
+		assert.Equal(t, hrp.StepTypeForeach, tc.TestSteps[2].Type())
+	}
+
+	caseRunner, err := hrp.NewCaseRunner(*tc, hrp.NewRunner(t))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	summary, err := caseRunner.NewSession().Start(nil)
+	assert.Nil(t, err)
+	assert.True(t, summary.Success)
+	assert.Equal(t, int64(4), atomic.LoadInt64(&hits))
+	assert.Equal(t, 1, summary.Stat.Skipped)
+}
@@ -0,0 +1,302 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	hrp "github.com/httprunner/httprunner/v5"
+)
+
+// newCookieServer mocks login endpoint which sets session cookie,
+// and profile endpoint which returns the user of session cookie
+func newCookieServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch strings.TrimSuffix(r.URL.Path, "/") {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: r.URL.Query().Get("user"), Path: "/"})
+			fmt.Fprint(w, `{"login": true}`)
+		default:
+			user := ""
+			if cookie, err := r.Cookie("session"); err == nil {
+				user = cookie.Value
+			}
+			fmt.Fprintf(w, `{"user": %q}`, user)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSessionCookieJarIsolation(t *testing.T) {
+	server := newCookieServer(t)
+
+	// each parameter iteration runs in a new session, cookies of former session should not be sent
+	testcase := &hrp.TestCase{
+		Config: hrp.NewConfig("cookie isolation").
+			SetBaseURL(server.URL).
+			WithParameters(map[string]interface{}{"user": []interface{}{"alice", "bob"}}),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("profile before login").
+				GET("/profile").
+				Validate().
+				AssertEqual("body.user", "", "check no session cookie"),
+			hrp.NewStep("login").
+				GET("/login").
+				WithParams(map[string]interface{}{"user": "$user"}),
+			hrp.NewStep("profile after login").
+				GET("/profile").
+				Validate().
+				AssertEqual("body.user", "$user", "check session cookie"),
+		},
+	}
+	err := hrp.NewRunner(t).Run(testcase)
+	assert.Nil(t, err)
+}
+
+func TestCookieJarSteps(t *testing.T) {
+	server := newCookieServer(t)
+	cookieFile := filepath.Join(t.TempDir(), "cookies.txt")
+
+	testcase := &hrp.TestCase{
+		Config: hrp.NewConfig("cookie jar steps").
+			SetBaseURL(server.URL).
+			WithVariables(map[string]interface{}{"cookie_file": cookieFile}),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("login").
+				GET("/login").
+				WithParams(map[string]interface{}{"user": "alice"}),
+			hrp.NewStep("export cookies").
+				CookieJar().
+				ExportFile("$cookie_file").
+				Extract().
+				WithJmesPath("cookies_detail", "saved_cookies").
+				Validate().
+				AssertEqual("cookies.session", "alice", "check session cookie").
+				AssertEqual("cookies_detail[0].domain", "127.0.0.1", "check cookie domain").
+				AssertEqual("cookies_detail[0].path", "/", "check cookie path"),
+			hrp.NewStep("clear cookies").
+				CookieJar().
+				Clear().
+				Validate().
+				AssertLengthEqual("cookies", 0, "check cookies cleared"),
+			hrp.NewStep("profile after clear").
+				GET("/profile").
+				Validate().
+				AssertEqual("body.user", "", "check no session cookie"),
+			hrp.NewStep("import cookies from variable").
+				CookieJar().
+				Import("$saved_cookies").
+				Validate().
+				AssertEqual("cookies.session", "alice", "check session cookie"),
+			hrp.NewStep("profile after import").
+				GET("/profile").
+				Validate().
+				AssertEqual("body.user", "alice", "check session cookie"),
+			hrp.NewStep("import cookies map").
+				CookieJar().
+				WithURL("/profile").
+				Import(map[string]interface{}{"session": "bob"}),
+			hrp.NewStep("profile after import map").
+				GET("/profile").
+				Validate().
+				AssertEqual("body.user", "bob", "check session cookie"),
+			hrp.NewStep("clear cookies again").
+				CookieJar().
+				Clear(),
+			hrp.NewStep("import cookies from file").
+				CookieJar().
+				ImportFile("$cookie_file").
+				Validate().
+				AssertEqual("cookies.session", "alice", "check session cookie"),
+			hrp.NewStep("profile after import file").
+				GET("/profile").
+				Validate().
+				AssertEqual("body.user", "alice", "check session cookie"),
+		},
+	}
+	err := hrp.NewRunner(t).Run(testcase)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	content, err := os.ReadFile(cookieFile)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	assert.Contains(t, string(content), "# Netscape HTTP Cookie File")
+	assert.Contains(t, string(content), "127.0.0.1\tFALSE\t/\tFALSE\t0\tsession\talice")
+}
+
+func TestCookieJarExportAttributes(t *testing.T) {
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{
+			Name: "token", Value: "abc", Domain: "localhost", Path: "/api",
+			Expires: expires, HttpOnly: true,
+		})
+		http.SetCookie(w, &http.Cookie{Name: "lang", Value: "en", Path: "/"})
+		fmt.Fprint(w, `{}`)
+	}))
+	t.Cleanup(server.Close)
+	baseURL := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	cookieFile := filepath.Join(t.TempDir(), "cookies.txt")
+
+	testcase := &hrp.TestCase{
+		Config: hrp.NewConfig("cookie attributes").
+			SetBaseURL(baseURL).
+			WithVariables(map[string]interface{}{"cookie_file": cookieFile}),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("login").GET("/api/login"),
+			hrp.NewStep("export cookies").
+				CookieJar().
+				WithURL("/api/login").
+				ExportFile("$cookie_file").
+				Validate().
+				AssertEqual("cookies_detail[0].name", "lang", "check host-only cookie").
+				AssertEqual("cookies_detail[0].domain", "localhost", "check cookie domain").
+				AssertEqual("cookies_detail[1].name", "token", "check domain cookie").
+				AssertEqual("cookies_detail[1].domain", ".localhost", "check cookie domain").
+				AssertEqual("cookies_detail[1].path", "/api", "check cookie path").
+				AssertEqual("cookies_detail[1].http_only", true, "check http only").
+				AssertEqual("cookies_detail[1].expires", expires.Unix(), "check expires"),
+			hrp.NewStep("export cookies out of path").
+				CookieJar().
+				WithURL("/other").
+				Validate().
+				AssertEqual("cookies", map[string]interface{}{"lang": "en"}, "check cookies scoped by path"),
+		},
+	}
+	err := hrp.NewRunner(t).Run(testcase)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	content, err := os.ReadFile(cookieFile)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	assert.Contains(t, string(content),
+		fmt.Sprintf("#HttpOnly_.localhost\tTRUE\t/api\tFALSE\t%d\ttoken\tabc", expires.Unix()))
+	assert.Contains(t, string(content), "localhost\tFALSE\t/\tFALSE\t0\tlang\ten")
+}
+
+func TestCookieJarImportNetscapeFile(t *testing.T) {
+	server := newCookieServer(t)
+	expires := time.Now().Add(time.Hour).Unix()
+	projectDir := newProjectDir(t, map[string][]byte{
+		"cookies.txt": []byte(fmt.Sprintf("# Netscape HTTP Cookie File\n"+
+			"#HttpOnly_127.0.0.1\tFALSE\t/\tFALSE\t%d\tsession\tcarol\n"+
+			"127.0.0.1\tFALSE\t/\tFALSE\t1\texpired\tvalue\n", expires)),
+	})
+
+	testcase := &hrp.TestCase{
+		Config: hrp.NewConfig("import netscape cookie file").
+			SetBaseURL(server.URL),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("import cookies").
+				CookieJar().
+				ImportFile("cookies.txt").
+				Validate().
+				AssertLengthEqual("cookies", 1, "check expired cookie ignored").
+				AssertEqual("cookies.session", "carol", "check session cookie"),
+			hrp.NewStep("profile").
+				GET("/profile").
+				Validate().
+				AssertEqual("body.user", "carol", "check session cookie"),
+		},
+	}
+	testcase.Config.Get().Path = projectDir
+	err := hrp.NewRunner(t).Run(testcase)
+	assert.Nil(t, err)
+}
+
+func TestDisableCookies(t *testing.T) {
+	server := newCookieServer(t)
+
+	testcase := &hrp.TestCase{
+		Config: hrp.NewConfig("disable cookies").
+			SetBaseURL(server.URL).
+			SetDisableCookies(true),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("login").
+				GET("/login").
+				WithParams(map[string]interface{}{"user": "alice"}),
+			hrp.NewStep("profile").
+				GET("/profile").
+				Validate().
+				AssertEqual("body.user", "", "check cookie not stored"),
+		},
+	}
+	err := hrp.NewRunner(t).Run(testcase)
+	assert.Nil(t, err)
+
+	testcase.TestSteps = []hrp.IStep{
+		hrp.NewStep("export cookies").CookieJar(),
+	}
+	err = hrp.NewRunner(nil).Run(testcase)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "cookie jar is disabled")
+	}
+}
+
+func TestReferencedTestCaseSharesCookies(t *testing.T) {
+	server := newCookieServer(t)
+
+	login := &hrp.TestCase{
+		Config: hrp.NewConfig("login"),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("login").
+				GET("/login").
+				WithParams(map[string]interface{}{"user": "alice"}),
+		},
+	}
+	testcase := &hrp.TestCase{
+		Config: hrp.NewConfig("reuse login cookies").
+			SetBaseURL(server.URL),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("login").CallRefCase(login),
+			hrp.NewStep("profile").
+				GET("/profile").
+				Validate().
+				AssertEqual("body.user", "alice", "check session cookie"),
+		},
+	}
+	err := hrp.NewRunner(t).Run(testcase)
+	assert.Nil(t, err)
+}
+
+func TestCookieJarLoadFromJSON(t *testing.T) {
+	server := newCookieServer(t)
+
+	content := `{
+	"config": {"name": "cookie jar", "base_url": "` + server.URL + `"},
+	"teststeps": [
+		{
+			"name": "import cookies",
+			"cookie_jar": {"action": "import", "cookies": {"session": "dave"}},
+			"validate": [{"eq": ["cookies.session", "dave"]}]
+		},
+		{
+			"name": "profile",
+			"request": {"method": "GET", "url": "/profile"},
+			"validate": [{"eq": ["body.user", "dave"]}]
+		},
+		{
+			"name": "clear cookies",
+			"cookie_jar": {"action": "clear"},
+			"validate": [{"len_eq": ["cookies_detail", 0]}]
+		}
+	]
+}`
+	_, err := runJSONTestCase(t, "", content)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,246 @@
+package tests
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	hrp "github.com/httprunner/httprunner/v5"
+)
+
+const userQuery = `query getUser($id: ID!) {
+  user(id: $id) {
+    id
+    name
+  }
+}`
+
+// newGraphQLServer mocks graphql server with automatic persisted queries support
+func newGraphQLServer(t *testing.T) (*httptest.Server, *[]map[string]interface{}) {
+	var mu sync.Mutex
+	var payloads []map[string]interface{}
+	persisted := make(map[string]string)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Query         string                 `json:"query"`
+			OperationName string                 `json:"operationName"`
+			Variables     map[string]interface{} `json:"variables"`
+			Extensions    struct {
+				PersistedQuery struct {
+					SHA256Hash string `json:"sha256Hash"`
+				} `json:"persistedQuery"`
+			} `json:"extensions"`
+		}
+		body := map[string]interface{}{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		raw, _ := json.Marshal(body)
+		_ = json.Unmarshal(raw, &payload)
+
+		mu.Lock()
+		payloads = append(payloads, body)
+		hash := payload.Extensions.PersistedQuery.SHA256Hash
+		if hash != "" {
+			if payload.Query != "" {
+				sum := sha256.Sum256([]byte(payload.Query))
+				if hex.EncodeToString(sum[:]) == hash {
+					persisted[hash] = payload.Query
+				}
+			} else {
+				payload.Query = persisted[hash]
+			}
+		}
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		var resp map[string]interface{}
+		switch {
+		case payload.Query == "":
+			resp = map[string]interface{}{
+				"errors": []interface{}{map[string]interface{}{
+					"message":    "PersistedQueryNotFound",
+					"extensions": map[string]interface{}{"code": "PERSISTED_QUERY_NOT_FOUND"},
+				}},
+			}
+		case strings.Contains(payload.Query, "user(id: $id)"):
+			id := payload.Variables["id"]
+			resp = map[string]interface{}{
+				"data": map[string]interface{}{
+					"user": map[string]interface{}{"id": id, "name": "user-" + id.(string)},
+				},
+			}
+		default:
+			resp = map[string]interface{}{
+				"data": nil,
+				"errors": []interface{}{
+					map[string]interface{}{"message": "Cannot query field \"unknown\""},
+					map[string]interface{}{"message": "Unauthorized"},
+				},
+			}
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+	return server, &payloads
+}
+
+func TestGraphQLQueryWithVariables(t *testing.T) {
+	server, payloads := newGraphQLServer(t)
+
+	step := hrp.NewStep("get user").
+		WithVariables(map[string]interface{}{"uid": "1001"}).
+		GraphQL("/graphql").
+		WithQuery(userQuery).
+		WithOperationName("getUser").
+		WithQueryVariables(map[string]interface{}{"id": "$uid"}).
+		WithHeaders(map[string]string{"Authorization": "Bearer token"}).
+		Extract().
+		WithJmesPath("body.data.user.name", "name").
+		Validate().
+		AssertEqual("status_code", 200, "check status code").
+		AssertEqual("body.data.user.id", "1001", "check user id")
+
+	stepResult, err := runSingleStep(t, hrp.NewRunner(t), server.URL, step)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	assert.True(t, stepResult.Success)
+	assert.Equal(t, hrp.StepTypeGraphQL, stepResult.StepType)
+	assert.Equal(t, "user-1001", stepResult.ExportVars["name"])
+
+	// graphql variables syntax is kept in query
+	if assert.Len(t, *payloads, 1) {
+		payload := (*payloads)[0]
+		assert.Equal(t, userQuery, payload["query"])
+		assert.Equal(t, "getUser", payload["operationName"])
+		assert.Nil(t, payload["extensions"])
+	}
+}
+
+func TestGraphQLQueryFile(t *testing.T) {
+	server, _ := newGraphQLServer(t)
+
+	// query file is located relative to project root dir which contains proj.json
+	projectDir := t.TempDir()
+	err := os.WriteFile(filepath.Join(projectDir, "proj.json"), []byte("{}"), 0o644)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	err = os.MkdirAll(filepath.Join(projectDir, "queries"), 0o755)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	err = os.WriteFile(filepath.Join(projectDir, "queries", "user.graphql"), []byte(userQuery), 0o644)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	content := `{
+	"config": {"name": "graphql", "base_url": "` + server.URL + `", "variables": {"uid": "42"}},
+	"teststeps": [
+		{
+			"name": "get user",
+			"graphql": {
+				"url": "/graphql",
+				"query_file": "queries/user.graphql",
+				"variables": {"id": "$uid"}
+			},
+			"extract": {"name": "body.data.user.name"},
+			"validate": [
+				{"eq": ["status_code", 200]},
+				{"eq": ["body.data.user.id", "42"]}
+			]
+		},
+		{
+			"name": "get user with persisted query",
+			"graphql": {
+				"url": "/graphql",
+				"query_file": "queries/user.graphql",
+				"variables": {"id": "$name"},
+				"persisted_query": true
+			},
+			"validate": [
+				{"eq": ["body.data.user.name", "user-user-42"]}
+			]
+		}
+	]
+}`
+	_, err = runJSONTestCase(t, projectDir, content)
+	assert.Nil(t, err)
+}
+
+func TestGraphQLPersistedQuery(t *testing.T) {
+	server, payloads := newGraphQLServer(t)
+	sum := sha256.Sum256([]byte(userQuery))
+	hash := hex.EncodeToString(sum[:])
+
+	newStep := func() hrp.IStep {
+		return hrp.NewStep("get user").
+			GraphQL("/graphql").
+			WithQuery(userQuery).
+			WithQueryVariables(map[string]interface{}{"id": "1"}).
+			WithPersistedQuery().
+			Validate().
+			AssertEqual("body.data.user.name", "user-1", "check user name")
+	}
+
+	// query is not persisted on server, resend with full query
+	_, err := runSingleStep(t, hrp.NewRunner(t), server.URL, newStep())
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	if assert.Len(t, *payloads, 2) {
+		assert.Nil(t, (*payloads)[0]["query"])
+		assert.Equal(t, userQuery, (*payloads)[1]["query"])
+		for _, payload := range *payloads {
+			assert.Equal(t, hash, payload["extensions"].(map[string]interface{})["persistedQuery"].(map[string]interface{})["sha256Hash"])
+		}
+	}
+
+	// query has been persisted, only hash is sent
+	_, err = runSingleStep(t, hrp.NewRunner(t), server.URL, newStep())
+	assert.Nil(t, err)
+	if assert.Len(t, *payloads, 3) {
+		assert.Nil(t, (*payloads)[2]["query"])
+	}
+}
+
+func TestGraphQLResponseErrors(t *testing.T) {
+	server, _ := newGraphQLServer(t)
+
+	step := hrp.NewStep("query unknown field").
+		GraphQL("/graphql").
+		WithQuery("{ unknown }").
+		Validate().
+		AssertEqual("status_code", 200, "check status code")
+	stepResult, err := runSingleStep(t, hrp.NewRunner(nil), server.URL, step)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), `graphql response errors: Cannot query field "unknown"; Unauthorized`)
+	}
+	assert.False(t, stepResult.Success)
+	validators := getValidationResults(t, stepResult)
+	if assert.Len(t, validators, 2) {
+		assert.Equal(t, "pass", validators[0].CheckResult)
+		assert.Equal(t, "body.errors", validators[1].Check)
+		assert.Equal(t, "fail", validators[1].CheckResult)
+	}
+
+	step = hrp.NewStep("query unknown field with errors allowed").
+		GraphQL("/graphql").
+		WithQuery("{ unknown }").
+		AllowErrors().
+		Validate().
+		AssertLengthEqual("body.errors", 2, "check errors count").
+		AssertEqual("body.errors[1].message", "Unauthorized", "check error message")
+	stepResult, err = runSingleStep(t, hrp.NewRunner(t), server.URL, step)
+	assert.Nil(t, err)
+	assert.True(t, stepResult.Success)
+}
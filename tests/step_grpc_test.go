@@ -0,0 +1,350 @@
+package tests
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	hrp "github.com/httprunner/httprunner/v5"
+)
+
+const greeterProto = `syntax = "proto3";
+
+package hello;
+
+service Greeter {
+  rpc SayHello(HelloRequest) returns (HelloReply);
+  rpc ListGreetings(HelloRequest) returns (stream HelloReply);
+  rpc CollectNames(stream HelloRequest) returns (HelloReply);
+  rpc Chat(stream HelloRequest) returns (stream HelloReply);
+}
+
+message HelloRequest {
+  string name = 1;
+  int32 count = 2;
+}
+
+message HelloReply {
+  string message = 1;
+  int32 index = 2;
+}
+`
+
+// greeterServer implements hello.Greeter with dynamic messages
+type greeterServer struct {
+	request protoreflect.MessageDescriptor
+	reply   protoreflect.MessageDescriptor
+}
+
+func (s *greeterServer) newReply(message string, index int) *dynamicpb.Message {
+	reply := dynamicpb.NewMessage(s.reply)
+	reply.Set(s.reply.Fields().ByName("message"), protoreflect.ValueOfString(message))
+	reply.Set(s.reply.Fields().ByName("index"), protoreflect.ValueOfInt32(int32(index)))
+	return reply
+}
+
+func (s *greeterServer) recv(stream grpc.ServerStream) (name string, count int, err error) {
+	request := dynamicpb.NewMessage(s.request)
+	if err = stream.RecvMsg(request); err != nil {
+		return
+	}
+	name = request.Get(s.request.Fields().ByName("name")).String()
+	count = int(request.Get(s.request.Fields().ByName("count")).Int())
+	return
+}
+
+// sayHello requires token in metadata, responds with header and trailer
+func (s *greeterServer) sayHello(_ interface{}, stream grpc.ServerStream) error {
+	md, _ := metadata.FromIncomingContext(stream.Context())
+	if tokens := md.Get("token"); len(tokens) == 0 || tokens[0] != "secret" {
+		return status.Error(codes.Unauthenticated, "invalid token")
+	}
+	name, _, err := s.recv(stream)
+	if err != nil {
+		return err
+	}
+	switch name {
+	case "":
+		return status.Error(codes.InvalidArgument, "name is required")
+	case "slow":
+		select {
+		case <-time.After(time.Second):
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+	_ = stream.SetHeader(metadata.Pairs("x-request-id", "1"))
+	stream.SetTrailer(metadata.Pairs("x-trace", "done"))
+	return stream.SendMsg(s.newReply("hello "+name, 0))
+}
+
+func (s *greeterServer) listGreetings(_ interface{}, stream grpc.ServerStream) error {
+	name, count, err := s.recv(stream)
+	if err != nil {
+		return err
+	}
+	for i := 1; i <= count; i++ {
+		if err := stream.SendMsg(s.newReply(fmt.Sprintf("hello %s #%d", name, i), i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *greeterServer) collectNames(_ interface{}, stream grpc.ServerStream) error {
+	var names []string
+	for {
+		name, _, err := s.recv(stream)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		names = append(names, name)
+	}
+	return stream.SendMsg(s.newReply("hello "+strings.Join(names, ","), len(names)))
+}
+
+func (s *greeterServer) chat(_ interface{}, stream grpc.ServerStream) error {
+	for i := 0; ; i++ {
+		name, _, err := s.recv(stream)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := stream.SendMsg(s.newReply("echo "+name, i)); err != nil {
+			return err
+		}
+	}
+}
+
+// newGRPCServer starts in-process hello.Greeter server with server reflection,
+// returns server address and proto file path.
+func newGRPCServer(t *testing.T) (string, string) {
+	protoPath := writeSpecFile(t, "greeter.proto", greeterProto)
+	fds, err := protoparse.Parser{
+		ImportPaths: []string{filepath.Dir(protoPath)},
+	}.ParseFiles(filepath.Base(protoPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := fds[0].UnwrapFile()
+	files := new(protoregistry.Files)
+	if err := files.RegisterFile(fd); err != nil {
+		t.Fatal(err)
+	}
+
+	greeter := &greeterServer{
+		request: fd.Messages().ByName("HelloRequest"),
+		reply:   fd.Messages().ByName("HelloReply"),
+	}
+	server := grpc.NewServer()
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "hello.Greeter",
+		HandlerType: (*interface{})(nil),
+		Streams: []grpc.StreamDesc{
+			{StreamName: "SayHello", Handler: greeter.sayHello},
+			{StreamName: "ListGreetings", Handler: greeter.listGreetings, ServerStreams: true},
+			{StreamName: "CollectNames", Handler: greeter.collectNames, ClientStreams: true},
+			{StreamName: "Chat", Handler: greeter.chat, ServerStreams: true, ClientStreams: true},
+		},
+		Metadata: "greeter.proto",
+	}, struct{}{})
+	reflectionpb.RegisterServerReflectionServer(server, reflection.NewServerV1(reflection.ServerOptions{
+		Services:           server,
+		DescriptorResolver: files,
+	}))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+	return listener.Addr().String(), protoPath
+}
+
+func runGRPCTestCase(t *testing.T, hrpRunner *hrp.HRPRunner, steps ...hrp.IStep) (*hrp.TestCaseSummary, error) {
+	testcase := hrp.TestCase{
+		Config:    hrp.NewConfig("grpc"),
+		TestSteps: steps,
+	}
+	caseRunner, err := hrp.NewCaseRunner(testcase, hrpRunner)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	return caseRunner.NewSession().Start(nil)
+}
+
+func TestGRPCUnaryWithReflection(t *testing.T) {
+	addr, _ := newGRPCServer(t)
+
+	summary, err := runGRPCTestCase(t, hrp.NewRunner(t),
+		hrp.NewStep("say hello").
+			WithVariables(map[string]interface{}{"user": "leo", "token": "secret"}).
+			GRPC(addr, "hello.Greeter/SayHello").
+			WithMetadata(map[string]string{"token": "$token"}).
+			WithBody(map[string]interface{}{"name": "$user"}).
+			Extract().
+			WithJmesPath("body.message", "message").
+			Validate().
+			AssertStatusCode(codes.OK, "check status code").
+			AssertEqual("body.message", "hello leo", "check message").
+			AssertEqual("body.index", 0, "check zero value").
+			AssertEqual(`headers."x-request-id"`, "1", "check header").
+			AssertEqual(`trailers."x-trace"`, "done", "check trailer"),
+		hrp.NewStep("say hello without token").
+			GRPC(addr, "/hello.Greeter/SayHello").
+			WithBody(map[string]interface{}{"name": "$message"}).
+			Validate().
+			AssertStatusCode(codes.Unauthenticated, "check status code").
+			AssertEqual("status_message", "invalid token", "check status message").
+			AssertEqual("body", nil, "check empty body"),
+	)
+	assert.Nil(t, err)
+	assert.True(t, summary.Success)
+	if assert.Len(t, summary.Records, 2) {
+		assert.Equal(t, hrp.StepTypeGRPC, summary.Records[0].StepType)
+		assert.Equal(t, "hello leo", summary.Records[0].ExportVars["message"])
+	}
+}
+
+func TestGRPCStreamingWithProtoFiles(t *testing.T) {
+	addr, protoPath := newGRPCServer(t)
+
+	summary, err := runGRPCTestCase(t, hrp.NewRunner(t),
+		hrp.NewStep("server streaming").
+			GRPC(addr, "hello.Greeter.ListGreetings").
+			WithProtoFiles(protoPath).
+			WithBody(map[string]interface{}{"name": "leo", "count": 3}).
+			Validate().
+			AssertLengthEqual("body", 3, "check messages count").
+			AssertEqual("body[2].message", "hello leo #3", "check last message"),
+		hrp.NewStep("client streaming").
+			GRPC(addr, "hello.Greeter/CollectNames").
+			WithProtoFiles(protoPath).
+			WithBody(
+				map[string]interface{}{"name": "a"},
+				map[string]interface{}{"name": "b"},
+				map[string]interface{}{"name": "c"},
+			).
+			Validate().
+			AssertEqual("body.message", "hello a,b,c", "check message").
+			AssertEqual("body.index", 3, "check names count"),
+		hrp.NewStep("bidi streaming").
+			GRPC(addr, "hello.Greeter/Chat").
+			WithProtoFiles(protoPath).
+			WithBody(
+				map[string]interface{}{"name": "a"},
+				map[string]interface{}{"name": "b"},
+			).
+			Validate().
+			AssertLengthEqual("body", 2, "check messages count").
+			AssertEqual("body[1].message", "echo b", "check echo message"),
+	)
+	assert.Nil(t, err)
+	assert.True(t, summary.Success)
+}
+
+func TestGRPCStatusAndDeadline(t *testing.T) {
+	addr, _ := newGRPCServer(t)
+
+	summary, err := runGRPCTestCase(t, hrp.NewRunner(t),
+		hrp.NewStep("invalid argument").
+			GRPC(addr, "hello.Greeter/SayHello").
+			WithMetadata(map[string]string{"token": "secret"}).
+			WithBody(map[string]interface{}{}).
+			Validate().
+			AssertStatusCode(codes.InvalidArgument, "check status code").
+			AssertContains("status_message", "required", "check status message"),
+		hrp.NewStep("deadline exceeded").
+			GRPC(addr, "hello.Greeter/SayHello").
+			WithMetadata(map[string]string{"token": "secret"}).
+			WithBody(map[string]interface{}{"name": "slow"}).
+			WithTimeout(0.1).
+			Validate().
+			AssertStatusCode(codes.DeadlineExceeded, "check status code"),
+	)
+	assert.Nil(t, err)
+	assert.True(t, summary.Success)
+
+	summary, err = runGRPCTestCase(t, hrp.NewRunner(nil),
+		hrp.NewStep("unknown method").
+			GRPC(addr, "hello.Greeter/SayGoodbye").
+			WithBody(map[string]interface{}{"name": "leo"}),
+	)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "method SayGoodbye not found in service hello.Greeter")
+	}
+	assert.False(t, summary.Success)
+}
+
+func TestGRPCLoadFromJSON(t *testing.T) {
+	addr, protoPath := newGRPCServer(t)
+
+	// proto files are located relative to project root dir which contains proj.json
+	projectDir := filepath.Dir(protoPath)
+	err := os.WriteFile(filepath.Join(projectDir, "proj.json"), []byte("{}"), 0o644)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	content := `{
+	"config": {"name": "grpc", "variables": {"addr": "` + addr + `"}},
+	"teststeps": [
+		{
+			"name": "say hello",
+			"grpc": {
+				"url": "$addr",
+				"method": "hello.Greeter/SayHello",
+				"proto_files": ["greeter.proto"],
+				"metadata": {"token": "secret"},
+				"body": {"name": "leo"},
+				"timeout": 5
+			},
+			"extract": {"message": "body.message"},
+			"validate": [
+				{"eq": ["status_code", 0]},
+				{"eq": ["body.message", "hello leo"]}
+			]
+		},
+		{
+			"name": "list greetings",
+			"grpc": {
+				"url": "$addr",
+				"method": "hello.Greeter/ListGreetings",
+				"body": {"name": "$message", "count": 2}
+			},
+			"validate": [
+				{"eq": ["body[0].message", "hello hello leo #1"]}
+			]
+		}
+	]
+}`
+	tc, err := runJSONTestCase(t, projectDir, content)
+	assert.Nil(t, err)
+	if assert.Len(t, tc.TestSteps, 2) {
+		assert.Equal(t, hrp.StepTypeGRPC, tc.TestSteps[0].Type())
+	}
+}
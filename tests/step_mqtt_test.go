@@ -0,0 +1,381 @@
+package tests
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.mqtt.golang/packets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	hrp "github.com/httprunner/httprunner/v5"
+)
+
+// mqttBroker is a minimal MQTT 3.1.1 broker, which routes messages with wildcards and keeps retained messages
+type mqttBroker struct {
+	mutex    sync.Mutex
+	connects []*packets.ConnectPacket
+	sessions map[*mqttBrokerSession]bool
+	retained map[string]*packets.PublishPacket
+}
+
+type mqttBrokerSession struct {
+	conn      net.Conn
+	mutex     sync.Mutex
+	messageID uint16
+	filters   map[string]byte // topic filter -> granted qos
+}
+
+func (s *mqttBrokerSession) write(packet packets.ControlPacket) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_ = packet.Write(s.conn)
+}
+
+func (s *mqttBrokerSession) deliver(publish *packets.PublishPacket, qos byte, retain bool) {
+	packet := packets.NewControlPacket(packets.Publish).(*packets.PublishPacket)
+	packet.TopicName = publish.TopicName
+	packet.Payload = publish.Payload
+	packet.Qos = qos
+	packet.Retain = retain
+	if qos > 0 {
+		s.mutex.Lock()
+		s.messageID++
+		packet.MessageID = s.messageID
+		s.mutex.Unlock()
+	}
+	s.write(packet)
+}
+
+func newMQTTBroker(t *testing.T) (*mqttBroker, string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	t.Cleanup(func() { listener.Close() })
+	broker := &mqttBroker{
+		sessions: make(map[*mqttBrokerSession]bool),
+		retained: make(map[string]*packets.PublishPacket),
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go broker.serve(conn)
+		}
+	}()
+	return broker, "tcp://" + listener.Addr().String()
+}
+
+func (b *mqttBroker) connectPacket(clientID string) *packets.ConnectPacket {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for _, packet := range b.connects {
+		if packet.ClientIdentifier == clientID {
+			return packet
+		}
+	}
+	return nil
+}
+
+func (b *mqttBroker) serve(conn net.Conn) {
+	session := &mqttBrokerSession{conn: conn, filters: make(map[string]byte)}
+	defer func() {
+		b.mutex.Lock()
+		delete(b.sessions, session)
+		b.mutex.Unlock()
+		conn.Close()
+	}()
+	for {
+		packet, err := packets.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		switch p := packet.(type) {
+		case *packets.ConnectPacket:
+			b.mutex.Lock()
+			b.connects = append(b.connects, p)
+			b.sessions[session] = true
+			b.mutex.Unlock()
+			session.write(packets.NewControlPacket(packets.Connack))
+		case *packets.PublishPacket:
+			switch p.Qos {
+			case 1:
+				ack := packets.NewControlPacket(packets.Puback).(*packets.PubackPacket)
+				ack.MessageID = p.MessageID
+				session.write(ack)
+			case 2:
+				rec := packets.NewControlPacket(packets.Pubrec).(*packets.PubrecPacket)
+				rec.MessageID = p.MessageID
+				session.write(rec)
+			}
+			b.publish(p)
+		case *packets.PubrelPacket:
+			comp := packets.NewControlPacket(packets.Pubcomp).(*packets.PubcompPacket)
+			comp.MessageID = p.MessageID
+			session.write(comp)
+		case *packets.PubrecPacket:
+			rel := packets.NewControlPacket(packets.Pubrel).(*packets.PubrelPacket)
+			rel.MessageID = p.MessageID
+			session.write(rel)
+		case *packets.SubscribePacket:
+			ack := packets.NewControlPacket(packets.Suback).(*packets.SubackPacket)
+			ack.MessageID = p.MessageID
+			b.mutex.Lock()
+			var retained []*packets.PublishPacket
+			var retainedQoss []byte
+			for i, filter := range p.Topics {
+				session.filters[filter] = p.Qoss[i]
+				ack.ReturnCodes = append(ack.ReturnCodes, p.Qoss[i])
+				for topic, message := range b.retained {
+					if matchTopic(filter, topic) {
+						retained = append(retained, message)
+						retainedQoss = append(retainedQoss, min(message.Qos, p.Qoss[i]))
+					}
+				}
+			}
+			b.mutex.Unlock()
+			session.write(ack)
+			for i, message := range retained {
+				session.deliver(message, retainedQoss[i], true)
+			}
+		case *packets.UnsubscribePacket:
+			b.mutex.Lock()
+			for _, filter := range p.Topics {
+				delete(session.filters, filter)
+			}
+			b.mutex.Unlock()
+			ack := packets.NewControlPacket(packets.Unsuback).(*packets.UnsubackPacket)
+			ack.MessageID = p.MessageID
+			session.write(ack)
+		case *packets.PingreqPacket:
+			session.write(packets.NewControlPacket(packets.Pingresp))
+		case *packets.DisconnectPacket:
+			return
+		}
+	}
+}
+
+func (b *mqttBroker) publish(p *packets.PublishPacket) {
+	b.mutex.Lock()
+	if p.Retain {
+		if len(p.Payload) == 0 {
+			delete(b.retained, p.TopicName)
+		} else {
+			b.retained[p.TopicName] = p
+		}
+	}
+	type delivery struct {
+		session *mqttBrokerSession
+		qos     byte
+	}
+	var deliveries []delivery
+	for session := range b.sessions {
+		for filter, qos := range session.filters {
+			if matchTopic(filter, p.TopicName) {
+				deliveries = append(deliveries, delivery{session, min(p.Qos, qos)})
+				break
+			}
+		}
+	}
+	b.mutex.Unlock()
+	for _, d := range deliveries {
+		d.session.deliver(p, d.qos, false)
+	}
+}
+
+func matchTopic(filter, topic string) bool {
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+	for i, level := range filterLevels {
+		if level == "#" {
+			return true
+		}
+		if i >= len(topicLevels) || (level != "+" && level != topicLevels[i]) {
+			return false
+		}
+	}
+	return len(filterLevels) == len(topicLevels)
+}
+
+func TestMQTTPublishSubscribe(t *testing.T) {
+	broker, url := newMQTTBroker(t)
+	testcase := hrp.TestCase{
+		Config: hrp.NewConfig("mqtt").
+			WithVariables(map[string]interface{}{"broker": url, "device": "d1", "secret": "s3cret"}),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("connect publisher").
+				MQTT("$broker").
+				Connect().
+				WithClientID("publisher"),
+			hrp.NewStep("connect subscriber").
+				MQTT("$broker").
+				Connect().
+				WithClientID("subscriber").
+				WithCredentials("admin", "$secret").
+				WithCleanSession(false).
+				WithWill("devices/subscriber/status", map[string]interface{}{"online": false}, 1, true),
+			hrp.NewStep("publish retained status").
+				MQTT("$broker").
+				Publish("devices/d0/status").
+				WithClientID("publisher").
+				WithPayload("offline").
+				WithQoS(1).
+				WithRetain(),
+			hrp.NewStep("subscribe status of all devices").
+				MQTT("$broker").
+				Subscribe("devices/+/status").
+				WithClientID("subscriber").
+				WithQoS(2),
+			hrp.NewStep("expect retained status").
+				MQTT("$broker").
+				Expect("devices/d0/status").
+				WithClientID("subscriber").
+				Validate().
+				AssertEqual("body", "offline", "check retained payload").
+				AssertEqual("retained", true, "check retained flag"),
+			hrp.NewStep("publish with qos 1").
+				MQTT("$broker").
+				Publish("devices/$device/status").
+				WithClientID("publisher").
+				WithPayload(map[string]interface{}{"seq": 1}).
+				WithQoS(1),
+			hrp.NewStep("publish with qos 2").
+				MQTT("$broker").
+				Publish("devices/$device/status").
+				WithClientID("publisher").
+				WithPayload(map[string]interface{}{"seq": 2, "device": "$device"}).
+				WithQoS(2),
+			hrp.NewStep("expect the second message").
+				MQTT("$broker").
+				Expect("devices/+/status").
+				WithClientID("subscriber").
+				WaitUntil("body.seq", "equals", 2).
+				WithTimeout(5000).
+				Extract().
+				WithJmesPath("topic", "topic").
+				Validate().
+				AssertEqual("body.device", "d1", "check parsed payload").
+				AssertEqual("qos", 2, "check qos"),
+			hrp.NewStep("expect the first message").
+				MQTT("$broker").
+				Expect("devices/#").
+				WithClientID("subscriber").
+				Validate().
+				AssertEqual("body.seq", 1, "unmatched message is kept").
+				AssertEqual("qos", 1, "check qos"),
+			hrp.NewStep("unsubscribe").
+				MQTT("$broker").
+				Unsubscribe("devices/+/status").
+				WithClientID("subscriber"),
+			hrp.NewStep("disconnect subscriber").
+				MQTT("$broker").
+				Disconnect().
+				WithClientID("subscriber"),
+		},
+	}
+	caseRunner, err := hrp.NewCaseRunner(testcase, hrp.NewRunner(t))
+	require.Nil(t, err)
+	session := caseRunner.NewSession()
+	defer session.ReleaseResources()
+	summary, err := session.Start(nil)
+	require.Nil(t, err)
+	assert.True(t, summary.Success)
+	assert.Equal(t, "devices/d1/status", summary.Records[7].ExportVars["topic"])
+	assert.Equal(t, hrp.StepTypeMQTT, summary.Records[7].StepType)
+
+	connect := broker.connectPacket("subscriber")
+	require.NotNil(t, connect)
+	assert.Equal(t, "admin", connect.Username)
+	assert.Equal(t, "s3cret", string(connect.Password))
+	assert.False(t, connect.CleanSession)
+	assert.True(t, connect.WillFlag)
+	assert.Equal(t, "devices/subscriber/status", connect.WillTopic)
+	assert.JSONEq(t, `{"online": false}`, string(connect.WillMessage))
+	assert.Equal(t, byte(1), connect.WillQos)
+	assert.True(t, connect.WillRetain)
+}
+
+func TestMQTTExpectTimeout(t *testing.T) {
+	_, url := newMQTTBroker(t)
+	testcase := &hrp.TestCase{
+		Config: hrp.NewConfig("mqtt expect timeout").
+			WithVariables(map[string]interface{}{"broker": url}),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("connect").MQTT("$broker").Connect(),
+			hrp.NewStep("subscribe").MQTT("$broker").Subscribe("alarms/#"),
+			hrp.NewStep("publish").MQTT("$broker").Publish("alarms/cpu").WithPayload("80"),
+			hrp.NewStep("expect unknown alarm").
+				MQTT("$broker").
+				Expect("alarms/#").
+				WaitUntil("body", "equals", "unknown").
+				WithTimeout(200),
+		},
+	}
+	start := time.Now()
+	err := hrp.NewRunner(nil).Run(testcase)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "no message matches until condition")
+	assert.Contains(t, err.Error(), "expect timeout")
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestMQTTLoadFromJSON(t *testing.T) {
+	broker, url := newMQTTBroker(t)
+	content := `{
+	"config": {"name": "mqtt", "variables": {"broker": "` + url + `"}},
+	"teststeps": [
+		{
+			"name": "connect",
+			"mqtt": {
+				"type": "connect",
+				"url": "$broker",
+				"client_id": "sensor",
+				"will": {"topic": "sensors/sensor/status", "payload": "gone"}
+			}
+		},
+		{
+			"name": "subscribe",
+			"mqtt": {"type": "subscribe", "url": "$broker", "client_id": "sensor", "topic": "sensors/+/temperature", "qos": 1}
+		},
+		{
+			"name": "publish",
+			"mqtt": {
+				"type": "publish",
+				"url": "$broker",
+				"client_id": "sensor",
+				"topic": "sensors/sensor/temperature",
+				"payload": {"value": 21.5},
+				"qos": 1
+			}
+		},
+		{
+			"name": "expect",
+			"mqtt": {
+				"type": "expect",
+				"url": "$broker",
+				"client_id": "sensor",
+				"topic": "sensors/+/temperature",
+				"until": {"check": "body.value", "assert": "greater_than", "expect": 20.5},
+				"timeout": 5000
+			},
+			"validate": [
+				{"eq": ["topic", "sensors/sensor/temperature"]},
+				{"eq": ["body.value", 21.5]}
+			]
+		}
+	]
+}`
+	tc, err := runJSONTestCase(t, "", content)
+	assert.Nil(t, err)
+	if assert.Len(t, tc.TestSteps, 4) {
+		assert.Equal(t, hrp.StepTypeMQTT, tc.TestSteps[0].Type())
+	}
+	connect := broker.connectPacket("sensor")
+	if assert.NotNil(t, connect) {
+		assert.Equal(t, "gone", string(connect.WillMessage))
+	}
+}
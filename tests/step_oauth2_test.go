@@ -0,0 +1,258 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	hrp "github.com/httprunner/httprunner/v5"
+)
+
+// oauth2Server mocks token endpoint and protected api
+type oauth2Server struct {
+	*httptest.Server
+	mutex     sync.Mutex
+	expiresIn int
+	grants    []string
+	tokens    map[string]bool // valid access tokens
+	issued    int
+}
+
+func (s *oauth2Server) issueToken(w http.ResponseWriter) {
+	s.issued++
+	token := fmt.Sprintf("token-%d", s.issued)
+	s.tokens[token] = true
+	result := map[string]interface{}{
+		"access_token":  token,
+		"token_type":    "bearer",
+		"refresh_token": fmt.Sprintf("refresh-%d", s.issued),
+	}
+	if s.expiresIn > 0 {
+		result["expires_in"] = s.expiresIn
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+func (s *oauth2Server) grantTypes() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string{}, s.grants...)
+}
+
+func newOAuth2Server(t *testing.T, expiresIn int) *oauth2Server {
+	s := &oauth2Server{
+		expiresIn: expiresIn,
+		tokens:    make(map[string]bool),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		switch strings.TrimSuffix(r.URL.Path, "/") {
+		case "/.well-known/openid-configuration":
+			fmt.Fprintf(w, `{"issuer": %q, "token_endpoint": %q}`, s.URL, s.URL+"/oauth/token")
+		case "/revoke":
+			s.tokens = make(map[string]bool)
+		case "/oauth/token":
+			_ = r.ParseForm()
+			clientID, clientSecret, ok := r.BasicAuth()
+			if !ok {
+				clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+			}
+			if clientID != "client" || clientSecret != "secret" {
+				http.Error(w, `{"error": "invalid_client"}`, http.StatusUnauthorized)
+				return
+			}
+			grantType := r.PostForm.Get("grant_type")
+			switch grantType {
+			case "client_credentials":
+			case "password":
+				if r.PostForm.Get("username") != "alice" || r.PostForm.Get("password") != "pass" {
+					http.Error(w, `{"error": "invalid_grant"}`, http.StatusBadRequest)
+					return
+				}
+			case "refresh_token":
+				if !strings.HasPrefix(r.PostForm.Get("refresh_token"), "refresh-") {
+					http.Error(w, `{"error": "invalid_grant"}`, http.StatusBadRequest)
+					return
+				}
+			default:
+				http.Error(w, `{"error": "unsupported_grant_type"}`, http.StatusBadRequest)
+				return
+			}
+			s.grants = append(s.grants, grantType)
+			s.issueToken(w)
+		default:
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !s.tokens[token] {
+				http.Error(w, `{"error": "invalid_token"}`, http.StatusUnauthorized)
+				return
+			}
+			body, _ := io.ReadAll(r.Body)
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"token": token,
+				"body":  string(body),
+			})
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestOAuth2ClientCredentials(t *testing.T) {
+	server := newOAuth2Server(t, 3600)
+
+	testcase := &hrp.TestCase{
+		Config: hrp.NewConfig("oauth2 client credentials").
+			SetBaseURL(server.URL).
+			WithVariables(map[string]interface{}{"client_secret": "secret"}).
+			SetOAuth2(&hrp.OAuth2Config{
+				TokenURL:     "/oauth/token",
+				ClientID:     "client",
+				ClientSecret: "$client_secret",
+				Scope:        "read",
+			}),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("first request").
+				GET("/api/users").
+				Validate().
+				AssertEqual("status_code", 200, "check status code").
+				AssertEqual("body.token", "token-1", "check token injected"),
+			hrp.NewStep("second request reuses token").
+				GET("/api/users").
+				Validate().
+				AssertEqual("body.token", "token-1", "check token cached"),
+			hrp.NewStep("explicit authorization header").
+				GET("/api/users").
+				WithHeaders(map[string]string{"Authorization": "Bearer invalid"}).
+				Validate().
+				AssertEqual("status_code", 401, "check token not injected"),
+		},
+	}
+	err := hrp.NewRunner(t).Run(testcase)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"client_credentials"}, server.grantTypes())
+}
+
+func TestOAuth2RefreshOnExpiry(t *testing.T) {
+	server := newOAuth2Server(t, 1)
+
+	testcase := &hrp.TestCase{
+		Config: hrp.NewConfig("oauth2 refresh on expiry").
+			SetBaseURL(server.URL).
+			SetOAuth2(&hrp.OAuth2Config{
+				GrantType:    hrp.OAuth2Password,
+				TokenURL:     server.URL + "/oauth/token",
+				ClientID:     "client",
+				ClientSecret: "secret",
+				ClientAuth:   "body",
+				Username:     "alice",
+				Password:     "pass",
+			}),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("first request").
+				GET("/api/users").
+				Validate().
+				AssertEqual("body.token", "token-1", "check token injected"),
+			hrp.NewStep("wait for token expiry").SetThinkTime(1.2),
+			hrp.NewStep("request with refreshed token").
+				GET("/api/users").
+				Validate().
+				AssertEqual("body.token", "token-2", "check token refreshed"),
+		},
+	}
+	err := hrp.NewRunner(t).Run(testcase)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"password", "refresh_token"}, server.grantTypes())
+}
+
+func TestOAuth2RefreshOnUnauthorized(t *testing.T) {
+	server := newOAuth2Server(t, 0)
+
+	testcase := &hrp.TestCase{
+		Config: hrp.NewConfig("oauth2 refresh on 401").
+			SetBaseURL(server.URL).
+			SetOAuth2(&hrp.OAuth2Config{
+				GrantType:    hrp.OAuth2RefreshToken,
+				TokenURL:     "/oauth/token",
+				ClientID:     "client",
+				ClientSecret: "secret",
+				RefreshToken: "refresh-0",
+			}),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("first request").
+				GET("/api/users").
+				Validate().
+				AssertEqual("body.token", "token-1", "check token injected"),
+			hrp.NewStep("revoke tokens").
+				GET("/revoke"),
+			hrp.NewStep("request after revoked").
+				POST("/api/users").
+				WithBody(map[string]interface{}{"name": "bob"}).
+				Validate().
+				AssertEqual("status_code", 200, "check status code").
+				AssertEqual("body.token", "token-2", "check token refreshed").
+				AssertContains("body.body", "bob", "check body resent"),
+		},
+	}
+	err := hrp.NewRunner(t).Run(testcase)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"refresh_token", "refresh_token"}, server.grantTypes())
+}
+
+func TestOAuth2Function(t *testing.T) {
+	server := newOAuth2Server(t, 0)
+	server.tokens["custom-token"] = true
+	t.Setenv("TEST_OAUTH2_TOKEN", "custom-token")
+
+	testcase := &hrp.TestCase{
+		Config: hrp.NewConfig("oauth2 token function").
+			SetBaseURL(server.URL).
+			SetOAuth2(&hrp.OAuth2Config{
+				Function: "${environ(TEST_OAUTH2_TOKEN)}",
+			}),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("request").
+				GET("/api/users").
+				Validate().
+				AssertEqual("body.token", "custom-token", "check token from function"),
+		},
+	}
+	err := hrp.NewRunner(t).Run(testcase)
+	assert.Nil(t, err)
+	assert.Empty(t, server.grantTypes())
+}
+
+func TestOAuth2LoadFromJSON(t *testing.T) {
+	server := newOAuth2Server(t, 3600)
+
+	content := `{
+	"config": {
+		"name": "oauth2 with oidc discovery",
+		"base_url": "` + server.URL + `",
+		"oauth2": {
+			"issuer": "` + server.URL + `",
+			"client_id": "client",
+			"client_secret": "secret",
+			"params": {"audience": "api"}
+		}
+	},
+	"teststeps": [
+		{
+			"name": "request",
+			"request": {"method": "GET", "url": "/api/users"},
+			"validate": [{"eq": ["body.token", "token-1"]}]
+		}
+	]
+}`
+	_, err := runJSONTestCase(t, "", content)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,196 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	hrp "github.com/httprunner/httprunner/v5"
+)
+
+func TestRedisCommands(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.HSet("user:1", "name", "alice", "age", "18")
+	server.RPush("queue", "a", "b", "c")
+
+	testcase := hrp.TestCase{
+		Config: hrp.NewConfig("redis").
+			WithVariables(map[string]interface{}{"addr": server.Addr(), "token": "abc"}).
+			SetRedis(&hrp.RedisConfig{Addr: "$addr"}),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("set cache").
+				Redis().
+				Set("session:$token", map[string]interface{}{"uid": 1}, 60).
+				Validate().
+				AssertEqual("result", "OK", "check set result"),
+			hrp.NewStep("get cache").
+				Redis().
+				Get("session:$token").
+				Extract().
+				WithJmesPath("result", "session").
+				Validate().
+				AssertEqual("result", `{"uid":1}`, "check cached value"),
+			hrp.NewStep("get missing key").
+				Redis().
+				Get("missing").
+				Validate().
+				AssertEqual("result", nil, "missing key is nil"),
+			hrp.NewStep("ttl").
+				Redis().
+				TTL("session:$token").
+				Validate().
+				AssertEqual("result", 60, "check ttl"),
+			hrp.NewStep("expire").
+				Redis().
+				Expire("session:$token", 10).
+				Validate().
+				AssertEqual("result", 1, "check expire"),
+			hrp.NewStep("hgetall").
+				Redis().
+				HGetAll("user:1").
+				Validate().
+				AssertEqual("result.name", "alice", "check hash field").
+				AssertEqual("result.age", "18", "check hash field"),
+			hrp.NewStep("lrange").
+				Redis().
+				LRange("queue", 0, -1).
+				Validate().
+				AssertEqual("result", []interface{}{"a", "b", "c"}, "check list"),
+			hrp.NewStep("raw command").
+				Redis().
+				Do("incrby", "counter", 5).
+				Validate().
+				AssertEqual("result", 5, "check raw command"),
+			hrp.NewStep("del").
+				Redis().
+				Del("session:$token", "counter").
+				Validate().
+				AssertEqual("result", 2, "check deleted keys"),
+		},
+	}
+	caseRunner, err := hrp.NewCaseRunner(testcase, hrp.NewRunner(t))
+	require.Nil(t, err)
+	session := caseRunner.NewSession()
+	defer session.ReleaseResources()
+	summary, err := session.Start(nil)
+	require.Nil(t, err)
+	assert.True(t, summary.Success)
+	assert.Equal(t, `{"uid":1}`, summary.Records[1].ExportVars["session"])
+	assert.Equal(t, hrp.StepTypeRedis, summary.Records[1].StepType)
+	assert.False(t, server.Exists("counter"))
+}
+
+func TestRedisSubscribe(t *testing.T) {
+	server := miniredis.RunT(t)
+	testcase := hrp.TestCase{
+		Config: hrp.NewConfig("redis pubsub").
+			SetRedis(&hrp.RedisConfig{URL: "redis://" + server.Addr() + "/0"}),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("subscribe").
+				Redis().
+				Subscribe("orders").
+				Validate().
+				AssertEqual("result", []interface{}{"orders"}, "check subscribed channels"),
+			hrp.NewStep("subscribe pattern").
+				Redis().
+				PSubscribe("events.*"),
+			hrp.NewStep("publish created").
+				Redis().
+				Publish("orders", map[string]interface{}{"id": 1, "status": "created"}).
+				Validate().
+				AssertEqual("result", 1, "check receivers"),
+			hrp.NewStep("publish paid").
+				Redis().
+				Publish("orders", map[string]interface{}{"id": 1, "status": "paid"}),
+			hrp.NewStep("publish event").
+				Redis().
+				Publish("events.login", "alice"),
+			hrp.NewStep("receive paid").
+				Redis().
+				Receive("orders").
+				WaitUntil("result.status", "equals", "paid").
+				WithTimeout(5000).
+				Validate().
+				AssertEqual("channel", "orders", "check channel").
+				AssertEqual("result.id", 1, "check parsed message"),
+			hrp.NewStep("receive created").
+				Redis().
+				Receive("orders").
+				WithTimeout(5000).
+				Validate().
+				AssertEqual("result.status", "created", "unmatched message is kept"),
+			hrp.NewStep("receive pattern message").
+				Redis().
+				Receive().
+				WithTimeout(5000).
+				Validate().
+				AssertEqual("channel", "events.login", "check channel").
+				AssertEqual("result", "alice", "check raw message"),
+		},
+	}
+	caseRunner, err := hrp.NewCaseRunner(testcase, hrp.NewRunner(t))
+	require.Nil(t, err)
+	session := caseRunner.NewSession()
+	summary, err := session.Start(nil)
+	require.Nil(t, err)
+	assert.True(t, summary.Success)
+
+	// subscriptions are stopped on releasing resources
+	session.ReleaseResources()
+	assert.Eventually(t, func() bool {
+		return server.PubSubNumSub("orders")["orders"] == 0
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestRedisReceiveTimeout(t *testing.T) {
+	server := miniredis.RunT(t)
+	testcase := &hrp.TestCase{
+		Config: hrp.NewConfig("redis receive timeout").
+			SetRedis(&hrp.RedisConfig{Addr: server.Addr()}),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("subscribe").Redis().Subscribe("orders"),
+			hrp.NewStep("receive nothing").Redis().Receive("orders").WithTimeout(200),
+		},
+	}
+	start := time.Now()
+	err := hrp.NewRunner(nil).Run(testcase)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "receive timeout")
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestRedisLoadFromJSON(t *testing.T) {
+	server := miniredis.RunT(t)
+	content := `{
+	"config": {
+		"name": "redis",
+		"variables": {"addr": "` + server.Addr() + `"},
+		"redis": {"addr": "$addr", "db": 1}
+	},
+	"teststeps": [
+		{
+			"name": "set",
+			"redis": {"command": "SET", "args": ["greeting", "hello"]}
+		},
+		{
+			"name": "get",
+			"redis": {"command": "get", "args": ["greeting"]},
+			"validate": [
+				{"eq": ["result", "hello"]}
+			]
+		}
+	]
+}`
+	tc, err := runJSONTestCase(t, "", content)
+	assert.Nil(t, err)
+	if assert.Len(t, tc.TestSteps, 2) {
+		assert.Equal(t, hrp.StepTypeRedis, tc.TestSteps[0].Type())
+	}
+	server.Select(1)
+	value, err := server.Get("greeting")
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", value)
+}
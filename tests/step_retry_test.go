@@ -0,0 +1,177 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	hrp "github.com/httprunner/httprunner/v5"
+	"github.com/httprunner/httprunner/v5/code"
+)
+
+// newFlakyServer returns a server which responds with failedCode and "pending" status
+// for the first failedTimes requests, then responds with 200 and "done" status.
+func newFlakyServer(failedTimes int64, failedCode int) (*httptest.Server, *int64) {
+	var count int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&count, 1)
+		w.Header().Set("Content-Type", "application/json")
+		if n <= failedTimes {
+			w.WriteHeader(failedCode)
+			_, _ = w.Write([]byte(`{"status":"pending"}`))
+			return
+		}
+		_, _ = w.Write([]byte(fmt.Sprintf(`{"status":"done","count":%d}`, n)))
+	}))
+	return server, &count
+}
+
+func runSingleStep(t *testing.T, hrpRunner *hrp.HRPRunner, baseURL string, step hrp.IStep) (*hrp.StepResult, error) {
+	testcase := hrp.TestCase{
+		Config:    hrp.NewConfig("retry").SetBaseURL(baseURL),
+		TestSteps: []hrp.IStep{step},
+	}
+	caseRunner, err := hrp.NewCaseRunner(testcase, hrpRunner)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	summary, err := caseRunner.NewSession().Start(nil)
+	if !assert.Len(t, summary.Records, 1) {
+		t.FailNow()
+	}
+	return summary.Records[0], err
+}
+
+// loadJSONTestCase writes content into testcase.json of dir, t.TempDir() is used if dir is empty,
+// and loads testcase from the json file.
+func loadJSONTestCase(t *testing.T, dir, content string) *hrp.TestCase {
+	if dir == "" {
+		dir = t.TempDir()
+	}
+	casePath := filepath.Join(dir, "testcase.json")
+	err := os.WriteFile(casePath, []byte(content), 0o644)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	testcase := hrp.TestCasePath(casePath)
+	tc, err := testcase.GetTestCase()
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	return tc
+}
+
+// runJSONTestCase loads testcase with loadJSONTestCase and runs it, returns loaded testcase and run error.
+func runJSONTestCase(t *testing.T, dir, content string) (*hrp.TestCase, error) {
+	tc := loadJSONTestCase(t, dir, content)
+	return tc, hrp.NewRunner(t).Run(tc)
+}
+
+func TestStepRetryOnStatusCodes(t *testing.T) {
+	server, count := newFlakyServer(2, http.StatusServiceUnavailable)
+	defer server.Close()
+
+	step := hrp.NewStep("get status").
+		GET("/status").
+		SetRetry(hrp.NewStepRetry(5).
+			WithInterval(10*time.Millisecond).
+			WhenStatusCodes(502, 503)).
+		Validate().
+		AssertEqual("status_code", 200, "check status code")
+
+	stepResult, err := runSingleStep(t, hrp.NewRunner(t), server.URL, step)
+	assert.Nil(t, err)
+	assert.True(t, stepResult.Success)
+	assert.Equal(t, int64(3), atomic.LoadInt64(count))
+	if assert.Len(t, stepResult.Attempts, 3) {
+		assert.False(t, stepResult.Attempts[0].Success)
+		assert.Equal(t, "status code 503", stepResult.Attempts[0].RetryReason)
+		assert.True(t, stepResult.Attempts[2].Success)
+		assert.Empty(t, stepResult.Attempts[2].RetryReason)
+	}
+}
+
+func TestStepRetryUntil(t *testing.T) {
+	server, count := newFlakyServer(3, http.StatusOK)
+	defer server.Close()
+
+	step := hrp.NewStep("poll status").
+		GET("/status").
+		SetRetry(hrp.NewStepRetry(5).
+			WithInterval(10*time.Millisecond).
+			WithExponentialBackoff(50*time.Millisecond).
+			WithJitter(0.1).
+			UntilEqual("$status", "done")).
+		Extract().
+		WithJmesPath("body.status", "status")
+
+	stepResult, err := runSingleStep(t, hrp.NewRunner(t), server.URL, step)
+	assert.Nil(t, err)
+	assert.True(t, stepResult.Success)
+	assert.Equal(t, int64(4), atomic.LoadInt64(count))
+	assert.Len(t, stepResult.Attempts, 4)
+	assert.Equal(t, "done", stepResult.ExportVars["status"])
+}
+
+func TestStepRetryExhausted(t *testing.T) {
+	server, count := newFlakyServer(10, http.StatusServiceUnavailable)
+	defer server.Close()
+
+	step := hrp.NewStep("get status").
+		GET("/status").
+		SetRetry(hrp.NewStepRetry(3).
+			WithInterval(10*time.Millisecond).
+			WhenValidatorsFail("status_code")).
+		Validate().
+		AssertEqual("status_code", 200, "check status code")
+
+	stepResult, err := runSingleStep(t, hrp.NewRunner(nil), server.URL, step)
+	assert.True(t, errors.Is(err, code.MaxRetryError))
+	assert.False(t, stepResult.Success)
+	assert.Equal(t, int64(3), atomic.LoadInt64(count))
+	assert.Len(t, stepResult.Attempts, 3)
+}
+
+func TestStepRetryConditionNotMatched(t *testing.T) {
+	server, count := newFlakyServer(10, http.StatusNotFound)
+	defer server.Close()
+
+	step := hrp.NewStep("get status").
+		GET("/status").
+		SetRetry(hrp.NewStepRetry(3).
+			WithInterval(10*time.Millisecond).
+			WhenStatusCodes(503)).
+		Validate().
+		AssertEqual("status_code", 200, "check status code")
+
+	stepResult, err := runSingleStep(t, hrp.NewRunner(nil), server.URL, step)
+	assert.NotNil(t, err)
+	assert.False(t, errors.Is(err, code.MaxRetryError))
+	assert.Equal(t, int64(1), atomic.LoadInt64(count))
+	assert.Len(t, stepResult.Attempts, 1)
+}
+
+func TestStepRetryLoadFromJSON(t *testing.T) {
+	server, count := newFlakyServer(1, http.StatusBadGateway)
+	defer server.Close()
+
+	content := `{
+	"config": {"name": "retry", "base_url": "` + server.URL + `"},
+	"teststeps": [{
+		"name": "get status",
+		"request": {"method": "GET", "url": "/status"},
+		"retry": {"max_attempts": 3, "interval": 0.01, "until": [{"eq": ["body.status", "done"]}]}
+	}]
+}`
+	_, err := runJSONTestCase(t, "", content)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), atomic.LoadInt64(count))
+}
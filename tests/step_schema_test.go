@@ -0,0 +1,192 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	hrp "github.com/httprunner/httprunner/v5"
+)
+
+const userOpenAPISpec = `openapi: 3.0.0
+info:
+  title: users
+  version: 1.0.0
+servers:
+  - url: https://api.example.com/v1
+paths:
+  /users/{id}:
+    get:
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: integer
+      responses:
+        "200":
+          description: user
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/User"
+components:
+  schemas:
+    User:
+      type: object
+      required: [id, name, tags]
+      properties:
+        id:
+          type: integer
+        name:
+          type: string
+        tags:
+          type: array
+          items:
+            type: string
+`
+
+// newContractServer returns user with valid contract for /v1/users/1,
+// and user with wrong id type and tag type for others.
+func newContractServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasPrefix(r.URL.Path, "/v1/users/1") {
+			_, _ = w.Write([]byte(`{"id":1,"name":"leo","tags":["admin"]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"2","name":"tom","tags":["guest",3]}`))
+	}))
+}
+
+func writeSpecFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestAssertJSONSchema(t *testing.T) {
+	server := newContractServer()
+	defer server.Close()
+
+	schema := map[string]interface{}{
+		"type":     "object",
+		"required": []interface{}{"id", "name", "email"},
+		"properties": map[string]interface{}{
+			"id":   map[string]interface{}{"type": "integer"},
+			"tags": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		},
+	}
+	step := hrp.NewStep("get user").
+		GET("/v1/users/2").
+		Validate().
+		AssertJSONSchema("body", schema, "check user schema")
+
+	stepResult, err := runSingleStep(t, hrp.NewRunner(nil), server.URL, step)
+	assert.NotNil(t, err)
+	results := getValidationResults(t, stepResult)
+	if !assert.Len(t, results, 1) {
+		t.FailNow()
+	}
+	assert.Equal(t, "fail", results[0].CheckResult)
+
+	violations := make(map[string]string)
+	for _, violation := range results[0].Violations {
+		violations[violation.Pointer] = violation.Keyword
+	}
+	assert.Equal(t, map[string]string{
+		"/email":  "required",
+		"/id":     "type",
+		"/tags/1": "type",
+	}, violations)
+}
+
+func TestAssertJSONSchemaRef(t *testing.T) {
+	server := newContractServer()
+	defer server.Close()
+
+	specPath := writeSpecFile(t, "openapi.yaml", userOpenAPISpec)
+	schemaPath := writeSpecFile(t, "user.json",
+		`{"type": "object", "required": ["id"], "properties": {"id": {"type": "integer", "minimum": 1}}}`)
+
+	step := hrp.NewStep("get user").
+		GET("/v1/users/1").
+		Validate().
+		AssertJSONSchema("body", specPath+"#/components/schemas/User", "check user component").
+		AssertJSONSchema("body", map[string]interface{}{"$ref": schemaPath}, "check user schema file").
+		AssertJSONSchema("body.tags", map[string]interface{}{"type": "array", "minItems": 1}, "check tags")
+
+	stepResult, err := runSingleStep(t, hrp.NewRunner(t), server.URL, step)
+	assert.Nil(t, err)
+	assert.True(t, stepResult.Success)
+	assert.Len(t, getValidationResults(t, stepResult), 3)
+}
+
+func TestAssertOpenAPI(t *testing.T) {
+	server := newContractServer()
+	defer server.Close()
+
+	specPath := writeSpecFile(t, "openapi.yaml", userOpenAPISpec)
+
+	step := hrp.NewStep("get valid user").
+		GET("/v1/users/1").
+		Validate().
+		AssertOpenAPI(specPath, "check contract")
+	stepResult, err := runSingleStep(t, hrp.NewRunner(t), server.URL, step)
+	assert.Nil(t, err)
+	assert.True(t, stepResult.Success)
+
+	step = hrp.NewStep("get invalid user").
+		GET("/v1/users/2").
+		Validate().
+		AssertOpenAPI(specPath, "check contract")
+	stepResult, err = runSingleStep(t, hrp.NewRunner(nil), server.URL, step)
+	assert.NotNil(t, err)
+	results := getValidationResults(t, stepResult)
+	if assert.Len(t, results, 1) {
+		pointers := []string{}
+		for _, violation := range results[0].Violations {
+			assert.Equal(t, "type", violation.Keyword)
+			pointers = append(pointers, violation.Pointer)
+		}
+		assert.ElementsMatch(t, []string{"/id", "/tags/1"}, pointers)
+	}
+
+	step = hrp.NewStep("get undocumented path").
+		GET("/v1/orders/1").
+		Validate().
+		AssertOpenAPI(specPath, "check contract")
+	stepResult, err = runSingleStep(t, hrp.NewRunner(nil), server.URL, step)
+	assert.NotNil(t, err)
+	results = getValidationResults(t, stepResult)
+	if assert.Len(t, results, 1) && assert.Len(t, results[0].Violations, 1) {
+		assert.Equal(t, "paths", results[0].Violations[0].Keyword)
+	}
+}
+
+func TestAssertSchemaLoadFromJSON(t *testing.T) {
+	server := newContractServer()
+	defer server.Close()
+
+	specPath := writeSpecFile(t, "openapi.yaml", userOpenAPISpec)
+	content := `{
+	"config": {"name": "contract", "base_url": "` + server.URL + `"},
+	"teststeps": [{
+		"name": "get user",
+		"request": {"method": "GET", "url": "/v1/users/1"},
+		"validate": [
+			{"check": "body", "assert": "openapi", "expect": "` + specPath + `"},
+			{"json_schema": ["body.name", {"type": "string", "minLength": 2}]}
+		]
+	}]
+}`
+	_, err := runJSONTestCase(t, filepath.Dir(specPath), content)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,162 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	hrp "github.com/httprunner/httprunner/v5"
+)
+
+const soapResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope">
+  <soap:Body>
+    <GetPriceResponse>
+      <Item id="1"><Name>apple</Name><Price>1.5</Price></Item>
+      <Item id="2"><Name>banana</Name><Price>0.8</Price></Item>
+    </GetPriceResponse>
+  </soap:Body>
+</soap:Envelope>`
+
+const htmlResponse = `<!DOCTYPE html>
+<html>
+<head><title>Products</title></head>
+<body>
+  <h1 class="title"> Product List </h1>
+  <ul id="products">
+    <li class="product">apple</li>
+    <li class="product">banana</li>
+  </ul>
+  <a class="next" href="/page?p=2">next</a>
+</body>
+</html>`
+
+// newSelectorServer returns SOAP xml for /soap, html page for /page,
+// multiple cookies for /cookies, and redirects /redirect to /page.
+func newSelectorServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/soap"):
+			w.Header().Set("Content-Type", "application/soap+xml")
+			_, _ = w.Write([]byte(soapResponse))
+		case strings.HasPrefix(r.URL.Path, "/page"):
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(htmlResponse))
+		case strings.HasPrefix(r.URL.Path, "/cookies"):
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+			http.SetCookie(w, &http.Cookie{Name: "theme", Value: "dark"})
+			_, _ = w.Write([]byte(`{"ok":true}`))
+		case strings.HasPrefix(r.URL.Path, "/redirect"):
+			http.Redirect(w, r, "/page/", http.StatusFound)
+		}
+	}))
+}
+
+func TestExtractXPath(t *testing.T) {
+	server := newSelectorServer()
+	defer server.Close()
+
+	step := hrp.NewStep("get price").
+		POST("/soap").
+		Extract().
+		WithXPath(`//Item[@id="2"]/Name`, "name").
+		WithXPath("//Item/Price", "prices").
+		WithXPath("count(//soap:Body//Item)", "count").
+		Validate().
+		AssertEqual(`xpath(//Item[Name="apple"]/@id)`, "1", "check item id").
+		AssertEqual("xpath(sum(//Price))", 2.3, "check total price").
+		AssertEqual("xpath(//Missing)", nil, "check missing node")
+
+	stepResult, err := runSingleStep(t, hrp.NewRunner(t), server.URL, step)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, "banana", stepResult.ExportVars["name"])
+	assert.Equal(t, []interface{}{"1.5", "0.8"}, stepResult.ExportVars["prices"])
+	assert.Equal(t, int64(2), stepResult.ExportVars["count"])
+}
+
+func TestExtractCSS(t *testing.T) {
+	server := newSelectorServer()
+	defer server.Close()
+
+	step := hrp.NewStep("get page").
+		GET("/page").
+		Extract().
+		WithCSS("h1.title", "", "title").
+		WithCSS("#products > li.product", "", "products").
+		WithCSS("a.next", "href", "next").
+		Validate().
+		AssertEqual("css(head title)", "Products", "check page title").
+		AssertLengthEqual("css(li.product)", 2, "check products count").
+		AssertEqual("css(a.missing)@href", nil, "check missing link")
+
+	stepResult, err := runSingleStep(t, hrp.NewRunner(t), server.URL, step)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, "Product List", stepResult.ExportVars["title"])
+	assert.Equal(t, []interface{}{"apple", "banana"}, stepResult.ExportVars["products"])
+	assert.Equal(t, "/page?p=2", stepResult.ExportVars["next"])
+}
+
+func TestExtractResponseMetadata(t *testing.T) {
+	server := newSelectorServer()
+	defer server.Close()
+
+	step := hrp.NewStep("get cookies").
+		GET("/cookies").
+		Extract().
+		WithJmesPath(`headers_all."Set-Cookie"`, "set_cookies").
+		WithJmesPath("content_length", "length").
+		Validate().
+		AssertLengthEqual(`headers_all."Set-Cookie"`, 2, "check cookie headers").
+		AssertStartsWith(`headers."Set-Cookie"`, "session=abc", "check first cookie header").
+		AssertGreaterOrEqual("elapsed_ms", 0, "check elapsed")
+
+	stepResult, err := runSingleStep(t, hrp.NewRunner(t), server.URL, step)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, []interface{}{"session=abc", "theme=dark"}, stepResult.ExportVars["set_cookies"])
+	assert.Equal(t, int64(len(`{"ok":true}`)), stepResult.ExportVars["length"])
+
+	step = hrp.NewStep("follow redirect").
+		GET("/redirect").
+		Validate().
+		AssertEqual("status_code", 200, "check status code").
+		AssertEqual("final_url", server.URL+"/page/", "check final url").
+		AssertEqual("content_length", len(htmlResponse), "check content length")
+	_, err = runSingleStep(t, hrp.NewRunner(t), server.URL, step)
+	assert.Nil(t, err)
+}
+
+func TestSelectorLoadFromJSON(t *testing.T) {
+	server := newSelectorServer()
+	defer server.Close()
+
+	content := `{
+	"config": {"name": "selector", "base_url": "` + server.URL + `"},
+	"teststeps": [
+		{
+			"name": "get price",
+			"request": {"method": "POST", "url": "/soap"},
+			"extract": {"name": "xpath(//Item[@id=\"1\"]/Name)"}
+		},
+		{
+			"name": "get page",
+			"request": {"method": "GET", "url": "/page"},
+			"validate": [
+				{"eq": ["css(li.product:first-child)", "$name"]},
+				{"eq": ["css(a.next)@href", "/page?p=2"]},
+				{"contains": ["final_url", "/page"]}
+			]
+		}
+	]
+}`
+	_, err := runJSONTestCase(t, "", content)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,233 @@
+package tests
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	hrp "github.com/httprunner/httprunner/v5"
+)
+
+// newSignServer echoes request headers, and verifies hmac signature with the default canonical string
+func newSignServer(t *testing.T, secret string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodyHash := sha256.Sum256(body)
+		stringToSign := r.Method + "\n" + r.URL.EscapedPath() + "\n" + r.URL.Query().Encode() + "\n" +
+			r.Header.Get("X-Timestamp") + "\n" + r.Header.Get("X-Nonce") + "\n" + hex.EncodeToString(bodyHash[:])
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(stringToSign))
+
+		headers := make(map[string]string)
+		for key := range r.Header {
+			headers[key] = r.Header.Get(key)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"host":     r.Host,
+			"headers":  headers,
+			"verified": r.Header.Get("X-Signature") == hex.EncodeToString(mac.Sum(nil)),
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSignHMAC(t *testing.T) {
+	server := newSignServer(t, "my-secret")
+
+	testcase := &hrp.TestCase{
+		Config: hrp.NewConfig("sign hmac").
+			SetBaseURL(server.URL).
+			WithVariables(map[string]interface{}{"secret": "my-secret"}).
+			SetSign(&hrp.RequestSign{
+				Type:   hrp.SignHMACSHA256,
+				Secret: "$secret",
+			}),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("signed get").
+				GET("/api/users").
+				WithParams(map[string]interface{}{"b": 2, "a": 1}).
+				Validate().
+				AssertEqual("body.verified", true, "check signature"),
+			hrp.NewStep("signed post").
+				POST("/api/users").
+				WithBody(map[string]interface{}{"name": "bob"}).
+				Validate().
+				AssertEqual("body.verified", true, "check signature with body"),
+			hrp.NewStep("signing disabled").
+				GET("/api/users").
+				SetSign(&hrp.RequestSign{Type: hrp.SignNone}).
+				Validate().
+				AssertEqual("body.headers.\"X-Signature\"", nil, "check not signed"),
+		},
+	}
+	err := hrp.NewRunner(t).Run(testcase)
+	assert.Nil(t, err)
+}
+
+func TestSignHMACCustomCanonicalString(t *testing.T) {
+	server := newSignServer(t, "my-secret")
+
+	mac := hmac.New(sha256.New, []byte("my-secret"))
+	mac.Write([]byte("GET\n/api/users/\nv1"))
+	expected := "HMAC key-1:" + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	step := hrp.NewStep("signed with custom canonical string").
+		GET("/api/users").
+		WithHeaders(map[string]string{"X-Api-Version": "v1"}).
+		SetSign(&hrp.RequestSign{
+			Type:            hrp.SignHMACSHA256,
+			KeyID:           "key-1",
+			Secret:          "my-secret",
+			CanonicalString: `{method}\n{path}\n{header:X-Api-Version}`,
+			Header:          "Authorization",
+			HeaderFormat:    "HMAC {key_id}:{signature}",
+			Encoding:        "base64",
+		}).
+		Validate().
+		AssertEqual("body.headers.Authorization", expected, "check signature")
+	stepResult, err := runSingleStep(t, hrp.NewRunner(t), server.URL, step)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	// signed headers are recorded in report
+	sessionData := stepResult.Data.(*hrp.SessionData)
+	headers := sessionData.ReqResps.Request.(map[string]interface{})["headers"].(map[string]string)
+	assert.Equal(t, expected, headers["Authorization"])
+	assert.NotEmpty(t, headers["X-Timestamp"])
+	assert.NotEmpty(t, headers["X-Nonce"])
+}
+
+func TestSignAWSSigV4(t *testing.T) {
+	// requests are sent to server as proxy, thus host of AWS test suite is kept
+	server := newSignServer(t, "")
+	awsSign := &hrp.RequestSign{
+		Type:    hrp.SignAWSSigV4,
+		KeyID:   "AKIDEXAMPLE",
+		Secret:  "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		Region:  "us-east-1",
+		Service: "service",
+	}
+
+	// get-vanilla, get-vanilla-query-order-key-case and get-vanilla-query-order-value in AWS SigV4 test suite,
+	// and query keys which are prefixes of other keys
+	testcase := &hrp.TestCase{
+		Config: hrp.NewConfig("sign aws sigv4").
+			SetProxies(map[string]string{"http": server.URL}).
+			SetSign(awsSign),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("get vanilla").
+				GET("http://example.amazonaws.com/").
+				WithHeaders(map[string]string{"X-Amz-Date": "20150830T123600Z"}).
+				Validate().
+				AssertEqual("body.host", "example.amazonaws.com", "check host").
+				AssertEqual("body.headers.Authorization",
+					"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+						"SignedHeaders=host;x-amz-date, "+
+						"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+					"check authorization"),
+			hrp.NewStep("get vanilla query order").
+				GET("http://example.amazonaws.com/?Param2=value2&Param1=value1").
+				WithHeaders(map[string]string{"X-Amz-Date": "20150830T123600Z"}).
+				Validate().
+				AssertEqual("body.headers.Authorization",
+					"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+						"SignedHeaders=host;x-amz-date, "+
+						"Signature=b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+					"check authorization"),
+			hrp.NewStep("get vanilla query order value").
+				GET("http://example.amazonaws.com/?Param1=value2&Param1=Value1").
+				WithHeaders(map[string]string{"X-Amz-Date": "20150830T123600Z"}).
+				Validate().
+				AssertEqual("body.headers.Authorization",
+					"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+						"SignedHeaders=host;x-amz-date, "+
+						"Signature=eedbc4e291e521cf13422ffca22be7d2eb8146eecf653089df300a15b2382bd1",
+					"check authorization"),
+			// canonical query is page=1&page2=1, key prefix is sorted first
+			hrp.NewStep("get query with prefix key").
+				GET("http://example.amazonaws.com/?page2=1&page=1").
+				WithHeaders(map[string]string{"X-Amz-Date": "20150830T123600Z"}).
+				Validate().
+				AssertEqual("body.headers.Authorization",
+					"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+						"SignedHeaders=host;x-amz-date, "+
+						"Signature=1513bf575f19025ae65cd8158e4a5da91d1bec44e22b761fb5ddffd900763164",
+					"check authorization"),
+			// canonical query is a=1&a=2&a-b=1
+			hrp.NewStep("get query with prefix key and repeated key").
+				GET("http://example.amazonaws.com/?a-b=1&a=2&a=1").
+				WithHeaders(map[string]string{"X-Amz-Date": "20150830T123600Z"}).
+				Validate().
+				AssertEqual("body.headers.Authorization",
+					"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+						"SignedHeaders=host;x-amz-date, "+
+						"Signature=c55a4bf05f068bf43585bea6cee5249a0f9f645d92763c513e8e1d19cd97f237",
+					"check authorization"),
+			hrp.NewStep("get with current date and session token").
+				GET("http://example.amazonaws.com/").
+				SetSign(&hrp.RequestSign{
+					Type:         hrp.SignAWSSigV4,
+					KeyID:        "AKIDEXAMPLE",
+					Secret:       "secret",
+					SessionToken: "session-token",
+					Region:       "us-east-1",
+					Service:      "service",
+				}).
+				Validate().
+				AssertEqual("body.headers.\"X-Amz-Security-Token\"", "session-token", "check session token").
+				AssertContains("body.headers.Authorization",
+					"SignedHeaders=host;x-amz-date;x-amz-security-token", "check signed headers"),
+		},
+	}
+	err := hrp.NewRunner(t).Run(testcase)
+	assert.Nil(t, err)
+}
+
+func TestSignFunction(t *testing.T) {
+	server := newSignServer(t, "")
+
+	step := hrp.NewStep("signed with function").
+		WithVariables(map[string]interface{}{
+			"signed_headers": map[string]interface{}{"X-Custom-Signature": "signed"},
+		}).
+		GET("/api/users").
+		SetSign(&hrp.RequestSign{
+			Type:     hrp.SignFunction,
+			Function: "$signed_headers",
+		}).
+		Validate().
+		AssertEqual("body.headers.\"X-Custom-Signature\"", "signed", "check signature header")
+	_, err := runSingleStep(t, hrp.NewRunner(t), server.URL, step)
+	assert.Nil(t, err)
+}
+
+func TestSignLoadFromJSON(t *testing.T) {
+	server := newSignServer(t, "my-secret")
+
+	content := `{
+	"config": {
+		"name": "sign",
+		"base_url": "` + server.URL + `",
+		"sign": {"type": "hmac_sha256", "secret": "my-secret"}
+	},
+	"teststeps": [
+		{
+			"name": "signed post",
+			"request": {"method": "POST", "url": "/api/users", "params": {"q": "x"}, "body": "raw body"},
+			"validate": [{"eq": ["body.verified", true]}]
+		}
+	]
+}`
+	_, err := runJSONTestCase(t, "", content)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,275 @@
+package tests
+
+import (
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	hrp "github.com/httprunner/httprunner/v5"
+)
+
+// newTCPEchoServer echoes bytes of each connection as is
+func newTCPEchoServer(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// newUDPEchoServer echoes each datagram to its sender
+func newUDPEchoServer(t *testing.T) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err)
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			_, _ = conn.WriteTo(buf[:n], addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func runSocketTestCase(t *testing.T, addr string, steps ...hrp.IStep) *hrp.TestCaseSummary {
+	testcase := hrp.TestCase{
+		Config: hrp.NewConfig("socket").
+			WithVariables(map[string]interface{}{"addr": addr}),
+		TestSteps: steps,
+	}
+	caseRunner, err := hrp.NewCaseRunner(testcase, hrp.NewRunner(t))
+	require.Nil(t, err)
+	session := caseRunner.NewSession()
+	defer session.ReleaseResources()
+	summary, err := session.Start(nil)
+	require.Nil(t, err)
+	return summary
+}
+
+func TestSocketTCPDelimiter(t *testing.T) {
+	addr := newTCPEchoServer(t)
+	summary := runSocketTestCase(t, addr,
+		hrp.NewStep("open").
+			TCP().
+			OpenConnection("$addr").
+			WithDelimiter("\r\n"),
+		hrp.NewStep("write json").
+			TCP().
+			Write("$addr").
+			WithText(map[string]interface{}{"cmd": "ping", "seq": 1}),
+		hrp.NewStep("write text").
+			TCP().
+			Write("$addr").
+			WithText("hello"),
+		hrp.NewStep("read json frame").
+			TCP().
+			Read("$addr").
+			Extract().
+			WithJmesPath("body.cmd", "cmd").
+			Validate().
+			AssertEqual("body.cmd", "ping", "check json field").
+			AssertEqual("body.seq", 1, "check json field"),
+		hrp.NewStep("read text frame").
+			TCP().
+			Read("$addr").
+			Validate().
+			AssertEqual("body", "hello", "check text frame").
+			AssertEqual("frame_size", 5, "check frame size without delimiter"),
+		hrp.NewStep("close").
+			TCP().
+			CloseConnection("$addr"),
+	)
+	assert.True(t, summary.Success)
+	assert.Equal(t, "ping", summary.Records[3].ExportVars["cmd"])
+	assert.Equal(t, hrp.StepTypeTCP, summary.Records[4].StepType)
+}
+
+func TestSocketTCPBinaryTemplate(t *testing.T) {
+	addr := newTCPEchoServer(t)
+	header := []*hrp.BinaryField{
+		{Name: "magic", Type: "bytes", Size: 2},
+		{Name: "cmd", Type: "uint16"},
+		{Name: "seq", Type: "int32"},
+		{Name: "name", Type: "string", Size: 8},
+		{Name: "online", Type: "bool"},
+	}
+	summary := runSocketTestCase(t, addr,
+		hrp.NewStep("open").
+			TCP().
+			OpenConnection("$addr").
+			WithLengthPrefix(2),
+		hrp.NewStep("login").
+			TCP().
+			WriteAndRead("$addr").
+			WithBinary(
+				&hrp.BinaryField{Type: "bytes", Value: "CA FE"},
+				&hrp.BinaryField{Type: "uint16", Value: "0x0102"},
+				&hrp.BinaryField{Type: "int32", Value: -2},
+				&hrp.BinaryField{Type: "string", Value: "bob", Size: 8},
+				&hrp.BinaryField{Type: "bool", Value: true},
+			).
+			DecodeWith(header...).
+			Validate().
+			AssertEqual("body.magic", "cafe", "check magic").
+			AssertEqual("body.cmd", 258, "check cmd").
+			AssertEqual("body.seq", -2, "check seq").
+			AssertEqual("body.name", "bob", "check padded string").
+			AssertEqual("body.online", true, "check bool").
+			AssertEqual("frame_size", 17, "check frame size without length prefix").
+			AssertFrameHex("CAFE 0102 FFFFFFFE 626f620000000000 01", "check frame bytes"),
+		hrp.NewStep("little endian with fixed size").
+			TCP().
+			WriteAndRead("$addr").
+			WithFixedSize(4).
+			WithLittleEndian().
+			WithBinary(&hrp.BinaryField{Type: "uint16", Value: 1}).
+			DecodeWith(&hrp.BinaryField{Name: "cmd", Type: "uint16"}).
+			Validate().
+			AssertEqual("body.cmd", 1, "check little endian").
+			AssertFrameHex("01000000", "check zero padding"),
+	)
+	assert.True(t, summary.Success)
+}
+
+func TestSocketUDP(t *testing.T) {
+	addr := newUDPEchoServer(t)
+	summary := runSocketTestCase(t, addr,
+		hrp.NewStep("open").
+			UDP().
+			OpenConnection("$addr"),
+		hrp.NewStep("hex datagram").
+			UDP().
+			WriteAndRead("$addr").
+			WithHex("de ad be ef").
+			Validate().
+			AssertFrameHex("DEADBEEF", "check datagram").
+			AssertEqual("body", "deadbeef", "binary body is kept in hex").
+			AssertEqual("frame_base64", "3q2+7w==", "check base64"),
+		hrp.NewStep("base64 datagram with length prefix").
+			UDP().
+			WriteAndRead("$addr").
+			WithLengthPrefix(1).
+			WithBase64("aGVsbG8=").
+			Validate().
+			AssertEqual("body", "hello", "check payload without prefix"),
+	)
+	assert.True(t, summary.Success)
+	assert.Equal(t, hrp.StepTypeUDP, summary.Records[1].StepType)
+}
+
+func TestSocketReadTimeout(t *testing.T) {
+	addr := newTCPEchoServer(t)
+	testcase := hrp.TestCase{
+		Config: hrp.NewConfig("socket timeout").
+			WithVariables(map[string]interface{}{"addr": addr}),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("open").TCP().OpenConnection("$addr"),
+			hrp.NewStep("read nothing").TCP().Read("$addr").WithTimeout(100),
+		},
+	}
+	caseRunner, err := hrp.NewCaseRunner(testcase, hrp.NewRunner(nil))
+	require.Nil(t, err)
+	session := caseRunner.NewSession()
+	defer session.ReleaseResources()
+	_, err = session.Start(nil)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "read frame failed")
+		assert.Contains(t, err.Error(), "i/o timeout")
+	}
+}
+
+func TestSocketFrameSizeLimit(t *testing.T) {
+	addr := newTCPEchoServer(t)
+	runFrame := func(name string, steps ...hrp.IStep) error {
+		testcase := hrp.TestCase{
+			Config: hrp.NewConfig(name).
+				WithVariables(map[string]interface{}{"addr": addr}),
+			TestSteps: append([]hrp.IStep{hrp.NewStep("open").TCP().OpenConnection("$addr")}, steps...),
+		}
+		caseRunner, err := hrp.NewCaseRunner(testcase, hrp.NewRunner(nil))
+		require.Nil(t, err)
+		session := caseRunner.NewSession()
+		defer session.ReleaseResources()
+		_, err = session.Start(nil)
+		return err
+	}
+
+	// high bit of 8 bytes length prefix is set
+	err := runFrame("negative length",
+		hrp.NewStep("write prefix").TCP().Write("$addr").WithHex("8000000000000001"),
+		hrp.NewStep("read frame").TCP().Read("$addr").WithLengthPrefix(8))
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "frame size 9223372036854775809 exceeds max size 16777216")
+	}
+
+	// 4 bytes length prefix exceeds the configured max size
+	err = runFrame("oversized length",
+		hrp.NewStep("write prefix").TCP().Write("$addr").WithHex("00000401"),
+		hrp.NewStep("read frame").TCP().Read("$addr").WithLengthPrefix(4).WithMaxFrameSize(1024))
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "frame size 1025 exceeds max size 1024")
+	}
+
+	// delimiter is not found within the max size
+	err = runFrame("missing delimiter",
+		hrp.NewStep("write text").TCP().Write("$addr").WithText("hello world"),
+		hrp.NewStep("read frame").TCP().Read("$addr").WithDelimiter("\n").WithMaxFrameSize(4))
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "frame size exceeds max size 4 before delimiter")
+	}
+}
+
+func TestSocketLoadFromJSON(t *testing.T) {
+	addr := newTCPEchoServer(t)
+	content := `{
+	"config": {"name": "socket", "variables": {"addr": "` + addr + `"}},
+	"teststeps": [
+		{
+			"name": "open",
+			"tcp": {"type": "open", "url": "$addr", "framing": {"length_prefix": 4, "byte_order": "little"}}
+		},
+		{
+			"name": "heartbeat",
+			"tcp": {
+				"type": "wr",
+				"url": "$addr",
+				"binary": [
+					{"type": "uint8", "value": 7},
+					{"type": "string", "value": "$addr"}
+				],
+				"decode": [
+					{"name": "cmd", "type": "uint8"},
+					{"name": "addr", "type": "string"}
+				]
+			},
+			"validate": [
+				{"eq": ["body.cmd", 7]},
+				{"eq": ["body.addr", "$addr"]}
+			]
+		}
+	]
+}`
+	tc, err := runJSONTestCase(t, "", content)
+	assert.Nil(t, err)
+	if assert.Len(t, tc.TestSteps, 2) {
+		assert.Equal(t, hrp.StepTypeTCP, tc.TestSteps[0].Type())
+	}
+}
@@ -0,0 +1,230 @@
+package tests
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	hrp "github.com/httprunner/httprunner/v5"
+)
+
+func newSQLiteDSN(t *testing.T) string {
+	dsn := filepath.Join(t.TempDir(), "test.db")
+	db, err := sql.Open("sqlite", dsn)
+	require.Nil(t, err)
+	defer db.Close()
+	_, err = db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT, age INTEGER)`)
+	require.Nil(t, err)
+	return dsn
+}
+
+func countSQLiteUsers(t *testing.T, dsn string) int {
+	db, err := sql.Open("sqlite", dsn)
+	require.Nil(t, err)
+	defer db.Close()
+	var count int
+	require.Nil(t, db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count))
+	return count
+}
+
+func TestSQLQueryAndExec(t *testing.T) {
+	dsn := newSQLiteDSN(t)
+	config := hrp.NewConfig("sql").
+		WithVariables(map[string]interface{}{"name": "alice", "age": 18})
+	// default database is configured in environs
+	config.Environs["SQL_DRIVER"] = "sqlite"
+	config.Environs["SQL_DSN"] = dsn
+	testcase := hrp.TestCase{
+		Config: config,
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("seed users").
+				SQL().
+				Exec("INSERT INTO users (name, age) VALUES (?, ?), (?, ?)", "$name", "$age", "bob", 20).
+				Validate().
+				AssertEqual("rows_affected", 2, "check inserted rows").
+				AssertEqual("last_insert_id", 2, "check last insert id"),
+			hrp.NewStep("query user by name").
+				SQL().
+				Query("SELECT id, name, age FROM users WHERE name = ?", "$name").
+				Extract().
+				WithJmesPath("rows[0].id", "user_id").
+				Validate().
+				AssertEqual("row_count", 1, "check row count").
+				AssertEqual("rows[0].name", "alice", "check name").
+				AssertEqual("rows[0].age", 18, "check age").
+				AssertEqual("columns", []interface{}{"id", "name", "age"}, "check columns"),
+			hrp.NewStep("query with extracted variable").
+				SQL().
+				WithDB("sqlite3", dsn).
+				Query("SELECT name FROM users WHERE id > ? ORDER BY id", "$user_id").
+				Validate().
+				AssertLengthEqual("rows", 1, "check rows").
+				AssertEqual("rows[0].name", "bob", "check name"),
+		},
+	}
+	caseRunner, err := hrp.NewCaseRunner(testcase, hrp.NewRunner(t))
+	require.Nil(t, err)
+	session := caseRunner.NewSession()
+	defer session.ReleaseResources()
+	summary, err := session.Start(nil)
+	require.Nil(t, err)
+	assert.True(t, summary.Success)
+	assert.EqualValues(t, 1, summary.Records[1].ExportVars["user_id"])
+	assert.Equal(t, hrp.StepTypeSQL, summary.Records[1].StepType)
+}
+
+func TestSQLTransaction(t *testing.T) {
+	dsn := newSQLiteDSN(t)
+	testcase := hrp.TestCase{
+		Config: hrp.NewConfig("sql transaction").
+			WithVariables(map[string]interface{}{"dsn": dsn}),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("begin").SQL().WithDB("sqlite3", "$dsn").Begin(),
+			hrp.NewStep("insert rolled back").
+				SQL().
+				WithDB("sqlite3", "$dsn").
+				Exec("INSERT INTO users (name, age) VALUES ('tmp', 1)"),
+			hrp.NewStep("rollback").SQL().WithDB("sqlite3", "$dsn").Rollback(),
+			hrp.NewStep("begin again").SQL().WithDB("sqlite3", "$dsn").Begin(),
+			hrp.NewStep("insert committed").
+				SQL().
+				WithDB("sqlite3", "$dsn").
+				Exec("INSERT INTO users (name, age) VALUES ('kept', 2)"),
+			hrp.NewStep("commit").SQL().WithDB("sqlite3", "$dsn").Commit(),
+			hrp.NewStep("begin seed data").SQL().WithDB("sqlite3", "$dsn").Begin(),
+			hrp.NewStep("insert in transaction").
+				SQL().
+				WithDB("sqlite3", "$dsn").
+				Exec("INSERT INTO users (name, age) VALUES ('seed', 3)"),
+			hrp.NewStep("query in transaction").
+				SQL().
+				WithDB("sqlite3", "$dsn").
+				Query("SELECT name FROM users ORDER BY id").
+				Validate().
+				AssertEqual("rows[*].name", []interface{}{"kept", "seed"}, "check visible rows"),
+		},
+	}
+	caseRunner, err := hrp.NewCaseRunner(testcase, hrp.NewRunner(t))
+	require.Nil(t, err)
+	session := caseRunner.NewSession()
+	summary, err := session.Start(nil)
+	require.Nil(t, err)
+	assert.True(t, summary.Success)
+
+	// transaction not committed is rolled back on releasing resources
+	session.ReleaseResources()
+	assert.Equal(t, 1, countSQLiteUsers(t, dsn))
+}
+
+func TestSQLCommitWithoutTransaction(t *testing.T) {
+	dsn := newSQLiteDSN(t)
+	testcase := &hrp.TestCase{
+		Config: hrp.NewConfig("sql commit").
+			WithVariables(map[string]interface{}{"dsn": dsn}),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("commit").SQL().WithDB("sqlite3", "$dsn").Commit(),
+		},
+	}
+	err := hrp.NewRunner(nil).Run(testcase)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "commit without sql transaction")
+	}
+}
+
+// textDriver mocks drivers returning bytes for all columns, e.g. mysql in text protocol
+type textDriver struct{}
+
+func (textDriver) Open(name string) (driver.Conn, error) { return textConn{}, nil }
+
+type textConn struct{}
+
+func (textConn) Prepare(query string) (driver.Stmt, error) { return textStmt{}, nil }
+func (textConn) Close() error                              { return nil }
+func (textConn) Begin() (driver.Tx, error)                 { return nil, driver.ErrSkip }
+
+type textStmt struct{}
+
+func (textStmt) Close() error  { return nil }
+func (textStmt) NumInput() int { return -1 }
+func (textStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(0), nil
+}
+func (textStmt) Query(args []driver.Value) (driver.Rows, error) { return &textRows{}, nil }
+
+type textRows struct{ done bool }
+
+func (r *textRows) Columns() []string { return []string{"id", "name", "price", "stock"} }
+func (r *textRows) Close() error      { return nil }
+func (r *textRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0], dest[1], dest[2], dest[3] = []byte("1"), []byte("apple"), []byte("12.50"), []byte("30")
+	return nil
+}
+
+func (r *textRows) ColumnTypeDatabaseTypeName(index int) string {
+	return []string{"BIGINT", "VARCHAR", "DECIMAL", "UNSIGNED INT"}[index]
+}
+
+func init() {
+	sql.Register("hrp-text", textDriver{})
+}
+
+func TestSQLTextProtocolValues(t *testing.T) {
+	testcase := &hrp.TestCase{
+		Config: hrp.NewConfig("sql text protocol"),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("query").
+				SQL().
+				WithDB("hrp-text", "products").
+				Query("SELECT id, name, price, stock FROM products").
+				Validate().
+				AssertEqual("rows[0].id", 1, "check integer column").
+				AssertEqual("rows[0].name", "apple", "check text column").
+				AssertEqual("rows[0].price", 12.5, "check decimal column").
+				AssertEqual("rows[0].stock", 30, "check unsigned column"),
+		},
+	}
+	err := hrp.NewRunner(t).Run(testcase)
+	assert.Nil(t, err)
+}
+
+func TestSQLLoadFromJSON(t *testing.T) {
+	dsn := newSQLiteDSN(t)
+	content := `{
+	"config": {"name": "sql", "variables": {"dsn": "` + dsn + `"}},
+	"teststeps": [
+		{
+			"name": "seed",
+			"sql": {
+				"type": "exec",
+				"driver": "sqlite3",
+				"dsn": "$dsn",
+				"query": "INSERT INTO users (name, age) VALUES (?, ?)",
+				"args": ["carol", 30]
+			}
+		},
+		{
+			"name": "query",
+			"sql": {"driver": "sqlite3", "dsn": "$dsn", "query": "SELECT name, age FROM users"},
+			"validate": [
+				{"eq": ["row_count", 1]},
+				{"eq": ["rows[0].name", "carol"]},
+				{"eq": ["rows[0].age", 30]}
+			]
+		}
+	]
+}`
+	tc, err := runJSONTestCase(t, "", content)
+	assert.Nil(t, err)
+	if assert.Len(t, tc.TestSteps, 2) {
+		assert.Equal(t, hrp.StepTypeSQL, tc.TestSteps[1].Type())
+	}
+}
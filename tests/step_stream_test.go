@@ -0,0 +1,205 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	hrp "github.com/httprunner/httprunner/v5"
+)
+
+// newStreamServer mocks streaming endpoints, e.g. chat completion in SSE
+func newStreamServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher := w.(http.Flusher)
+		write := func(content string) {
+			fmt.Fprint(w, content)
+			flusher.Flush()
+			time.Sleep(10 * time.Millisecond)
+		}
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/chat"):
+			w.Header().Set("Content-Type", "text/event-stream")
+			write(": keep-alive\n\n")
+			write("id: 1\nevent: start\ndata: {\"role\": \"assistant\"}\n\n")
+			for _, delta := range []string{"Hello", " world"} {
+				write(fmt.Sprintf("data: {\"delta\": %q}\n\n", delta))
+			}
+			write("data: multi\r\ndata: line\r\n\r\n")
+			write("data: [DONE]\n\n")
+		case strings.HasPrefix(r.URL.Path, "/ticks"):
+			// endless stream until client disconnects
+			w.Header().Set("Content-Type", "text/event-stream")
+			for seq := 0; r.Context().Err() == nil; seq++ {
+				write(fmt.Sprintf("event: tick\ndata: {\"seq\": %d}\n\n", seq))
+			}
+		case strings.HasPrefix(r.URL.Path, "/ndjson"):
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			for i := 1; i <= 3; i++ {
+				write(fmt.Sprintf("{\"index\": %d, \"done\": %v}\n", i, i == 3))
+			}
+		default:
+			w.Header().Set("Content-Type", "text/plain")
+			write("chunk-1")
+			write("chunk-2")
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestStreamSSE(t *testing.T) {
+	server := newStreamServer(t)
+
+	step := hrp.NewStep("chat completion").
+		POST("/chat").
+		SetStream(hrp.NewRequestStream()).
+		WithBody(map[string]interface{}{"prompt": "hi"}).
+		Extract().
+		WithJmesPath("events[1].data.delta", "first_delta").
+		Validate().
+		AssertEqual("status_code", 200, "check status code").
+		AssertLengthEqual("events", 5, "check events count").
+		AssertEqual("events[0].event", "start", "check event name").
+		AssertEqual("events[0].id", "1", "check event id").
+		AssertEqual("events[0].data.role", "assistant", "check json data").
+		AssertEqual("events[1].event", "message", "check default event name").
+		AssertEqual("events[3].data", "multi\nline", "check multi-line data").
+		AssertEqual("events[-1].data", "[DONE]", "check last event").
+		AssertLengthEqual("event_latencies_ms", 4, "check latencies count").
+		AssertGreaterOrEqual("ttfb_ms", 0, "check time to first byte").
+		AssertEqual("end_reason", "eof", "check end reason").
+		AssertContains("body", "data: [DONE]", "check raw body")
+
+	stepResult, err := runSingleStep(t, hrp.NewRunner(t), server.URL, step)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	assert.True(t, stepResult.Success)
+	assert.Equal(t, "Hello", stepResult.ExportVars["first_delta"])
+}
+
+func TestStreamWaitUntil(t *testing.T) {
+	server := newStreamServer(t)
+
+	step := hrp.NewStep("wait for tick").
+		WithVariables(map[string]interface{}{"expected_seq": 3}).
+		GET("/ticks").
+		SetStream(hrp.NewRequestStream().WaitUntil("data.seq", "equals", "$expected_seq", "wait seq 3")).
+		Validate().
+		AssertLengthEqual("events", 4, "check events count").
+		AssertEqual("end_reason", "until", "check end reason")
+	stepResult, err := runSingleStep(t, hrp.NewRunner(t), server.URL, step)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	validators := getValidationResults(t, stepResult)
+	if assert.Len(t, validators, 3) {
+		assert.Equal(t, "until data.seq", validators[0].Check)
+		assert.Equal(t, "pass", validators[0].CheckResult)
+		assert.EqualValues(t, 3, validators[0].CheckValue)
+	}
+
+	// stream ends by max events before until condition is met
+	step = hrp.NewStep("wait for tick with max events").
+		GET("/ticks").
+		SetStream(hrp.NewRequestStream().
+			WithMaxEvents(2).
+			WaitUntil("data.seq", "equals", 10)).
+		Validate().
+		AssertEqual("end_reason", "max_events", "check end reason")
+	stepResult, err = runSingleStep(t, hrp.NewRunner(nil), server.URL, step)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "no stream event matches until condition: data.seq equals 10")
+	}
+	assert.False(t, stepResult.Success)
+	validators = getValidationResults(t, stepResult)
+	if assert.Len(t, validators, 2) {
+		assert.Equal(t, "fail", validators[0].CheckResult)
+		assert.Equal(t, "pass", validators[1].CheckResult)
+	}
+}
+
+func TestStreamDuration(t *testing.T) {
+	server := newStreamServer(t)
+
+	step := hrp.NewStep("read ticks in duration").
+		GET("/ticks").
+		SetStream(hrp.NewRequestStream().WithDuration(200*time.Millisecond)).
+		Validate().
+		AssertEqual("end_reason", "duration", "check end reason").
+		AssertLengthGreaterThan("events", 1, "check events received").
+		AssertEqual("events[0].event", "tick", "check event name")
+	start := time.Now()
+	_, err := runSingleStep(t, hrp.NewRunner(t), server.URL, step)
+	assert.Nil(t, err)
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestStreamNDJSONAndChunked(t *testing.T) {
+	server := newStreamServer(t)
+
+	step := hrp.NewStep("ndjson").
+		GET("/ndjson").
+		SetStream(hrp.NewRequestStream().WaitUntil("data.done", "equals", true)).
+		Validate().
+		AssertLengthEqual("events", 3, "check events count").
+		AssertEqual("events[2].data.index", 3, "check last event")
+	_, err := runSingleStep(t, hrp.NewRunner(t), server.URL, step)
+	assert.Nil(t, err)
+
+	step = hrp.NewStep("chunked").
+		GET("/chunked").
+		SetStream(hrp.NewRequestStream().WithFormat(hrp.StreamFormatChunked)).
+		Validate().
+		AssertEqual("events[0].data", "chunk-1", "check first chunk").
+		AssertEqual("body", "chunk-1chunk-2", "check body")
+	_, err = runSingleStep(t, hrp.NewRunner(t), server.URL, step)
+	assert.Nil(t, err)
+
+	// raw body is truncated while events are still parsed
+	step = hrp.NewStep("chunked with max raw size").
+		GET("/chunked").
+		SetStream(hrp.NewRequestStream().WithFormat(hrp.StreamFormatChunked).WithMaxRawSize(4)).
+		Validate().
+		AssertLengthEqual("events", 2, "check events count").
+		AssertEqual("events[1].data", "chunk-2", "check last chunk").
+		AssertEqual("body", "chun", "check truncated body").
+		AssertEqual("content_length", 14, "check total size")
+	_, err = runSingleStep(t, hrp.NewRunner(t), server.URL, step)
+	assert.Nil(t, err)
+}
+
+func TestStreamLoadFromJSON(t *testing.T) {
+	server := newStreamServer(t)
+
+	content := `{
+	"config": {"name": "stream", "base_url": "` + server.URL + `"},
+	"teststeps": [
+		{
+			"name": "wait for tick",
+			"request": {
+				"method": "GET",
+				"url": "/ticks",
+				"stream": {
+					"format": "sse",
+					"max_events": 10,
+					"until": {"check": "data.seq", "assert": "equals", "expect": 2}
+				}
+			},
+			"extract": {"last_seq": "events[-1].data.seq"},
+			"validate": [
+				{"eq": ["end_reason", "until"]},
+				{"len_eq": ["events", 3]}
+			]
+		}
+	]
+}`
+	_, err := runJSONTestCase(t, "", content)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,474 @@
+package tests
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	hrp "github.com/httprunner/httprunner/v5"
+)
+
+func runConfigStep(t *testing.T, hrpRunner *hrp.HRPRunner, config *hrp.TConfig, step hrp.IStep) (*hrp.StepResult, error) {
+	testcase := hrp.TestCase{
+		Config:    config,
+		TestSteps: []hrp.IStep{step},
+	}
+	caseRunner, err := hrp.NewCaseRunner(testcase, hrpRunner)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	summary, err := caseRunner.NewSession().Start(nil)
+	if !assert.Len(t, summary.Records, 1) {
+		t.FailNow()
+	}
+	return summary.Records[0], err
+}
+
+// newProjectDir creates project root dir with proj.json, and writes files into it
+func newProjectDir(t *testing.T, files map[string][]byte) string {
+	dir := t.TempDir()
+	files["proj.json"] = []byte("{}")
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func pemEncode(blockType string, der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+}
+
+// newClientCert generates self-signed CA and client certificate signed by it
+func newClientCert(t *testing.T) (caPool *x509.CertPool, certPEM, keyPEM []byte) {
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "hrp test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, _ := x509.ParseCertificate(caDER)
+
+	clientKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	clientTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "hrp client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	clientDER, err := x509.CreateCertificate(rand.Reader, clientTemplate, caCert, &clientKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(clientKey)
+
+	caPool = x509.NewCertPool()
+	caPool.AddCert(caCert)
+	return caPool, pemEncode("CERTIFICATE", clientDER), pemEncode("EC PRIVATE KEY", keyDER)
+}
+
+func newTLSServer(t *testing.T, clientCAs *x509.CertPool) *httptest.Server {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client := ""
+		if len(r.TLS.PeerCertificates) > 0 {
+			client = r.TLS.PeerCertificates[0].Subject.CommonName
+		}
+		fmt.Fprintf(w, `{"server_name": %q, "client": %q}`, r.TLS.ServerName, client)
+	}))
+	if clientCAs != nil {
+		server.TLS = &tls.Config{
+			ClientAuth: tls.RequireAndVerifyClientCert,
+			ClientCAs:  clientCAs,
+		}
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRequestVerifyWithCABundle(t *testing.T) {
+	server := newTLSServer(t, nil)
+	projectDir := newProjectDir(t, map[string][]byte{
+		"ca.pem": pemEncode("CERTIFICATE", server.Certificate().Raw),
+	})
+	newConfig := func() *hrp.TConfig {
+		config := hrp.NewConfig("tls").SetBaseURL(server.URL).SetVerifySSL(true)
+		config.Path = projectDir
+		return config
+	}
+
+	// verification is enforced, certificate signed by unknown authority
+	_, err := runConfigStep(t, hrp.NewRunner(nil), newConfig(), hrp.NewStep("verify").GET("/"))
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "certificate")
+	}
+
+	// verify with custom CA bundle
+	step := hrp.NewStep("verify with ca").GET("/").
+		Validate().
+		AssertEqual("status_code", 200, "check status code")
+	_, err = runConfigStep(t, hrp.NewRunner(t),
+		newConfig().SetTLS(&hrp.RequestTLS{CACert: "ca.pem"}), step)
+	assert.Nil(t, err)
+
+	// override SNI, test certificate is valid for example.com
+	step = hrp.NewStep("verify with server name").GET("/").
+		SetTLS(&hrp.RequestTLS{ServerName: "example.com"}).
+		Validate().
+		AssertEqual("body.server_name", "example.com", "check sni")
+	_, err = runConfigStep(t, hrp.NewRunner(t),
+		newConfig().SetTLS(&hrp.RequestTLS{CACert: "ca.pem"}), step)
+	assert.Nil(t, err)
+
+	_, err = runConfigStep(t, hrp.NewRunner(nil),
+		newConfig().SetTLS(&hrp.RequestTLS{CACert: "ca.pem", ServerName: "other.com"}),
+		hrp.NewStep("verify with invalid server name").GET("/"))
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "other.com")
+	}
+
+	// step disables verification explicitly
+	step = hrp.NewStep("skip verify").GET("/").SetVerify(false).
+		Validate().
+		AssertEqual("status_code", 200, "check status code")
+	_, err = runConfigStep(t, hrp.NewRunner(t), newConfig(), step)
+	assert.Nil(t, err)
+}
+
+// newCACert generates self-signed CA certificate which signs nothing
+func newCACert(t *testing.T) []byte {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(3),
+		Subject:               pkix.Name{CommonName: "hrp other ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pemEncode("CERTIFICATE", der)
+}
+
+func TestRequestVerifyEnabledByTLSSettings(t *testing.T) {
+	server := newTLSServer(t, nil)
+	projectDir := newProjectDir(t, map[string][]byte{
+		"other_ca.pem": newCACert(t),
+	})
+	newConfig := func() *hrp.TConfig {
+		config := hrp.NewConfig("tls").SetBaseURL(server.URL)
+		config.Path = projectDir
+		return config
+	}
+
+	// CA bundle without verify enabled, server certificate does not chain to it
+	_, err := runConfigStep(t, hrp.NewRunner(nil),
+		newConfig().SetTLS(&hrp.RequestTLS{CACert: "other_ca.pem"}),
+		hrp.NewStep("ca only").GET("/"))
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "certificate signed by unknown authority")
+	}
+
+	// CA bundle could not be bypassed by disabling verification
+	_, err = runConfigStep(t, hrp.NewRunner(nil),
+		newConfig().SetTLS(&hrp.RequestTLS{CACert: "other_ca.pem"}),
+		hrp.NewStep("ca with skip verify").GET("/").SetVerify(false))
+	assert.NotNil(t, err)
+
+	// server name only also enables verification
+	_, err = runConfigStep(t, hrp.NewRunner(nil), newConfig(),
+		hrp.NewStep("server name only").GET("/").
+			SetTLS(&hrp.RequestTLS{ServerName: "example.com"}))
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "certificate signed by unknown authority")
+	}
+
+	// other TLS settings could disable verification explicitly
+	step := hrp.NewStep("server name with skip verify").GET("/").
+		SetTLS(&hrp.RequestTLS{ServerName: "example.com"}).
+		Validate().
+		AssertEqual("body.server_name", "example.com", "check sni")
+	_, err = runConfigStep(t, hrp.NewRunner(t), newConfig().SetVerifySSL(false), step)
+	assert.Nil(t, err)
+
+	// no TLS settings, shared transport skips verification as before
+	_, err = runConfigStep(t, hrp.NewRunner(t), newConfig(), hrp.NewStep("default").GET("/"))
+	assert.Nil(t, err)
+}
+
+func TestRequestClientCertificate(t *testing.T) {
+	caPool, certPEM, keyPEM := newClientCert(t)
+	server := newTLSServer(t, caPool)
+	projectDir := newProjectDir(t, map[string][]byte{
+		"client.pem": certPEM,
+		"client.key": keyPEM,
+		"ca.pem":     pemEncode("CERTIFICATE", server.Certificate().Raw),
+	})
+	config := hrp.NewConfig("mtls").SetBaseURL(server.URL)
+	config.Path = projectDir
+
+	_, err := runConfigStep(t, hrp.NewRunner(nil), config, hrp.NewStep("without client cert").GET("/"))
+	assert.NotNil(t, err)
+
+	step := hrp.NewStep("with client cert").GET("/").
+		SetTLS(&hrp.RequestTLS{CACert: "ca.pem", Cert: "client.pem", Key: "client.key"}).
+		Validate().
+		AssertEqual("body.client", "hrp client", "check client certificate")
+	_, err = runConfigStep(t, hrp.NewRunner(t), config, step)
+	assert.Nil(t, err)
+}
+
+func newRedirectServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var hops int
+		if _, err := fmt.Sscanf(r.URL.Path, "/redirect/%d", &hops); err == nil {
+			location := "/final"
+			if hops > 1 {
+				location = fmt.Sprintf("/redirect/%d", hops-1)
+			}
+			http.Redirect(w, r, location, http.StatusFound)
+			return
+		}
+		fmt.Fprintf(w, `{"path": %q}`, r.URL.Path)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRequestRedirects(t *testing.T) {
+	server := newRedirectServer(t)
+
+	step := hrp.NewStep("follow redirects").
+		GET("/redirect/3").
+		Validate().
+		AssertEqual("status_code", 200, "check status code").
+		AssertEqual("body.path", "/final", "check final path").
+		AssertLengthEqual("redirects", 3, "check redirect chain").
+		AssertEqual("redirects[0].status_code", 302, "check redirect status").
+		AssertEqual("redirects[0].location", "/redirect/2", "check redirect location").
+		AssertEqual("redirects[2].url", server.URL+"/redirect/1", "check redirect url")
+	_, err := runConfigStep(t, hrp.NewRunner(t), hrp.NewConfig("redirects").SetBaseURL(server.URL), step)
+	assert.Nil(t, err)
+
+	// testcase config disables redirects
+	step = hrp.NewStep("disable redirects").
+		GET("/redirect/3").
+		Validate().
+		AssertEqual("status_code", 302, "check status code").
+		AssertEqual(`headers.Location`, "/redirect/2", "check location").
+		AssertLengthEqual("redirects", 0, "check redirect chain")
+	_, err = runConfigStep(t, hrp.NewRunner(t),
+		hrp.NewConfig("redirects").SetBaseURL(server.URL).SetAllowRedirects(false, 0), step)
+	assert.Nil(t, err)
+
+	// step overrides testcase config
+	step = hrp.NewStep("enable redirects").
+		GET("/redirect/1").
+		SetAllowRedirects(true).
+		Validate().
+		AssertEqual("status_code", 200, "check status code")
+	_, err = runConfigStep(t, hrp.NewRunner(t),
+		hrp.NewConfig("redirects").SetBaseURL(server.URL).SetAllowRedirects(false, 0), step)
+	assert.Nil(t, err)
+
+	// exceed max redirect hops
+	_, err = runConfigStep(t, hrp.NewRunner(nil), hrp.NewConfig("redirects").SetBaseURL(server.URL),
+		hrp.NewStep("too many redirects").GET("/redirect/3").SetMaxRedirects(2))
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "stopped after 2 redirects")
+	}
+}
+
+func TestRequestProxies(t *testing.T) {
+	// HTTP proxy receives requests with absolute URI
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"proxied": %q}`, r.URL.String())
+	}))
+	defer proxy.Close()
+
+	step := hrp.NewStep("request via proxy").
+		GET("http://upstream.hrp.invalid/get").
+		SetProxies(map[string]string{"http": "$proxy_url"}).
+		Validate().
+		AssertEqual("body.proxied", "http://upstream.hrp.invalid/get/", "check proxied url")
+	_, err := runConfigStep(t, hrp.NewRunner(t),
+		hrp.NewConfig("proxies").WithVariables(map[string]interface{}{"proxy_url": proxy.URL}), step)
+	assert.Nil(t, err)
+
+	// proxy configured in testcase config
+	step = hrp.NewStep("request via config proxy").
+		GET("http://upstream.hrp.invalid/get").
+		Validate().
+		AssertEqual("status_code", 200, "check status code")
+	_, err = runConfigStep(t, hrp.NewRunner(t),
+		hrp.NewConfig("proxies").SetProxies(map[string]string{"all": proxy.URL}), step)
+	assert.Nil(t, err)
+}
+
+var digestParamRegexp = regexp.MustCompile(`(\w+)=("[^"]*"|[^,]*)`)
+
+// newAuthServer checks basic, bearer and digest auth with user hrp and password secret
+func newAuthServer(t *testing.T) *httptest.Server {
+	md5Hex := func(s string) string {
+		sum := md5.Sum([]byte(s))
+		return hex.EncodeToString(sum[:])
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization := r.Header.Get("Authorization")
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/basic"):
+			if user, password, ok := r.BasicAuth(); ok && user == "hrp" && password == "secret" {
+				fmt.Fprint(w, `{"auth": "basic"}`)
+				return
+			}
+		case strings.HasPrefix(r.URL.Path, "/bearer"):
+			if authorization == "Bearer token123" {
+				fmt.Fprint(w, `{"auth": "bearer"}`)
+				return
+			}
+		case strings.HasPrefix(r.URL.Path, "/digest"):
+			params := map[string]string{}
+			for _, m := range digestParamRegexp.FindAllStringSubmatch(strings.TrimPrefix(authorization, "Digest "), -1) {
+				params[m[1]] = strings.Trim(m[2], `"`)
+			}
+			ha1 := md5Hex("hrp:test:secret")
+			ha2 := md5Hex(r.Method + ":" + params["uri"])
+			expected := md5Hex(strings.Join([]string{ha1, "n0nce", params["nc"], params["cnonce"], "auth", ha2}, ":"))
+			if strings.HasPrefix(authorization, "Digest ") && params["response"] == expected && params["opaque"] == "op" {
+				fmt.Fprint(w, `{"auth": "digest"}`)
+				return
+			}
+			w.Header().Set("WWW-Authenticate", `Digest realm="test", qop="auth,auth-int", nonce="n0nce", opaque="op"`)
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRequestAuth(t *testing.T) {
+	server := newAuthServer(t)
+	config := func() *hrp.TConfig {
+		return hrp.NewConfig("auth").SetBaseURL(server.URL).
+			WithVariables(map[string]interface{}{"user": "hrp", "password": "secret"})
+	}
+
+	// auth configured in testcase config
+	step := hrp.NewStep("basic auth").
+		GET("/basic").
+		Validate().
+		AssertEqual("body.auth", "basic", "check basic auth")
+	_, err := runConfigStep(t, hrp.NewRunner(t),
+		config().SetAuth(&hrp.HTTPAuth{Type: hrp.HTTPAuthBasic, Username: "$user", Password: "$password"}), step)
+	assert.Nil(t, err)
+
+	// step overrides testcase config
+	step = hrp.NewStep("bearer auth").
+		GET("/bearer").
+		SetAuth(map[string]string{"type": "bearer", "token": "token123"}).
+		Validate().
+		AssertEqual("body.auth", "bearer", "check bearer auth")
+	_, err = runConfigStep(t, hrp.NewRunner(t),
+		config().SetAuth(&hrp.HTTPAuth{Type: hrp.HTTPAuthBasic, Username: "$user", Password: "$password"}), step)
+	assert.Nil(t, err)
+
+	// digest auth with request body resent after challenge
+	step = hrp.NewStep("digest auth").
+		POST("/digest").
+		SetAuth(map[string]string{"type": "digest", "username": "$user", "password": "$password"}).
+		WithBody(map[string]interface{}{"foo": "bar"}).
+		Validate().
+		AssertEqual("status_code", 200, "check status code").
+		AssertEqual("body.auth", "digest", "check digest auth")
+	_, err = runConfigStep(t, hrp.NewRunner(t), config(), step)
+	assert.Nil(t, err)
+
+	step = hrp.NewStep("digest auth with wrong password").
+		GET("/digest").
+		SetAuth(map[string]string{"type": "digest", "username": "$user", "password": "wrong"}).
+		Validate().
+		AssertEqual("status_code", 401, "check status code")
+	_, err = runConfigStep(t, hrp.NewRunner(t), config(), step)
+	assert.Nil(t, err)
+}
+
+func TestRequestTransportLoadFromJSON(t *testing.T) {
+	tlsServer := newTLSServer(t, nil)
+	redirectServer := newRedirectServer(t)
+	authServer := newAuthServer(t)
+	projectDir := newProjectDir(t, map[string][]byte{
+		"ca.pem": pemEncode("CERTIFICATE", tlsServer.Certificate().Raw),
+	})
+
+	content := `{
+	"config": {
+		"name": "transport",
+		"verify": true,
+		"tls": {"ca_cert": "ca.pem"},
+		"allow_redirects": false,
+		"auth": {"type": "basic", "username": "hrp", "password": "secret"}
+	},
+	"teststeps": [
+		{
+			"name": "verify with ca",
+			"request": {"method": "GET", "url": "` + tlsServer.URL + `/"},
+			"validate": [{"eq": ["status_code", 200]}]
+		},
+		{
+			"name": "redirects disabled",
+			"request": {"method": "GET", "url": "` + redirectServer.URL + `/redirect/2"},
+			"validate": [{"eq": ["status_code", 302]}]
+		},
+		{
+			"name": "redirects enabled by step",
+			"request": {"method": "GET", "url": "` + redirectServer.URL + `/redirect/2", "allow_redirects": true, "max_redirects": 5},
+			"validate": [{"eq": ["status_code", 200]}, {"len_eq": ["redirects", 2]}]
+		},
+		{
+			"name": "basic auth from config",
+			"request": {"method": "GET", "url": "` + authServer.URL + `/basic"},
+			"validate": [{"eq": ["body.auth", "basic"]}]
+		},
+		{
+			"name": "digest auth",
+			"request": {"method": "GET", "url": "` + authServer.URL + `/digest", "auth": {"type": "digest", "username": "hrp", "password": "secret"}},
+			"validate": [{"eq": ["body.auth", "digest"]}]
+		}
+	]
+}`
+	_, err := runJSONTestCase(t, projectDir, content)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,158 @@
+package tests
+
+import (
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+
+	hrp "github.com/httprunner/httprunner/v5"
+)
+
+// otlpCollector is a local OTLP/HTTP collector stand-in
+type otlpCollector struct {
+	mutex   sync.Mutex
+	spans   map[string]string // span name -> trace id
+	metrics []string
+}
+
+func (c *otlpCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	switch r.URL.Path {
+	case "/v1/traces":
+		var req collectortrace.ExportTraceServiceRequest
+		if err := proto.Unmarshal(body, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, resourceSpans := range req.ResourceSpans {
+			for _, scopeSpans := range resourceSpans.ScopeSpans {
+				for _, span := range scopeSpans.Spans {
+					c.spans[span.Name] = hex.EncodeToString(span.TraceId)
+				}
+			}
+		}
+	case "/v1/metrics":
+		var req collectormetrics.ExportMetricsServiceRequest
+		if err := proto.Unmarshal(body, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, resourceMetrics := range req.ResourceMetrics {
+			for _, scopeMetrics := range resourceMetrics.ScopeMetrics {
+				for _, metric := range scopeMetrics.Metrics {
+					c.metrics = append(c.metrics, metric.Name)
+				}
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "application/x-protobuf")
+}
+
+func TestTelemetryExport(t *testing.T) {
+	collector := &otlpCollector{spans: make(map[string]string)}
+	collectorServer := httptest.NewServer(collector)
+	defer collectorServer.Close()
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	recorder := &eventRecorder{}
+	testcase := &hrp.TestCase{
+		Config: hrp.NewConfig("telemetry demo").SetBaseURL(server.URL),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("get traced").
+				GET("/traced").
+				Validate().
+				AssertEqual("status_code", 200, "check status code"),
+		},
+	}
+	err := hrp.NewRunner(t).
+		SetHTTPStatOn().
+		SetTelemetry(collectorServer.URL).
+		AddListeners(hrp.RunEventHandler(recorder.handle)).
+		Run(testcase)
+	require.Nil(t, err)
+
+	// traceparent: version-traceid-spanid-flags
+	parts := strings.Split(traceparent, "-")
+	require.Len(t, parts, 4)
+	traceID := parts[1]
+
+	var stepResult *hrp.StepResult
+	for _, event := range recorder.events {
+		if event.Type == hrp.RunEventStepEnd {
+			stepResult = event.Data.(*hrp.StepResult)
+		}
+	}
+	require.NotNil(t, stepResult)
+	assert.Equal(t, traceID, stepResult.TraceID)
+
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	assert.Equal(t, traceID, collector.spans["telemetry demo"])
+	assert.Equal(t, traceID, collector.spans["get traced"])
+	assert.Contains(t, collector.metrics, "hrp.step.duration")
+	assert.Contains(t, collector.metrics, "hrp.step.count")
+}
+
+func TestTelemetryShutdownError(t *testing.T) {
+	// collector rejects all exports
+	collectorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "rejected", http.StatusBadRequest)
+	}))
+	defer collectorServer.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	testcase := &hrp.TestCase{
+		Config: hrp.NewConfig("telemetry rejected").SetBaseURL(server.URL),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("get").GET("/"),
+		},
+	}
+	err := hrp.NewRunner(t).
+		SetTelemetry(collectorServer.URL).
+		Run(testcase)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "shutdown")
+}
+
+func TestTelemetryDisabled(t *testing.T) {
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer server.Close()
+
+	testcase := hrp.TestCase{
+		Config: hrp.NewConfig("no telemetry").SetBaseURL(server.URL),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("get").GET("/"),
+		},
+	}
+	caseRunner, err := hrp.NewCaseRunner(testcase, hrp.NewRunner(t))
+	require.Nil(t, err)
+	summary, err := caseRunner.NewSession().Start(nil)
+	require.Nil(t, err)
+	assert.Empty(t, traceparent)
+	assert.Empty(t, summary.Records[0].TraceID)
+}
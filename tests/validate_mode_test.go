@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	hrp "github.com/httprunner/httprunner/v5"
+)
+
+func newUserServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":2,"name":"leo","role":"guest"}`))
+	}))
+}
+
+func newValidateStep() *hrp.StepRequestWithOptionalArgs {
+	return hrp.NewStep("get user").GET("/user")
+}
+
+func getValidationResults(t *testing.T, stepResult *hrp.StepResult) []*hrp.ValidationResult {
+	sessionData, ok := stepResult.Data.(*hrp.SessionData)
+	if !assert.True(t, ok) {
+		t.FailNow()
+	}
+	return sessionData.Validators
+}
+
+func TestValidateModeAll(t *testing.T) {
+	server := newUserServer()
+	defer server.Close()
+
+	step := newValidateStep().
+		SetValidateMode(hrp.ValidateModeAll).
+		Validate().
+		AssertEqual("status_code", 200, "check status code").
+		AssertEqual("body.id", 1, "check user id").
+		AssertEqual("body.name", "leo", "check user name").
+		AssertEqual("body.role", "admin", "check user role")
+
+	stepResult, err := runSingleStep(t, hrp.NewRunner(nil), server.URL, step)
+	if !assert.NotNil(t, err) {
+		t.FailNow()
+	}
+	assert.False(t, stepResult.Success)
+	assert.Contains(t, err.Error(), "2 of 4 validators failed")
+	assert.Contains(t, err.Error(), "body.id equals 1, got 2 (check user id)")
+	assert.Contains(t, err.Error(), "body.role equals admin, got guest (check user role)")
+
+	results := getValidationResults(t, stepResult)
+	if assert.Len(t, results, 4) {
+		assert.Equal(t, "pass", results[0].CheckResult)
+		assert.Equal(t, "fail", results[1].CheckResult)
+		assert.Equal(t, "pass", results[2].CheckResult)
+		assert.Equal(t, "fail", results[3].CheckResult)
+	}
+}
+
+func TestValidateModeFromConfig(t *testing.T) {
+	server := newUserServer()
+	defer server.Close()
+
+	step := newValidateStep().
+		Validate().
+		AssertEqual("body.id", 1, "check user id").
+		AssertEqual("body.role", "admin", "check user role")
+
+	testcase := hrp.TestCase{
+		Config: hrp.NewConfig("validate mode").
+			SetBaseURL(server.URL).
+			SetValidateMode(hrp.ValidateModeAll),
+		TestSteps: []hrp.IStep{step},
+	}
+	caseRunner, err := hrp.NewCaseRunner(testcase, hrp.NewRunner(nil))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	summary, err := caseRunner.NewSession().Start(nil)
+	assert.NotNil(t, err)
+	if assert.Len(t, summary.Records, 1) {
+		assert.Len(t, getValidationResults(t, summary.Records[0]), 2)
+	}
+}
+
+func TestValidateModeFirst(t *testing.T) {
+	server := newUserServer()
+	defer server.Close()
+
+	step := newValidateStep().
+		Validate().
+		AssertEqual("body.id", 1, "check user id").
+		AssertEqual("body.role", "admin", "check user role")
+
+	stepResult, err := runSingleStep(t, hrp.NewRunner(nil), server.URL, step)
+	if !assert.NotNil(t, err) {
+		t.FailNow()
+	}
+	assert.Contains(t, err.Error(), "step validation failed")
+	assert.NotContains(t, err.Error(), "validators failed")
+	assert.Len(t, getValidationResults(t, stepResult), 1)
+}
+
+func TestValidateLiteralWithProtocolFieldTags(t *testing.T) {
+	server := newUserServer()
+	defer server.Close()
+
+	// field tags of other protocols are not searched in http response
+	step := hrp.NewStep("get user").
+		WithVariables(map[string]interface{}{"state": "processing_topic", "status": "processing_result"}).
+		GET("/user").
+		Validate().
+		AssertEqual("$state", "processing_topic", "check literal from variable").
+		AssertEqual("$status", "processing_result", "check literal from variable").
+		AssertEqual("trailers", "trailers", "check literal").
+		AssertEqual("events", "events", "check literal").
+		AssertEqual("message_count", "message_count", "check literal").
+		AssertEqual("frame_size", "frame_size", "check literal").
+		AssertEqual("qos", "qos", "check literal").
+		AssertEqual("retained", "retained", "check literal").
+		AssertEqual("rows", "rows", "check literal").
+		AssertEqual("last_insert_id", "last_insert_id", "check literal").
+		AssertEqual("channel", "channel", "check literal")
+
+	stepResult, err := runSingleStep(t, hrp.NewRunner(t), server.URL, step)
+	assert.Nil(t, err)
+	assert.True(t, stepResult.Success)
+}
@@ -0,0 +1,264 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	hrp "github.com/httprunner/httprunner/v5"
+)
+
+// newWSFeedServer pushes two messages and closes the first connection remotely,
+// the following connections push another two messages and then echo.
+func newWSFeedServer(connections *int64) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if atomic.AddInt64(connections, 1) == 1 {
+			_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"seq": 1}`))
+			_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"seq": 2}`))
+			_ = conn.WriteMessage(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, "restart"))
+			return
+		}
+		_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"seq": 3}`))
+		_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"event": "done"}`))
+		for {
+			messageType, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(messageType, message); err != nil {
+				return
+			}
+		}
+	}))
+}
+
+func TestWebSocketSubscribe(t *testing.T) {
+	var connections int64
+	server := newWSFeedServer(&connections)
+	defer server.Close()
+
+	testcase := &hrp.TestCase{
+		Config: hrp.NewConfig("websocket subscription").
+			WithVariables(map[string]interface{}{
+				"feedURL": "ws" + strings.TrimPrefix(server.URL, "http") + "/feed",
+			}).
+			SetWebSocket(3, 50, 0, 0),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("subscribe feed").
+				WebSocket().
+				Subscribe("$feedURL"),
+			hrp.NewStep("wait for done event").
+				WebSocket().
+				WaitMessage("$feedURL").
+				WaitUntil("body.event", "equals", "done").
+				WithTimeout(5000).
+				Validate().
+				AssertEqual("message_type", "text", "check message type"),
+			hrp.NewStep("check collected messages").
+				WebSocket().
+				Messages("$feedURL").
+				Validate().
+				AssertEqual("message_count", 4, "check messages count").
+				AssertEqual("reconnections", 1, "check reconnections").
+				AssertEqual("body[0].seq", 1, "check first message").
+				AssertEqual("body[2].seq", 3, "check message after reconnection"),
+			hrp.NewStep("write after reconnection").
+				WebSocket().
+				Write("$feedURL").
+				WithTextMessage("hello world"),
+			hrp.NewStep("wait for echo").
+				WebSocket().
+				WaitMessage("$feedURL").
+				WaitMatch(`^hello \w+$`).
+				WithTimeout(5000).
+				Validate().
+				AssertEqual("body", "hello world", "check echo message"),
+			hrp.NewStep("close feed").
+				WebSocket().
+				CloseConnection("$feedURL").
+				WithTimeout(5000),
+		},
+	}
+	caseRunner, err := hrp.NewCaseRunner(*testcase, hrp.NewRunner(t))
+	require.Nil(t, err)
+	session := caseRunner.NewSession()
+	defer session.ReleaseResources()
+	summary, err := session.Start(nil)
+	require.Nil(t, err)
+	assert.True(t, summary.Success)
+	assert.Equal(t, int64(2), atomic.LoadInt64(&connections))
+}
+
+func TestWebSocketReadReconnect(t *testing.T) {
+	var connections int64
+	server := newWSFeedServer(&connections)
+	defer server.Close()
+
+	readStep := func(name string, seq int) hrp.IStep {
+		return hrp.NewStep(name).
+			WebSocket().
+			Read("$feedURL").
+			WithTimeout(5000).
+			Validate().
+			AssertEqual("body.seq", seq, "check message seq")
+	}
+	testcase := &hrp.TestCase{
+		Config: hrp.NewConfig("websocket reconnection").
+			WithVariables(map[string]interface{}{
+				"feedURL": "ws" + strings.TrimPrefix(server.URL, "http") + "/feed",
+			}).
+			SetWebSocket(3, 50, 0, 0),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("open feed").
+				WebSocket().
+				OpenConnection("$feedURL"),
+			readStep("read first", 1),
+			readStep("read second", 2),
+			// connection is closed by server and reconnected
+			readStep("read after reconnection", 3),
+		},
+	}
+	err := hrp.NewRunner(t).Run(testcase)
+	require.Nil(t, err)
+	assert.Equal(t, int64(2), atomic.LoadInt64(&connections))
+}
+
+func TestWebSocketConcurrentReconnect(t *testing.T) {
+	var connections int64
+	server := newWSFeedServer(&connections)
+	defer server.Close()
+
+	testcase := &hrp.TestCase{
+		Config: hrp.NewConfig("websocket concurrent reconnection").
+			WithVariables(map[string]interface{}{
+				"feedURL": "ws" + strings.TrimPrefix(server.URL, "http") + "/feed",
+			}).
+			SetWebSocket(3, 300, 0, 0),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("subscribe feed").
+				WebSocket().
+				Subscribe("$feedURL"),
+			hrp.NewStep("wait for last message").
+				WebSocket().
+				WaitMessage("$feedURL").
+				WaitUntil("body.seq", "equals", 2).
+				WithTimeout(5000),
+			hrp.NewStep("sleep").SetThinkTime(0.05),
+			// subscription is reconnecting, write waits for it and reuses the new connection
+			hrp.NewStep("write during reconnection").
+				WebSocket().
+				Write("$feedURL").
+				WithTextMessage("hello world"),
+			hrp.NewStep("wait for echo").
+				WebSocket().
+				WaitMessage("$feedURL").
+				WaitMatch(`^hello \w+$`).
+				WithTimeout(5000),
+			hrp.NewStep("check collected messages").
+				WebSocket().
+				Messages("$feedURL").
+				Validate().
+				AssertEqual("reconnections", 1, "check reconnections"),
+		},
+	}
+	caseRunner, err := hrp.NewCaseRunner(*testcase, hrp.NewRunner(t))
+	require.Nil(t, err)
+	session := caseRunner.NewSession()
+	defer session.ReleaseResources()
+	summary, err := session.Start(nil)
+	require.Nil(t, err)
+	assert.True(t, summary.Success)
+	assert.Equal(t, int64(2), atomic.LoadInt64(&connections))
+}
+
+func TestWebSocketNoReconnectAfterRelease(t *testing.T) {
+	// server keeps connections open without sending messages
+	var connections int64
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		atomic.AddInt64(&connections, 1)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	testcase := &hrp.TestCase{
+		Config: hrp.NewConfig("websocket read timeout").
+			WithVariables(map[string]interface{}{
+				"feedURL": "ws" + strings.TrimPrefix(server.URL, "http") + "/feed",
+			}).
+			SetWebSocket(3, 50, 0, 0),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("open feed").
+				WebSocket().
+				OpenConnection("$feedURL"),
+			hrp.NewStep("read timeout").
+				WebSocket().
+				Read("$feedURL").
+				WithTimeout(100),
+		},
+	}
+	caseRunner, err := hrp.NewCaseRunner(*testcase, hrp.NewRunner(t))
+	require.Nil(t, err)
+	session := caseRunner.NewSession()
+	_, err = session.Start(nil)
+	require.NotNil(t, err)
+
+	// resources are released when session ends, read left by the timed out step
+	// fails on locally closed connection, which is not reconnected
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&connections))
+}
+
+func TestWebSocketWaitTimeout(t *testing.T) {
+	var connections int64
+	server := newWSFeedServer(&connections)
+	defer server.Close()
+
+	testcase := &hrp.TestCase{
+		Config: hrp.NewConfig("websocket wait timeout").
+			WithVariables(map[string]interface{}{
+				"feedURL": "ws" + strings.TrimPrefix(server.URL, "http") + "/feed",
+			}).
+			SetWebSocket(3, 50, 0, 0),
+		TestSteps: []hrp.IStep{
+			hrp.NewStep("subscribe feed").
+				WebSocket().
+				Subscribe("$feedURL"),
+			hrp.NewStep("wait for unknown event").
+				WebSocket().
+				WaitMessage("$feedURL").
+				WaitUntil("body.event", "equals", "unknown").
+				WithTimeout(200),
+		},
+	}
+	start := time.Now()
+	err := hrp.NewRunner(nil).Run(testcase)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "no message matches until condition")
+	assert.Contains(t, err.Error(), "wait timeout")
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Equal(t, int64(2), atomic.LoadInt64(&connections))
+}